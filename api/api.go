@@ -6,21 +6,21 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"image"
 	"io"
 	"log/slog"
-	"os"
-	"path"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/dtbead/moonpool/entry"
 	mdb "github.com/dtbead/moonpool/internal/db"
 	"github.com/dtbead/moonpool/internal/db/archive"
+	"github.com/dtbead/moonpool/internal/db/migration"
 	"github.com/dtbead/moonpool/internal/db/thumbnail"
 	"github.com/dtbead/moonpool/internal/file"
 	"github.com/dtbead/moonpool/internal/log"
 	"github.com/dtbead/moonpool/internal/media"
+	"github.com/dtbead/moonpool/internal/storage"
 )
 
 const (
@@ -38,16 +38,29 @@ const (
 type Orientation int
 
 var (
-	ErrThumbnailNotFound = errors.New("thumbnail not found")
-	ErrDuplicateEntry    = errors.New("duplicate entry")
+	ErrThumbnailNotFound  = errors.New("thumbnail not found")
+	ErrPreviewNotFound    = errors.New("preview not found")
+	ErrNotAnimated        = errors.New("entry is not animated")
+	ErrNotVideo           = errors.New("entry is not a video")
+	ErrWebPlayable        = errors.New("entry is already web-playable")
+	ErrTranscodeFormat    = errors.New("invalid transcode format")
+	ErrStoryboardNotFound = errors.New("storyboard not found")
+	ErrDuplicateEntry     = errors.New("duplicate entry")
+	ErrNoPerceptualHash   = errors.New("entry has no perceptual hash")
+	ErrNoExif             = errors.New("entry has no exif metadata")
+	ErrUnknownHashType    = file.ErrUnknownHashType
 )
 
 type API struct {
 	log       slog.Logger
 	archive   archive.Archiver
 	thumbnail thumbnail.Thumbnailer
+	storage   storage.Storage
+	layout    file.Layout
 	Config    Config
 	db        *sql.DB // db provides low-level access to main moonpool database
+
+	hashIndexes *hashIndexCache
 }
 
 type WithTX struct {
@@ -58,16 +71,42 @@ type WithTX struct {
 
 type Config struct {
 	ArchiveLocation, ThumbnailLocation, MediaLocation string
+	// PerceptualHashTypes are generated when no specific hash type is requested. If empty, every type
+	// in file.PerceptualHashTypes is generated.
+	PerceptualHashTypes []string
+	// VideoHashFrames is the amount of frames sampled from a video for its file.VHash. If 0 or less,
+	// media.DefaultVideoFrames is used.
+	VideoHashFrames int
+	// JobMaxAttempts is the amount of times a job is attempted before it is marked as failed. If 0 or less,
+	// DefaultJobMaxAttempts is used.
+	JobMaxAttempts int
+	// ThumbnailSizes are the bounding boxes thumbnails are scaled to fit in. Sizes of 0 or less use
+	// media.DefaultThumbnailSizes.
+	ThumbnailSizes media.ThumbnailSizes
+	// ThumbnailFormats are encoded alongside the JPEG thumbnails every entry has, e.g. "webp" or "avif".
+	ThumbnailFormats []string
+	// AnimatedPreviews enables generating a short animated preview of every video and animated GIF.
+	AnimatedPreviews bool
+	// StoryboardFrames is the amount of frames in the storyboard of a video. If 0 or less,
+	// media.DefaultStoryboardFrames is used.
+	StoryboardFrames int
+	// TranscodeFormat is the format of renditions of videos web browsers can't play, one of
+	// media.TranscodeFormats. If empty, videos aren't transcoded.
+	TranscodeFormat string
+	// Storage selects where media files are stored. By default, they're stored in MediaLocation.
+	Storage storage.Config
+	// Layout is the layout media is stored in by a new archive. An archive keeps the layout it was created with
+	// until it's moved by Relayout. If empty, file.DefaultLayout is used.
+	Layout file.Layout
 }
 
 type Importer interface {
 	FileData() io.Reader
 	Timestamp() entry.Timestamp
 	Hash() entry.Hashes
-	Path() string
 	Extension() string
 	FileSize() int
-	Store(baseDirectory string) (path string, err error)
+	Store(ctx context.Context, s storage.Storage, key string) error
 }
 
 func New(c Config, l *slog.Logger) (*API, error) {
@@ -102,6 +141,11 @@ func New(c Config, l *slog.Logger) (*API, error) {
 
 	c.MediaLocation = cleanPath(c.MediaLocation)
 
+	s, err := storage.Open(c.Storage, c.MediaLocation)
+	if err != nil {
+		return nil, err
+	}
+
 	archive := archive.NewArchiver(archive.New(a), a)
 	thumbnail := thumbnail.NewThumbnailer(thumbnail.New(t), t)
 
@@ -111,19 +155,30 @@ func New(c Config, l *slog.Logger) (*API, error) {
 		return &API{}, err
 	}
 
-	if err := mdb.InitializeThumbnail(a); err != nil {
+	if err := mdb.InitializeThumbnail(t); err != nil {
 		a.Close()
 		t.Close()
 		return &API{}, err
 	}
 
-	return &API{
+	moonpool := &API{
 		log:       *l,
 		archive:   archive,
 		thumbnail: thumbnail,
+		storage:   s,
 		Config:    c,
 		db:        a,
-	}, nil
+
+		hashIndexes: newHashIndexCache(),
+	}
+
+	if err := moonpool.initLayout(context.Background()); err != nil {
+		a.Close()
+		t.Close()
+		return &API{}, err
+	}
+
+	return moonpool, nil
 }
 
 func Open(c Config, l *slog.Logger) (*API, error) {
@@ -141,10 +196,18 @@ func Open(c Config, l *slog.Logger) (*API, error) {
 		return &API{}, errors.New("archive db path does not exist")
 	}
 
-	if !file.DoesPathExist(c.MediaLocation) || c.MediaLocation == "" {
-		return &API{}, errors.New("media path does not exist")
+	if t := strings.ToLower(c.Storage.Type); t == "" || t == "local" {
+		if !file.DoesPathExist(c.MediaLocation) || c.MediaLocation == "" {
+			return &API{}, errors.New("media path does not exist")
+		}
 	}
 
+	s, err := storage.Open(c.Storage, c.MediaLocation)
+	if err != nil {
+		return &API{}, err
+	}
+	moonpool.storage = s
+
 	if c.ThumbnailLocation != "" {
 		if !file.DoesPathExist(c.ThumbnailLocation) {
 			return &API{}, errors.New("thumbnail db path does not exist")
@@ -155,6 +218,13 @@ func Open(c Config, l *slog.Logger) (*API, error) {
 			return &API{}, err
 		}
 
+		applied, err := mdb.MigrateThumbnail(context.Background(), t, migration.LATEST, false)
+		if err != nil {
+			t.Close()
+			return &API{}, fmt.Errorf("failed to migrate thumbnail db, %w", err)
+		}
+		logMigrations(l, "thumbnail", applied)
+
 		moonpool.thumbnail = thumbnail.NewThumbnailer(thumbnail.New(t), t)
 	}
 
@@ -163,14 +233,67 @@ func Open(c Config, l *slog.Logger) (*API, error) {
 		return &API{}, err
 	}
 
+	applied, err := mdb.MigrateArchive(context.Background(), a, migration.LATEST, false)
+	if err != nil {
+		a.Close()
+		return &API{}, fmt.Errorf("failed to migrate archive db, %w", err)
+	}
+	logMigrations(l, "archive", applied)
+
 	moonpool.db = a
 	moonpool.archive = archive.NewArchiver(archive.New(a), a)
 	moonpool.log = *l
 	moonpool.Config = c
+	moonpool.hashIndexes = newHashIndexCache()
+
+	if err := moonpool.initLayout(context.Background()); err != nil {
+		a.Close()
+		return &API{}, err
+	}
 
 	return moonpool, nil
 }
 
+// initLayout loads the layout media is stored in, recording Config.Layout as the layout of a new archive.
+func (a *API) initLayout(ctx context.Context) error {
+	configured := a.Config.Layout
+	if configured == (file.Layout{}) {
+		configured = file.DefaultLayout
+	}
+
+	l, err := a.archive.GetLayout(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := configured.Validate(); err != nil {
+			return err
+		}
+		if err := a.archive.SetLayout(ctx, configured); err != nil {
+			return err
+		}
+		l = configured
+	} else if err != nil {
+		return err
+	}
+	a.layout = l
+
+	if a.Config.Layout != (file.Layout{}) && a.Config.Layout != l {
+		a.log.LogAttrs(ctx, log.LogLevelWarn,
+			"media is stored in layout '"+l.String()+"' rather than the configured '"+a.Config.Layout.String()+
+				"'. run 'moonpool archive relayout' to move it",
+			slog.String("layout", l.String()),
+			slog.String("configured_layout", a.Config.Layout.String()))
+	}
+	return nil
+}
+
+func logMigrations(l *slog.Logger, database string, applied []migration.Migrator) {
+	for _, m := range applied {
+		l.LogAttrs(context.Background(), log.LogLevelInfo, "applied "+database+" migration "+m.String(),
+			slog.String("database", database),
+			slog.Int64("version", m.Version),
+			slog.String("direction", m.Direction()))
+	}
+}
+
 // Close manually runs a SQL checkpoint and closes the API connection. Calling Close()
 // will implicitly close the sql.DB connection as well
 func (a *API) Close(ctx context.Context) error {
@@ -190,6 +313,22 @@ func (a *API) Close(ctx context.Context) error {
 	return nil
 }
 
+// Conn returns a copy of the API whose archive queries all run on a single connection, along with a function
+// releasing the connection. Savepoints only apply to the connection they were created on, so a single
+// goroutine writing within savepoints should use Conn, rather than the connection pool of the API. The copy
+// must not be used once released.
+func (a *API) Conn(ctx context.Context) (*API, func() error, error) {
+	c, err := a.db.Conn(ctx)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to get db connection", slog.Any("error", err))
+		return nil, nil, err
+	}
+
+	pinned := *a
+	pinned.archive = a.archive.WithConn(c)
+	return &pinned, c.Close, nil
+}
+
 func (a *API) BeginTX(ctx context.Context) (apiTX WithTX, err error) {
 	q, tx, err := a.archive.NewTx(ctx, nil)
 	if err != nil {
@@ -240,8 +379,13 @@ func (a *API) Import(ctx context.Context, i Importer) (archive_id int64, err err
 			slog.String("sha1", byteToHex(h.SHA1[:])),
 			slog.String("SHA256", byteToHex(h.SHA256[:]))))
 
+	entryPath, err := a.layout.Path(h, i.Extension())
+	if err != nil {
+		return -1, err
+	}
+
 	// add new database entry
-	archive_id, err = a.archive.NewEntry(ctx, file.BuildPath(h.MD5[:], i.Extension()), i.Extension())
+	archive_id, err = a.archive.NewEntry(ctx, entryPath, i.Extension())
 	if err != nil && archive.IsErrorConstraint(err) {
 		return -1, ErrDuplicateEntry
 	}
@@ -249,7 +393,7 @@ func (a *API) Import(ctx context.Context, i Importer) (archive_id int64, err err
 		a.log.LogAttrs(ctx, log.LogLevelError, "failed to create new entry",
 			slog.Any("error", err),
 			slog.Group("media",
-				slog.String("path", file.BuildPath(h.MD5[:], i.Extension())),
+				slog.String("path", entryPath),
 				slog.String("extension", i.Extension()),
 				slog.Group("hashes",
 					slog.String("md5", byteToHex(h.MD5[:])),
@@ -267,9 +411,9 @@ func (a *API) Import(ctx context.Context, i Importer) (archive_id int64, err err
 	}
 
 	// assign timestamps to entry
-	err = a.SetTimestamps(ctx, archive_id, entry.Timestamp{
-		DateImported: time.Now(),
-	})
+	t := i.Timestamp()
+	t.DateImported = time.Now()
+	err = a.SetTimestamps(ctx, archive_id, t)
 	if err != nil {
 		a.log.LogAttrs(ctx, log.LogLevelWarn, "failed to set timestamps", slog.Any("error", err))
 		return -1, err
@@ -286,7 +430,7 @@ func (a *API) Import(ctx context.Context, i Importer) (archive_id int64, err err
 		a.log.LogAttrs(ctx, log.LogLevelError, "failed to set metadata",
 			slog.Any("error", err),
 			slog.Group("media",
-				slog.String("path", file.BuildPath(h.MD5[:], i.Extension())),
+				slog.String("path", entryPath),
 				slog.String("extension", i.Extension()),
 				slog.Group("hashes",
 					slog.String("md5", byteToHex(h.MD5[:])),
@@ -296,20 +440,25 @@ func (a *API) Import(ctx context.Context, i Importer) (archive_id int64, err err
 		return -1, err
 	}
 
-	// copy file to moonpool managed folder
-	switch a.Config.MediaLocation {
-	default:
-		a.log.LogAttrs(ctx, log.LogLevelInfo, "copying media to "+a.Config.MediaLocation)
-	case "":
+	// copy file to moonpool managed storage
+	if l, ok := a.storage.(storage.Local); ok && a.Config.MediaLocation == "" {
 		a.log.LogAttrs(ctx, log.LogLevelWarn, "config had no path to store media to. copying media to current directory instead")
+	} else if ok {
+		a.log.LogAttrs(ctx, log.LogLevelInfo, "copying media to "+l.Path(""))
 	}
 
-	entryPath, err := i.Store(a.Config.MediaLocation)
-	if err != nil {
-		a.log.LogAttrs(ctx, log.LogLevelError, "failed to store media to %s"+entryPath, slog.Any("error", err))
+	if err := i.Store(ctx, a.storage, entryPath); err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to store media to "+entryPath, slog.Any("error", err))
 		return -1, err
 	}
 
+	// a broken exif should not keep a file from being imported
+	if err := a.setExif(ctx, archive_id, entryPath, true); err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelWarn, "failed to read exif of archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id))
+	}
+
 	// import
 	err = finalizeImport()
 	if err != nil {
@@ -418,7 +567,12 @@ func (a *API) GetTimestamps(ctx context.Context, archive_id int64) (entry.Timest
 }
 
 func (a *API) GetFile(ctx context.Context, archive_id int64) (io.ReadCloser, error) {
-	rc, err := a.archive.GetFile(ctx, archive_id, a.Config.MediaLocation)
+	e, err := a.archive.GetEntry(ctx, archive_id)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := a.storage.Get(ctx, e.Path)
 	if err != nil {
 		a.log.LogAttrs(ctx, log.LogLevelError, "failed to fetch media file for archive_id "+int64ToString(archive_id), slog.Any("error", err),
 			slog.Int64("archive_id", archive_id),
@@ -453,14 +607,36 @@ func (a *API) GetRelativePath(ctx context.Context, archive_id int64) (string, er
 	return archive.Path, nil
 }
 
-// GetAbsolutePath takes an archive_id and returns an absolute filepath that points to a given entry
+// GetAbsolutePath takes an archive_id and returns an absolute filepath that points to a given entry.
+// GetAbsolutePath returns an error if media isn't stored on the local filesystem.
 func (a *API) GetAbsolutePath(ctx context.Context, archive_id int64) (string, error) {
+	l, ok := a.storage.(storage.Local)
+	if !ok {
+		return "", errors.New("media is not stored on the local filesystem")
+	}
+
 	archive, err := a.archive.GetEntry(ctx, archive_id)
 	if err != nil {
 		return "", err
 	}
 
-	return file.CleanPath(a.Config.MediaLocation + "/" + archive.Path), nil
+	return l.Path(archive.Path), nil
+}
+
+// localPath returns a path on the local filesystem to the media file of an entry, for tools such as ffmpeg.
+// release must be called once the path is no longer used.
+func (a *API) localPath(ctx context.Context, archive_id int64) (path string, release func(), err error) {
+	archive, err := a.archive.GetEntry(ctx, archive_id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return storage.LocalPath(ctx, a.storage, archive.Path)
+}
+
+// Storage returns the Storage media files are stored in.
+func (a *API) Storage() storage.Storage {
+	return a.storage
 }
 
 // GetPage returns a list of archives within a given range. Valid sort options are
@@ -483,6 +659,8 @@ func (a *API) GetFileMetadata(ctx context.Context, archive_id int64) (entry.File
 }
 
 // GenerateFileMetadata automatically generates and sets a given archive_id metadata according to entry.FileMetadata.
+// Audio entries have no dimensions, and an orientation of "none". The streams of videos and audio are probed
+// for their duration, framerate, codecs and bitrate.
 func (a *API) GenerateFileMetadata(ctx context.Context, archive_id int64) error {
 	rd, err := a.GetFile(ctx, archive_id)
 	if err != nil {
@@ -490,19 +668,55 @@ func (a *API) GenerateFileMetadata(ctx context.Context, archive_id int64) error
 	}
 	defer rd.Close()
 
-	metadata := entry.FileMetadata{}
-	d, err := media.GetDimensions(rd)
+	mediaType, err := a.mediaType(ctx, archive_id)
 	if err != nil {
 		return err
 	}
-	metadata.MediaHeight = int64(d.Height)
-	metadata.MediaWidth = int64(d.Width)
 
-	o, err := OrientationToString(getOrientation(int64(d.Width), int64(d.Height)))
-	if err != nil {
-		return err
+	metadata := entry.FileMetadata{}
+	if mediaType == "audio" {
+		metadata.MediaOrientation, _ = OrientationToString(NONE)
+	} else {
+		d, err := media.GetDimensions(rd)
+		if err != nil {
+			return err
+		}
+
+		if mediaType == "image" {
+			orientation, err := a.exifOrientation(ctx, archive_id)
+			if err != nil {
+				return err
+			}
+			d.Width, d.Height = media.OrientDimensions(d.Width, d.Height, orientation)
+		}
+		metadata.MediaHeight = int64(d.Height)
+		metadata.MediaWidth = int64(d.Width)
+
+		o, err := OrientationToString(getOrientation(int64(d.Width), int64(d.Height)))
+		if err != nil {
+			return err
+		}
+		metadata.MediaOrientation = o
+	}
+
+	if mediaType == "video" || mediaType == "audio" {
+		path, release, err := a.localPath(ctx, archive_id)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		m, err := media.ProbeStreams(path)
+		if err != nil {
+			return err
+		}
+		metadata.MediaDuration = time.Duration(m.Duration * float64(time.Second))
+		metadata.MediaFramerate = m.Framerate
+		metadata.MediaVideoCodec = m.VideoCodec
+		metadata.MediaAudioCodec = m.AudioCodec
+		metadata.MediaBitrate = m.Bitrate
+		metadata.MediaHasAudio = m.HasAudio
 	}
-	metadata.MediaOrientation = o
 
 	s, err := file.GetSize(rd)
 	if err != nil {
@@ -524,6 +738,100 @@ func (a *API) GenerateFileMetadata(ctx context.Context, archive_id int64) error
 	return a.archive.SetFileMetadata(ctx, archive_id, metadata)
 }
 
+// GetExif returns the capture metadata embedded in an entry. It returns ErrNoExif if the entry has none.
+func (a *API) GetExif(ctx context.Context, archive_id int64) (entry.Exif, error) {
+	e, err := a.archive.GetExif(ctx, archive_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entry.Exif{}, ErrNoExif
+	}
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to get exif of archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id))
+		return entry.Exif{}, err
+	}
+
+	return e, nil
+}
+
+// exifOrientation returns the EXIF orientation of an entry, or media.OrientationNormal if it has none.
+func (a *API) exifOrientation(ctx context.Context, archive_id int64) (int64, error) {
+	e, err := a.GetExif(ctx, archive_id)
+	if errors.Is(err, ErrNoExif) || (err == nil && e.Orientation == 0) {
+		return media.OrientationNormal, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return e.Orientation, nil
+}
+
+// GenerateExif reads the EXIF and XMP metadata embedded in an entry. Its creation date is only set to the
+// date it was captured when it has none, so a date edited by hand is kept. Entries without any are left untouched.
+// Entries imported before their metadata was read are queued through BackfillJobs with JobMetadata.
+func (a *API) GenerateExif(ctx context.Context, archive_id int64) error {
+	path, err := a.GetRelativePath(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+
+	return a.setExif(ctx, archive_id, path, false)
+}
+
+// setExif reads the EXIF and XMP metadata of the stored file at key into archive_id. The capture date of a
+// photo is preferred over the date its file was created, which usually is only the date it was copied.
+// Unless overwriteDate is set, an existing creation date is left as is.
+func (a *API) setExif(ctx context.Context, archive_id int64, key string, overwriteDate bool) error {
+	f, err := a.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	e, err := media.ReadExif(f)
+	if errors.Is(err, media.ErrNoExif) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := a.archive.SetExif(ctx, archive_id, e); err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to set exif of archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id))
+		return err
+	}
+
+	if e.DateTimeOriginal.IsZero() {
+		return nil
+	}
+
+	if !overwriteDate {
+		t, err := a.GetTimestamps(ctx, archive_id)
+		if err != nil {
+			return err
+		}
+		if !t.DateCreated.IsZero() && t.DateCreated.UnixMilli() != 0 {
+			return nil
+		}
+	}
+	return a.SetTimestamps(ctx, archive_id, entry.Timestamp{DateCreated: e.DateTimeOriginal})
+}
+
+// mediaType returns the top-level media type of an entry, such as "image", "video" or "audio", according
+// to its extension.
+func (a *API) mediaType(ctx context.Context, archive_id int64) (string, error) {
+	e, err := a.GetEntry(ctx, archive_id)
+	if err != nil {
+		return "", err
+	}
+
+	mediaType, _, _ := strings.Cut(file.GetMimeTypeByExtension(e.Extension), "/")
+	return mediaType, nil
+}
+
 // GetMostRecentArchiveID gets the the most recently imported archive_id.
 func (a *API) GetMostRecentArchiveID(ctx context.Context) (int64, error) {
 	ctxChild, cancel := context.WithTimeout(ctx, time.Millisecond*200)
@@ -558,74 +866,216 @@ func (a *API) GetMostRecentArchiveID(ctx context.Context) (int64, error) {
 	}
 }
 
-// hashType is unused
-func (a *API) GetPerceptualHash(ctx context.Context, archive_id int64, hashType string) (uint64, error) {
-	phash, err := a.archive.GetPerceptualHash(ctx, archive_id, "PHash")
+// GetPerceptualHash returns a single perceptual hash of an entry. An empty hashType returns the PHash.
+// See file.PerceptualHashTypes for every valid hashType.
+func (a *API) GetPerceptualHash(ctx context.Context, archive_id int64, hashType string) (entry.PerceptualHash, error) {
+	if hashType == "" {
+		hashType = file.PHash
+	}
+
+	if file.PerceptualHashBits(hashType) == 0 {
+		return entry.PerceptualHash{}, ErrUnknownHashType
+	}
+
+	phash, err := a.archive.GetPerceptualHash(ctx, archive_id, hashType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entry.PerceptualHash{}, ErrNoPerceptualHash
+	}
 	if err != nil {
 		a.log.LogAttrs(ctx, log.LogLevelError,
 			"failed to fetch perceptual hash for archive_id "+int64ToString(archive_id),
-			slog.Any("error", err))
-		return 0, err
+			slog.Any("error", err),
+			slog.String("hash_type", hashType))
+		return entry.PerceptualHash{}, err
 	}
 	return phash, nil
 }
 
-// hashType is unused
+// GetPerceptualHashes returns every type of perceptual hash stored for an entry, ordered by type.
+func (a *API) GetPerceptualHashes(ctx context.Context, archive_id int64) ([]entry.PerceptualHash, error) {
+	hashes, err := a.archive.GetPerceptualHashesByArchiveID(ctx, archive_id)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError,
+			"failed to fetch perceptual hashes for archive_id "+int64ToString(archive_id),
+			slog.Any("error", err))
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// GeneratePerceptualHash generates and stores a single type of perceptual hash. An empty hashType generates
+// every type in Config.PerceptualHashTypes instead.
 func (a *API) GeneratePerceptualHash(ctx context.Context, archive_id int64, hashType string, r io.Reader) error {
+	if hashType == "" {
+		return a.GeneratePerceptualHashes(ctx, archive_id, r, a.perceptualHashTypes()...)
+	}
+	return a.GeneratePerceptualHashes(ctx, archive_id, r, hashType)
+}
+
+// GeneratePerceptualHashes decodes an image once and generates every given type of perceptual hash from it,
+// replacing any existing hash of the same type.
+func (a *API) GeneratePerceptualHashes(ctx context.Context, archive_id int64, r io.Reader, hashTypes ...string) error {
 	if r == nil {
 		return errors.New("given nil io.Reader")
 	}
 
-	i, _, err := image.Decode(r)
+	for _, hashType := range hashTypes {
+		if file.PerceptualHashBits(hashType) == 0 {
+			return ErrUnknownHashType
+		}
+	}
+
+	i, err := media.DecodeImage(r)
 	if err != nil {
 		a.log.LogAttrs(ctx, log.LogLevelError,
 			"failed to decode image for perceptual hash generation on archive_id "+int64ToString(archive_id),
 			slog.Any("error", err),
-			slog.String("hash_type", hashType),
+			slog.Any("hash_types", hashTypes),
 		)
 		return err
 	}
 
-	hash, err := file.GetPerceptualHash(i)
+	orientation, err := a.exifOrientation(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+
+	hashes, err := file.GetPerceptualHashes(media.ApplyOrientation(i, orientation), hashTypes...)
 	if err != nil {
 		a.log.LogAttrs(ctx, log.LogLevelError, "failed to generate perceptual hash on archive_id "+int64ToString(archive_id),
 			slog.Any("error", err))
 		return err
 	}
 
-	a.log.LogAttrs(ctx, log.LogLevelVerbose,
-		fmt.Sprintf("generated perceptual hash as '%d' for archive_id %d", hash.Hash, archive_id),
-		slog.Int64("archive_id", archive_id),
-		slog.Any("perceptual hash", hash))
+	return a.setPerceptualHashes(ctx, archive_id, hashes)
+}
+
+// GenerateVideoPerceptualHash samples Config.VideoHashFrames evenly-spaced frames from a video entry and stores
+// their fingerprint as a file.VHash. Every type in Config.PerceptualHashTypes is also generated from the middle
+// frame, so videos can be compared against images.
+func (a *API) GenerateVideoPerceptualHash(ctx context.Context, archive_id int64) error {
+	path, release, err := a.localPath(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	n := a.Config.VideoHashFrames
+	if n <= 0 {
+		n = media.DefaultVideoFrames
+	}
 
-	if err := a.archive.SetPerceptualHash(ctx, archive_id, hash.Type, hash.Hash); err != nil {
+	frames, err := media.ExtractVideoFrames(path, n)
+	if err != nil {
 		a.log.LogAttrs(ctx, log.LogLevelError,
-			"failed to set perceptual hash on archive_id "+int64ToString(archive_id),
+			"failed to extract video frames for perceptual hash generation on archive_id "+int64ToString(archive_id),
+			slog.Any("error", err))
+		return err
+	}
+
+	hashes, err := file.GetPerceptualHashes(frames[len(frames)/2], a.perceptualHashTypes()...)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to generate perceptual hash on archive_id "+int64ToString(archive_id),
+			slog.Any("error", err))
+		return err
+	}
+
+	fingerprint, err := media.VideoFingerprint(frames)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to generate video fingerprint on archive_id "+int64ToString(archive_id),
 			slog.Any("error", err))
 		return err
 	}
 
+	hashes = append(hashes, file.PerceptualHashes{Type: file.VHash, Hash: fingerprint[0], Extended: fingerprint})
+	return a.setPerceptualHashes(ctx, archive_id, hashes)
+}
+
+// setPerceptualHashes stores every given hash, replacing any existing hash of the same type.
+func (a *API) setPerceptualHashes(ctx context.Context, archive_id int64, hashes []file.PerceptualHashes) error {
+	for _, hash := range hashes {
+		a.log.LogAttrs(ctx, log.LogLevelVerbose,
+			fmt.Sprintf("generated %s as '%x' for archive_id %d", hash.Type, hash.Words(), archive_id),
+			slog.Int64("archive_id", archive_id),
+			slog.Any("perceptual hash", hash))
+
+		if err := a.archive.SetPerceptualHash(ctx, archive_id, hash.Type, hash.Words()); err != nil {
+			a.log.LogAttrs(ctx, log.LogLevelError,
+				"failed to set perceptual hash on archive_id "+int64ToString(archive_id),
+				slog.Any("error", err),
+				slog.String("hash_type", hash.Type))
+			return err
+		}
+		a.updateHashIndex(ctx, archive_id, hash.Type, hash.Words())
+	}
+
 	return nil
 }
 
+// perceptualHashTypes returns the hash types generated by default.
+func (a *API) perceptualHashTypes() []string {
+	if len(a.Config.PerceptualHashTypes) == 0 {
+		return file.PerceptualHashTypes
+	}
+	return a.Config.PerceptualHashTypes
+}
+
 // GetThumbnail returns the thumbnail data from a given entry. Valid sizes are "small", "medium", and "large".
 // Valid formats are "jpeg"
 func (a *API) GetThumbnail(ctx context.Context, archive_id int64, size, format string) ([]byte, error) {
+	data, err := a.getThumbnail(ctx, archive_id, size, format)
+	if errors.Is(err, ErrThumbnailNotFound) {
+		a.log.LogAttrs(ctx, log.LogLevelWarn, "missing thumbnail for archive_id "+int64ToString(archive_id),
+			slog.Int64("archive_id", archive_id),
+			slog.String("thumbnail_size", size),
+			slog.String("thumbnail_format", format))
+	}
+
+	return data, err
+}
+
+// GetPreferredThumbnail returns the thumbnail of the first format in formats an entry has, along with that
+// format. JPEG is tried last if it is not in formats, since every entry with thumbnails has one.
+func (a *API) GetPreferredThumbnail(ctx context.Context, archive_id int64, size string, formats ...string) ([]byte, string, error) {
+	if !slices.Contains(formats, "jpeg") {
+		formats = append(formats, "jpeg")
+	}
+
+	for _, format := range formats {
+		data, err := a.getThumbnail(ctx, archive_id, size, format)
+		if errors.Is(err, ErrThumbnailNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		return data, format, nil
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelWarn, "missing thumbnail for archive_id "+int64ToString(archive_id),
+		slog.Int64("archive_id", archive_id),
+		slog.String("thumbnail_size", size),
+		slog.Any("thumbnail_formats", formats))
+	return nil, "", ErrThumbnailNotFound
+}
+
+func (a *API) getThumbnail(ctx context.Context, archive_id int64, size, format string) ([]byte, error) {
 	var data []byte
 	var err error
 
 	switch format {
 	case "jpeg":
 		data, err = a.thumbnail.GetJpeg(ctx, archive_id, size)
+	case "webp":
+		data, err = a.thumbnail.GetWebp(ctx, archive_id, size)
+	case "avif":
+		data, err = a.thumbnail.GetAvif(ctx, archive_id, size)
 	default:
 		return nil, errors.New("unknown format")
 	}
 
 	if errors.Is(err, sql.ErrNoRows) {
-		a.log.LogAttrs(ctx, log.LogLevelWarn, "missing thumbnail for archive_id "+int64ToString(archive_id),
-			slog.Int64("archive_id", archive_id),
-			slog.String("thumbnail_size", size),
-			slog.String("thumbnail_format", format))
 		return nil, ErrThumbnailNotFound
 	}
 
@@ -652,6 +1102,12 @@ func (a *API) RemoveArchive(ctx context.Context, archive_id int64) error {
 		return err
 	}
 
+	// renditions are removed alongside their entry, so they have to be fetched first
+	renditions, err := a.archive.GetRenditions(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+
 	if err := a.archive.DeleteEntry(ctx, archive_id); err != nil {
 		return err
 	}
@@ -664,26 +1120,24 @@ func (a *API) RemoveArchive(ctx context.Context, archive_id int64) error {
 		)
 	}
 
-	fullPath := a.Config.MediaLocation + "/" + entry.Path
-	baseDirectory := path.Dir(fullPath)
-	if err := os.Remove(fullPath); err != nil {
+	if err := a.storage.Delete(ctx, entry.Path); err != nil {
 		return err
 	}
 
-	if file.IsDirectoryEmpty(baseDirectory) {
-		if err := os.Remove(baseDirectory); err != nil {
+	for _, r := range renditions {
+		if err := a.storage.Delete(ctx, r.Path); err != nil {
 			a.log.LogAttrs(ctx, log.LogLevelWarn,
-				fmt.Sprintf("failed to remove empty media directory at '%s', %v", baseDirectory, err),
+				fmt.Sprintf("failed to remove rendition at '%s', %v", r.Path, err),
 				slog.Any("error", err),
 				slog.Int64("archive_id", archive_id),
 			)
-			return err
 		}
 	}
 
 	if err := a.archive.ReleaseSavepoint(ctx, "remove"); err != nil {
 		return err
 	}
+	a.dropHashIndexes()
 
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/dtbead/moonpool/entry"
 	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/storage"
 )
 
 type mockEntry struct {
@@ -33,8 +34,11 @@ func newMockEntry() mockEntry {
 		SHA256: randomBytes(32),
 	}
 
+	// mock entries are stored in the layout of new archives
+	path, _ := file.DefaultLayout.Path(h, ".png")
+
 	return mockEntry{
-		PathRelative:  file.BuildPath(h.MD5[:], ".png"),
+		PathRelative:  path,
 		PathExtension: ".png",
 		Hashes:        h,
 	}
@@ -44,10 +48,6 @@ func (m mockEntry) Hash() entry.Hashes {
 	return m.Hashes
 }
 
-func (m mockEntry) Path() string {
-	return m.PathRelative
-}
-
 func (m mockEntry) Extension() string {
 	return m.PathExtension
 }
@@ -83,8 +83,8 @@ func (m *mockEntry) CloseFile() {
 }
 
 // empty method
-func (m mockEntry) Store(baseDirectory string) (path string, err error) {
-	return "", nil
+func (m mockEntry) Store(ctx context.Context, s storage.Storage, key string) error {
+	return nil
 }
 
 // empty method
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"image"
 	"io"
 	"os"
 	"reflect"
@@ -86,7 +89,7 @@ func TestAPI_Import(t *testing.T) {
 				t.Errorf("API.Import() error on getting entry. %v", err)
 			}
 
-			validPath := fmt.Sprintf("%s/%s%s", byteToHex(hashes.Md5[:1]), byteToHex(hashes.Md5), tt.args.i.Extension())
+			validPath := fmt.Sprintf("%s/%s/%s%s", byteToHex(hashes.Sha256[:1]), byteToHex(hashes.Sha256[1:2]), byteToHex(hashes.Sha256), tt.args.i.Extension())
 			if validPath != entry.Path {
 				t.Errorf("API.Import() path = %v, want %v", entry.Path, validPath)
 			}
@@ -138,7 +141,7 @@ func TestAPI_Import_Multiple(t *testing.T) {
 					t.Errorf("API.Import() error on getting entry. %v", err)
 				}
 
-				validPath := fmt.Sprintf("%s/%s%s", byteToHex(hashes.Md5[:1]), byteToHex(hashes.Md5), mockEntry.Extension())
+				validPath := fmt.Sprintf("%s/%s/%s%s", byteToHex(hashes.Sha256[:1]), byteToHex(hashes.Sha256[1:2]), byteToHex(hashes.Sha256), mockEntry.Extension())
 				if validPath != entry.Path {
 					t.Errorf("API.Import() path = %v, want %v", entry.Path, validPath)
 				}
@@ -148,6 +151,171 @@ func TestAPI_Import_Multiple(t *testing.T) {
 	}
 }
 
+func TestAPI_Import_Exif(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jpg, err := os.ReadFile("testdata/6ba11adbdb35ee10f9353608a7b97ef248733a72.jpg")
+	if err != nil {
+		t.Fatalf("failed to open test file. %v\n", err)
+	}
+
+	png := encodeTestPNG(t, image.NewGray(image.Rect(0, 0, 8, 8)))
+
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		b         []byte
+		extension string
+		want      entry.Exif
+		wantErr   error
+	}{
+		{"exif", jpg, ".jpg", entry.Exif{
+			DateTimeOriginal: time.Date(2009, 5, 19, 17, 14, 14, 0, time.Local),
+			Make:             "NIKON CORPORATION",
+			Model:            "NIKON D2Xs",
+			Lens:             "17.0-35.0 mm f/2.8",
+			Orientation:      1,
+		}, nil},
+		{"no exif", png, ".png", entry.Exif{}, ErrNoExif},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := t.TempDir() + "/image" + tt.extension
+			if err := os.WriteFile(path, tt.b, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			i, err := importer.New(f, tt.extension)
+			if err != nil {
+				t.Fatalf("importer.New() failed to create new entry. %v", err)
+			}
+
+			archive_id, err := mockAPI.Import(ctx, i)
+			if err != nil {
+				t.Fatalf("API.Import() error = %v", err)
+			}
+
+			got, err := mockAPI.GetExif(ctx, archive_id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("API.GetExif() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.DateTimeOriginal.Equal(tt.want.DateTimeOriginal) {
+				t.Errorf("API.GetExif() DateTimeOriginal = %v, want %v", got.DateTimeOriginal, tt.want.DateTimeOriginal)
+			}
+			got.DateTimeOriginal, tt.want.DateTimeOriginal = time.Time{}, time.Time{}
+			if got != tt.want {
+				t.Errorf("API.GetExif() = %+v, want %+v", got, tt.want)
+			}
+
+			ts, err := mockAPI.GetTimestamps(ctx, archive_id)
+			if err != nil {
+				t.Fatalf("API.GetTimestamps() error = %v", err)
+			}
+
+			// without a capture date, the creation date of the file itself is kept
+			wantCreated := i.Timestamp().DateCreated
+			if tt.wantErr == nil {
+				wantCreated = time.Date(2009, 5, 19, 17, 14, 14, 0, time.Local)
+			}
+			if !ts.DateCreated.Equal(wantCreated.Truncate(time.Millisecond)) {
+				t.Errorf("API.Import() DateCreated = %v, want %v", ts.DateCreated, wantCreated)
+			}
+
+			// reading the metadata again keeps a creation date edited by hand
+			edited := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			if err := mockAPI.SetTimestamps(ctx, archive_id, entry.Timestamp{DateCreated: edited}); err != nil {
+				t.Fatal(err)
+			}
+			if err := mockAPI.GenerateExif(ctx, archive_id); err != nil {
+				t.Fatalf("API.GenerateExif() error = %v", err)
+			}
+
+			ts, err = mockAPI.GetTimestamps(ctx, archive_id)
+			if err != nil {
+				t.Fatalf("API.GetTimestamps() error = %v", err)
+			}
+			if !ts.DateCreated.Equal(edited) {
+				t.Errorf("API.GenerateExif() DateCreated = %v, want %v", ts.DateCreated, edited)
+			}
+		})
+	}
+}
+
+func TestAPI_GenerateFileMetadata_Orientation(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	tests := []struct {
+		name            string
+		orientation     int64
+		wantWidth       int64
+		wantHeight      int64
+		wantOrientation string
+	}{
+		{"no exif", 0, 40, 20, "landscape"},
+		{"normal", 1, 40, 20, "landscape"},
+		{"rotate 180", 3, 40, 20, "landscape"},
+		{"rotate 90", 6, 20, 40, "portrait"},
+		{"rotate 270", 8, 20, 40, "portrait"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// every entry needs unique content to be imported
+			img := image.NewGray(image.Rect(0, 0, 40, 20))
+			img.Pix[0] = uint8(tt.orientation)
+			archive_id := importTestImage(ctx, t, mockAPI, img)
+
+			if tt.orientation != 0 {
+				if err := mockAPI.archive.SetExif(ctx, archive_id, entry.Exif{Orientation: tt.orientation}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := mockAPI.GenerateFileMetadata(ctx, archive_id); err != nil {
+				t.Fatalf("API.GenerateFileMetadata() error = %v", err)
+			}
+
+			m, err := mockAPI.GetFileMetadata(ctx, archive_id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.MediaWidth != tt.wantWidth || m.MediaHeight != tt.wantHeight || m.MediaOrientation != tt.wantOrientation {
+				t.Errorf("API.GenerateFileMetadata() = %dx%d %s, want %dx%d %s",
+					m.MediaWidth, m.MediaHeight, m.MediaOrientation, tt.wantWidth, tt.wantHeight, tt.wantOrientation)
+			}
+
+			if err := mockAPI.GenerateThumbnail(ctx, archive_id); err != nil {
+				t.Fatalf("API.GenerateThumbnail() error = %v", err)
+			}
+
+			thumb, err := mockAPI.GetThumbnail(ctx, archive_id, "large", "jpeg")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (c.Width > c.Height) != (tt.wantWidth > tt.wantHeight) {
+				t.Errorf("API.GenerateThumbnail() = %dx%d, want %s", c.Width, c.Height, tt.wantOrientation)
+			}
+		})
+	}
+}
+
 func TestAPI_GetHashes(t *testing.T) {
 	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
 	if err != nil {
@@ -433,7 +601,7 @@ func TestAPI_GetFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			entry, err := importer.New(tt.args.file, ".png")
+			entry, err := importer.New(tt.args.file, ".jpg")
 			if err != nil {
 				t.Fatalf("importer.New() failed to create new entry. %v", err)
 			}
@@ -664,7 +832,7 @@ func TestAPI_GetAbsolutePath(t *testing.T) {
 		wantErr bool
 	}{
 		{"generic", mockAPI, args{context.Background(), archive_id},
-			tmpDir + "/" + file.BuildPath(entry.Hashes.MD5, entry.Extension()), false},
+			tmpDir + "/" + entry.PathRelative, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
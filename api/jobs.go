@@ -0,0 +1,441 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/db/archive"
+	"github.com/dtbead/moonpool/internal/log"
+)
+
+// Job types, each generating one kind of derived data for an entry.
+const (
+	JobMetadata       = "metadata"
+	JobThumbnail      = "thumbnail"
+	JobPerceptualHash = "phash"
+	JobBlurHash       = "blurhash"
+	JobPreview        = "preview"
+	JobStoryboard     = "storyboard"
+	JobTranscode      = "transcode"
+)
+
+// Job statuses. A job is pending until a worker claims it, and returns to pending after a failed attempt
+// until it runs out of attempts.
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+const (
+	// DefaultJobMaxAttempts is the amount of times a job is attempted before it is marked as failed.
+	DefaultJobMaxAttempts = 5
+	// JobRetryDelay is the delay before a failed job is attempted again. It doubles after every attempt,
+	// up to JobMaxRetryDelay.
+	JobRetryDelay    = 30 * time.Second
+	JobMaxRetryDelay = time.Hour
+	// JobPollInterval is how often an idle worker checks for new jobs.
+	JobPollInterval = time.Second
+)
+
+// JobTypes are every job type, in the order they are queued after an import. Blurhashes are generated
+// from thumbnails when available, so they are queued after them.
+var JobTypes = []string{JobMetadata, JobThumbnail, JobPerceptualHash, JobBlurHash, JobPreview, JobStoryboard, JobTranscode}
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrUnknownJobType = errors.New("unknown job type")
+	ErrJobDisabled    = errors.New("job type is disabled")
+	ErrJobNotFailed   = errors.New("only failed jobs can be retried")
+)
+
+// EnqueueJob queues a new job for an archive_id and returns its job_id. If the same type of job is
+// already pending, its job_id is returned instead. It returns ErrJobDisabled for a type of job that is
+// turned off in Config.
+func (a *API) EnqueueJob(ctx context.Context, archive_id int64, jobType string) (int64, error) {
+	if !slices.Contains(JobTypes, jobType) {
+		return -1, ErrUnknownJobType
+	}
+	if !a.jobEnabled(jobType) {
+		return -1, ErrJobDisabled
+	}
+
+	job_id, err := a.archive.NewJob(ctx, archive_id, jobType)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to queue "+jobType+" job for archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id))
+		return -1, err
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelVerbose, "queued "+jobType+" job "+int64ToString(job_id)+" for archive_id "+int64ToString(archive_id),
+		slog.Int64("archive_id", archive_id),
+		slog.Int64("job_id", job_id))
+
+	return job_id, nil
+}
+
+// EnqueueJobs queues every given type of job for an archive_id. If no types are given, every type in
+// JobTypes that is enabled is queued.
+func (a *API) EnqueueJobs(ctx context.Context, archive_id int64, jobTypes ...string) error {
+	if len(jobTypes) == 0 {
+		for _, jobType := range JobTypes {
+			if a.jobEnabled(jobType) {
+				jobTypes = append(jobTypes, jobType)
+			}
+		}
+	}
+
+	for _, jobType := range jobTypes {
+		if _, err := a.EnqueueJob(ctx, archive_id, jobType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackfillJobs queues a job of jobType for every entry that never had one, such as entries imported before
+// jobType existed or while it was disabled. If all is set, it is queued for every entry instead, such as
+// to read metadata added by a newer version of moonpool. It returns the amount of jobs queued.
+func (a *API) BackfillJobs(ctx context.Context, jobType string, all bool) (int64, error) {
+	if !slices.Contains(JobTypes, jobType) {
+		return 0, ErrUnknownJobType
+	}
+	if !a.jobEnabled(jobType) {
+		return 0, ErrJobDisabled
+	}
+
+	queued, err := a.archive.EnqueueJobs(ctx, jobType, all)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to queue "+jobType+" jobs",
+			slog.Any("error", err),
+			slog.Bool("all", all))
+		return 0, err
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelInfo, "queued "+int64ToString(queued)+" "+jobType+" jobs",
+		slog.Int64("queued", queued),
+		slog.Bool("all", all))
+	return queued, nil
+}
+
+func (a *API) GetJob(ctx context.Context, job_id int64) (entry.Job, error) {
+	j, err := a.archive.GetJob(ctx, job_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entry.Job{}, ErrJobNotFound
+	}
+
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to get job "+int64ToString(job_id),
+			slog.Any("error", err),
+			slog.Int64("job_id", job_id))
+		return entry.Job{}, err
+	}
+
+	return j, nil
+}
+
+// GetJobs returns jobs of a given status, newest first. An empty status returns jobs of any status.
+func (a *API) GetJobs(ctx context.Context, status string, limit, offset int64) ([]entry.Job, error) {
+	j, err := a.archive.GetJobs(ctx, status, limit, offset)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to get jobs",
+			slog.Any("error", err),
+			slog.String("status", status))
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// GetEntryJobs returns every job of an archive_id, oldest first.
+func (a *API) GetEntryJobs(ctx context.Context, archive_id int64) ([]entry.Job, error) {
+	j, err := a.archive.GetJobsByArchiveID(ctx, archive_id)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to get jobs for archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id))
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// CountJobs returns the amount of jobs of every status.
+func (a *API) CountJobs(ctx context.Context) (map[string]int64, error) {
+	counts, err := a.archive.CountJobs(ctx)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to count jobs",
+			slog.Any("error", err))
+		return nil, err
+	}
+
+	for _, status := range []string{JobPending, JobRunning, JobDone, JobFailed} {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
+	}
+	return counts, nil
+}
+
+// RetryJob queues a failed job again with its attempts reset.
+func (a *API) RetryJob(ctx context.Context, job_id int64) error {
+	j, err := a.GetJob(ctx, job_id)
+	if err != nil {
+		return err
+	}
+
+	if j.Status != JobFailed {
+		return ErrJobNotFailed
+	}
+
+	err = a.archive.RetryJob(ctx, job_id)
+	if archive.IsErrorConstraint(err) {
+		// the same type of job was queued again since this one failed
+		return nil
+	}
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to retry job "+int64ToString(job_id),
+			slog.Any("error", err),
+			slog.Int64("job_id", job_id))
+		return err
+	}
+
+	return nil
+}
+
+// ProcessNextJob claims and runs the oldest job that is ready to run. It returns false if no job was ready.
+// An error is only returned if the queue itself could not be accessed, a failing job is recorded and retried
+// with an exponential backoff until it runs out of attempts.
+func (a *API) ProcessNextJob(ctx context.Context) (bool, error) {
+	j, err := a.archive.ClaimJob(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to claim job",
+			slog.Any("error", err))
+		return false, err
+	}
+
+	start := time.Now()
+	jobErr := a.runJob(ctx, j)
+
+	status, message, runAfter := JobDone, "", time.Now()
+	switch {
+	case errors.Is(jobErr, ErrJobDisabled):
+		// left pending for when its type of job is turned on again, rather than running out of attempts
+		message = jobErr.Error()
+		status, runAfter = JobPending, time.Now().Add(JobMaxRetryDelay)
+	case jobErr != nil:
+		message = jobErr.Error()
+		status, runAfter = JobPending, time.Now().Add(jobBackoff(j.Attempts))
+		if j.Attempts >= a.jobMaxAttempts() {
+			status = JobFailed
+		}
+	}
+
+	err = a.archive.FinishJob(ctx, j.JobID, status, message, runAfter)
+	if archive.IsErrorConstraint(err) {
+		// the same type of job was queued again while this one was running, which makes retrying redundant
+		status = JobFailed
+		err = a.archive.FinishJob(ctx, j.JobID, status, message, runAfter)
+	}
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to update job "+int64ToString(j.JobID),
+			slog.Any("error", err),
+			slog.Int64("job_id", j.JobID))
+		return true, err
+	}
+
+	attrs := []slog.Attr{
+		slog.Int64("job_id", j.JobID),
+		slog.Int64("archive_id", j.ArchiveID),
+		slog.String("type", j.Type),
+		slog.Int64("attempt", j.Attempts),
+		slog.Duration("duration", time.Since(start)),
+	}
+
+	switch status {
+	case JobDone:
+		a.log.LogAttrs(ctx, log.LogLevelVerbose, "finished "+j.Type+" job for archive_id "+int64ToString(j.ArchiveID), attrs...)
+	case JobPending:
+		a.log.LogAttrs(ctx, log.LogLevelWarn, j.Type+" job for archive_id "+int64ToString(j.ArchiveID)+" failed, retrying later",
+			append(attrs, slog.Any("error", jobErr), slog.Time("run_after", runAfter))...)
+	case JobFailed:
+		a.log.LogAttrs(ctx, log.LogLevelError, j.Type+" job for archive_id "+int64ToString(j.ArchiveID)+" failed",
+			append(attrs, slog.Any("error", jobErr))...)
+	}
+
+	return true, nil
+}
+
+// RunJobWorkers processes queued jobs with a pool of workers until ctx is cancelled. Jobs left running by a
+// previous process are queued again first. If workers is less than 1, a single worker is used.
+func (a *API) RunJobWorkers(ctx context.Context, workers int) error {
+	reset, err := a.archive.ResetRunningJobs(ctx)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to reset running jobs",
+			slog.Any("error", err))
+		return err
+	}
+	if reset > 0 {
+		a.log.LogAttrs(ctx, log.LogLevelInfo, "queued "+int64ToString(reset)+" interrupted jobs again")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.jobWorker(ctx)
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// RunPendingJobs processes every job that is ready to run with a pool of workers, returning once none are left.
+func (a *API) RunPendingJobs(ctx context.Context, workers int) error {
+	var wg sync.WaitGroup
+	errs := make([]error, max(workers, 1))
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				ok, err := a.ProcessNextJob(ctx)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				if !ok {
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(append(errs, ctx.Err())...)
+}
+
+func (a *API) jobWorker(ctx context.Context) {
+	t := time.NewTicker(JobPollInterval)
+	defer t.Stop()
+
+	for {
+		ok, _ := a.ProcessNextJob(ctx)
+		if ok {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// jobEnabled reports whether a type of job is turned on in Config. Jobs that are turned off aren't queued,
+// so that BackfillJobs queues them once they are turned on.
+func (a *API) jobEnabled(jobType string) bool {
+	switch jobType {
+	case JobPreview:
+		return a.Config.AnimatedPreviews
+	case JobTranscode:
+		return a.Config.TranscodeFormat != ""
+	default:
+		return true
+	}
+}
+
+func (a *API) runJob(ctx context.Context, j entry.Job) error {
+	switch j.Type {
+	case JobMetadata:
+		// dimensions depend on the EXIF orientation, so it is read first
+		if err := a.GenerateExif(ctx, j.ArchiveID); err != nil {
+			return err
+		}
+		return a.GenerateFileMetadata(ctx, j.ArchiveID)
+	case JobThumbnail:
+		return a.GenerateThumbnail(ctx, j.ArchiveID)
+	case JobBlurHash:
+		return a.GenerateBlurHash(ctx, j.ArchiveID)
+	case JobPreview:
+		if !a.jobEnabled(j.Type) {
+			return ErrJobDisabled
+		}
+
+		err := a.GeneratePreview(ctx, j.ArchiveID)
+		if errors.Is(err, ErrNotAnimated) {
+			return nil
+		}
+		return err
+	case JobStoryboard:
+		err := a.GenerateStoryboard(ctx, j.ArchiveID)
+		if errors.Is(err, ErrNotVideo) {
+			return nil
+		}
+		return err
+	case JobTranscode:
+		if !a.jobEnabled(j.Type) {
+			return ErrJobDisabled
+		}
+
+		err := a.GenerateRendition(ctx, j.ArchiveID)
+		if errors.Is(err, ErrNotVideo) || errors.Is(err, ErrWebPlayable) {
+			return nil
+		}
+		return err
+	case JobPerceptualHash:
+		mediaType, err := a.mediaType(ctx, j.ArchiveID)
+		if err != nil {
+			return err
+		}
+
+		switch mediaType {
+		case "audio":
+			// audio has no picture to hash, a cover art is not representative of its content
+			return nil
+		case "video":
+			return a.GenerateVideoPerceptualHash(ctx, j.ArchiveID)
+		}
+
+		f, err := a.GetFile(ctx, j.ArchiveID)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return a.GeneratePerceptualHash(ctx, j.ArchiveID, "", f)
+	default:
+		return ErrUnknownJobType
+	}
+}
+
+func (a *API) jobMaxAttempts() int64 {
+	if a.Config.JobMaxAttempts <= 0 {
+		return DefaultJobMaxAttempts
+	}
+	return int64(a.Config.JobMaxAttempts)
+}
+
+// jobBackoff returns how long to wait before attempting a job again after it failed its n'th attempt.
+func jobBackoff(attempts int64) time.Duration {
+	d := JobRetryDelay
+	for i := int64(1); i < attempts && d < JobMaxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, JobMaxRetryDelay)
+}
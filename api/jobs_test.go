@@ -0,0 +1,391 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"image"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/importer"
+)
+
+func TestAPI_EnqueueJob(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 2, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		archive_id int64
+		jobType    string
+		wantErr    error
+	}{
+		{"thumbnail", archive_ids[0], JobThumbnail, nil},
+		{"phash", archive_ids[0], JobPerceptualHash, nil},
+		{"other entry", archive_ids[1], JobThumbnail, nil},
+		{"unknown type", archive_ids[0], "foo", ErrUnknownJobType},
+		{"disabled type", archive_ids[0], JobPreview, ErrJobDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job_id, err := mockAPI.EnqueueJob(ctx, tt.archive_id, tt.jobType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("API.EnqueueJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			again, err := mockAPI.EnqueueJob(ctx, tt.archive_id, tt.jobType)
+			if err != nil {
+				t.Fatalf("API.EnqueueJob() error = %v", err)
+			}
+			if again != job_id {
+				t.Errorf("API.EnqueueJob() queued a duplicate job %d, want existing job %d", again, job_id)
+			}
+
+			j, err := mockAPI.GetJob(ctx, job_id)
+			if err != nil {
+				t.Fatalf("API.GetJob() error = %v", err)
+			}
+			if j.ArchiveID != tt.archive_id || j.Type != tt.jobType || j.Status != JobPending || j.Attempts != 0 {
+				t.Errorf("API.GetJob() = %+v, want a pending %s job for archive_id %d", j, tt.jobType, tt.archive_id)
+			}
+		})
+	}
+
+	if err := mockAPI.EnqueueJobs(ctx, archive_ids[1]); err != nil {
+		t.Fatalf("API.EnqueueJobs() error = %v", err)
+	}
+
+	// previews and transcodes are turned off
+	enabled := len(JobTypes) - 2
+	jobs, err := mockAPI.GetEntryJobs(ctx, archive_ids[1])
+	if err != nil {
+		t.Fatalf("API.GetEntryJobs() error = %v", err)
+	}
+	if len(jobs) != enabled {
+		t.Errorf("API.GetEntryJobs() returned %d jobs, want %d", len(jobs), enabled)
+	}
+
+	counts, err := mockAPI.CountJobs(ctx)
+	if err != nil {
+		t.Fatalf("API.CountJobs() error = %v", err)
+	}
+	if counts[JobPending] != 2+int64(enabled) || counts[JobFailed] != 0 {
+		t.Errorf("API.CountJobs() = %v, want %d pending jobs", counts, 2+enabled)
+	}
+
+	if _, err := mockAPI.GetJob(ctx, 9999); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("API.GetJob() error = %v, want %v", err, ErrJobNotFound)
+	}
+}
+
+func TestAPI_ProcessNextJob_Retry(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir(), JobMaxAttempts: 2}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	// mock entries have no file, so every attempt fails
+	archive_ids, err := GenerateMockData(mockAPI, 1, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+
+	job_id, err := mockAPI.EnqueueJob(ctx, archive_ids[0], JobPerceptualHash)
+	if err != nil {
+		t.Fatalf("API.EnqueueJob() error = %v", err)
+	}
+
+	if ok, err := mockAPI.ProcessNextJob(ctx); !ok || err != nil {
+		t.Fatalf("API.ProcessNextJob() = %v, %v, want true, nil", ok, err)
+	}
+
+	j, err := mockAPI.GetJob(ctx, job_id)
+	if err != nil {
+		t.Fatalf("API.GetJob() error = %v", err)
+	}
+	if j.Status != JobPending || j.Attempts != 1 || j.Error == "" {
+		t.Errorf("API.GetJob() = %+v, want a pending job with 1 attempt and an error", j)
+	}
+	if d := time.Until(j.RunAfter); d < JobRetryDelay-time.Second || d > JobRetryDelay {
+		t.Errorf("job retries in %v, want %v", d, JobRetryDelay)
+	}
+
+	if ok, _ := mockAPI.ProcessNextJob(ctx); ok {
+		t.Errorf("API.ProcessNextJob() ran a job before its retry delay")
+	}
+
+	// skip the retry delay
+	if err := mockAPI.archive.FinishJob(ctx, job_id, JobPending, j.Error, time.Now()); err != nil {
+		t.Fatalf("failed to reset job, %v", err)
+	}
+
+	if ok, err := mockAPI.ProcessNextJob(ctx); !ok || err != nil {
+		t.Fatalf("API.ProcessNextJob() = %v, %v, want true, nil", ok, err)
+	}
+
+	j, err = mockAPI.GetJob(ctx, job_id)
+	if err != nil {
+		t.Fatalf("API.GetJob() error = %v", err)
+	}
+	if j.Status != JobFailed || j.Attempts != 2 {
+		t.Errorf("API.GetJob() = %+v, want a failed job with 2 attempts", j)
+	}
+
+	if err := mockAPI.RetryJob(ctx, job_id); err != nil {
+		t.Fatalf("API.RetryJob() error = %v", err)
+	}
+
+	j, err = mockAPI.GetJob(ctx, job_id)
+	if err != nil {
+		t.Fatalf("API.GetJob() error = %v", err)
+	}
+	if j.Status != JobPending || j.Attempts != 0 || j.Error != "" {
+		t.Errorf("API.GetJob() = %+v, want a pending job with no attempts", j)
+	}
+
+	if err := mockAPI.RetryJob(ctx, job_id); !errors.Is(err, ErrJobNotFailed) {
+		t.Errorf("API.RetryJob() error = %v, want %v", err, ErrJobNotFailed)
+	}
+}
+
+func TestAPI_runJob_PerceptualHash(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	ctx := context.Background()
+	archive_id := importTestPNG(ctx, t, mockAPI)
+
+	if err := mockAPI.runJob(ctx, entry.Job{ArchiveID: archive_id, Type: JobPerceptualHash}); err != nil {
+		t.Fatalf("API.runJob() error = %v", err)
+	}
+
+	path, err := mockAPI.GetAbsolutePath(ctx, archive_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a corrupt image is never mistaken for a video
+	if err := mockAPI.runJob(ctx, entry.Job{ArchiveID: archive_id, Type: JobPerceptualHash}); !errors.Is(err, image.ErrFormat) {
+		t.Errorf("API.runJob() on a corrupt image error = %v, want %v", err, image.ErrFormat)
+	}
+}
+
+func TestAPI_ProcessNextJob_Disabled(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", JobMaxAttempts: 1,
+		AnimatedPreviews: true, TranscodeFormat: "mp4"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 1, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+	for _, jobType := range []string{JobPreview, JobTranscode} {
+		job_id, err := mockAPI.EnqueueJob(ctx, archive_ids[0], jobType)
+		if err != nil {
+			t.Fatalf("API.EnqueueJob() error = %v", err)
+		}
+
+		// turned off after the job was queued
+		mockAPI.Config.AnimatedPreviews, mockAPI.Config.TranscodeFormat = false, ""
+		if ok, err := mockAPI.ProcessNextJob(ctx); !ok || err != nil {
+			t.Fatalf("API.ProcessNextJob() = %v, %v, want true, nil", ok, err)
+		}
+		mockAPI.Config.AnimatedPreviews, mockAPI.Config.TranscodeFormat = true, "mp4"
+
+		j, err := mockAPI.GetJob(ctx, job_id)
+		if err != nil {
+			t.Fatalf("API.GetJob() error = %v", err)
+		}
+		if j.Status != JobPending || j.Error != ErrJobDisabled.Error() {
+			t.Errorf("API.GetJob() = %+v, want a pending %s job", j, jobType)
+		}
+	}
+}
+
+func TestAPI_ResetRunningJobs(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 2, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+	claim := func(archive_id int64, jobType string) {
+		if _, err := mockAPI.EnqueueJob(ctx, archive_id, jobType); err != nil {
+			t.Fatalf("API.EnqueueJob() error = %v", err)
+		}
+		if _, err := mockAPI.archive.ClaimJob(ctx); err != nil {
+			t.Fatalf("failed to claim job, %v", err)
+		}
+	}
+
+	// an interrupted job, and one that was queued again after it was interrupted
+	claim(archive_ids[0], JobMetadata)
+	claim(archive_ids[1], JobThumbnail)
+	if _, err := mockAPI.EnqueueJob(ctx, archive_ids[1], JobThumbnail); err != nil {
+		t.Fatalf("API.EnqueueJob() error = %v", err)
+	}
+
+	reset, err := mockAPI.archive.ResetRunningJobs(ctx)
+	if err != nil {
+		t.Fatalf("ResetRunningJobs() error = %v", err)
+	}
+	if reset != 1 {
+		t.Errorf("ResetRunningJobs() = %d, want 1", reset)
+	}
+
+	counts, err := mockAPI.CountJobs(ctx)
+	if err != nil {
+		t.Fatalf("API.CountJobs() error = %v", err)
+	}
+	if counts[JobPending] != 2 || counts[JobRunning] != 0 {
+		t.Errorf("API.CountJobs() = %v, want 2 pending and none running", counts)
+	}
+}
+
+func TestAPI_BackfillJobs(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 2, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+
+	// the first entry already had its metadata read
+	job_id, err := mockAPI.EnqueueJob(ctx, archive_ids[0], JobMetadata)
+	if err != nil {
+		t.Fatalf("API.EnqueueJob() error = %v", err)
+	}
+	if err := mockAPI.archive.FinishJob(ctx, job_id, JobDone, "", time.Now()); err != nil {
+		t.Fatalf("failed to finish job, %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		jobType string
+		all     bool
+		want    int64
+		wantErr error
+	}{
+		{"entries without a job", JobMetadata, false, 1, nil},
+		{"already pending", JobMetadata, false, 0, nil},
+		{"every entry", JobMetadata, true, 1, nil},
+		{"new job type", JobStoryboard, false, 2, nil},
+		{"disabled job type", JobPreview, false, 0, ErrJobDisabled},
+		{"disabled transcode", JobTranscode, true, 0, ErrJobDisabled},
+		{"unknown job type", "foo", true, 0, ErrUnknownJobType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mockAPI.BackfillJobs(ctx, tt.jobType, tt.all)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("API.BackfillJobs() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("API.BackfillJobs() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_RunPendingJobs(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	f, err := os.Open("testdata/6ba11adbdb35ee10f9353608a7b97ef248733a72.jpg")
+	if err != nil {
+		t.Fatalf("failed to open test file. %v\n", err)
+	}
+	defer f.Close()
+
+	entry, err := importer.New(f, ".jpg")
+	if err != nil {
+		t.Fatalf("importer.New() failed to create new entry. %v", err)
+	}
+
+	ctx := context.Background()
+
+	archive_id, err := mockAPI.Import(ctx, entry)
+	if err != nil {
+		t.Fatalf("API.Import() failed to import entry. %v", err)
+	}
+
+	if err := mockAPI.EnqueueJobs(ctx, archive_id, JobThumbnail, JobPerceptualHash, JobBlurHash); err != nil {
+		t.Fatalf("API.EnqueueJobs() error = %v", err)
+	}
+
+	if err := mockAPI.RunPendingJobs(ctx, 1); err != nil {
+		t.Fatalf("API.RunPendingJobs() error = %v", err)
+	}
+
+	jobs, err := mockAPI.GetEntryJobs(ctx, archive_id)
+	if err != nil {
+		t.Fatalf("API.GetEntryJobs() error = %v", err)
+	}
+	for _, j := range jobs {
+		if j.Status != JobDone {
+			t.Errorf("%s job has status %s, want %s. %s", j.Type, j.Status, JobDone, j.Error)
+		}
+	}
+
+	if _, err := mockAPI.GetPerceptualHash(ctx, archive_id, ""); err != nil {
+		t.Errorf("API.GetPerceptualHash() error = %v", err)
+	}
+
+	if _, err := mockAPI.GetBlurHashString(ctx, archive_id); err != nil {
+		t.Errorf("API.GetBlurHashString() error = %v", err)
+	}
+}
+
+func Test_jobBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int64
+		want     time.Duration
+	}{
+		{1, JobRetryDelay},
+		{2, 2 * JobRetryDelay},
+		{3, 4 * JobRetryDelay},
+		{50, JobMaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := jobBackoff(tt.attempts); got != tt.want {
+			t.Errorf("jobBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
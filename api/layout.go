@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/log"
+	"github.com/dtbead/moonpool/internal/storage"
+)
+
+// move is a stored file being moved by Relayout.
+type move struct {
+	from, to string
+}
+
+// Layout returns the layout media is stored in.
+func (a *API) Layout() file.Layout {
+	return a.layout
+}
+
+// Relayout moves every stored file, along with its renditions, into layout l and records l as the layout
+// of the archive. Each entry is moved on its own: its files are moved first, and only then is its path
+// updated in a transaction, moving its files back if that fails. An interrupted Relayout never loses a file,
+// and running it again finishes the remaining entries. Relayout returns the amount of entries moved.
+func (a *API) Relayout(ctx context.Context, l file.Layout) (int, error) {
+	if err := l.Validate(); err != nil {
+		return 0, err
+	}
+
+	files, err := a.archive.GetStoredFiles(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, f := range files {
+		ok, err := a.relayoutEntry(ctx, f, l)
+		if err != nil {
+			a.log.LogAttrs(ctx, log.LogLevelError, "failed to move archive_id "+int64ToString(f.ArchiveID)+" into layout '"+l.String()+"'",
+				slog.Any("error", err),
+				slog.Int64("archive_id", f.ArchiveID),
+				slog.String("path", f.Path))
+			return moved, err
+		}
+		if ok {
+			moved++
+		}
+	}
+
+	if err := a.archive.SetLayout(ctx, l); err != nil {
+		return moved, err
+	}
+	a.layout = l
+
+	a.log.LogAttrs(ctx, log.LogLevelInfo, fmt.Sprintf("moved %d entries into layout '%s'", moved, l.String()),
+		slog.Int("moved", moved),
+		slog.String("layout", l.String()))
+	return moved, nil
+}
+
+// relayoutEntry moves the files of a single entry into layout l. It reports whether the entry had to move.
+func (a *API) relayoutEntry(ctx context.Context, f entry.StoredFile, l file.Layout) (bool, error) {
+	newPath, err := l.Path(f.Hashes, f.Extension)
+	if err != nil {
+		return false, err
+	}
+	if newPath == f.Path {
+		return false, nil
+	}
+
+	renditions, err := a.archive.GetRenditions(ctx, f.ArchiveID)
+	if err != nil {
+		return false, err
+	}
+
+	moves := []move{{f.Path, newPath}}
+	for _, r := range renditions {
+		moves = append(moves, move{r.Path, renditionPath(newPath, strings.TrimPrefix(path.Ext(r.Path), "."))})
+	}
+
+	for i, m := range moves {
+		if err := a.moveStored(ctx, m); err != nil {
+			a.undoMoves(ctx, moves[:i])
+			return false, err
+		}
+	}
+
+	if err := a.updateStoredPaths(ctx, f.ArchiveID, newPath, renditions, moves[1:]); err != nil {
+		a.undoMoves(ctx, moves)
+		return false, err
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelVerbose, "moved archive_id "+int64ToString(f.ArchiveID)+" to "+newPath,
+		slog.Int64("archive_id", f.ArchiveID),
+		slog.String("from", f.Path),
+		slog.String("to", newPath))
+	return true, nil
+}
+
+// moveStored moves a stored file. A file that was already moved by an interrupted Relayout is left alone.
+func (a *API) moveStored(ctx context.Context, m move) error {
+	err := storage.Move(ctx, a.storage, m.from, m.to)
+	if errors.Is(err, storage.ErrNotExist) {
+		if _, statErr := a.storage.Stat(ctx, m.to); statErr == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// undoMoves moves files back to where they were before a failed relayout of their entry.
+func (a *API) undoMoves(ctx context.Context, moves []move) {
+	for _, m := range moves {
+		if err := storage.Move(ctx, a.storage, m.to, m.from); err != nil {
+			a.log.LogAttrs(ctx, log.LogLevelWarn,
+				fmt.Sprintf("failed to move '%s' back to '%s', %v", m.to, m.from, err),
+				slog.Any("error", err),
+				slog.String("from", m.to),
+				slog.String("to", m.from))
+		}
+	}
+}
+
+// updateStoredPaths points an entry and its renditions at their moved files in a single transaction, run on
+// a connection of its own so the savepoint covers every update.
+func (a *API) updateStoredPaths(ctx context.Context, archive_id int64, newPath string, renditions []entry.Rendition, moves []move) error {
+	c, release, err := a.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := c.archive.NewSavepoint(ctx, "relayout"); err != nil {
+		return err
+	}
+	defer c.archive.Rollback(context.WithoutCancel(ctx), "relayout")
+
+	if err := c.archive.UpdateEntryPath(ctx, archive_id, newPath); err != nil {
+		return err
+	}
+
+	for i, r := range renditions {
+		if err := c.archive.UpdateRenditionPath(ctx, archive_id, r.Mimetype, moves[i].to); err != nil {
+			return err
+		}
+	}
+
+	return c.archive.ReleaseSavepoint(ctx, "relayout")
+}
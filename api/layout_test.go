@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/storage"
+)
+
+func TestAPI_Layout(t *testing.T) {
+	archivePath := t.TempDir() + "/archive.sqlite3"
+
+	tests := []struct {
+		name   string
+		layout file.Layout
+		want   file.Layout
+	}{
+		{"new archive", file.LegacyLayout, file.LegacyLayout},
+		{"recorded layout is kept", file.DefaultLayout, file.LegacyLayout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAPI, err := newMockAPI(Config{ArchiveLocation: archivePath, ThumbnailLocation: ":memory:", MediaLocation: t.TempDir(),
+				Layout: tt.layout}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mockAPI.Close(context.Background())
+
+			if got := mockAPI.Layout(); got != tt.want {
+				t.Errorf("API.Layout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mockAPI.Layout(); got != file.DefaultLayout {
+		t.Errorf("API.Layout() without a configured layout = %v, want %v", got, file.DefaultLayout)
+	}
+}
+
+func TestAPI_Relayout(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir(),
+		Layout: file.LegacyLayout}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	archive_id := importTestPNG(ctx, t, mockAPI)
+
+	oldPath, err := mockAPI.GetRelativePath(ctx, archive_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := entry.Rendition{ArchiveID: archive_id, Path: renditionPath(oldPath, "mp4"), Mimetype: "video/mp4",
+		VideoCodec: "h264", AudioCodec: "aac", FileSize: 9}
+	if err := os.WriteFile(mockAPI.Config.MediaLocation+"/"+r.Path, []byte("rendition"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mockAPI.archive.NewRendition(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+
+	// a second entry already moved by an interrupted relayout, before its path was updated
+	second := importTestImage(ctx, t, mockAPI, image.NewGray(image.Rect(0, 0, 8, 8)))
+	secondPath, err := mockAPI.GetRelativePath(ctx, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondHashes, err := mockAPI.GetHashes(ctx, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondNewPath, err := file.DefaultLayout.Path(secondHashes, ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Move(ctx, mockAPI.Storage(), secondPath, secondNewPath); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := mockAPI.Relayout(ctx, file.DefaultLayout)
+	if err != nil {
+		t.Fatalf("API.Relayout() error = %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("API.Relayout() = %d, want 2", moved)
+	}
+	if got := mockAPI.Layout(); got != file.DefaultLayout {
+		t.Errorf("API.Layout() after API.Relayout() = %v, want %v", got, file.DefaultLayout)
+	}
+
+	hashes, err := mockAPI.GetHashes(ctx, archive_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPath, err := file.DefaultLayout.Path(hashes, ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := mockAPI.GetRelativePath(ctx, archive_id); err != nil || got != wantPath {
+		t.Errorf("API.GetRelativePath() after API.Relayout() = %v, %v, want %v", got, err, wantPath)
+	}
+	if got, err := mockAPI.GetRelativePath(ctx, second); err != nil || got != secondNewPath {
+		t.Errorf("API.GetRelativePath() of moved entry after API.Relayout() = %v, %v, want %v", got, err, secondNewPath)
+	}
+
+	rc, err := mockAPI.GetFile(ctx, archive_id)
+	if err != nil {
+		t.Fatalf("API.GetFile() after API.Relayout() error = %v", err)
+	}
+	io.Copy(io.Discard, rc)
+	rc.Close()
+
+	if _, err := os.Stat(mockAPI.Config.MediaLocation + "/" + oldPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("API.Relayout() left file at %s, error = %v", oldPath, err)
+	}
+
+	renditions, err := mockAPI.GetRenditions(ctx, archive_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renditions) != 1 || renditions[0].Path != renditionPath(wantPath, "mp4") {
+		t.Fatalf("API.GetRenditions() after API.Relayout() = %v, want a rendition at %s", renditions, renditionPath(wantPath, "mp4"))
+	}
+	if _, err := os.Stat(mockAPI.Config.MediaLocation + "/" + renditions[0].Path); err != nil {
+		t.Errorf("API.Relayout() didn't move rendition to %s, error = %v", renditions[0].Path, err)
+	}
+
+	if moved, err := mockAPI.Relayout(ctx, file.DefaultLayout); err != nil || moved != 0 {
+		t.Errorf("API.Relayout() into the current layout = %d, %v, want 0", moved, err)
+	}
+
+	if _, err := mockAPI.Relayout(ctx, file.Layout{Hash: "crc32", Depth: 1, Width: 2}); err == nil {
+		t.Errorf("API.Relayout() into an invalid layout error = nil, want error")
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/log"
+)
+
+var (
+	ErrNoteNotFound = errors.New("note not found")
+	ErrEmptyNote    = errors.New("note title and text cannot be empty")
+)
+
+// NewNote attaches a new note to an archive_id and returns its note_id. An entry can have any
+// amount of notes.
+func (a *API) NewNote(ctx context.Context, archive_id int64, title, text string) (int64, error) {
+	title, text = strings.TrimSpace(title), strings.TrimSpace(text)
+	if title == "" || text == "" {
+		return -1, ErrEmptyNote
+	}
+
+	if !a.archive.DoesArchiveIDExist(ctx, archive_id) {
+		return -1, errors.New("archive_id does not exist")
+	}
+
+	note_id, err := a.archive.NewNote(ctx, archive_id, title, text)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to create note for archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id))
+		return -1, err
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelVerbose, "created note "+int64ToString(note_id)+" for archive_id "+int64ToString(archive_id),
+		slog.Int64("archive_id", archive_id),
+		slog.Int64("note_id", note_id))
+
+	return note_id, nil
+}
+
+func (a *API) GetNote(ctx context.Context, note_id int64) (entry.Note, error) {
+	n, err := a.archive.GetNote(ctx, note_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entry.Note{}, ErrNoteNotFound
+	}
+
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to get note "+int64ToString(note_id),
+			slog.Any("error", err),
+			slog.Int64("note_id", note_id))
+		return entry.Note{}, err
+	}
+
+	return n, nil
+}
+
+// GetNotes returns every note of an archive_id, oldest first.
+func (a *API) GetNotes(ctx context.Context, archive_id int64) ([]entry.Note, error) {
+	n, err := a.archive.GetNotes(ctx, archive_id)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to get notes for archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id))
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// UpdateNote replaces the title and text of an existing note.
+func (a *API) UpdateNote(ctx context.Context, note_id int64, title, text string) error {
+	title, text = strings.TrimSpace(title), strings.TrimSpace(text)
+	if title == "" || text == "" {
+		return ErrEmptyNote
+	}
+
+	err := a.archive.UpdateNote(ctx, note_id, title, text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoteNotFound
+	}
+
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to update note "+int64ToString(note_id),
+			slog.Any("error", err),
+			slog.Int64("note_id", note_id))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNote removes a note. It returns ErrNoteNotFound if the note doesn't exist.
+func (a *API) DeleteNote(ctx context.Context, note_id int64) error {
+	err := a.archive.DeleteNote(ctx, note_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoteNotFound
+	}
+
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to delete note "+int64ToString(note_id),
+			slog.Any("error", err),
+			slog.Int64("note_id", note_id))
+		return err
+	}
+
+	return nil
+}
+
+// SearchNotes returns notes whose title or text contains query, ignoring case. Results are sorted by the
+// most recently modified note first.
+func (a *API) SearchNotes(ctx context.Context, query string, limit, offset int64) ([]entry.Note, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	n, err := a.archive.SearchNotes(ctx, query, limit, offset)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to search notes for '"+query+"'",
+			slog.Any("error", err),
+			slog.String("query", query))
+		return nil, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAPI_Notes(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 2, false, true)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+
+	first, err := mockAPI.NewNote(ctx, archive_ids[0], "source", "found on a forum thread")
+	if err != nil {
+		t.Fatalf("failed to create note, %v", err)
+	}
+
+	second, err := mockAPI.NewNote(ctx, archive_ids[0], "context", "taken at the 100% zoom_level")
+	if err != nil {
+		t.Fatalf("failed to create note, %v", err)
+	}
+
+	if _, err := mockAPI.NewNote(ctx, archive_ids[1], "source", "scanned from a magazine"); err != nil {
+		t.Fatalf("failed to create note, %v", err)
+	}
+
+	notes, err := mockAPI.GetNotes(ctx, archive_ids[0])
+	if err != nil {
+		t.Fatalf("failed to get notes, %v", err)
+	}
+	if len(notes) != 2 || notes[0].NoteID != first || notes[1].NoteID != second {
+		t.Fatalf("GetNotes() = %+v, want note_id's [%d %d]", notes, first, second)
+	}
+
+	if err := mockAPI.UpdateNote(ctx, first, "source", "found on an image board"); err != nil {
+		t.Fatalf("failed to update note, %v", err)
+	}
+
+	n, err := mockAPI.GetNote(ctx, first)
+	if err != nil {
+		t.Fatalf("failed to get note, %v", err)
+	}
+	if n.Text != "found on an image board" {
+		t.Errorf("UpdateNote() did not update text, got '%s'", n.Text)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"match title", "SOURCE", 2},
+		{"match text", "image board", 1},
+		{"literal percent", "100%", 1},
+		{"literal underscore", "zoom_level", 1},
+		{"wildcard is not special", "_", 1},
+		{"no match", "foobar", 0},
+		{"empty query", " ", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mockAPI.SearchNotes(ctx, tt.query, 50, 0)
+			if err != nil {
+				t.Fatalf("API.SearchNotes() error = %v", err)
+			}
+
+			if len(got) != tt.want {
+				t.Errorf("API.SearchNotes() = %+v, want %d notes", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := mockAPI.NewNote(ctx, archive_ids[0], "", "text"); !errors.Is(err, ErrEmptyNote) {
+		t.Errorf("NewNote() with empty title, got error %v, want %v", err, ErrEmptyNote)
+	}
+
+	if err := mockAPI.DeleteNote(ctx, second); err != nil {
+		t.Fatalf("failed to delete note, %v", err)
+	}
+
+	if _, err := mockAPI.GetNote(ctx, second); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("GetNote() on deleted note, got error %v, want %v", err, ErrNoteNotFound)
+	}
+
+	if err := mockAPI.UpdateNote(ctx, second, "foo", "bar"); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("UpdateNote() on deleted note, got error %v, want %v", err, ErrNoteNotFound)
+	}
+
+	if err := mockAPI.DeleteNote(ctx, second); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("DeleteNote() on deleted note, got error %v, want %v", err, ErrNoteNotFound)
+	}
+}
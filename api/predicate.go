@@ -0,0 +1,281 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dtbead/moonpool/internal/query"
+)
+
+// mimeTypes are the top-level media types accepted by the "type" predicate.
+var mimeTypes = []string{"image", "video", "audio", "text", "application"}
+
+// orientations are the values accepted by the "orientation" predicate.
+var orientations = []string{"landscape", "portrait", "square"}
+
+// bitrateUnits maps a case-insensitive bitrate suffix to its amount of bits per second.
+var bitrateUnits = map[string]float64{
+	"":     1,
+	"bps":  1,
+	"k":    1e3,
+	"kbps": 1e3,
+	"m":    1e6,
+	"mbps": 1e6,
+}
+
+// sizeUnits maps a case-insensitive size suffix to its amount of bytes.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// resolvePredicates replaces every query.Predicate in n with a query.Range or query.Match.
+func resolvePredicates(n query.Node) (query.Node, error) {
+	return query.Transform(n, func(n query.Node) (query.Node, error) {
+		p, ok := n.(query.Predicate)
+		if !ok {
+			return n, nil
+		}
+		return resolvePredicate(p)
+	})
+}
+
+func resolvePredicate(p query.Predicate) (query.Node, error) {
+	switch p.Field {
+	case query.FieldWidth, query.FieldHeight, query.FieldTagCount, query.FieldFramerate:
+		return parseRange(p, parseCount)
+	case query.FieldSize:
+		return parseRange(p, parseSize)
+	case query.FieldBitrate:
+		return parseRange(p, parseBitrate)
+	case query.FieldDuration:
+		return parseRange(p, parseDuration)
+	case query.FieldAudio:
+		switch strings.ToLower(p.Value) {
+		case "yes", "true":
+			return query.Range{Field: query.FieldAudio, Min: 1, Max: 1}, nil
+		case "no", "false":
+			return query.Range{Field: query.FieldAudio, Min: 0, Max: 0}, nil
+		}
+		return nil, predicateError(p, "must be yes or no")
+	case query.FieldVideoCodec, query.FieldAudioCodec:
+		return query.Match{Field: p.Field, Value: strings.ToLower(p.Value)}, nil
+	case query.FieldImported, query.FieldCreated, query.FieldModified:
+		return parseRange(p, parseDate)
+	case query.FieldOrientation:
+		v := strings.ToLower(p.Value)
+		if !slices.Contains(orientations, v) {
+			return nil, predicateError(p, "must be one of "+strings.Join(orientations, ", "))
+		}
+		return query.Match{Field: query.FieldOrientation, Value: v}, nil
+	case query.FieldType:
+		v := strings.ToLower(p.Value)
+		if strings.Contains(v, "/") {
+			return query.Match{Field: query.FieldMimetype, Value: v}, nil
+		}
+		if !slices.Contains(mimeTypes, v) {
+			return nil, predicateError(p, "must be a mimetype or one of "+strings.Join(mimeTypes, ", "))
+		}
+		return query.Match{Field: query.FieldMimetype, Value: v + "/", Prefix: true}, nil
+	case query.FieldMimetype:
+		v := strings.ToLower(p.Value)
+		if prefix, ok := strings.CutSuffix(v, "*"); ok && prefix != "" {
+			return query.Match{Field: query.FieldMimetype, Value: prefix, Prefix: true}, nil
+		}
+		return query.Match{Field: query.FieldMimetype, Value: v}, nil
+	default:
+		return nil, &query.Error{Pos: p.Pos, Msg: "unknown field '" + p.Field + "'"}
+	}
+}
+
+// parseRange parses the value of a numeric predicate. parse returns the inclusive range a single value
+// covers, e.g. "2024" covers the whole year. Valid forms are:
+//
+//	5, =5      exactly 5
+//	>5, >=5    greater than (or equal to) 5
+//	<5, <=5    less than (or equal to) 5
+//	1..5       between 1 and 5, inclusively
+//	1.., ..5   open ranges
+func parseRange(p query.Predicate, parse func(string) (min, max int64, err error)) (query.Node, error) {
+	r := query.Range{Field: p.Field, Min: math.MinInt64, Max: math.MaxInt64}
+
+	value := func(s string) (int64, int64, error) {
+		min, max, err := parse(s)
+		if err != nil {
+			return 0, 0, predicateError(p, err.Error())
+		}
+		return min, max, nil
+	}
+
+	var err error
+	var min, max int64
+	switch v := p.Value; {
+	case strings.Contains(v, ".."):
+		start, end, _ := strings.Cut(v, "..")
+		if start == "" && end == "" {
+			return nil, predicateError(p, "range needs at least one bound")
+		}
+		if start != "" {
+			if r.Min, _, err = value(start); err != nil {
+				return nil, err
+			}
+		}
+		if end != "" {
+			if _, r.Max, err = value(end); err != nil {
+				return nil, err
+			}
+		}
+		if r.Min > r.Max {
+			return nil, predicateError(p, "range start is greater than its end")
+		}
+	case strings.HasPrefix(v, ">="):
+		if r.Min, _, err = value(v[2:]); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(v, "<="):
+		if _, r.Max, err = value(v[2:]); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(v, ">"):
+		if _, max, err = value(v[1:]); err != nil {
+			return nil, err
+		}
+		if max == math.MaxInt64 {
+			return nil, predicateError(p, "value is too large")
+		}
+		r.Min = max + 1
+	case strings.HasPrefix(v, "<"):
+		if min, _, err = value(v[1:]); err != nil {
+			return nil, err
+		}
+		if min == math.MinInt64 {
+			return nil, predicateError(p, "value is too small")
+		}
+		r.Max = min - 1
+	default:
+		if r.Min, r.Max, err = value(strings.TrimPrefix(v, "=")); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func parseCount(s string) (int64, int64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || i < 0 {
+		return 0, 0, fmt.Errorf("'%s' is not a positive number", s)
+	}
+	return i, i, nil
+}
+
+// parseSize parses a file size such as "500", "1.5MB" or "2GiB" into bytes.
+func parseSize(s string) (int64, int64, error) {
+	return parseUnit(s, "size", sizeUnits)
+}
+
+// parseBitrate parses a bitrate such as "320k", "320kbps" or "2.5Mbps" into bits per second.
+func parseBitrate(s string) (int64, int64, error) {
+	return parseUnit(s, "bitrate", bitrateUnits)
+}
+
+// parseUnit parses a positive decimal number followed by one of units, named name in errors.
+func parseUnit(s, name string, units map[string]float64) (int64, int64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	unit, ok := units[strings.ToLower(s[i:])]
+	if !ok {
+		return 0, 0, fmt.Errorf("'%s' is not a valid %s unit", s[i:], name)
+	}
+
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || f < 0 {
+		return 0, 0, fmt.Errorf("'%s' is not a valid %s", s, name)
+	}
+
+	b := math.Round(f * unit)
+	if b >= math.MaxInt64 {
+		return 0, 0, fmt.Errorf("'%s' is too large", s)
+	}
+	return int64(b), int64(b), nil
+}
+
+// parseDuration parses a duration such as "90", "1:30", "1:02:03" or "1m30s" into milliseconds. A duration
+// of whole seconds covers that entire second, e.g. "5" covers 5 to 5.999 seconds.
+func parseDuration(s string) (int64, int64, error) {
+	var d time.Duration
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			return 0, 0, fmt.Errorf("'%s' is not a valid duration", s)
+		}
+		for _, part := range parts {
+			i, err := strconv.ParseInt(part, 10, 64)
+			if err != nil || i < 0 || i > math.MaxInt32 {
+				return 0, 0, fmt.Errorf("'%s' is not a valid duration", s)
+			}
+			d = d*60 + time.Duration(i)*time.Second
+		}
+	} else {
+		v := s
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			v += "s"
+		}
+
+		var err error
+		d, err = time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return 0, 0, fmt.Errorf("'%s' is not a valid duration, expected seconds, m:ss or e.g. 1m30s", s)
+		}
+	}
+
+	ms := d.Milliseconds()
+	if d%time.Second == 0 {
+		return ms, ms + 999, nil
+	}
+	return ms, ms, nil
+}
+
+// parseDate parses a year, month or day in local time, returning the first and last millisecond of it.
+func parseDate(s string) (int64, int64, error) {
+	layouts := []struct {
+		layout     string
+		y, m, days int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	}
+
+	for _, l := range layouts {
+		t, err := time.ParseInLocation(l.layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		end := t.AddDate(l.y, l.m, l.days)
+		return t.UTC().UnixMilli(), end.UTC().UnixMilli() - 1, nil
+	}
+
+	return 0, 0, fmt.Errorf("'%s' is not a date, expected YYYY, YYYY-MM or YYYY-MM-DD", s)
+}
+
+func predicateError(p query.Predicate, msg string) error {
+	return &query.Error{Pos: p.Pos + len(p.Field) + 1, Msg: "invalid " + p.Field + ", " + msg}
+}
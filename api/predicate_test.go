@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dtbead/moonpool/entry"
+)
+
+func TestAPI_QueryTags_Predicates(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 4, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+
+	metadata := []entry.FileMetadata{
+		{FileMimetype: "image/png", FileSize: 2_000_000, MediaWidth: 2560, MediaHeight: 1440, MediaOrientation: "landscape"},
+		{FileMimetype: "video/mp4", FileSize: 40_000_000, MediaWidth: 1080, MediaHeight: 1920, MediaOrientation: "portrait",
+			MediaDuration: 95 * time.Second, MediaFramerate: 29.97, MediaVideoCodec: "h264", MediaAudioCodec: "aac", MediaBitrate: 3_200_000, MediaHasAudio: true},
+		{FileMimetype: "image/jpeg", FileSize: 500_000, MediaWidth: 800, MediaHeight: 800, MediaOrientation: "square"},
+		{FileMimetype: "video/webm", FileSize: 10_000_000, MediaWidth: 1280, MediaHeight: 720, MediaOrientation: "landscape",
+			MediaDuration: 10 * time.Second, MediaFramerate: 24, MediaVideoCodec: "vp9", MediaBitrate: 1_000_000},
+	}
+	imported := []time.Time{
+		time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local),
+		time.Date(2024, 6, 30, 23, 59, 0, 0, time.Local),
+		time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local),
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local),
+	}
+
+	for i, archive_id := range archive_ids {
+		if err := mockAPI.archive.SetFileMetadata(ctx, archive_id, metadata[i]); err != nil {
+			t.Fatalf("failed to set file metadata, %v", err)
+		}
+
+		if err := mockAPI.SetTimestamps(ctx, archive_id, entry.Timestamp{DateImported: imported[i]}); err != nil {
+			t.Fatalf("failed to set timestamps, %v", err)
+		}
+	}
+
+	if err := mockAPI.AssignTags(ctx, archive_ids[0], []string{"foo", "bar"}); err != nil {
+		t.Fatalf("failed to assign tags, %v", err)
+	}
+
+	if err := mockAPI.AssignTags(ctx, archive_ids[1], []string{"foo"}); err != nil {
+		t.Fatalf("failed to assign tags, %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		q       string
+		want    []int64
+		wantErr bool
+	}{
+		{"width greater than", "width:>1920", []int64{1}, false},
+		{"height range", "height:800..1440", []int64{3, 1}, false},
+		{"orientation", "orientation:portrait", []int64{2}, false},
+		{"type", "type:image", []int64{3, 1}, false},
+		{"full mimetype", "type:video/mp4", []int64{2}, false},
+		{"size less than", "size:<5MB", []int64{3, 1}, false},
+		{"size exact", "size:=500KB", []int64{3}, false},
+		{"imported month range", "imported:2024-01..2024-06", []int64{2, 1}, false},
+		{"imported day", "imported:2024-07-01", []int64{3}, false},
+		{"no tags", "tagcount:0", []int64{3, 4}, false},
+		{"combined with tags", "foo type:image", []int64{1}, false},
+		{"negated predicate", "foo -orientation:landscape", []int64{2}, false},
+		{"predicate OR", "tagcount:>=2 | size:>10MB", []int64{2, 1}, false},
+		{"duration", "duration:1:35", []int64{2}, false},
+		{"duration range", "duration:1m..2m", []int64{2}, false},
+		{"framerate", "framerate:30", []int64{2}, false},
+		{"bitrate", "bitrate:>=3Mbps", []int64{2}, false},
+		{"video codec", "vcodec:H264", []int64{2}, false},
+		{"audio codec", "acodec:opus", nil, false},
+		{"has audio", "audio:yes", []int64{2}, false},
+		{"no audio", "audio:no", []int64{4}, false},
+		{"no match", "width:>10000", nil, false},
+		{"invalid predicate", "size:big", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := mockAPI.QueryTags(ctx, "imported", "descending", tt.q, 0, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("API.QueryTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("API.QueryTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/log"
+	"github.com/dtbead/moonpool/internal/media"
+)
+
+// GenerateRendition transcodes a video web browsers can't play into Config.TranscodeFormat, stored alongside
+// the original. It returns ErrNotVideo for any other entry, ErrWebPlayable if the original already plays, and
+// ErrTranscodeFormat if Config.TranscodeFormat isn't one of media.TranscodeFormats.
+func (a *API) GenerateRendition(ctx context.Context, archive_id int64) error {
+	mimetype, ok := media.TranscodeFormats[a.Config.TranscodeFormat]
+	if !ok {
+		return ErrTranscodeFormat
+	}
+
+	mediaType, err := a.mediaType(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+	if mediaType != "video" {
+		return ErrNotVideo
+	}
+
+	e, err := a.archive.GetEntry(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+
+	originalPath, release, err := a.localPath(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	m, err := a.GetFileMetadata(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+
+	// codecs are only known once the metadata job has run
+	videoCodec, audioCodec := m.MediaVideoCodec, m.MediaAudioCodec
+	if videoCodec == "" {
+		s, err := media.ProbeStreams(originalPath)
+		if err != nil {
+			return err
+		}
+		videoCodec, audioCodec = s.VideoCodec, s.AudioCodec
+	}
+
+	if media.IsWebPlayable(m.FileMimetype, videoCodec, audioCodec) {
+		return ErrWebPlayable
+	}
+
+	r := entry.Rendition{
+		ArchiveID: archive_id,
+		Path:      renditionPath(e.Path, a.Config.TranscodeFormat),
+		Mimetype:  mimetype,
+	}
+
+	// transcode to a temporary file first, so a partial transcode is never stored
+	tmp, err := os.CreateTemp("", "moonpool_rendition_*."+a.Config.TranscodeFormat)
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := media.Transcode(originalPath, tmp.Name(), a.Config.TranscodeFormat); err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to transcode archive_id "+int64ToString(archive_id),
+			slog.Int64("archive_id", archive_id),
+			slog.String("transcode_format", a.Config.TranscodeFormat),
+			slog.Any("error", err))
+		return err
+	}
+
+	s, err := media.ProbeStreams(tmp.Name())
+	if err != nil {
+		return err
+	}
+	r.VideoCodec, r.AudioCodec = s.VideoCodec, s.AudioCodec
+
+	f, err := os.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r.FileSize = fi.Size()
+
+	if err := a.storage.Put(ctx, r.Path, f); err != nil {
+		return err
+	}
+
+	if err := a.archive.NewRendition(ctx, r); err != nil {
+		a.storage.Delete(ctx, r.Path)
+		return err
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelInfo,
+		"generated rendition for archive_id "+int64ToString(archive_id),
+		slog.Int64("archive_id", archive_id),
+		slog.String("path", r.Path),
+		slog.String("mimetype", r.Mimetype))
+	return nil
+}
+
+// GetRenditions returns every web-playable transcode of an entry.
+func (a *API) GetRenditions(ctx context.Context, archive_id int64) ([]entry.Rendition, error) {
+	return a.archive.GetRenditions(ctx, archive_id)
+}
+
+// renditionPath returns the path of the rendition of an entry in a given format, next to the original, e.g.
+// "ab/abcdef.mkv" is transcoded to "ab/abcdef.rendition.mp4".
+func renditionPath(originalPath, format string) string {
+	return strings.TrimSuffix(originalPath, path.Ext(originalPath)) + ".rendition." + format
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/dtbead/moonpool/entry"
+)
+
+func Test_renditionPath(t *testing.T) {
+	tests := []struct {
+		originalPath, format string
+		want                 string
+	}{
+		{"ab/abcdef.mkv", "mp4", "ab/abcdef.rendition.mp4"},
+		{"ab/abcdef.avi", "webm", "ab/abcdef.rendition.webm"},
+		{"ab/abcdef", "mp4", "ab/abcdef.rendition.mp4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.originalPath, func(t *testing.T) {
+			if got := renditionPath(tt.originalPath, tt.format); got != tt.want {
+				t.Errorf("renditionPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_GenerateRendition(t *testing.T) {
+	tests := []struct {
+		name            string
+		transcodeFormat string
+		wantErr         error
+	}{
+		{"not a video", "mp4", ErrNotVideo},
+		{"invalid format", "mkv", ErrTranscodeFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir(),
+				TranscodeFormat: tt.transcodeFormat}, t)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			archive_id := importTestPNG(ctx, t, mockAPI)
+
+			if err := mockAPI.GenerateRendition(ctx, archive_id); !errors.Is(err, tt.wantErr) {
+				t.Errorf("API.GenerateRendition() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPI_RemoveArchive_Renditions(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	archive_id := importTestPNG(ctx, t, mockAPI)
+
+	p, err := mockAPI.GetPath(ctx, archive_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := entry.Rendition{ArchiveID: archive_id, Path: renditionPath(p.FileRelative, "mp4"), Mimetype: "video/mp4",
+		VideoCodec: "h264", AudioCodec: "aac", FileSize: 9}
+	renditionFile := mockAPI.Config.MediaLocation + "/" + r.Path
+	if err := os.WriteFile(renditionFile, []byte("rendition"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mockAPI.archive.NewRendition(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := mockAPI.GetRenditions(ctx, archive_id)
+	if err != nil {
+		t.Fatalf("API.GetRenditions() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != r {
+		t.Errorf("API.GetRenditions() = %v, want %v", got, []entry.Rendition{r})
+	}
+
+	if err := mockAPI.RemoveArchive(ctx, archive_id); err != nil {
+		t.Fatalf("API.RemoveArchive() error = %v", err)
+	}
+
+	if _, err := os.Stat(renditionFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("API.RemoveArchive() left rendition at %s, error = %v", r.Path, err)
+	}
+}
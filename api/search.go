@@ -4,55 +4,89 @@ import (
 	"context"
 	"log/slog"
 	"strconv"
-	"strings"
 
 	"github.com/dtbead/moonpool/internal/log"
+	"github.com/dtbead/moonpool/internal/query"
 )
 
-type QueryTags struct {
-	TagsInclude, TagsExclude []string
+// ErrInvalidQuery is matched (through errors.Is) by every query syntax error. The error message describes
+// what went wrong and where, and is safe to show to a user.
+var ErrInvalidQuery = query.ErrSyntax
+
+// ParseQuery parses a tag search query. See package internal/query for the full syntax, in short:
+//
+//	cat AND dog, cat & dog, cat, dog    entries with both tags
+//	cat OR dog, cat | dog               entries with either tag
+//	NOT cat, -cat, !cat                 entries without a tag
+//	(cat OR dog) -bird                  grouping, adjacent terms are AND'ed
+//	cat*                                any tag beginning with "cat"
+//	"foo, bar"                          quoted tag containing special characters
+//
+// Entries can also be filtered by their metadata with "field:value" predicates, which combine with
+// tags like any other term:
+//
+//	width:>1920, height:<=1080          media dimensions in pixels
+//	size:<5MB, size:1KiB..2MiB          file size, in B, KB, MB, GB, KiB, MiB or GiB
+//	orientation:portrait                landscape, portrait or square
+//	type:video, type:image/png          top-level media type or full mimetype
+//	mimetype:image/*                    mimetype, optionally by prefix
+//	imported:2024-01..2024-06           import, creation (created:) or modification (modified:) date
+//	tagcount:0                          amount of tags assigned
+//	duration:>5m, duration:1:30..3:00   video or audio length, in seconds, m:ss, h:mm:ss or e.g. 1m30s
+//	framerate:60                        video framerate, rounded to whole frames per second
+//	bitrate:>=320k                      bitrate, in bps, kbps (k) or Mbps (m)
+//	vcodec:h264, acodec:opus            video or audio codec, as named by ffprobe
+//	audio:yes                           whether a video has an audio stream, never matching other entries
+//
+// Numeric predicates accept >, >=, <, <=, = and ranges "a..b", "a.." or "..b". Dates are given as
+// YYYY, YYYY-MM or YYYY-MM-DD in local time and cover the entire period.
+func ParseQuery(s string) (query.Node, error) {
+	n, err := query.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return resolvePredicates(n)
 }
 
+// QueryTags parses and runs a tag search query, returning a page of matching archive_id's and the total
+// amount of matches. Syntax errors and invalid predicates match ErrInvalidQuery.
+//
 // Valid sort options are "imported", "created", and "modified".
 // Valid order options are "descending", "ascending".
-func (a *API) QueryTags(ctx context.Context, sort, order string, q QueryTags) ([]int64, error) {
-	res, err := a.archive.SearchTagByList(ctx, sort, order, q.TagsInclude, q.TagsExclude)
+// limit limits how many archive_id's are returned, a limit of 0 or less returns every match.
+// offset skips the first matches, e.g. a limit of 50 with an offset of 50 returns matches 51-100.
+func (a *API) QueryTags(ctx context.Context, sort, order, q string, limit, offset int64) (archive_ids []int64, total int64, err error) {
+	n, err := ParseQuery(q)
 	if err != nil {
-		return nil, err
+		a.log.LogAttrs(ctx, log.LogLevelVerbose, "failed to parse query",
+			slog.Any("error", err),
+			slog.String("query", q))
+		return nil, 0, err
 	}
 
-	a.log.LogAttrs(ctx, log.LogLevelVerbose,
-		"found "+strconv.Itoa(len(res))+" archive_id's",
-		slog.Any("query_tags", q))
-
-	return res, nil
-}
+	archive_ids, err = a.archive.SearchTagQuery(ctx, sort, order, n, limit, offset)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to search query",
+			slog.Any("error", err),
+			slog.String("query", n.String()))
+		return nil, 0, err
+	}
 
-// BuildQuery takes a string and returns a QueryTag to be used in API.QueryTags.
-// Each tag can be separated by a comma (,) and have a prefixed special character to
-// assign specific tag modifiers to it.
-//
-// tag modifiers:
-// dash (-) = exclude tag from search
-func BuildQuery(s string) QueryTags {
-	var tagsInclude, tagsExclude []string
-
-	tags := strings.Split(s, ",")
-	for _, tag := range tags {
-		if tag == "" {
-			break
-		}
-
-		tag = strings.TrimSpace(tag)
-
-		if strings.HasPrefix(tag, "-") {
-			tagsExclude = append(tagsExclude, tag[len("-"):])
-		} else {
-			tagsInclude = append(tagsInclude, tag)
-		}
+	total, err = a.archive.CountTagQuery(ctx, n)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to count query",
+			slog.Any("error", err),
+			slog.String("query", n.String()))
+		return nil, 0, err
 	}
 
-	return QueryTags{tagsInclude, tagsExclude}
+	a.log.LogAttrs(ctx, log.LogLevelVerbose,
+		"found "+strconv.FormatInt(total, 10)+" archive_id's",
+		slog.String("query", n.String()),
+		slog.Int64("limit", limit),
+		slog.Int64("offset", offset))
+
+	return archive_ids, total, nil
 }
 
 // SearchHash takes a hexadecimal string of either md5, sha1, or sha256, and returns an archive_id.
@@ -1,28 +1,103 @@
 package api
 
 import (
-	"reflect"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/dtbead/moonpool/internal/query"
 )
 
-func TestBuildQuery(t *testing.T) {
+func TestParseQuery(t *testing.T) {
 	type args struct {
 		s string
 	}
 	tests := []struct {
-		name string
-		args args
-		want QueryTags
+		name    string
+		args    args
+		want    string
+		wantErr bool
 	}{
-		{"include only", args{"foobar"}, QueryTags{[]string{"foobar"}, nil}},
-		{"include + exclude", args{"foobar,-bar"}, QueryTags{[]string{"foobar"}, []string{"bar"}}},
-		{"include + exclude with excess whitespace", args{"foobar, -bar"}, QueryTags{[]string{"foobar"}, []string{"bar"}}},
-		{"empty args", args{""}, QueryTags{nil, nil}},
+		{"include only", args{"foobar"}, `"foobar"`, false},
+		{"include + exclude", args{"foobar,-bar"}, `("foobar" AND NOT "bar")`, false},
+		{"include + exclude with excess whitespace", args{"foobar, -bar"}, `("foobar" AND NOT "bar")`, false},
+		{"exclude only", args{"-bar"}, `NOT "bar"`, false},
+		{"boolean", args{"(foo OR bar*) AND NOT baz"}, `(("foo" OR "bar"*) AND NOT "baz")`, false},
+		{"predicate", args{"width:>1920 -tagcount:0"}, `(width:[1921..] AND NOT tagcount:[0..0])`, false},
+		{"predicate range", args{"height:720..1080"}, `height:[720..1080]`, false},
+		{"predicate open range", args{"height:..1080"}, `height:[..1080]`, false},
+		{"predicate size units", args{"size:<5MB | size:>=1KiB"}, `(size:[..4999999] OR size:[1024..])`, false},
+		{"predicate decimal size", args{"size:1.5kb"}, `size:[1500..1500]`, false},
+		{"predicate type", args{"type:Video"}, `mimetype:"video/"*`, false},
+		{"predicate full mimetype", args{"type:image/png"}, `mimetype:"image/png"`, false},
+		{"predicate mimetype prefix", args{"mimetype:image/*"}, `mimetype:"image/"*`, false},
+		{"predicate orientation", args{"orientation:portrait"}, `orientation:"portrait"`, false},
+		{"predicate is case insensitive", args{"WIDTH:=5"}, `width:[5..5]`, false},
+		{"predicate duration", args{"duration:90"}, `duration:[90000..90999]`, false},
+		{"predicate duration clock", args{"duration:>1:02:03"}, `duration:[3724000..]`, false},
+		{"predicate duration units", args{"duration:1.5s..1m"}, `duration:[1500..60999]`, false},
+		{"predicate bitrate", args{"bitrate:<320kbps"}, `bitrate:[..319999]`, false},
+		{"predicate codec", args{"vcodec:HEVC"}, `vcodec:"hevc"`, false},
+		{"predicate audio", args{"audio:no"}, `audio:[0..0]`, false},
+		{"unknown field is a tag", args{"artist:foo"}, `"artist:foo"`, false},
+		{"invalid predicate number", args{"width:>big"}, "", true},
+		{"invalid predicate unit", args{"size:5XB"}, "", true},
+		{"invalid predicate orientation", args{"orientation:diagonal"}, "", true},
+		{"invalid predicate type", args{"type:picture"}, "", true},
+		{"invalid predicate range", args{"width:10..5"}, "", true},
+		{"invalid predicate date", args{"imported:2024-13"}, "", true},
+		{"invalid predicate duration", args{"duration:1:2:3:4"}, "", true},
+		{"invalid predicate audio", args{"audio:maybe"}, "", true},
+		{"missing predicate value", args{"width:"}, "", true},
+		{"empty args", args{""}, "", true},
+		{"syntax error", args{"foo AND (bar"}, "", true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := BuildQuery(tt.args.s); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("BuildQuery() = %v, want %v", got, tt.want)
+			got, err := ParseQuery(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrInvalidQuery) {
+					t.Errorf("ParseQuery() error = %v, does not match ErrInvalidQuery", err)
+				}
+				return
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("ParseQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuery_Date(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		min, max time.Time
+	}{
+		{"year", "imported:2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)},
+		{"month", "created:2024-02", time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{"day", "modified:2024-02-29", time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local), time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{"month range", "imported:2024-01..2024-06", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.s)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+
+			r, ok := got.(query.Range)
+			if !ok {
+				t.Fatalf("ParseQuery() = %T, want query.Range", got)
+			}
+
+			if r.Min != tt.min.UnixMilli() || r.Max != tt.max.UnixMilli()-1 {
+				t.Errorf("ParseQuery() = [%d..%d], want [%d..%d]", r.Min, r.Max, tt.min.UnixMilli(), tt.max.UnixMilli()-1)
 			}
 		})
 	}
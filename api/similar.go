@@ -0,0 +1,295 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/bktree"
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/log"
+)
+
+// DefaultSimilarDistance is a reasonable maximum Hamming distance between two perceptual hashes for
+// their images to be considered near-duplicates, such as the same image resized or recompressed.
+const DefaultSimilarDistance = 10
+
+var ErrInvalidDistance = errors.New("hamming distance must be between 0 and the size of the hash in bits")
+
+// FindSimilar returns every entry whose perceptual hash of hashType is within maxHammingDistance bits of
+// archive_id's, closest first. archive_id itself is never included. An empty hashType compares PHashes.
+//
+// Comparing AHash or PHash tolerates recompression and resizing, while DHash and the 16x16 types are more
+// sensitive to crops and edits. Comparing VHash finds re-encoded or trimmed copies of a video, see
+// file.VideoHashDistance.
+func (a *API) FindSimilar(ctx context.Context, archive_id int64, hashType string, maxHammingDistance int) ([]entry.Similar, error) {
+	hashType, err := similarHashType(hashType, maxHammingDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := a.GetPerceptualHash(ctx, archive_id, hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := a.searchPerceptualHashes(ctx, hashType, hash.Hash, maxHammingDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	similar := make([]entry.Similar, 0, 16)
+	for _, m := range matches {
+		if m.ID != archive_id {
+			similar = append(similar, entry.Similar{ArchiveID: m.ID, Distance: m.Distance})
+		}
+	}
+
+	slices.SortFunc(similar, func(x, y entry.Similar) int {
+		return cmp.Or(cmp.Compare(x.Distance, y.Distance), cmp.Compare(x.ArchiveID, y.ArchiveID))
+	})
+
+	return similar, nil
+}
+
+// FindDuplicateGroups clusters every entry with a perceptual hash of hashType into groups of near-duplicates.
+// Two entries are in the same group if they are within maxHammingDistance bits of each other, or are both
+// near-duplicates of a third entry in the group. Entries without any near-duplicate are left out. An empty
+// hashType compares PHashes.
+//
+// Each group is sorted by archive_id, and groups are sorted by their lowest archive_id.
+func (a *API) FindDuplicateGroups(ctx context.Context, hashType string, maxHammingDistance int) ([][]int64, error) {
+	hashType, err := similarHashType(hashType, maxHammingDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := a.archive.GetPerceptualHashes(ctx, hashType)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to fetch perceptual hashes",
+			slog.Any("error", err))
+		return nil, err
+	}
+
+	tree := newHashIndex(hashType)
+	index := make(map[int64]int, len(hashes))
+	for i, h := range hashes {
+		tree.Add(h.ArchiveID, h.Hash)
+		index[h.ArchiveID] = i
+	}
+
+	// union-find over the index of every hash
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i, h := range hashes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, m := range tree.Search(h.Hash, maxHammingDistance) {
+			if x, y := find(i), find(index[m.ID]); x != y {
+				parent[max(x, y)] = min(x, y)
+			}
+		}
+	}
+
+	members := make(map[int][]int64)
+	for i, h := range hashes {
+		root := find(i)
+		members[root] = append(members[root], h.ArchiveID)
+	}
+
+	groups := make([][]int64, 0, len(members))
+	for _, g := range members {
+		if len(g) > 1 {
+			slices.Sort(g)
+			groups = append(groups, g)
+		}
+	}
+
+	slices.SortFunc(groups, func(x, y []int64) int {
+		return cmp.Compare(x[0], y[0])
+	})
+
+	a.log.LogAttrs(ctx, log.LogLevelVerbose,
+		"found "+strconv.Itoa(len(groups))+" duplicate groups among "+strconv.Itoa(len(hashes))+" perceptual hashes",
+		slog.Int("max_distance", maxHammingDistance),
+		slog.String("hash_type", hashType))
+
+	return groups, nil
+}
+
+// similarHashType validates a hash type and distance for a similarity search, defaulting to PHash.
+func similarHashType(hashType string, maxHammingDistance int) (string, error) {
+	if hashType == "" {
+		hashType = file.PHash
+	}
+
+	bits := file.PerceptualHashBits(hashType)
+	if bits == 0 {
+		return "", ErrUnknownHashType
+	}
+
+	if maxHammingDistance < 0 || maxHammingDistance > bits {
+		return "", ErrInvalidDistance
+	}
+	return hashType, nil
+}
+
+// hashIndex finds every hash within a distance of another.
+type hashIndex interface {
+	Add(id int64, hash []uint64)
+	Search(hash []uint64, maxDistance int) []bktree.Match
+}
+
+func newHashIndex(hashType string) hashIndex {
+	if hashType == file.VHash {
+		return &videoIndex{}
+	}
+	return &bktree.Tree{}
+}
+
+// videoIndex compares video fingerprints one by one, since file.VideoHashDistance does not satisfy the
+// triangle inequality a BK-tree relies on.
+type videoIndex struct {
+	hashes []bktree.Match
+}
+
+func (v *videoIndex) Add(id int64, hash []uint64) {
+	v.hashes = append(v.hashes, bktree.Match{ID: id, Hash: hash})
+}
+
+func (v *videoIndex) Search(hash []uint64, maxDistance int) []bktree.Match {
+	var matches []bktree.Match
+	for _, h := range v.hashes {
+		if d := file.VideoHashDistance(hash, h.Hash); d <= maxDistance {
+			matches = append(matches, bktree.Match{ID: h.ID, Hash: h.Hash, Distance: d})
+		}
+	}
+	return matches
+}
+
+// hashIndexCache keeps an index of every perceptual hash type searched by FindSimilar, so each search doesn't
+// have to read every hash in the archive. Each index remembers the version of the hashes it was built from,
+// so hashes changed by another process, such as 'archive jobs --run', are noticed on the next search.
+type hashIndexCache struct {
+	mu      sync.Mutex
+	indexes map[string]*cachedHashIndex
+}
+
+type cachedHashIndex struct {
+	index   hashIndex
+	ids     map[int64]bool
+	version int64
+}
+
+func newHashIndexCache() *hashIndexCache {
+	return &hashIndexCache{indexes: make(map[string]*cachedHashIndex)}
+}
+
+// searchPerceptualHashes returns every hash of hashType within maxDistance of hash, building the index of
+// hashType if it isn't cached yet or its hashes changed since.
+func (a *API) searchPerceptualHashes(ctx context.Context, hashType string, hash []uint64, maxDistance int) ([]bktree.Match, error) {
+	if a.hashIndexes == nil {
+		c, err := a.perceptualHashIndex(ctx, hashType)
+		if err != nil {
+			return nil, err
+		}
+		return c.index.Search(hash, maxDistance), nil
+	}
+
+	a.hashIndexes.mu.Lock()
+	defer a.hashIndexes.mu.Unlock()
+
+	version, err := a.archive.GetPerceptualHashVersion(ctx, hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := a.hashIndexes.indexes[hashType]
+	if !ok || c.version != version {
+		if c, err = a.perceptualHashIndex(ctx, hashType); err != nil {
+			return nil, err
+		}
+		a.hashIndexes.indexes[hashType] = c
+	}
+
+	return c.index.Search(hash, maxDistance), nil
+}
+
+// updateHashIndex adds a new hash to the cached index of its type, after it was stored. Since hashes can't be
+// removed from an index, an index is dropped instead when an entry replaces its hash, and rebuilt on its next
+// search. It's dropped as well if any other hash of its type was stored since it was built.
+func (a *API) updateHashIndex(ctx context.Context, archive_id int64, hashType string, hash []uint64) {
+	if a.hashIndexes == nil {
+		return
+	}
+
+	a.hashIndexes.mu.Lock()
+	defer a.hashIndexes.mu.Unlock()
+
+	c, ok := a.hashIndexes.indexes[hashType]
+	if !ok {
+		return
+	}
+
+	version, err := a.archive.GetPerceptualHashVersion(ctx, hashType)
+	if err != nil || version != c.version+1 || c.ids[archive_id] {
+		delete(a.hashIndexes.indexes, hashType)
+		return
+	}
+
+	c.index.Add(archive_id, hash)
+	c.ids[archive_id] = true
+	c.version = version
+}
+
+// dropHashIndexes drops every cached index, such as when an entry is removed.
+func (a *API) dropHashIndexes() {
+	if a.hashIndexes == nil {
+		return
+	}
+
+	a.hashIndexes.mu.Lock()
+	defer a.hashIndexes.mu.Unlock()
+	clear(a.hashIndexes.indexes)
+}
+
+// perceptualHashIndex builds an index of every perceptual hash of hashType in the archive.
+func (a *API) perceptualHashIndex(ctx context.Context, hashType string) (*cachedHashIndex, error) {
+	// read before the hashes, so a hash stored in between makes the index look stale rather than current
+	version, err := a.archive.GetPerceptualHashVersion(ctx, hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := a.archive.GetPerceptualHashes(ctx, hashType)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to fetch perceptual hashes",
+			slog.Any("error", err))
+		return nil, err
+	}
+
+	c := &cachedHashIndex{index: newHashIndex(hashType), ids: make(map[int64]bool, len(hashes)), version: version}
+	for _, h := range hashes {
+		c.index.Add(h.ArchiveID, h.Hash)
+		c.ids[h.ArchiveID] = true
+	}
+	return c, nil
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/file"
+)
+
+func TestAPI_FindSimilar(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 6, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+
+	/*
+		archive_id 1, 2, 3 are near-duplicates of each other through 2
+		archive_id 4, 5 are exact duplicates
+		archive_id 6 has no perceptual hash
+	*/
+	hashes := []uint64{
+		0xFF00FF00FF00FF00,
+		0xFF00FF00FF00FF0F, // 4 bits from 1
+		0xFF00FF00FF00FFFF, // 4 bits from 2, 8 bits from 1
+		0x00FF00FF00FF00FF,
+		0x00FF00FF00FF00FF,
+	}
+	for i, h := range hashes {
+		if err := mockAPI.archive.SetPerceptualHash(ctx, archive_ids[i], "PHash", []uint64{h}); err != nil {
+			t.Fatalf("failed to set perceptual hash, %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		archive_id  int64
+		maxDistance int
+		want        []entry.Similar
+		wantErr     error
+	}{
+		{"closest first", 2, 4, []entry.Similar{{ArchiveID: 1, Distance: 4}, {ArchiveID: 3, Distance: 4}}, nil},
+		{"within distance", 1, 4, []entry.Similar{{ArchiveID: 2, Distance: 4}}, nil},
+		{"wider distance", 1, 8, []entry.Similar{{ArchiveID: 2, Distance: 4}, {ArchiveID: 3, Distance: 8}}, nil},
+		{"exact duplicate", 4, 0, []entry.Similar{{ArchiveID: 5, Distance: 0}}, nil},
+		{"no match", 1, 0, []entry.Similar{}, nil},
+		{"no perceptual hash", 6, 4, nil, ErrNoPerceptualHash},
+		{"negative distance", 1, -1, nil, ErrInvalidDistance},
+		{"distance too large", 1, 65, nil, ErrInvalidDistance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mockAPI.FindSimilar(ctx, tt.archive_id, "", tt.maxDistance)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("API.FindSimilar() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("API.FindSimilar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	groupTests := []struct {
+		name        string
+		maxDistance int
+		want        [][]int64
+	}{
+		{"exact only", 0, [][]int64{{4, 5}}},
+		{"transitive", 4, [][]int64{{1, 2, 3}, {4, 5}}},
+		{"everything", 64, [][]int64{{1, 2, 3, 4, 5}}},
+	}
+	for _, tt := range groupTests {
+		t.Run("groups "+tt.name, func(t *testing.T) {
+			got, err := mockAPI.FindDuplicateGroups(ctx, "", tt.maxDistance)
+			if err != nil {
+				t.Fatalf("API.FindDuplicateGroups() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("API.FindDuplicateGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := mockAPI.FindSimilar(ctx, 1, "foo", 4); !errors.Is(err, ErrUnknownHashType) {
+		t.Errorf("API.FindSimilar() with unknown hash type, got error %v, want %v", err, ErrUnknownHashType)
+	}
+
+	// 256-bit hashes are compared by every word and are stored separately from PHash
+	extended := [][]uint64{
+		{1, 0, 0, 0},
+		{1, 0, 0, 0b111},
+		{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)},
+	}
+	for i, h := range extended {
+		if err := mockAPI.archive.SetPerceptualHash(ctx, archive_ids[i], "PHash16", h); err != nil {
+			t.Fatalf("failed to set perceptual hash, %v", err)
+		}
+	}
+
+	got, err := mockAPI.FindSimilar(ctx, 1, "PHash16", 100)
+	if err != nil {
+		t.Fatalf("API.FindSimilar() error = %v", err)
+	}
+	if want := []entry.Similar{{ArchiveID: 2, Distance: 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("API.FindSimilar() = %v, want %v", got, want)
+	}
+
+	if _, err := mockAPI.FindSimilar(ctx, 1, "PHash16", 256); err != nil {
+		t.Errorf("API.FindSimilar() with distance 256 on a 256-bit hash, got error %v", err)
+	}
+
+	// video fingerprints match trimmed copies regardless of where their frames line up
+	videos := [][]uint64{
+		{0x0, 0xff, 0xffff, 0xffffff},
+		{0xffff, 0xffffff},
+		{0x1, 0xfe, 0xfffe, 0xfffffe},
+		{^uint64(0)},
+	}
+	for i, h := range videos {
+		if err := mockAPI.archive.SetPerceptualHash(ctx, archive_ids[i], "VHash", h); err != nil {
+			t.Fatalf("failed to set perceptual hash, %v", err)
+		}
+	}
+
+	got, err = mockAPI.FindSimilar(ctx, 1, "VHash", DefaultSimilarDistance)
+	if err != nil {
+		t.Fatalf("API.FindSimilar() error = %v", err)
+	}
+	if want := []entry.Similar{{ArchiveID: 2, Distance: 0}, {ArchiveID: 3, Distance: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("API.FindSimilar() = %v, want %v", got, want)
+	}
+
+	groups, err := mockAPI.FindDuplicateGroups(ctx, "VHash", DefaultSimilarDistance)
+	if err != nil {
+		t.Fatalf("API.FindDuplicateGroups() error = %v", err)
+	}
+	if want := [][]int64{{1, 2, 3}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("API.FindDuplicateGroups() = %v, want %v", groups, want)
+	}
+}
+
+func TestAPI_FindSimilar_Cached(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 3, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+	setHash := func(archive_id int64, h uint64) {
+		if err := mockAPI.setPerceptualHashes(ctx, archive_id, []file.PerceptualHashes{{Type: file.PHash, Hash: h}}); err != nil {
+			t.Fatalf("failed to set perceptual hash, %v", err)
+		}
+	}
+
+	setHash(archive_ids[0], 0xFF00)
+	setHash(archive_ids[1], 0xFF0F)
+
+	tests := []struct {
+		name   string
+		update func()
+		want   []entry.Similar
+	}{
+		{"builds index", func() {}, []entry.Similar{{ArchiveID: 2, Distance: 4}}},
+		{"new hash is added", func() { setHash(archive_ids[2], 0xFF01) }, []entry.Similar{{ArchiveID: 3, Distance: 1}, {ArchiveID: 2, Distance: 4}}},
+		{"replaced hash is rebuilt", func() { setHash(archive_ids[1], 0x00FF) }, []entry.Similar{{ArchiveID: 3, Distance: 1}}},
+		{"removed entry is dropped", func() {
+			if err := mockAPI.RemoveArchive(ctx, archive_ids[2]); err != nil {
+				t.Fatal(err)
+			}
+		}, []entry.Similar{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.update()
+
+			got, err := mockAPI.FindSimilar(ctx, archive_ids[0], file.PHash, 4)
+			if err != nil {
+				t.Fatalf("API.FindSimilar() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("API.FindSimilar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_FindSimilar_CachedShared(t *testing.T) {
+	archivePath := t.TempDir() + "/archive.sqlite3"
+
+	// writer stands in for another process, such as 'archive jobs --run', sharing the archive with reader
+	writer, err := newMockAPI(Config{ArchiveLocation: archivePath, ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+	reader, err := newMockAPI(Config{ArchiveLocation: archivePath, ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(writer, 3, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+	setHash := func(archive_id int64, h uint64) {
+		if err := writer.setPerceptualHashes(ctx, archive_id, []file.PerceptualHashes{{Type: file.PHash, Hash: h}}); err != nil {
+			t.Fatalf("failed to set perceptual hash, %v", err)
+		}
+	}
+
+	setHash(archive_ids[0], 0xFF00)
+	setHash(archive_ids[1], 0xFF0F)
+
+	tests := []struct {
+		name   string
+		update func()
+		want   []entry.Similar
+	}{
+		{"builds index", func() {}, []entry.Similar{{ArchiveID: 2, Distance: 4}}},
+		{"new hash is added", func() { setHash(archive_ids[2], 0xFF01) }, []entry.Similar{{ArchiveID: 3, Distance: 1}, {ArchiveID: 2, Distance: 4}}},
+		{"replaced hash is rebuilt", func() { setHash(archive_ids[1], 0x00FF) }, []entry.Similar{{ArchiveID: 3, Distance: 1}}},
+		{"removed entry is dropped", func() {
+			if err := writer.RemoveArchive(ctx, archive_ids[2]); err != nil {
+				t.Fatal(err)
+			}
+		}, []entry.Similar{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.update()
+
+			got, err := reader.FindSimilar(ctx, archive_ids[0], file.PHash, 4)
+			if err != nil {
+				t.Fatalf("API.FindSimilar() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("API.FindSimilar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_GeneratePerceptualHashes(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 1, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+
+	img, err := os.ReadFile("testdata/6ba11adbdb35ee10f9353608a7b97ef248733a72.jpg")
+	if err != nil {
+		t.Fatalf("failed to read test file. %v", err)
+	}
+
+	if err := mockAPI.GeneratePerceptualHashes(ctx, archive_ids[0], bytes.NewReader(img), "foo"); !errors.Is(err, ErrUnknownHashType) {
+		t.Errorf("API.GeneratePerceptualHashes() with unknown type, got error %v, want %v", err, ErrUnknownHashType)
+	}
+
+	if err := mockAPI.GeneratePerceptualHash(ctx, archive_ids[0], "", bytes.NewReader(img)); err != nil {
+		t.Fatalf("API.GeneratePerceptualHash() error = %v", err)
+	}
+
+	hashes, err := mockAPI.GetPerceptualHashes(ctx, archive_ids[0])
+	if err != nil {
+		t.Fatalf("API.GetPerceptualHashes() error = %v", err)
+	}
+
+	if len(hashes) != len(file.PerceptualHashTypes) {
+		t.Fatalf("API.GetPerceptualHashes() returned %d hashes, want %d", len(hashes), len(file.PerceptualHashTypes))
+	}
+
+	for _, h := range hashes {
+		if len(h.Hash)*64 != file.PerceptualHashBits(h.Type) {
+			t.Errorf("%s has %d bits, want %d", h.Type, len(h.Hash)*64, file.PerceptualHashBits(h.Type))
+		}
+
+		got, err := mockAPI.GetPerceptualHash(ctx, archive_ids[0], h.Type)
+		if err != nil {
+			t.Fatalf("API.GetPerceptualHash() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, h) {
+			t.Errorf("API.GetPerceptualHash() = %v, want %v", got, h)
+		}
+	}
+
+	phash, err := mockAPI.GetPerceptualHash(ctx, archive_ids[0], "")
+	if err != nil {
+		t.Fatalf("API.GetPerceptualHash() error = %v", err)
+	}
+	if phash.Type != file.PHash || phash.Hash[0] != 14274222685500242926 {
+		t.Errorf("API.GetPerceptualHash() = %v, want PHash 14274222685500242926", phash)
+	}
+}
@@ -17,7 +17,7 @@ func TestAPI_QueryTags(t *testing.T) {
 		t.Fatalf("failed to create mock API. %v", err)
 	}
 
-	archive_ids, err := GenerateMockData(mockAPI, 2, false, true)
+	archive_ids, err := GenerateMockData(mockAPI, 3, false, true)
 	if err != nil {
 		t.Fatalf("failed to generate mock data, %v", err)
 	}
@@ -53,18 +53,24 @@ func TestAPI_QueryTags(t *testing.T) {
 		t.Fatalf("failed to set tag, %v", err)
 	}
 
+	err = mockAPI.AssignTags(context.Background(), archive_ids[2], []string{"bar", "foobar"})
+	if err != nil {
+		t.Fatalf("failed to set tag, %v", err)
+	}
+
 	/*
 		foo -> foo_alias
 		bar -> bar_alias
 
 		'foo' tag IN archive_id[1, 2]
 		'foo', 'bar' tags IN archive_id[2]
+		'bar', 'foobar' tags IN archive_id[3]
 	*/
 
 	type args struct {
 		ctx         context.Context
 		sort, order string
-		q           QueryTags
+		q           string
 	}
 	tests := []struct {
 		name    string
@@ -73,21 +79,36 @@ func TestAPI_QueryTags(t *testing.T) {
 		want    []int64
 		wantErr bool
 	}{
-		{"tagInclude only", mockAPI, args{context.Background(), "imported", "descending", QueryTags{[]string{"foo"}, nil}}, []int64{2, 1}, false},
-		{"tagInclude + tagExclude", mockAPI, args{context.Background(), "imported", "descending", QueryTags{[]string{"foo"}, []string{"bar"}}}, []int64{1}, false},
-		{"resolve tagInclude alias", mockAPI, args{context.Background(), "imported", "descending", QueryTags{[]string{"foo_alias"}, []string{}}}, []int64{2, 1}, false},
-		{"resolve tagExclude alias", mockAPI, args{context.Background(), "imported", "descending", QueryTags{[]string{"foo"}, []string{"bar_alias"}}}, []int64{1}, false},
-		{"tagInclude only ascending order", mockAPI, args{context.Background(), "imported", "ascending", QueryTags{[]string{"foo"}, nil}}, []int64{1, 2}, false},
-		{"resolve tagInclude alias ascending order", mockAPI, args{context.Background(), "imported", "ascending", QueryTags{[]string{"foo_alias"}, []string{}}}, []int64{1, 2}, false},
+		{"tagInclude only", mockAPI, args{context.Background(), "imported", "descending", "foo"}, []int64{2, 1}, false},
+		{"tagInclude + tagExclude", mockAPI, args{context.Background(), "imported", "descending", "foo, -bar"}, []int64{1}, false},
+		{"resolve tagInclude alias", mockAPI, args{context.Background(), "imported", "descending", "foo_alias"}, []int64{2, 1}, false},
+		{"resolve tagExclude alias", mockAPI, args{context.Background(), "imported", "descending", "foo, -bar_alias"}, []int64{1}, false},
+		{"tagInclude only ascending order", mockAPI, args{context.Background(), "imported", "ascending", "foo"}, []int64{1, 2}, false},
+		{"resolve tagInclude alias ascending order", mockAPI, args{context.Background(), "imported", "ascending", "foo_alias"}, []int64{1, 2}, false},
+		{"AND", mockAPI, args{context.Background(), "imported", "descending", "foo AND bar"}, []int64{2}, false},
+		{"OR", mockAPI, args{context.Background(), "imported", "descending", "foo OR bar"}, []int64{3, 2, 1}, false},
+		{"NOT only", mockAPI, args{context.Background(), "imported", "descending", "NOT foo"}, []int64{3}, false},
+		{"grouping", mockAPI, args{context.Background(), "imported", "descending", "(foo OR foobar) -bar"}, []int64{1}, false},
+		{"prefix wildcard", mockAPI, args{context.Background(), "imported", "descending", "foo*"}, []int64{3, 2, 1}, false},
+		{"prefix wildcard matches alias", mockAPI, args{context.Background(), "imported", "descending", "bar_al*"}, []int64{3, 2}, false},
+		{"prefix wildcard with LIKE characters", mockAPI, args{context.Background(), "imported", "descending", "fo%*"}, nil, false},
+		{"quoted", mockAPI, args{context.Background(), "imported", "descending", `"foo" AND "bar"`}, []int64{2}, false},
+		{"no match", mockAPI, args{context.Background(), "imported", "descending", "doesnotexist"}, nil, false},
+		{"syntax error", mockAPI, args{context.Background(), "imported", "descending", "foo AND"}, nil, true},
+		{"invalid sort", mockAPI, args{context.Background(), "foobar", "descending", "foo"}, nil, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.a.QueryTags(tt.args.ctx, tt.args.sort, tt.args.order, tt.args.q)
+			got, total, err := tt.a.QueryTags(tt.args.ctx, tt.args.sort, tt.args.order, tt.args.q, 0, 0)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("API.QueryTags() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
+			if total != int64(len(tt.want)) {
+				t.Errorf("API.QueryTags() total = %d, want %d", total, len(tt.want))
+			}
+
 			if !slices.Equal(got, tt.want) {
 				for _, archive_id := range got {
 					ts, err := tt.a.GetTimestamps(tt.args.ctx, archive_id)
@@ -103,6 +124,63 @@ func TestAPI_QueryTags(t *testing.T) {
 	}
 }
 
+func TestAPI_QueryTags_Pagination(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+
+	archive_ids, err := GenerateMockData(mockAPI, 7, false, false)
+	if err != nil {
+		t.Fatalf("failed to generate mock data, %v", err)
+	}
+
+	ctx := context.Background()
+	for i, archive_id := range archive_ids {
+		err := mockAPI.SetTimestamps(ctx, archive_id, entry.Timestamp{
+			DateImported: time.Now().Add(time.Duration(i) * time.Hour)})
+		if err != nil {
+			t.Fatalf("failed to set import timestamp, %v", err)
+		}
+
+		if err := mockAPI.AssignTags(ctx, archive_id, []string{"foo"}); err != nil {
+			t.Fatalf("failed to set tag, %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		order         string
+		limit, offset int64
+		want          []int64
+	}{
+		{"first page", "descending", 3, 0, []int64{7, 6, 5}},
+		{"second page", "descending", 3, 3, []int64{4, 3, 2}},
+		{"partial last page", "descending", 3, 6, []int64{1}},
+		{"past last page", "descending", 3, 9, nil},
+		{"ascending first page", "ascending", 3, 0, []int64{1, 2, 3}},
+		{"ascending second page", "ascending", 3, 3, []int64{4, 5, 6}},
+		{"no limit", "ascending", 0, 4, []int64{5, 6, 7}},
+		{"negative offset", "ascending", 2, -1, []int64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := mockAPI.QueryTags(ctx, "imported", tt.order, "foo", tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("API.QueryTags() error = %v", err)
+			}
+
+			if total != 7 {
+				t.Errorf("API.QueryTags() total = %d, want 7", total)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("API.QueryTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestAPI_GetTagsByRange(t *testing.T) {
 	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
 	if err != nil {
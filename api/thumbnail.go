@@ -3,42 +3,71 @@ package api
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"image"
 	"log/slog"
+	"strings"
 
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/db/thumbnail"
+	"github.com/dtbead/moonpool/internal/file"
 	"github.com/dtbead/moonpool/internal/log"
 	"github.com/dtbead/moonpool/internal/media"
 )
 
+// GenerateThumbnail encodes every size and format of thumbnail for an entry before storing them at once, so
+// the thumbnail database isn't held while ffmpeg encodes them.
 func (a *API) GenerateThumbnail(ctx context.Context, archive_id int64) error {
-	if err := a.thumbnail.NewSavepoint(ctx, "thumbnail"); err != nil {
+	file, err := a.GetFile(ctx, archive_id)
+	if err != nil {
 		return err
 	}
-	defer a.archive.Rollback(ctx, "thumbnail")
+	defer file.Close()
 
-	file, err := a.archive.GetFile(ctx, archive_id, a.Config.MediaLocation)
+	mediaType, err := a.mediaType(ctx, archive_id)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
 	var imageSrc image.Image
-	imageSrc, err = media.DecodeImage(file)
-	if err != nil {
-		file.Close()
-
-		path, err := a.GetAbsolutePath(ctx, archive_id)
+	switch mediaType {
+	case "audio":
+		path, release, err := a.localPath(ctx, archive_id)
 		if err != nil {
 			return err
 		}
+		defer release()
 
-		imageSrc, err = media.GenerateVideoThumbnail(path)
+		imageSrc, err = media.GenerateAudioThumbnail(path)
 		if err != nil {
 			return err
 		}
+	default:
+		imageSrc, err = media.DecodeImage(file)
+		if err == nil {
+			orientation, err := a.exifOrientation(ctx, archive_id)
+			if err != nil {
+				return err
+			}
+			imageSrc = media.ApplyOrientation(imageSrc, orientation)
+		} else {
+			file.Close()
+
+			path, release, err := a.localPath(ctx, archive_id)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			imageSrc, err = media.GenerateVideoThumbnail(path)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	icons, err := media.GenerateIcons(&imageSrc)
+	icons, err := media.GenerateIcons(&imageSrc, a.Config.ThumbnailSizes)
 	if err != nil {
 		return err
 	}
@@ -48,11 +77,43 @@ func (a *API) GenerateThumbnail(ctx context.Context, archive_id int64) error {
 		return err
 	}
 
+	// other formats are optional, since every thumbnail can be served as JPEG
+	formats := make(map[string]thumbnail.Sizes)
+	for _, format := range a.Config.ThumbnailFormats {
+		if format == "jpeg" {
+			continue
+		}
+
+		f, err := encodeThumbnailFormat(icons, format)
+		if err != nil {
+			a.log.LogAttrs(ctx, log.LogLevelWarn,
+				"failed to generate "+format+" thumbnails for archive_id "+int64ToString(archive_id),
+				slog.Int64("archive_id", archive_id),
+				slog.Any("error", err))
+			continue
+		}
+		formats[format] = f
+	}
+
+	if err := a.thumbnail.NewSavepoint(ctx, "thumbnail"); err != nil {
+		return err
+	}
+	defer a.archive.Rollback(ctx, "thumbnail")
+
 	err = a.thumbnail.NewJpeg(ctx, archive_id, t)
 	if err != nil {
 		return err
 	}
 
+	for format, f := range formats {
+		if err := a.newThumbnailFormat(ctx, archive_id, format, f); err != nil {
+			a.log.LogAttrs(ctx, log.LogLevelWarn,
+				"failed to store "+format+" thumbnails for archive_id "+int64ToString(archive_id),
+				slog.Int64("archive_id", archive_id),
+				slog.Any("error", err))
+		}
+	}
+
 	if err := a.thumbnail.ReleaseSavepoint(ctx, "thumbnail"); err != nil {
 		return err
 	}
@@ -64,6 +125,27 @@ func (a *API) GenerateThumbnail(ctx context.Context, archive_id int64) error {
 	return nil
 }
 
+// encodeThumbnailFormat encodes icons as a thumbnail format other than JPEG.
+func encodeThumbnailFormat(icons entry.Icons, format string) (thumbnail.Sizes, error) {
+	switch format {
+	case "webp", "avif":
+		return media.EncodeIcons(icons, format)
+	default:
+		return thumbnail.Sizes{}, errors.New("unknown thumbnail format")
+	}
+}
+
+func (a *API) newThumbnailFormat(ctx context.Context, archive_id int64, format string, t thumbnail.Sizes) error {
+	switch format {
+	case "webp":
+		return a.thumbnail.NewWebp(ctx, archive_id, t)
+	case "avif":
+		return a.thumbnail.NewAvif(ctx, archive_id, t)
+	default:
+		return errors.New("unknown thumbnail format")
+	}
+}
+
 func (a *API) GenerateBlurHash(ctx context.Context, archive_id int64) error {
 	if err := a.thumbnail.NewSavepoint(ctx, "blurhash"); err != nil {
 		return err
@@ -94,17 +176,23 @@ func (a *API) GenerateBlurHash(ctx context.Context, archive_id int64) error {
 	}
 
 	newBlurHashFromFile := func() error {
-		f, err := a.archive.GetFile(ctx, archive_id, a.Config.MediaLocation)
+		f, err := a.GetFile(ctx, archive_id)
 		if err != nil {
 			return err
 		}
+		defer f.Close()
 
-		img, _, err := image.Decode(f)
+		img, err := media.DecodeImage(f)
 		if err != nil {
 			return err
 		}
 
-		hash, err := media.EncodeBlurHash(img)
+		orientation, err := a.exifOrientation(ctx, archive_id)
+		if err != nil {
+			return err
+		}
+
+		hash, err := media.EncodeBlurHash(media.ApplyOrientation(img, orientation))
 		if err != nil {
 			return err
 		}
@@ -135,6 +223,146 @@ func (a *API) GenerateBlurHash(ctx context.Context, archive_id int64) error {
 	return nil
 }
 
+// GeneratePreview renders a short animated preview of a video or an animated GIF, fitting in the small
+// thumbnail size. It returns ErrNotAnimated for any other entry.
+func (a *API) GeneratePreview(ctx context.Context, archive_id int64) error {
+	e, err := a.GetEntry(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+
+	mimetype := file.GetMimeTypeByExtension(e.Extension)
+	switch {
+	case strings.HasPrefix(mimetype, "video/"):
+	case mimetype == "image/gif":
+		f, err := a.GetFile(ctx, archive_id)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		animated, err := media.IsAnimatedGif(f)
+		if err != nil {
+			return err
+		}
+		if !animated {
+			return ErrNotAnimated
+		}
+	default:
+		return ErrNotAnimated
+	}
+
+	path, release, err := a.localPath(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// without a known duration, the preview simply starts at the beginning
+	var duration float64
+	if m, err := media.ProbeStreams(path); err == nil {
+		duration = m.Duration
+	}
+
+	preview, err := media.GeneratePreview(path, duration, a.Config.ThumbnailSizes.OrDefault().Small)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to generate preview for archive_id "+int64ToString(archive_id),
+			slog.Int64("archive_id", archive_id),
+			slog.Any("error", err))
+		return err
+	}
+
+	if err := a.thumbnail.NewPreview(ctx, archive_id, media.PreviewMimetype, preview); err != nil {
+		return err
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelInfo,
+		"generated preview for archive_id "+int64ToString(archive_id),
+		slog.Int64("archive_id", archive_id))
+	return nil
+}
+
+// GetPreview returns the animated preview of an entry and its mimetype. It returns ErrPreviewNotFound if the
+// entry has none.
+func (a *API) GetPreview(ctx context.Context, archive_id int64) ([]byte, string, error) {
+	mimetype, preview, err := a.thumbnail.GetPreview(ctx, archive_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrPreviewNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return preview, mimetype, nil
+}
+
+func (a *API) HasPreview(ctx context.Context, archive_id int64) (bool, error) {
+	return a.thumbnail.HasPreview(ctx, archive_id)
+}
+
+// HasPreviews returns which of archive_ids have an animated preview, such as every entry on a page.
+func (a *API) HasPreviews(ctx context.Context, archive_ids []int64) (map[int64]bool, error) {
+	return a.thumbnail.HasPreviews(ctx, archive_ids)
+}
+
+// StoryboardSpriteURL is the URL the cues of a storyboard reference its sprite sheet by, relative to the
+// storyboard itself.
+const StoryboardSpriteURL = "storyboard/sprite"
+
+// GenerateStoryboard tiles Config.StoryboardFrames evenly-spaced frames of a video into a sprite sheet, and
+// stores it alongside a WebVTT track of when each frame is shown. It returns ErrNotVideo for any other entry.
+func (a *API) GenerateStoryboard(ctx context.Context, archive_id int64) error {
+	mediaType, err := a.mediaType(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+	if mediaType != "video" {
+		return ErrNotVideo
+	}
+
+	path, release, err := a.localPath(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	n := a.Config.StoryboardFrames
+	if n <= 0 {
+		n = media.DefaultStoryboardFrames
+	}
+
+	s, err := media.GenerateStoryboard(path, n, StoryboardSpriteURL)
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelError, "failed to generate storyboard for archive_id "+int64ToString(archive_id),
+			slog.Int64("archive_id", archive_id),
+			slog.Any("error", err))
+		return err
+	}
+
+	if err := a.thumbnail.NewStoryboard(ctx, archive_id, s.Sprite, s.VTT); err != nil {
+		return err
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelInfo,
+		"generated storyboard for archive_id "+int64ToString(archive_id),
+		slog.Int64("archive_id", archive_id),
+		slog.Int("storyboard_frames", n))
+	return nil
+}
+
+// GetStoryboard returns the storyboard of a video. It returns ErrStoryboardNotFound if the entry has none.
+func (a *API) GetStoryboard(ctx context.Context, archive_id int64) (media.Storyboard, error) {
+	sprite, vtt, err := a.thumbnail.GetStoryboard(ctx, archive_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return media.Storyboard{}, ErrStoryboardNotFound
+	}
+	if err != nil {
+		return media.Storyboard{}, err
+	}
+
+	return media.Storyboard{Sprite: sprite, VTT: vtt}, nil
+}
+
 func (a *API) GetBlurHashString(ctx context.Context, archive_id int64) (string, error) {
 	return a.thumbnail.GetBlurHash(ctx, archive_id)
 }
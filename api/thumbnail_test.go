@@ -0,0 +1,211 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"maps"
+	"testing"
+
+	"github.com/dtbead/moonpool/internal/db/thumbnail"
+	"github.com/dtbead/moonpool/internal/media"
+)
+
+func TestAPI_GenerateThumbnail_Sizes(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir(),
+		ThumbnailSizes: media.ThumbnailSizes{Small: 10, Medium: 30}}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	archive_id := importTestImage(ctx, t, mockAPI, image.NewGray(image.Rect(0, 0, 40, 20)))
+
+	if err := mockAPI.GenerateThumbnail(ctx, archive_id); err != nil {
+		t.Fatalf("API.GenerateThumbnail() error = %v", err)
+	}
+
+	tests := []struct {
+		size string
+		want image.Point
+	}{
+		{"small", image.Pt(10, 5)},
+		{"medium", image.Pt(30, 15)},
+		// the default large size is bigger than the image itself, which is never scaled up
+		{"large", image.Pt(40, 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			thumb, err := mockAPI.GetThumbnail(ctx, archive_id, tt.size, "jpeg")
+			if err != nil {
+				t.Fatalf("API.GetThumbnail() error = %v", err)
+			}
+
+			c, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := image.Pt(c.Width, c.Height); got != tt.want {
+				t.Errorf("API.GenerateThumbnail() %s = %v, want %v", tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_GetPreferredThumbnail(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	jpegOnly, webp := int64(1), int64(2)
+	if err := mockAPI.thumbnail.NewJpeg(ctx, jpegOnly, thumbnail.Sizes{Small: []byte("jpeg")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mockAPI.thumbnail.NewJpeg(ctx, webp, thumbnail.Sizes{Small: []byte("jpeg")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mockAPI.thumbnail.NewWebp(ctx, webp, thumbnail.Sizes{Small: []byte("webp")}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		archive_id int64
+		formats    []string
+		want       string
+		wantErr    error
+	}{
+		{"webp", webp, []string{"avif", "webp"}, "webp", nil},
+		{"jpeg fallback", jpegOnly, []string{"avif", "webp"}, "jpeg", nil},
+		{"no preference", webp, nil, "jpeg", nil},
+		{"explicit jpeg", webp, []string{"jpeg", "webp"}, "jpeg", nil},
+		{"missing thumbnail", 3, []string{"webp"}, "", ErrThumbnailNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, format, err := mockAPI.GetPreferredThumbnail(ctx, tt.archive_id, "small", tt.formats...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("API.GetPreferredThumbnail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if format != tt.want || string(data) != tt.want {
+				t.Errorf("API.GetPreferredThumbnail() = %q, %q, want %q", data, format, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_GetPreview(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := mockAPI.thumbnail.NewPreview(ctx, 1, media.PreviewMimetype, []byte("preview")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		archive_id int64
+		want       string
+		wantErr    error
+	}{
+		{"preview", 1, "preview", nil},
+		{"missing preview", 2, "", ErrPreviewNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, mimetype, err := mockAPI.GetPreview(ctx, tt.archive_id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("API.GetPreview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(data) != tt.want {
+				t.Errorf("API.GetPreview() = %q, want %q", data, tt.want)
+			}
+			if err == nil && mimetype != media.PreviewMimetype {
+				t.Errorf("API.GetPreview() mimetype = %q, want %q", mimetype, media.PreviewMimetype)
+			}
+
+			exists, err := mockAPI.HasPreview(ctx, tt.archive_id)
+			if err != nil {
+				t.Fatalf("API.HasPreview() error = %v", err)
+			}
+			if exists != (tt.wantErr == nil) {
+				t.Errorf("API.HasPreview() = %v, want %v", exists, tt.wantErr == nil)
+			}
+		})
+	}
+
+	previews, err := mockAPI.HasPreviews(ctx, []int64{1, 2})
+	if err != nil {
+		t.Fatalf("API.HasPreviews() error = %v", err)
+	}
+	if want := map[int64]bool{1: true, 2: false}; !maps.Equal(previews, want) {
+		t.Errorf("API.HasPreviews() = %v, want %v", previews, want)
+	}
+}
+
+func TestAPI_GeneratePreview_NotAnimated(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	archive_id := importTestPNG(ctx, t, mockAPI)
+
+	if err := mockAPI.GeneratePreview(ctx, archive_id); !errors.Is(err, ErrNotAnimated) {
+		t.Errorf("API.GeneratePreview() error = %v, want %v", err, ErrNotAnimated)
+	}
+}
+
+func TestAPI_GetStoryboard(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:"}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := mockAPI.thumbnail.NewStoryboard(ctx, 1, []byte("sprite"), "WEBVTT\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		archive_id int64
+		want       media.Storyboard
+		wantErr    error
+	}{
+		{"storyboard", 1, media.Storyboard{Sprite: []byte("sprite"), VTT: "WEBVTT\n"}, nil},
+		{"missing storyboard", 2, media.Storyboard{}, ErrStoryboardNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mockAPI.GetStoryboard(ctx, tt.archive_id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("API.GetStoryboard() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got.Sprite, tt.want.Sprite) || got.VTT != tt.want.VTT {
+				t.Errorf("API.GetStoryboard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPI_GenerateStoryboard_NotVideo(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	archive_id := importTestPNG(ctx, t, mockAPI)
+
+	if err := mockAPI.GenerateStoryboard(ctx, archive_id); !errors.Is(err, ErrNotVideo) {
+		t.Errorf("API.GenerateStoryboard() error = %v, want %v", err, ErrNotVideo)
+	}
+}
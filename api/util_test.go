@@ -0,0 +1,39 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"image"
+	imagepng "image/png"
+	"testing"
+
+	"github.com/dtbead/moonpool/importer"
+)
+
+// encodeTestPNG returns img encoded as a PNG.
+func encodeTestPNG(t *testing.T, img image.Image) []byte {
+	var png bytes.Buffer
+	if err := imagepng.Encode(&png, img); err != nil {
+		t.Fatal(err)
+	}
+	return png.Bytes()
+}
+
+// importTestPNG imports a small blank PNG.
+func importTestPNG(ctx context.Context, t *testing.T, mockAPI *API) int64 {
+	return importTestImage(ctx, t, mockAPI, image.NewGray(image.Rect(0, 0, 4, 4)))
+}
+
+// importTestImage imports img as a PNG.
+func importTestImage(ctx context.Context, t *testing.T, mockAPI *API, img image.Image) int64 {
+	i, err := importer.New(bytes.NewReader(encodeTestPNG(t, img)), ".png")
+	if err != nil {
+		t.Fatalf("importer.New() failed to create new entry. %v", err)
+	}
+
+	archive_id, err := mockAPI.Import(ctx, i)
+	if err != nil {
+		t.Fatalf("API.Import() error = %v", err)
+	}
+	return archive_id
+}
@@ -0,0 +1,384 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dtbead/moonpool/entry"
+	mdb "github.com/dtbead/moonpool/internal/db"
+	"github.com/dtbead/moonpool/internal/db/archive"
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/log"
+	"github.com/dtbead/moonpool/internal/storage"
+)
+
+const (
+	// ProblemMissingFile is an entry without a stored file. It can't be repaired.
+	ProblemMissingFile = "missing_file"
+	// ProblemCorruptFile is a stored file whose size or SHA-256 hash differs from its entry. It can't be
+	// repaired.
+	ProblemCorruptFile = "corrupt_file"
+	// ProblemOrphanFile is a stored file without an entry. It's repaired by moving it into LostAndFound.
+	ProblemOrphanFile = "orphan_file"
+	// ProblemMissingRendition is a rendition without a stored file. It's repaired by removing the rendition
+	// and transcoding the entry again.
+	ProblemMissingRendition = "missing_rendition"
+	// ProblemMissingHashes is an entry without hashes. It's repaired by hashing its file.
+	ProblemMissingHashes = "missing_hashes"
+	// ProblemMissingTimestamps is an entry without timestamps. It's repaired by dating it to when its file
+	// was last modified.
+	ProblemMissingTimestamps = "missing_timestamps"
+	// ProblemMissingMetadata is an entry without file metadata. It's repaired by queueing a JobMetadata.
+	ProblemMissingMetadata = "missing_metadata"
+	// ProblemMissingThumbnail is an entry without thumbnails. It's repaired by queueing a JobThumbnail.
+	ProblemMissingThumbnail = "missing_thumbnail"
+
+	// LostAndFound is the directory of the media storage orphaned files are moved into by a repair. Files in
+	// it are never reported as orphans.
+	LostAndFound = "lost+found"
+)
+
+// VerifyOptions change what Verify checks. Rehash reads every stored file to compare it against its SHA-256
+// hash, rather than only its size. Repair fixes every problem that can be fixed.
+type VerifyOptions struct {
+	Rehash, Repair bool
+}
+
+// Problem is a single inconsistency found by Verify. ArchiveID is 0 for orphaned files, and Path is the key
+// of the stored file, if any. Repaired is set if the problem was fixed, or a job fixing it was queued.
+type Problem struct {
+	Kind      string `json:"kind"`
+	ArchiveID int64  `json:"archive_id,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	Repaired  bool   `json:"repaired"`
+}
+
+// VerifyReport is the result of Verify. Entries and Files are the amount of entries and stored files that
+// were checked, and Rehashed is the amount of files that were read in full.
+type VerifyReport struct {
+	DateStarted  time.Time `json:"date_started"`
+	DateFinished time.Time `json:"date_finished"`
+	Entries      int       `json:"entries"`
+	Files        int       `json:"files"`
+	Rehashed     int       `json:"rehashed"`
+	Problems     []Problem `json:"problems"`
+}
+
+// Count returns the amount of problems of a kind.
+func (r VerifyReport) Count(kind string) int {
+	n := 0
+	for _, p := range r.Problems {
+		if p.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Verify checks that every entry has its stored file, hashes, timestamps, metadata and thumbnails, that
+// every stored file matches its entry, and that no stored file is left without an entry. Files are only
+// streamed through their hash, never read into memory at once.
+func (a *API) Verify(ctx context.Context, opt VerifyOptions) (VerifyReport, error) {
+	report := VerifyReport{DateStarted: time.Now(), Problems: []Problem{}}
+
+	entries, err := a.archive.GetEntries(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	known := make(map[string]bool, len(entries))
+	missing := 0
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		problems, err := a.verifyEntry(ctx, e, opt, &report, known)
+		if err != nil {
+			a.log.LogAttrs(ctx, log.LogLevelError, "failed to verify archive_id "+int64ToString(e.ID),
+				slog.Any("error", err),
+				slog.Int64("archive_id", e.ID))
+			return report, err
+		}
+		for _, p := range problems {
+			if p.Kind == ProblemMissingFile {
+				missing++
+			}
+		}
+		report.Problems = append(report.Problems, problems...)
+		report.Entries++
+	}
+
+	orphans, err := a.verifyOrphans(ctx, opt, known, missing)
+	if err != nil {
+		return report, err
+	}
+	report.Problems = append(report.Problems, orphans...)
+	report.DateFinished = time.Now()
+
+	a.log.LogAttrs(ctx, log.LogLevelInfo, fmt.Sprintf("verified %d entries, found %d problem(s)", report.Entries, len(report.Problems)),
+		slog.Int("entries", report.Entries),
+		slog.Int("files", report.Files),
+		slog.Int("problems", len(report.Problems)),
+		slog.Bool("rehash", opt.Rehash),
+		slog.Bool("repair", opt.Repair))
+	return report, nil
+}
+
+// verifyEntry checks a single entry, adding the keys of its stored files to known, along with the keys a
+// relayout may have moved them to.
+func (a *API) verifyEntry(ctx context.Context, e archive.Archive, opt VerifyOptions, report *VerifyReport, known map[string]bool) ([]Problem, error) {
+	var problems []Problem
+	known[e.Path] = true
+
+	info, err := a.storage.Stat(ctx, e.Path)
+	fileExists := err == nil
+	if errors.Is(err, storage.ErrNotExist) {
+		problems = append(problems, Problem{Kind: ProblemMissingFile, ArchiveID: e.ID, Path: e.Path})
+	} else if err != nil {
+		return nil, err
+	} else {
+		report.Files++
+	}
+
+	hashes, err := a.archive.GetHashes(ctx, e.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	layoutPaths := a.layoutPaths(e, hashes)
+	for _, lp := range layoutPaths {
+		known[lp] = true
+		if fileExists {
+			continue
+		}
+		if _, err := a.storage.Stat(ctx, lp); err == nil {
+			problems[0].Detail = "found at " + lp + ", run 'moonpool archive relayout' to finish moving it"
+		}
+	}
+
+	switch {
+	case len(hashes.Sha256) == 0:
+		p := Problem{Kind: ProblemMissingHashes, ArchiveID: e.ID, Path: e.Path}
+		if opt.Repair && fileExists {
+			err := a.rehashEntry(ctx, e)
+			if err == nil {
+				report.Rehashed++
+			}
+			p.Repaired, p.Detail = a.repair(ctx, e.ID, p.Kind, err)
+		}
+		problems = append(problems, p)
+	case opt.Rehash && fileExists:
+		sum, err := a.sha256Stored(ctx, e.Path)
+		if err != nil {
+			return nil, err
+		}
+		report.Rehashed++
+
+		if !bytes.Equal(sum, hashes.Sha256) {
+			problems = append(problems, Problem{Kind: ProblemCorruptFile, ArchiveID: e.ID, Path: e.Path,
+				Detail: fmt.Sprintf("sha256 is %s, expected %s", hex.EncodeToString(sum), hex.EncodeToString(hashes.Sha256))})
+		}
+	}
+
+	m, err := a.archive.GetFileMetadata(ctx, e.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		p := Problem{Kind: ProblemMissingMetadata, ArchiveID: e.ID, Path: e.Path}
+		if opt.Repair && fileExists {
+			p.Repaired, p.Detail = a.repairWithJob(ctx, e.ID, p.Kind, JobMetadata)
+		}
+		problems = append(problems, p)
+	} else if err != nil {
+		return nil, err
+	} else if fileExists && m.FileSize != info.Size {
+		problems = append(problems, Problem{Kind: ProblemCorruptFile, ArchiveID: e.ID, Path: e.Path,
+			Detail: fmt.Sprintf("size is %d bytes, expected %d bytes", info.Size, m.FileSize)})
+	}
+
+	t, err := a.archive.GetTimestamps(ctx, e.ID)
+	if err != nil {
+		return nil, err
+	}
+	if t.DateImported.UnixMilli() == 0 {
+		p := Problem{Kind: ProblemMissingTimestamps, ArchiveID: e.ID, Path: e.Path}
+		if opt.Repair {
+			date := time.Now()
+			if fileExists && !info.ModTime.IsZero() {
+				date = info.ModTime
+			}
+			p.Repaired, p.Detail = a.repair(ctx, e.ID, p.Kind,
+				a.archive.SetTimestamps(ctx, e.ID, mdb.Timestamp{DateCreated: date, DateModified: date, DateImported: time.Now()}))
+		}
+		problems = append(problems, p)
+	}
+
+	if a.thumbnail != nil {
+		_, err := a.getThumbnail(ctx, e.ID, "small", "jpeg")
+		if errors.Is(err, ErrThumbnailNotFound) {
+			p := Problem{Kind: ProblemMissingThumbnail, ArchiveID: e.ID, Path: e.Path}
+			if opt.Repair && fileExists {
+				p.Repaired, p.Detail = a.repairWithJob(ctx, e.ID, p.Kind, JobThumbnail)
+			}
+			problems = append(problems, p)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	renditions, err := a.archive.GetRenditions(ctx, e.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range renditions {
+		known[r.Path] = true
+		for _, lp := range layoutPaths {
+			known[renditionPath(lp, strings.TrimPrefix(path.Ext(r.Path), "."))] = true
+		}
+
+		_, err := a.storage.Stat(ctx, r.Path)
+		if err == nil {
+			report.Files++
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotExist) {
+			return nil, err
+		}
+
+		p := Problem{Kind: ProblemMissingRendition, ArchiveID: e.ID, Path: r.Path}
+		if opt.Repair {
+			if err := a.archive.DeleteRendition(ctx, e.ID, r.Mimetype); err != nil {
+				p.Repaired, p.Detail = a.repair(ctx, e.ID, p.Kind, err)
+			} else if !a.jobEnabled(JobTranscode) {
+				// without transcoding, removing the rendition is the whole repair
+				p.Repaired, p.Detail = a.repair(ctx, e.ID, p.Kind, nil)
+			} else {
+				p.Repaired, p.Detail = a.repairWithJob(ctx, e.ID, p.Kind, JobTranscode)
+			}
+		}
+		problems = append(problems, p)
+	}
+
+	return problems, nil
+}
+
+// verifyOrphans reports every stored file that isn't in known, outside of LostAndFound. While entries are
+// missing their file, orphans aren't repaired, since they may be those files left behind by a relayout.
+func (a *API) verifyOrphans(ctx context.Context, opt VerifyOptions, known map[string]bool, missing int) ([]Problem, error) {
+	// without a media directory, the local storage is the current or root directory, full of unrelated files
+	if l, ok := a.storage.(storage.Local); ok && (a.Config.MediaLocation == "" || l.Path("") == "/") {
+		a.log.LogAttrs(ctx, log.LogLevelWarn, "config had no path media is stored in. skipping search for orphaned files")
+		return nil, nil
+	}
+
+	files, err := a.storage.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, f := range files {
+		if known[f.Key] || strings.HasPrefix(f.Key, LostAndFound+"/") {
+			continue
+		}
+
+		p := Problem{Kind: ProblemOrphanFile, Path: f.Key, Detail: fmt.Sprintf("%d bytes", f.Size)}
+		if opt.Repair && missing > 0 {
+			p.Detail += fmt.Sprintf(", not moved while %d entries are missing their file", missing)
+		} else if opt.Repair {
+			p.Repaired, p.Detail = a.repair(ctx, 0, p.Kind, storage.Move(ctx, a.storage, f.Key, LostAndFound+"/"+f.Key))
+			if p.Repaired {
+				p.Detail = "moved to " + LostAndFound + "/" + f.Key
+			}
+		}
+		problems = append(problems, p)
+	}
+	return problems, nil
+}
+
+// layoutPaths returns the paths an entry is stored at in the layout of the archive and in the configured
+// layout, other than its own path. An interrupted relayout may have left its file at one of them.
+func (a *API) layoutPaths(e archive.Archive, hashes archive.HashesChksum) []string {
+	if len(hashes.Sha256) == 0 {
+		return nil
+	}
+
+	var paths []string
+	for _, l := range []file.Layout{a.layout, a.Config.Layout} {
+		if l == (file.Layout{}) {
+			continue
+		}
+		p, err := l.Path(entry.Hashes{MD5: hashes.Md5, SHA1: hashes.Sha1, SHA256: hashes.Sha256}, e.Extension)
+		if err != nil || p == e.Path || slices.Contains(paths, p) {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// rehashEntry sets the hashes of an entry from its stored file.
+func (a *API) rehashEntry(ctx context.Context, e archive.Archive) error {
+	rc, err := a.storage.Get(ctx, e.Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	h, _, err := file.GetHash(rc)
+	if err != nil {
+		return err
+	}
+	return a.SetHashes(ctx, e.ID, entry.Hashes(h))
+}
+
+// sha256Stored streams a stored file through SHA-256.
+func (a *API) sha256Stored(ctx context.Context, key string) ([]byte, error) {
+	rc, err := a.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// repair logs the outcome of a repair, returning whether it succeeded and the detail of its problem.
+func (a *API) repair(ctx context.Context, archive_id int64, kind string, err error) (bool, string) {
+	if err != nil {
+		a.log.LogAttrs(ctx, log.LogLevelWarn, "failed to repair "+kind+" of archive_id "+int64ToString(archive_id),
+			slog.Any("error", err),
+			slog.Int64("archive_id", archive_id),
+			slog.String("problem", kind))
+		return false, "repair failed, " + err.Error()
+	}
+
+	a.log.LogAttrs(ctx, log.LogLevelInfo, "repaired "+kind+" of archive_id "+int64ToString(archive_id),
+		slog.Int64("archive_id", archive_id),
+		slog.String("problem", kind))
+	return true, ""
+}
+
+// repairWithJob queues a job that repairs a problem.
+func (a *API) repairWithJob(ctx context.Context, archive_id int64, kind, jobType string) (bool, string) {
+	_, err := a.EnqueueJob(ctx, archive_id, jobType)
+	if ok, detail := a.repair(ctx, archive_id, kind, err); !ok {
+		return ok, detail
+	}
+	return true, "queued " + jobType + " job"
+}
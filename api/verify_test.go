@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/storage"
+)
+
+func TestAPI_Verify(t *testing.T) {
+	tests := []struct {
+		name         string
+		opt          VerifyOptions
+		damage       func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string)
+		wantKind     string
+		wantRepaired bool
+		wantFixed    bool
+	}{
+		{"missing file", VerifyOptions{}, func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string) {
+			if err := os.Remove(filepath.Join(a.Config.MediaLocation, path)); err != nil {
+				t.Fatal(err)
+			}
+		}, ProblemMissingFile, false, false},
+		{"corrupt file", VerifyOptions{Rehash: true}, func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string) {
+			f, err := os.OpenFile(filepath.Join(a.Config.MediaLocation, path), os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := f.WriteAt([]byte{0xFF}, 0); err != nil {
+				t.Fatal(err)
+			}
+		}, ProblemCorruptFile, false, false},
+		{"truncated file", VerifyOptions{}, func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string) {
+			if err := os.Truncate(filepath.Join(a.Config.MediaLocation, path), 1); err != nil {
+				t.Fatal(err)
+			}
+		}, ProblemCorruptFile, false, false},
+		{"orphan file", VerifyOptions{Repair: true}, func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string) {
+			if err := os.WriteFile(filepath.Join(a.Config.MediaLocation, "orphan.png"), []byte("orphan"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, ProblemOrphanFile, true, true},
+		{"missing rendition", VerifyOptions{Repair: true}, func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string) {
+			r := entry.Rendition{ArchiveID: archive_id, Path: renditionPath(path, "mp4"), Mimetype: "video/mp4",
+				VideoCodec: "h264", AudioCodec: "aac", FileSize: 9}
+			if err := a.archive.NewRendition(ctx, r); err != nil {
+				t.Fatal(err)
+			}
+		}, ProblemMissingRendition, true, true},
+		{"missing thumbnail", VerifyOptions{Repair: true}, func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string) {
+			if err := a.thumbnail.DeleteThumbnail(ctx, archive_id); err != nil {
+				t.Fatal(err)
+			}
+		}, ProblemMissingThumbnail, true, false},
+		{"interrupted relayout", VerifyOptions{Repair: true}, func(ctx context.Context, t *testing.T, a *API, archive_id int64, path string) {
+			a.Config.Layout = file.LegacyLayout
+			newPath, err := a.Config.Layout.Path(storedHashes(ctx, t, a, archive_id), ".png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := storage.Move(ctx, a.storage, path, newPath); err != nil {
+				t.Fatal(err)
+			}
+		}, ProblemMissingFile, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			archive_id := importTestPNG(ctx, t, mockAPI)
+			second := importTestImage(ctx, t, mockAPI, image.NewGray(image.Rect(0, 0, 8, 8)))
+			for _, id := range []int64{archive_id, second} {
+				if err := mockAPI.GenerateThumbnail(ctx, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			path, err := mockAPI.GetRelativePath(ctx, archive_id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := mockAPI.Verify(ctx, VerifyOptions{Rehash: true})
+			if err != nil {
+				t.Fatalf("API.Verify() error = %v", err)
+			}
+			if len(report.Problems) != 0 || report.Entries != 2 || report.Files != 2 || report.Rehashed != 2 {
+				t.Fatalf("API.Verify() before damage = %+v, want 2 entries and files without problems", report)
+			}
+
+			tt.damage(ctx, t, mockAPI, archive_id, path)
+
+			report, err = mockAPI.Verify(ctx, tt.opt)
+			if err != nil {
+				t.Fatalf("API.Verify() error = %v", err)
+			}
+			if len(report.Problems) != 1 || report.Problems[0].Kind != tt.wantKind || report.Problems[0].Repaired != tt.wantRepaired {
+				t.Fatalf("API.Verify() problems = %+v, want a single %s problem, repaired %v", report.Problems, tt.wantKind, tt.wantRepaired)
+			}
+
+			if !tt.wantFixed {
+				return
+			}
+
+			report, err = mockAPI.Verify(ctx, VerifyOptions{})
+			if err != nil {
+				t.Fatalf("API.Verify() after repair error = %v", err)
+			}
+			if len(report.Problems) != 0 {
+				t.Errorf("API.Verify() after repair problems = %+v, want none", report.Problems)
+			}
+		})
+	}
+}
+
+func TestAPI_Verify_OrphanWhileMissing(t *testing.T) {
+	mockAPI, err := newMockAPI(Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	archive_id := importTestPNG(ctx, t, mockAPI)
+	if err := mockAPI.GenerateThumbnail(ctx, archive_id); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := mockAPI.GetRelativePath(ctx, archive_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a file moved into a layout that isn't known to the archive
+	newPath, err := file.LegacyLayout.Path(storedHashes(ctx, t, mockAPI, archive_id), ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Move(ctx, mockAPI.storage, path, newPath); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := mockAPI.Verify(ctx, VerifyOptions{Repair: true})
+	if err != nil {
+		t.Fatalf("API.Verify() error = %v", err)
+	}
+	if report.Count(ProblemMissingFile) != 1 || report.Count(ProblemOrphanFile) != 1 {
+		t.Fatalf("API.Verify() problems = %+v, want a missing and an orphaned file", report.Problems)
+	}
+	for _, p := range report.Problems {
+		if p.Repaired {
+			t.Errorf("API.Verify() problem = %+v, want it left alone", p)
+		}
+	}
+
+	if _, err := mockAPI.storage.Stat(ctx, newPath); err != nil {
+		t.Errorf("storage.Stat() of the moved file error = %v, want it left in place", err)
+	}
+}
+
+// storedHashes returns the hashes of an entry.
+func storedHashes(ctx context.Context, t *testing.T, a *API, archive_id int64) entry.Hashes {
+	files, err := a.archive.GetStoredFiles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f.ArchiveID == archive_id {
+			return f.Hashes
+		}
+	}
+	t.Fatalf("archive_id %d isn't stored", archive_id)
+	return entry.Hashes{}
+}
@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dtbead/moonpool/api"
 	"github.com/dtbead/moonpool/config"
 	mdb "github.com/dtbead/moonpool/internal/db"
+	"github.com/dtbead/moonpool/internal/db/migration"
+	"github.com/dtbead/moonpool/internal/file"
 	"github.com/dtbead/moonpool/internal/log"
 	"github.com/urfave/cli/v2"
 )
@@ -24,6 +28,150 @@ var archive = cli.Command{
 		&archiveTags,
 		&archiveImport,
 		&archiveThumbnails,
+		&archiveMigrate,
+		&archiveNotes,
+		&archiveDuplicates,
+		&archiveJobs,
+		&archiveRelayout,
+		&archiveVerify,
+	},
+}
+
+var archiveRelayout = cli.Command{
+	Name:  "relayout",
+	Usage: "move every stored file into a new storage layout",
+	Description: `moves every stored file, along with its renditions, to its path in the layout given by --hash, --depth
+		and --width, which default to the layout in the config. each entry is moved on its own before its path is
+		updated, so an interrupted relayout can simply be run again.`,
+	Action: func(cCtx *cli.Context) error {
+		// the configured layout is where media is moved to, not where it is now
+		c := apiConfig(moonpoolConfig)
+		c.Layout = file.Layout{}
+
+		moonpool, err := api.Open(c, log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		l := file.Layout(moonpoolConfig.StorageLayout)
+		if l == (file.Layout{}) {
+			l = file.DefaultLayout
+		}
+		if cCtx.IsSet("hash") {
+			l.Hash = cCtx.String("hash")
+		}
+		if cCtx.IsSet("depth") {
+			l.Depth = cCtx.Int("depth")
+		}
+		if cCtx.IsSet("width") {
+			l.Width = cCtx.Int("width")
+		}
+
+		fmt.Printf("moving media from layout '%s' into '%s'\n", moonpool.Layout(), l)
+		moved, err := moonpool.Relayout(cCtx.Context, l)
+		if err != nil {
+			return fmt.Errorf("moved %d entries before failing, %w", moved, err)
+		}
+
+		fmt.Printf("moved %d entries\n", moved)
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "hash",
+			Usage: "hash files are named after, one of md5, sha1 or sha256",
+		},
+		&cli.IntFlag{
+			Name:  "depth",
+			Usage: "amount of directories files are nested in",
+		},
+		&cli.IntFlag{
+			Name:  "width",
+			Usage: "amount of hash characters each directory is named after",
+		},
+	},
+}
+
+var archiveVerify = cli.Command{
+	Name:  "verify",
+	Usage: "check stored media and entries for missing or corrupted data",
+	Description: `checks that every entry has its stored file, hashes, timestamps, metadata and thumbnails, and that
+		no stored file is left without an entry. with --rehash, every stored file is read in full and compared
+		against its sha256 hash, rather than only its size. with --repair, orphaned files are moved into
+		"lost+found" and missing data is restored, or queued as a job for "archive jobs --run" to process.`,
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		report, err := moonpool.Verify(cCtx.Context, api.VerifyOptions{Rehash: cCtx.Bool("rehash"), Repair: cCtx.Bool("repair")})
+		if err != nil {
+			return err
+		}
+
+		if cCtx.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			for _, p := range report.Problems {
+				fmt.Printf("%s\tarchive_id: %d\tpath: %s", p.Kind, p.ArchiveID, p.Path)
+				if p.Repaired {
+					fmt.Print("\trepaired")
+				}
+				if p.Detail != "" {
+					fmt.Printf("\t(%s)", p.Detail)
+				}
+				fmt.Println()
+			}
+			fmt.Printf("checked %d entries and %d files (%d rehashed), found %d problem(s) in %s\n",
+				report.Entries, report.Files, report.Rehashed, len(report.Problems), report.DateFinished.Sub(report.DateStarted).Round(time.Millisecond))
+		}
+
+		unrepaired := 0
+		for _, p := range report.Problems {
+			if !p.Repaired {
+				unrepaired++
+			}
+		}
+		if unrepaired > 0 {
+			return fmt.Errorf("%d problem(s) left unrepaired", unrepaired)
+		}
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "rehash",
+			Usage: "read every stored file and compare it against its sha256 hash",
+		},
+		&cli.BoolFlag{
+			Name:  "repair",
+			Usage: "fix every problem that can be fixed",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the report as json",
+		},
+	},
+}
+
+var archiveNotes = cli.Command{
+	Name:     "notes",
+	Category: "notes",
+	Usage:    "manage notes",
+	Subcommands: []*cli.Command{
+		&notesList,
+		&notesAdd,
+		&notesEdit,
+		&notesDelete,
+		&notesSearch,
 	},
 }
 
@@ -117,11 +265,7 @@ var archiveRemove = cli.Command{
 	Name:  "remove",
 	Usage: "completely remove an entry from moonpool",
 	Action: func(cCtx *cli.Context) error {
-		moonpool, err := api.Open(api.Config{
-			ArchiveLocation:   moonpoolConfig.ArchivePath,
-			ThumbnailLocation: moonpoolConfig.ThumbnailPath,
-			MediaLocation:     moonpoolConfig.MediaPath,
-		}, log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel)))
+		moonpool, err := api.Open(apiConfig(moonpoolConfig), log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel)))
 		if err != nil {
 			return err
 		}
@@ -141,6 +285,208 @@ var archiveRemove = cli.Command{
 	},
 }
 
+var archiveMigrate = cli.Command{
+	Name:  "migrate",
+	Usage: "upgrade or downgrade the archive database schema",
+	Description: `applies every pending migration to the archive and thumbnail databases.
+		--to only applies to the archive database; the thumbnail database is always brought to its latest version.`,
+	Action: func(cCtx *cli.Context) error {
+		archive, err := mdb.OpenSQLite3(moonpoolConfig.ArchivePath)
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+
+		if err := runMigrations(cCtx, "archive", archive, mdb.MigrateArchive, cCtx.Int64("to")); err != nil {
+			return err
+		}
+
+		if moonpoolConfig.ThumbnailPath == "" {
+			return nil
+		}
+
+		thumbnail, err := mdb.OpenSQLite3(moonpoolConfig.ThumbnailPath)
+		if err != nil {
+			return err
+		}
+		defer thumbnail.Close()
+
+		return runMigrations(cCtx, "thumbnail", thumbnail, mdb.MigrateThumbnail, migration.LATEST)
+	},
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "to",
+			Usage: "database version to migrate to (-1 for latest)",
+			Value: migration.LATEST,
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "print pending migrations without applying them",
+		},
+	},
+}
+
+var archiveDuplicates = cli.Command{
+	Name:  "duplicates",
+	Usage: "find near-duplicate entries by their perceptual hash",
+	Description: `groups every entry whose perceptual hash is within --distance bits of another entry.
+		with --id, only lists entries similar to the given archive id, closest first.`,
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		if archive_id := cCtx.Int64("id"); archive_id > 0 {
+			similar, err := moonpool.FindSimilar(cCtx.Context, archive_id, cCtx.String("type"), cCtx.Int("distance"))
+			if err != nil {
+				return err
+			}
+
+			for _, s := range similar {
+				fmt.Printf("archive_id: %d\tdistance: %d\n", s.ArchiveID, s.Distance)
+			}
+			fmt.Printf("found %d similar entries\n", len(similar))
+			return nil
+		}
+
+		groups, err := moonpool.FindDuplicateGroups(cCtx.Context, cCtx.String("type"), cCtx.Int("distance"))
+		if err != nil {
+			return err
+		}
+
+		for i, group := range groups {
+			fmt.Printf("group %d (%d entries)\n", i+1, len(group))
+			for _, archive_id := range group {
+				entry, err := moonpool.GetEntry(cCtx.Context, archive_id)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("\tarchive_id: %d\tpath: %s\n", archive_id, entry.Path)
+			}
+		}
+		fmt.Printf("found %d duplicate groups\n", len(groups))
+
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:    "distance",
+			Aliases: []string{"d"},
+			Usage:   "maximum hamming distance between two perceptual hashes",
+			Value:   api.DefaultSimilarDistance,
+		},
+		&cli.StringFlag{
+			Name:  "type",
+			Usage: "perceptual hash type to compare (" + strings.Join(file.PerceptualHashTypes, ", ") + ", " + file.VHash + ")",
+			Value: file.PHash,
+		},
+		&cli.Int64Flag{
+			Name:  "id",
+			Usage: "only find entries similar to this archive id",
+		},
+	},
+}
+
+var archiveJobs = cli.Command{
+	Name:  "jobs",
+	Usage: "inspect and run background jobs",
+	Description: `lists the amount of jobs of each status, followed by the most recent jobs.
+		with --run, every pending job is processed before listing, as "moonpool launch" would.
+		with --retry, a failed job is queued again.
+		with --enqueue, jobs of the given types are queued for every entry that never had one,
+		or for every entry with --all. this backfills entries imported before a job type existed.
+		photos imported before their EXIF orientation was applied are corrected by reading their metadata first,
+		"--enqueue metadata --all --run", then "--enqueue thumbnail,phash,blurhash --all --run".`,
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		if job_id := cCtx.Int64("retry"); job_id > 0 {
+			if err := moonpool.RetryJob(cCtx.Context, job_id); err != nil {
+				return err
+			}
+			fmt.Printf("queued job %d again\n", job_id)
+		}
+
+		for _, jobType := range cCtx.StringSlice("enqueue") {
+			n, err := moonpool.BackfillJobs(cCtx.Context, strings.TrimSpace(jobType), cCtx.Bool("all"))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("queued %d %s jobs\n", n, jobType)
+		}
+
+		if cCtx.Bool("run") {
+			if err := moonpool.RunPendingJobs(cCtx.Context, cCtx.Int("workers")); err != nil {
+				return err
+			}
+		}
+
+		counts, err := moonpool.CountJobs(cCtx.Context)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("pending: %d\trunning: %d\tdone: %d\tfailed: %d\n",
+			counts[api.JobPending], counts[api.JobRunning], counts[api.JobDone], counts[api.JobFailed])
+
+		jobs, err := moonpool.GetJobs(cCtx.Context, cCtx.String("status"), cCtx.Int64("limit"), 0)
+		if err != nil {
+			return err
+		}
+
+		for _, j := range jobs {
+			fmt.Printf("job_id: %d\tarchive_id: %d\ttype: %s\tstatus: %s\tattempts: %d\n",
+				j.JobID, j.ArchiveID, j.Type, j.Status, j.Attempts)
+			if j.Error != "" {
+				fmt.Printf("\terror: %s\n", j.Error)
+			}
+		}
+
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "status",
+			Usage: "only list jobs of this status (pending, running, done, failed)",
+		},
+		&cli.Int64Flag{
+			Name:  "limit",
+			Usage: "maximum amount of jobs to list",
+			Value: 20,
+		},
+		&cli.BoolFlag{
+			Name:  "run",
+			Usage: "process every pending job before listing",
+		},
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "amount of jobs to run at once with --run",
+			Value: config.DefaultValues().JobWorkers,
+		},
+		&cli.Int64Flag{
+			Name:  "retry",
+			Usage: "job id of a failed job to queue again",
+		},
+		&cli.StringSliceFlag{
+			Name:  "enqueue",
+			Usage: "job types to queue for existing entries (" + strings.Join(api.JobTypes, ", ") + ")",
+		},
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "with --enqueue, queue the job for every entry, even ones that already ran it",
+		},
+	},
+}
+
 var tagsSet = cli.Command{
 	Name:     "set",
 	Category: "tags",
@@ -151,7 +497,7 @@ var tagsSet = cli.Command{
 		removing tags: --tag "-foo, -bar"`,
 	Action: func(cCtx *cli.Context) error {
 		moonpool, err := api.Open(
-			api.Config{ArchiveLocation: moonpoolConfig.ArchivePath, MediaLocation: moonpoolConfig.MediaPath},
+			apiConfig(moonpoolConfig),
 			slog.New(slog.NewTextHandler(os.Stdout, nil)))
 		if err != nil {
 			return err
@@ -237,14 +583,15 @@ var tagsQuery = cli.Command{
 	Usage:    "search for a custom tag query",
 	Action: func(cCtx *cli.Context) error {
 		moonpool, err := api.Open(
-			api.Config{ArchiveLocation: moonpoolConfig.ArchivePath, MediaLocation: moonpoolConfig.MediaPath},
+			apiConfig(moonpoolConfig),
 			slog.New(slog.NewTextHandler(os.Stdout, nil)))
 		if err != nil {
 			return err
 		}
 		defer moonpool.Close(cCtx.Context)
 
-		res, err := moonpool.QueryTags(cCtx.Context, "imported", "descending", api.BuildQuery(cCtx.String("tags")))
+		res, total, err := moonpool.QueryTags(cCtx.Context, cCtx.String("sort"), cCtx.String("order"), cCtx.String("tags"),
+			cCtx.Int64("limit"), cCtx.Int64("offset"))
 		if err != nil {
 			return err
 		}
@@ -257,12 +604,31 @@ var tagsQuery = cli.Command{
 			fmt.Printf("archive_id: %d\tpath: %s\textension:%s\n", entry.ArchiveID, entry.Path, entry.Extension)
 		}
 
+		fmt.Printf("showing %d of %d result(s)\n", len(res), total)
 		return nil
 	},
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "tags",
-			Usage: "comma-separated tags to query for",
+			Usage: `tag query, e.g. "(cat OR dog) AND NOT bird* width:>1920 size:<5MB"`,
+		},
+		&cli.StringFlag{
+			Name:  "sort",
+			Usage: "sort results by 'imported', 'created' or 'modified'",
+			Value: "imported",
+		},
+		&cli.StringFlag{
+			Name:  "order",
+			Usage: "order results 'descending' or 'ascending'",
+			Value: "descending",
+		},
+		&cli.Int64Flag{
+			Name:  "limit",
+			Usage: "maximum amount of results to show, 0 shows every result",
+		},
+		&cli.Int64Flag{
+			Name:  "offset",
+			Usage: "amount of results to skip",
 		},
 	},
 }
@@ -275,7 +641,7 @@ var tagsList = cli.Command{
 	Args:     true,
 	Action: func(cCtx *cli.Context) error {
 		moonpool, err := api.Open(
-			api.Config{ArchiveLocation: moonpoolConfig.ArchivePath, MediaLocation: moonpoolConfig.MediaPath},
+			apiConfig(moonpoolConfig),
 			slog.New(slog.NewTextHandler(os.Stdout, nil)))
 		if err != nil {
 			return err
@@ -318,7 +684,7 @@ var thumbnailGenerateIcons = cli.Command{
 	Args:     true,
 	Action: func(cCtx *cli.Context) error {
 		moonpool, err := api.Open(
-			api.Config{ArchiveLocation: moonpoolConfig.ArchivePath, MediaLocation: moonpoolConfig.MediaPath, ThumbnailLocation: moonpoolConfig.ThumbnailPath},
+			apiConfig(moonpoolConfig),
 			slog.New(slog.NewTextHandler(os.Stdout, nil)))
 		if err != nil {
 			return err
@@ -348,7 +714,7 @@ var thumbnailGenerateBlurHash = cli.Command{
 	Args:     true,
 	Action: func(cCtx *cli.Context) error {
 		moonpool, err := api.Open(
-			api.Config{ArchiveLocation: moonpoolConfig.ArchivePath, MediaLocation: moonpoolConfig.MediaPath, ThumbnailLocation: moonpoolConfig.ThumbnailPath},
+			apiConfig(moonpoolConfig),
 			slog.New(slog.NewTextHandler(os.Stdout, nil)))
 		if err != nil {
 			return err
@@ -375,3 +741,191 @@ var thumbnailGenerateBlurHash = cli.Command{
 		},
 	},
 }
+
+var notesList = cli.Command{
+	Name:     "list",
+	Aliases:  []string{"l"},
+	Category: "notes",
+	Usage:    "list all notes associated with an archive_id",
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		if !moonpool.DoesEntryExist(cCtx.Context, cCtx.Int64("id")) {
+			fmt.Println("id does not exist")
+			return nil
+		}
+
+		notes, err := moonpool.GetNotes(cCtx.Context, cCtx.Int64("id"))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("found %d note(s)\n", len(notes))
+		for _, n := range notes {
+			printNote(n)
+		}
+
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:     "id",
+			Usage:    "archive id to list notes from",
+			Required: true,
+		},
+	},
+}
+
+var notesAdd = cli.Command{
+	Name:     "add",
+	Aliases:  []string{"a"},
+	Category: "notes",
+	Usage:    "add a new note to an archive_id",
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		note_id, err := moonpool.NewNote(cCtx.Context, cCtx.Int64("id"), cCtx.String("title"), cCtx.String("text"))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("created note %d\n", note_id)
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:     "id",
+			Usage:    "archive id to add note to",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "title",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "text",
+			Required: true,
+		},
+	},
+}
+
+var notesEdit = cli.Command{
+	Name:     "edit",
+	Aliases:  []string{"e"},
+	Category: "notes",
+	Usage:    "replace the title and/or text of a note",
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		n, err := moonpool.GetNote(cCtx.Context, cCtx.Int64("note"))
+		if err != nil {
+			return err
+		}
+
+		if cCtx.IsSet("title") {
+			n.Title = cCtx.String("title")
+		}
+
+		if cCtx.IsSet("text") {
+			n.Text = cCtx.String("text")
+		}
+
+		return moonpool.UpdateNote(cCtx.Context, n.NoteID, n.Title, n.Text)
+	},
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:     "note",
+			Usage:    "note id to edit",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name: "title",
+		},
+		&cli.StringFlag{
+			Name: "text",
+		},
+	},
+}
+
+var notesDelete = cli.Command{
+	Name:     "delete",
+	Aliases:  []string{"d"},
+	Category: "notes",
+	Usage:    "delete a note",
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		return moonpool.DeleteNote(cCtx.Context, cCtx.Int64("note"))
+	},
+	Flags: []cli.Flag{
+		&cli.Int64Flag{
+			Name:     "note",
+			Usage:    "note id to delete",
+			Required: true,
+		},
+	},
+}
+
+var notesSearch = cli.Command{
+	Name:     "search",
+	Aliases:  []string{"s"},
+	Category: "notes",
+	Usage:    "search for notes containing text",
+	Action: func(cCtx *cli.Context) error {
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
+
+		notes, err := moonpool.SearchNotes(cCtx.Context, cCtx.String("query"), cCtx.Int64("limit"), 0)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("found %d note(s)\n", len(notes))
+		for _, n := range notes {
+			printNote(n)
+		}
+
+		return nil
+	},
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "query",
+			Usage:    "text to search for in note titles and text",
+			Required: true,
+		},
+		&cli.Int64Flag{
+			Name:  "limit",
+			Usage: "max amount of notes to return",
+			Value: 50,
+		},
+	},
+}
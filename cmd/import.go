@@ -1,16 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"slices"
-	"strings"
+	"runtime"
+	"syscall"
+	"time"
 
 	"github.com/dtbead/moonpool/api"
-	"github.com/dtbead/moonpool/importer"
 	"github.com/dtbead/moonpool/internal/log"
+	"github.com/dtbead/moonpool/internal/pipeline"
 	"github.com/urfave/cli/v2"
 )
 
@@ -19,128 +21,141 @@ var archiveImport = cli.Command{
 	Usage: "imports a new file into moonpool",
 	Action: func(cCtx *cli.Context) error {
 		path := cCtx.Path("path")
+		manifestPath := cCtx.Path("manifest")
 
-		moonpool, err := api.Open(
-			api.Config{ArchiveLocation: moonpoolConfig.ArchivePath, MediaLocation: moonpoolConfig.MediaPath, ThumbnailLocation: moonpoolConfig.ThumbnailPath},
-			log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel)))
-		if err != nil {
-			return err
-		}
-		defer moonpool.Close(cCtx.Context)
-
-		var imported, failed int
-		var ext strings.Builder
+		var manifest *pipeline.Manifest
+		if resume := cCtx.Path("resume"); resume != "" {
+			m, err := pipeline.LoadManifest(resume)
+			if err != nil {
+				fmt.Printf("failed to load manifest \"%s\"\n", resume)
+				return err
+			}
+			manifest = m
 
-		var supportedExt []string = []string{
-			".png",
-			".jpg",
-			".jpeg",
-			".webp",
-			".gif",
-			".mp4",
+			if path == "" {
+				path = m.Root
+			}
+			if manifestPath == "" {
+				manifestPath = resume
+			}
+		} else if manifestPath != "" {
+			manifest = pipeline.NewManifest("")
 		}
 
-		moonpoolTX, err := moonpool.BeginTX(cCtx.Context)
-		if err != nil {
-			fmt.Println("failed to begin tx")
-			return err
+		if path == "" {
+			return errors.New("a path to import from is required")
+		}
+		if manifest != nil && manifest.Root == "" {
+			manifest.Root, _ = filepath.Abs(path)
 		}
 
-		var scan = func(path string, d os.DirEntry, inpErr error) (err error) {
-			ext.Reset()
-			ext.WriteString(filepath.Ext(path))
+		// finish the file being imported on an interrupt, so that the manifest can be resumed
+		ctx, stop := signal.NotifyContext(cCtx.Context, os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-			if !slices.Contains(supportedExt, ext.String()) {
-				fmt.Printf("skipped \"%s\" (unsupported format)\n", path)
-				failed++
-				return nil
+		saveManifest := func() {
+			if manifest == nil {
+				return
 			}
-
-			f, err := os.Open(path)
-			if err != nil {
-				failed++
-				return err
+			if err := manifest.Save(manifestPath); err != nil {
+				fmt.Printf("\nfailed to save manifest \"%s\", %v\n", manifestPath, err)
 			}
-			defer f.Close()
+		}
 
-			archive_id, err := fileImport(*cCtx, moonpoolTX.API, f, ext.String())
-			if err != nil {
-				failed++
-				return err
-			}
+		moonpool, err := api.Open(
+			apiConfig(moonpoolConfig),
+			log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel)))
+		if err != nil {
+			return err
+		}
+		defer moonpool.Close(cCtx.Context)
 
-			_ = moonpool.GeneratePerceptualHash(cCtx.Context, archive_id, "", f)
-			_ = moonpool.GenerateThumbnail(cCtx.Context, archive_id)
+		var lastPrint, lastSave time.Time
+		progress, err := pipeline.Import(ctx, moonpool, path, pipeline.Options{
+			Jobs:     cCtx.Int("jobs"),
+			Tags:     cCtx.StringSlice("tags"),
+			Process:  cCtx.Bool("process"),
+			Manifest: manifest,
+			Progress: func(p pipeline.Progress) {
+				switch p.Last.Status {
+				case pipeline.StatusSkipped:
+					fmt.Printf("\rskipped \"%s\" (%v)\n", p.Last.Path, p.Last.Err)
+				case pipeline.StatusFailed:
+					fmt.Printf("\rfailed to import \"%s\", %v\n", p.Last.Path, p.Last.Err)
+				}
+
+				if time.Since(lastSave) >= time.Second {
+					lastSave = time.Now()
+					saveManifest()
+				}
+
+				if p.Done < p.Total && time.Since(lastPrint) < 100*time.Millisecond {
+					return
+				}
+				lastPrint = time.Now()
+				fmt.Printf("\r%d/%d files (%d imported, %d duplicates, %d skipped, %d failed)",
+					p.Done, p.Total, p.Imported, p.Duplicates, p.Skipped, p.Failed)
+			},
+		})
+		if progress.Total > 0 {
+			fmt.Println()
+		}
+		saveManifest()
 
-			imported++
-			return nil
+		if progress.Resumed > 0 {
+			fmt.Printf("resumed after %d files that were already completed\n", progress.Resumed)
 		}
 
-		err = filepath.WalkDir(path, scan)
 		if err != nil {
+			if manifest != nil && ctx.Err() != nil {
+				fmt.Printf("import interrupted, continue with 'moonpool archive import --resume %s'\n", manifestPath)
+			}
 			return err
 		}
 
-		err = moonpoolTX.Commit(cCtx.Context)
-		if err != nil {
-			return err
+		fmt.Printf("imported %d entries (%d duplicates, %d skipped, %d failed)\n",
+			progress.Imported, progress.Duplicates, progress.Skipped, progress.Failed)
+		if progress.Imported > 0 && !cCtx.Bool("process") {
+			fmt.Println("thumbnails, hashes and metadata are generated in the background by 'moonpool launch' or 'moonpool archive jobs --run'")
 		}
 
-		fmt.Printf("imported %d entries (%d failed)\n", imported, failed)
+		if progress.Failed > 0 {
+			if manifest != nil {
+				fmt.Printf("retry the failed files with 'moonpool archive import --resume %s'\n", manifestPath)
+			}
+			return fmt.Errorf("failed to import %d files", progress.Failed)
+		}
 		return nil
 	},
 	Flags: []cli.Flag{
 		&cli.PathFlag{
-			Name:     "path",
-			Aliases:  []string{"f, p"},
-			Usage:    "file or folder to import from",
-			Required: true,
+			Name:    "path",
+			Aliases: []string{"f, p"},
+			Usage:   "file or folder to import from, defaults to the folder of the resumed manifest",
 		},
 		&cli.StringSliceFlag{
 			Name:    "tags",
 			Aliases: []string{"t"},
 			Usage:   "tags to assign with during import",
 		},
+		&cli.IntFlag{
+			Name:    "jobs",
+			Aliases: []string{"j"},
+			Usage:   "amount of files to hash, and background jobs to process, at once",
+			Value:   runtime.NumCPU(),
+		},
+		&cli.BoolFlag{
+			Name:  "process",
+			Usage: "generate thumbnails, hashes and metadata right after importing instead of in the background",
+		},
+		&cli.PathFlag{
+			Name:    "manifest",
+			Aliases: []string{"m"},
+			Usage:   "write the result of every file to a JSON manifest, or CSV if the path ends in .csv",
+		},
+		&cli.PathFlag{
+			Name:  "resume",
+			Usage: "continue an interrupted import from its manifest, skipping files that were already completed",
+		},
 	},
 }
-
-func fileImport(cCtx cli.Context, moonpool api.API, f *os.File, ext string) (archive_id int64, err error) {
-	importer, err := importer.New(f, ext)
-	if err != nil {
-		return -1, err
-	}
-
-	err = moonpool.NewSavepoint(cCtx.Context, "folderimport")
-	if err != nil {
-		return -1, err
-	}
-	defer moonpool.RollbackSavepoint(cCtx.Context, "folderimport")
-
-	archive_id, err = moonpool.Import(cCtx.Context, importer)
-	if err != nil {
-		return -1, err
-	}
-
-	err = moonpool.SetTimestamps(cCtx.Context, archive_id, importer.Timestamp())
-	if err != nil {
-		return -1, err
-	}
-
-	err = moonpool.AssignTags(cCtx.Context, archive_id, cCtx.StringSlice("tags"))
-	if err != nil {
-		return -1, err
-	}
-
-	err = moonpool.GenerateFileMetadata(cCtx.Context, archive_id)
-	if err != nil {
-		return -1, err
-	}
-
-	err = moonpool.ReleaseSavepoint(cCtx.Context, "folderimport")
-	if err != nil {
-		return -1, err
-	}
-
-	f.Seek(0, io.SeekStart)
-	return archive_id, nil
-}
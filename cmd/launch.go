@@ -28,11 +28,18 @@ var launch = cli.Command{
 			moonpoolConfig.WebUIPort = cCtx.Int("webui")
 		}
 
+		if cCtx.IsSet("workers") {
+			moonpoolConfig.JobWorkers = cCtx.Int("workers")
+		}
+
+		if cCtx.IsSet("scrub") {
+			moonpoolConfig.ScrubMetadata = cCtx.Bool("scrub")
+		}
+
 		loggerMain := log.New(log.StringToLogLevel(moonpoolConfig.Logging.LogLevel))
 		loggerWebUI := loggerMain.WithGroup("webui")
 
-		apiConfig := api.Config{ArchiveLocation: moonpoolConfig.ArchivePath, MediaLocation: moonpoolConfig.MediaPath, ThumbnailLocation: moonpoolConfig.ThumbnailPath}
-		moonpoolAPI, err := api.Open(apiConfig, loggerMain)
+		moonpoolAPI, err := api.Open(apiConfig(moonpoolConfig), loggerMain)
 		if err != nil {
 			return err
 		}
@@ -40,16 +47,30 @@ var launch = cli.Command{
 		webFrontend, err := www.New(moonpoolAPI, www.Config{
 			DynamicWebReloading:     moonpoolConfig.Debug.DynamicWebReloading.Enable,
 			DynamicWebReloadingPath: moonpoolConfig.Debug.DynamicWebReloading.Path,
+			ScrubMetadata:           moonpoolConfig.ScrubMetadata,
 			Log:                     loggerWebUI,
 		})
 		if err != nil {
 			return err
 		}
 
+		jobsCtx, stopJobs := context.WithCancel(context.Background())
+		jobsDone := make(chan struct{})
+		services := make(chan error, 2)
+		go func() {
+			defer close(jobsDone)
+			if err := moonpoolAPI.RunJobWorkers(jobsCtx, moonpoolConfig.JobWorkers); err != nil {
+				services <- fmt.Errorf("failed to start job workers, %w", err)
+			}
+		}()
+
 		shutdown := func() error {
+			stopJobs()
+			<-jobsDone
+
 			return errors.Join(
-				moonpoolAPI.Close(context.Background()),
 				webFrontend.Shutdown(context.Background()),
+				moonpoolAPI.Close(context.Background()),
 			)
 		}
 
@@ -66,7 +87,6 @@ var launch = cli.Command{
 			}
 		}()
 
-		services := make(chan error, 1)
 		go func() {
 			err := webFrontend.Start(fmt.Sprintf("%s:%d", moonpoolConfig.ListenAddress, moonpoolConfig.WebUIPort))
 			if err != nil {
@@ -94,5 +114,15 @@ var launch = cli.Command{
 			Usage: "port to launch webui on",
 			Value: config.DefaultValues().WebUIPort,
 		},
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "amount of background jobs to run at once",
+			Value: config.DefaultValues().JobWorkers,
+		},
+		&cli.BoolFlag{
+			Name:  "scrub",
+			Usage: "strip location and device metadata from every photo served",
+			Value: config.DefaultValues().ScrubMetadata,
+		},
 	},
 }
@@ -27,7 +27,7 @@ var mock = cli.Command{
 		}
 
 		moonpool, err := api.Open(
-			api.Config{ArchiveLocation: c.ArchivePath, MediaLocation: c.MediaPath},
+			apiConfig(c),
 			slog.New(slog.NewTextHandler(os.Stdout, nil)))
 		if err != nil {
 			return err
@@ -1,5 +1,45 @@
 package cmd
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dtbead/moonpool/api"
+	"github.com/dtbead/moonpool/config"
+	"github.com/dtbead/moonpool/entry"
+	"github.com/dtbead/moonpool/internal/db/migration"
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/storage"
+	"github.com/urfave/cli/v2"
+)
+
+func printNote(n entry.Note) {
+	fmt.Printf("note_id: %d\tarchive_id: %d\tmodified: %s\n\t%s\n\t%s\n",
+		n.NoteID, n.ArchiveID, n.DateModified.Local().Format(time.DateTime), n.Title, strings.ReplaceAll(n.Text, "\n", "\n\t"))
+}
+
+// apiConfig returns the settings of c that the API is opened with.
+func apiConfig(c config.Config) api.Config {
+	return api.Config{
+		ArchiveLocation:     c.ArchivePath,
+		ThumbnailLocation:   c.ThumbnailPath,
+		MediaLocation:       c.MediaPath,
+		Storage:             storage.Config(c.Storage),
+		Layout:              file.Layout(c.StorageLayout),
+		PerceptualHashTypes: c.PerceptualHashTypes,
+		VideoHashFrames:     c.VideoHashFrames,
+		JobMaxAttempts:      c.JobMaxAttempts,
+		ThumbnailSizes:      c.ThumbnailSizes,
+		ThumbnailFormats:    c.ThumbnailFormats,
+		AnimatedPreviews:    c.AnimatedPreviews,
+		StoryboardFrames:    c.StoryboardFrames,
+		TranscodeFormat:     c.TranscodeFormat,
+	}
+}
+
 func contains(set []string, value string) bool {
 	for _, k := range set {
 		if k == value {
@@ -8,3 +48,32 @@ func contains(set []string, value string) bool {
 	}
 	return false
 }
+
+// runMigrations prints the current version of db and every migration applied (or, with --dry-run, pending).
+func runMigrations(cCtx *cli.Context, name string, db *sql.DB,
+	migrate func(context.Context, *sql.DB, int64, bool) ([]migration.Migrator, error), target int64) error {
+	current, err := migration.GetVersion(cCtx.Context, db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s database is at version %d\n", name, current)
+
+	dryRun := cCtx.Bool("dry-run")
+	plan, err := migrate(cCtx.Context, db, target, dryRun)
+	for _, m := range plan {
+		if dryRun {
+			fmt.Printf("\tpending: %s\n", m)
+		} else {
+			fmt.Printf("\tapplied: %s\n", m)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s database, %w", name, err)
+	}
+
+	if len(plan) == 0 {
+		fmt.Printf("\tnothing to do\n")
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/dtbead/moonpool/internal/log"
+	"github.com/dtbead/moonpool/internal/media"
 )
 
 const (
@@ -19,6 +20,26 @@ type DynamicWebReloading struct {
 	Path   string
 }
 
+// Storage selects where media files are stored. Type is either "local", which stores them in MediaPath, or
+// "s3", which stores them in Bucket of an Amazon S3 or S3-compatible service such as MinIO at Endpoint. Every
+// other field only applies to "s3". PathStyle is required by most self-hosted services, and the default AWS
+// credentials are used if AccessKeyID is empty.
+type Storage struct {
+	Type                         string
+	Endpoint, Region             string
+	Bucket, Prefix               string
+	AccessKeyID, SecretAccessKey string
+	PathStyle                    bool
+}
+
+// Layout is the layout media is stored in by a new archive, see file.Layout. Hash is one of "md5", "sha1" or
+// "sha256", files are nested Depth directories deep, and each directory is named after Width characters of
+// the hash. Existing archives keep their layout until moved with "moonpool archive relayout".
+type Layout struct {
+	Hash         string
+	Depth, Width int
+}
+
 type Config struct {
 	Debug struct {
 		DynamicWebReloading DynamicWebReloading
@@ -31,20 +52,57 @@ type Config struct {
 		FileLogging     bool
 	}
 	MediaPath     string
+	Storage       Storage
+	StorageLayout Layout
 	ArchivePath   string
 	ThumbnailPath string
 	ListenAddress string
 	WebUIPort     int
+	// PerceptualHashTypes are the perceptual hashes generated for every imported image,
+	// e.g. "AHash", "DHash", "PHash", "WHash", "AHash16", "DHash16", "PHash16".
+	PerceptualHashTypes []string
+	// VideoHashFrames is the amount of frames sampled from every imported video for its fingerprint.
+	VideoHashFrames int
+	// JobWorkers is the amount of background jobs, such as thumbnail generation, run at once by "launch".
+	JobWorkers int
+	// JobMaxAttempts is the amount of times a background job is attempted before it is marked as failed.
+	JobMaxAttempts int
+	// ThumbnailSizes are the largest width and height, in pixels, of each size of thumbnail.
+	ThumbnailSizes media.ThumbnailSizes
+	// ThumbnailFormats are encoded alongside the JPEG thumbnails every entry has, e.g. "webp" or "avif".
+	// Both are encoded through ffmpeg.
+	ThumbnailFormats []string
+	// AnimatedPreviews generates a short animated preview of every video and animated GIF, shown when hovering
+	// over them while browsing.
+	AnimatedPreviews bool
+	// StoryboardFrames is the amount of frames of every imported video shown when hovering over its seek bar.
+	StoryboardFrames int
+	// TranscodeFormat is the format imported videos web browsers can't play are transcoded into, either "mp4"
+	// (H.264/AAC) or "webm" (VP9/Opus). Originals are kept either way. If empty, videos aren't transcoded.
+	TranscodeFormat string
+	// ScrubMetadata removes the location and device metadata of every photo served by "launch". Stored
+	// files are left untouched either way.
+	ScrubMetadata bool
 }
 
 // DefaultValues returns a config with sane defaults
 func DefaultValues() Config {
 	c := Config{
-		ListenAddress: "127.0.0.1",
-		WebUIPort:     9996,
-		MediaPath:     "media/",
-		ArchivePath:   "archive.sqlite3",
-		ThumbnailPath: "thumb.db",
+		ListenAddress:       "127.0.0.1",
+		WebUIPort:           9996,
+		MediaPath:           "media/",
+		ArchivePath:         "archive.sqlite3",
+		ThumbnailPath:       "thumb.db",
+		PerceptualHashTypes: []string{"AHash", "DHash", "PHash", "WHash", "PHash16"},
+		VideoHashFrames:     16,
+		JobWorkers:          2,
+		JobMaxAttempts:      5,
+		ThumbnailSizes:      media.DefaultThumbnailSizes,
+		ThumbnailFormats:    []string{"webp"},
+		AnimatedPreviews:    true,
+		StoryboardFrames:    25,
+		Storage:             Storage{Type: "local"},
+		StorageLayout:       Layout{Hash: "sha256", Depth: 2, Width: 2},
 	}
 	c.Logging.FileLogging = false
 	c.Logging.LogLevel = log.StringToLogLevel("info").String()
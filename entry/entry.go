@@ -23,11 +23,45 @@ type Metadata struct {
 	Paths     Path
 }
 
+// FileMetadata is the metadata of an entry. Stream metadata, from MediaDuration onwards, is only set for
+// videos and audio.
 type FileMetadata struct {
-	FileMimetype            string
-	FileSize                int64 // bytes
-	MediaOrientation        string
-	MediaHeight, MediaWidth int64
+	FileMimetype                     string
+	FileSize                         int64 // bytes
+	MediaOrientation                 string
+	MediaHeight, MediaWidth          int64
+	MediaDuration                    time.Duration
+	MediaFramerate                   float64 // frames per second
+	MediaVideoCodec, MediaAudioCodec string
+	MediaBitrate                     int64 // bits per second
+	MediaHasAudio                    bool
+}
+
+// Exif is the capture metadata embedded in a photo through EXIF or XMP. Orientation is the EXIF orientation
+// from 1 to 8, or 0 if unknown. Latitude and Longitude are in decimal degrees and Altitude in meters, which
+// are only set if HasGPS is.
+type Exif struct {
+	DateTimeOriginal              time.Time
+	Make, Model, Lens             string
+	Orientation                   int64
+	HasGPS                        bool
+	Latitude, Longitude, Altitude float64
+}
+
+// Rendition is a transcode of an entry that web browsers can play. Path is relative to the media directory,
+// and AudioCodec is empty for renditions without audio.
+type Rendition struct {
+	ArchiveID              int64
+	Path, Mimetype         string
+	VideoCodec, AudioCodec string
+	FileSize               int64 // bytes
+}
+
+// StoredFile is the media file of an entry, stored at Path relative to the media storage.
+type StoredFile struct {
+	ArchiveID       int64
+	Path, Extension string
+	Hashes          Hashes
 }
 
 type Tags struct {
@@ -66,6 +100,36 @@ type TagCount struct {
 	Count int64
 }
 
+type Note struct {
+	NoteID, ArchiveID         int64
+	Title, Text               string
+	DateCreated, DateModified time.Time
+}
+
+// PerceptualHash is a perceptual hash of an entry. Hash holds a single word for 64-bit hashes, or every
+// 64-bit word of a larger hash, most significant first.
+type PerceptualHash struct {
+	ArchiveID int64
+	Type      string
+	Hash      []uint64
+}
+
+// Similar is an entry whose perceptual hash is Distance bits away from another entry.
+type Similar struct {
+	ArchiveID int64
+	Distance  int
+}
+
+// Job is a single unit of background processing on an entry, such as generating its thumbnails.
+// Error holds the error of the most recent failed attempt.
+type Job struct {
+	JobID, ArchiveID          int64
+	Type, Status, Error       string
+	Attempts                  int64
+	RunAfter                  time.Time
+	DateCreated, DateModified time.Time
+}
+
 type Thumbnail struct {
 	Webp, Jpeg Icons
 }
@@ -2,12 +2,15 @@
 package importer
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
 	"io"
 	"os"
 
 	"github.com/dtbead/moonpool/entry"
 	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/storage"
 )
 
 type Importer struct {
@@ -15,22 +18,13 @@ type Importer struct {
 	e    entry.Entry
 }
 
-func (i Importer) Path() string {
-	return i.e.Metadata.Paths.FileRelative
-}
-
 func (i Importer) Extension() string {
 	return i.e.Metadata.Paths.FileExtension
 }
 
-// Store copies a file into baseDirectory with its filename as its MD5 hash + its extension.
-// For example: if baseDirectory is "media", and a filehash is "78f7f3b074f759b5dbc2ba0224457b15"
-// then "media/78/78f7f3b074f759b5dbc2ba0224457b15.png" would be where the file gets stored.
-//
-// Store will also return said path as a string.
-func (i Importer) Store(baseDirectory string) (path string, err error) {
-	s := fmt.Sprintf("%s/%s", baseDirectory, i.e.Metadata.Paths.FileRelative)
-	return s, file.Copy(s, i.file)
+// Store copies a file into s at key, such as the path of its hashes in a file.Layout.
+func (i Importer) Store(ctx context.Context, s storage.Storage, key string) error {
+	return s.Put(ctx, key, i.FileData())
 }
 
 func (i Importer) Timestamp() entry.Timestamp {
@@ -50,13 +44,31 @@ func (i Importer) FileData() io.Reader {
 	return i.file
 }
 
+// New reads and hashes r into a new Importer. The format of r is detected from its content, which decides
+// the extension it is stored with. extension is only used to reject files labeled as a different format,
+// e.g. a PNG saved as "image.jpg", and may be left empty if r has no extension such as during an upload.
+//
+// r is stored later on if it is an io.ReadSeeker, such as *os.File or multipart.File.
 func New(r io.Reader, extension string) (Importer, error) {
-	f, isFile := r.(*os.File)
-	if isFile {
-		defer f.Seek(0, io.SeekStart)
+	s, seekable := r.(io.ReadSeeker)
+	if seekable {
+		defer s.Seek(0, io.SeekStart)
+	}
+
+	header := make([]byte, file.SniffLength)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return Importer{}, err
+	}
+	header = header[:n]
+
+	t, err := file.CheckExtension(header, extension)
+	if err != nil {
+		return Importer{}, err
 	}
+	extension = t.Extension
 
-	hashes, size, err := file.GetHash(r)
+	hashes, size, err := file.GetHash(io.MultiReader(bytes.NewReader(header), r))
 	if err != nil {
 		return Importer{}, err
 	}
@@ -66,7 +78,6 @@ func New(r io.Reader, extension string) (Importer, error) {
 			Metadata: entry.Metadata{
 				Hash: entry.Hashes(hashes),
 				Paths: entry.Path{
-					FileRelative:  file.BuildPath(hashes.MD5, extension),
 					FileExtension: extension,
 				},
 			},
@@ -76,9 +87,11 @@ func New(r io.Reader, extension string) (Importer, error) {
 		},
 	}
 
-	if isFile {
-		i.file = f
+	if seekable {
+		i.file = s
+	}
 
+	if f, isFile := r.(*os.File); isFile {
 		dateModified, err := file.DateModified(f)
 		if err != nil {
 			return Importer{}, err
@@ -98,11 +111,11 @@ func New(r io.Reader, extension string) (Importer, error) {
 	return i, nil
 }
 
-// resetFileSeek checks whether a given io.Reader is of *os.File
+// resetFileSeek checks whether a given io.Reader is an io.Seeker
 // and resets the file pointer for future read/write ops.
 func resetFileSeek(r io.Reader) {
-	f, ok := r.(*os.File)
+	s, ok := r.(io.Seeker)
 	if ok {
-		f.Seek(0, io.SeekStart)
+		s.Seek(0, io.SeekStart)
 	}
 }
@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/dtbead/moonpool/entry"
 	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/storage"
 	"github.com/go-test/deep"
 )
 
@@ -19,6 +21,23 @@ func Test_New(t *testing.T) {
 		t.Fatalf("failed to open test file. %v", err)
 	}
 
+	want := Importer{
+		file: nil,
+		e: entry.Entry{
+			Metadata: entry.Metadata{
+				Hash: entry.Hashes{
+					MD5:    stringToHex("2ec268313d4d0bbc765144b6334df68b"),
+					SHA1:   stringToHex("6ba11adbdb35ee10f9353608a7b97ef248733a72"),
+					SHA256: stringToHex("7aaa7471fed00d0bcb416f123d364ec28a9080708601bd308cc4301d3fadb0e1"),
+				},
+				Timestamp: entry.Timestamp{},
+				Paths: entry.Path{
+					FileExtension: ".jpg",
+				},
+			},
+		},
+	}
+
 	type args struct {
 		r         io.Reader
 		extension string
@@ -29,23 +48,11 @@ func Test_New(t *testing.T) {
 		want    Importer
 		wantErr bool
 	}{
-		{"generic", args{f, ".jpg"}, Importer{
-			file: nil,
-			e: entry.Entry{
-				Metadata: entry.Metadata{
-					Hash: entry.Hashes{
-						MD5:    stringToHex("2ec268313d4d0bbc765144b6334df68b"),
-						SHA1:   stringToHex("6ba11adbdb35ee10f9353608a7b97ef248733a72"),
-						SHA256: stringToHex("7aaa7471fed00d0bcb416f123d364ec28a9080708601bd308cc4301d3fadb0e1"),
-					},
-					Timestamp: entry.Timestamp{},
-					Paths: entry.Path{
-						FileRelative:  "2e/2ec268313d4d0bbc765144b6334df68b.jpg",
-						FileExtension: ".jpg",
-					},
-				},
-			},
-		}, false},
+		{"generic", args{f, ".jpg"}, want, false},
+		{"no extension", args{f, ""}, want, false},
+		{"alias extension", args{f, ".JPEG"}, want, false},
+		{"mislabeled", args{f, ".png"}, Importer{}, true},
+		{"unsupported", args{strings.NewReader("foobar"), ".txt"}, Importer{}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -92,11 +99,16 @@ func TestImporter_Store(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if _, err := tt.i.Store(tt.args.baseDirectory); (err != nil) != tt.wantErr {
+			key, err := file.DefaultLayout.Path(tt.i.Hash(), tt.i.Extension())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.i.Store(context.Background(), storage.NewLocal(tt.args.baseDirectory), key); (err != nil) != tt.wantErr {
 				t.Errorf("Importer.Store() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			dest := file.CleanPath(fmt.Sprintf("%s/%s", tt.args.baseDirectory, tt.i.e.Metadata.Paths.FileRelative))
+			dest := file.CleanPath(fmt.Sprintf("%s/%s", tt.args.baseDirectory, key))
 
 			file, err := os.Open(dest)
 			if err != nil {
@@ -0,0 +1,103 @@
+// Package bktree implements a BK-tree over perceptual hashes using Hamming distance as its metric.
+//
+// A BK-tree finds every hash within a distance of a query without comparing against every stored hash,
+// which makes near-duplicate searches over large archives practical. Hashes are any amount of 64-bit
+// words, but every hash in a single tree must be the same size.
+package bktree
+
+import "math/bits"
+
+// Match is a hash found by Search.
+type Match struct {
+	ID       int64
+	Hash     []uint64
+	Distance int
+}
+
+type node struct {
+	id       int64
+	hash     []uint64
+	children []*node // indexed by distance to this node
+}
+
+// Tree is a BK-tree of ID's keyed by their hash. The zero value is an empty tree ready to use.
+// A Tree is not safe for concurrent use.
+type Tree struct {
+	root *node
+	size int
+}
+
+// Distance returns the Hamming distance between a and b. Missing words of the shorter hash count as zero.
+func Distance(a, b []uint64) int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+
+	d := 0
+	for i := range a {
+		var w uint64
+		if i < len(b) {
+			w = b[i]
+		}
+		d += bits.OnesCount64(a[i] ^ w)
+	}
+	return d
+}
+
+// Len returns the amount of ID's in the tree.
+func (t *Tree) Len() int {
+	return t.size
+}
+
+// Add inserts an ID with its hash. The same ID may be added more than once.
+func (t *Tree) Add(id int64, hash []uint64) {
+	t.size++
+
+	n := &node{id: id, hash: hash}
+	if t.root == nil {
+		t.root = n
+		return
+	}
+
+	cur := t.root
+	for {
+		d := Distance(cur.hash, hash)
+		if d >= len(cur.children) {
+			cur.children = append(cur.children, make([]*node, d-len(cur.children)+1)...)
+		}
+
+		if cur.children[d] == nil {
+			cur.children[d] = n
+			return
+		}
+		cur = cur.children[d]
+	}
+}
+
+// Search returns every ID whose hash is within maxDistance of hash, in no particular order.
+func (t *Tree) Search(hash []uint64, maxDistance int) []Match {
+	if t.root == nil || maxDistance < 0 {
+		return nil
+	}
+
+	var matches []Match
+	stack := []*node{t.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d := Distance(n.hash, hash)
+		if d <= maxDistance {
+			matches = append(matches, Match{ID: n.id, Hash: n.hash, Distance: d})
+		}
+
+		// by the triangle inequality, only children between d-maxDistance and d+maxDistance can match
+		for i := max(d-maxDistance, 0); i <= d+maxDistance && i < len(n.children); i++ {
+			if c := n.children[i]; c != nil {
+				stack = append(stack, c)
+			}
+		}
+	}
+
+	return matches
+}
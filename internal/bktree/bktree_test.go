@@ -0,0 +1,110 @@
+package bktree
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestTree_Search(t *testing.T) {
+	tree := Tree{}
+	tree.Add(1, []uint64{0b0000})
+	tree.Add(2, []uint64{0b0001})
+	tree.Add(3, []uint64{0b0011})
+	tree.Add(4, []uint64{0b1111})
+	tree.Add(5, []uint64{^uint64(0)})
+
+	tests := []struct {
+		name        string
+		hash        []uint64
+		maxDistance int
+		want        []int64
+	}{
+		{"exact", []uint64{0b0000}, 0, []int64{1}},
+		{"distance 1", []uint64{0b0000}, 1, []int64{1, 2}},
+		{"distance 2", []uint64{0b0000}, 2, []int64{1, 2, 3}},
+		{"distance 4", []uint64{0b0000}, 4, []int64{1, 2, 3, 4}},
+		{"everything", []uint64{0b0000}, 64, []int64{1, 2, 3, 4, 5}},
+		{"inverted", []uint64{^uint64(0)}, 0, []int64{5}},
+		{"no match", []uint64{0b1000_0000}, 0, nil},
+		{"negative distance", []uint64{0b0000}, -1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for _, m := range tree.Search(tt.hash, tt.maxDistance) {
+				if m.Distance != Distance(m.Hash, tt.hash) {
+					t.Errorf("Search() distance of %d = %d, want %d", m.ID, m.Distance, Distance(m.Hash, tt.hash))
+				}
+				got = append(got, m.ID)
+			}
+			slices.Sort(got)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if tree.Len() != 5 {
+		t.Errorf("Len() = %d, want 5", tree.Len())
+	}
+}
+
+func TestTree_Search_Random(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+
+	// 256-bit hashes
+	hashes := make([][]uint64, 2000)
+	tree := Tree{}
+	for i := range hashes {
+		hashes[i] = []uint64{r.Uint64(), r.Uint64(), r.Uint64(), r.Uint64()}
+		// cluster half of the hashes closely together so small distances find something
+		if i%2 == 0 && i > 0 {
+			hashes[i] = slices.Clone(hashes[0])
+			hashes[i][i%4] ^= 1 << (i % 64)
+			hashes[i][(i+1)%4] ^= 1 << (i % 7)
+		}
+		tree.Add(int64(i), hashes[i])
+	}
+
+	for _, maxDistance := range []int{0, 1, 2, 10, 100} {
+		var want []int64
+		for i, h := range hashes {
+			if Distance(h, hashes[0]) <= maxDistance {
+				want = append(want, int64(i))
+			}
+		}
+
+		var got []int64
+		for _, m := range tree.Search(hashes[0], maxDistance) {
+			got = append(got, m.ID)
+		}
+		slices.Sort(got)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("Search() with distance %d returned %d matches, want %d", maxDistance, len(got), len(want))
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []uint64
+		want int
+	}{
+		{"equal", []uint64{1, 2}, []uint64{1, 2}, 0},
+		{"single word", []uint64{0b1010}, []uint64{0b0101}, 4},
+		{"multiple words", []uint64{0, ^uint64(0)}, []uint64{1, 0}, 65},
+		{"different length", []uint64{1}, []uint64{1, 0b11}, 2},
+		{"empty", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Distance(tt.a, tt.b); got != tt.want {
+				t.Errorf("Distance() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
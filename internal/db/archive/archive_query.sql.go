@@ -27,6 +27,79 @@ func (q *Queries) AssignTag(ctx context.Context, arg AssignTagParams) error {
 	return err
 }
 
+const ClaimJob = `-- name: ClaimJob :one
+UPDATE jobs SET status = 'running', attempts = attempts + 1, date_modified = (?1)
+WHERE job_id == (
+	SELECT job_id FROM jobs WHERE status == 'pending' AND run_after <= (?1)
+	ORDER BY run_after ASC, job_id ASC LIMIT 1
+)
+RETURNING job_id, archive_id, type, status, attempts, error, run_after, date_created, date_modified
+`
+
+func (q *Queries) ClaimJob(ctx context.Context, now int64) (Job, error) {
+	row := q.queryRow(ctx, q.claimJobStmt, ClaimJob, now)
+	var i Job
+	err := row.Scan(
+		&i.JobID,
+		&i.ArchiveID,
+		&i.Type,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+		&i.RunAfter,
+		&i.DateCreated,
+		&i.DateModified,
+	)
+	return i, err
+}
+
+const CountJobs = `-- name: CountJobs :many
+SELECT status, count(*) AS total FROM jobs GROUP BY status
+`
+
+type CountJobsRow struct {
+	Status string
+	Total  int64
+}
+
+func (q *Queries) CountJobs(ctx context.Context) ([]CountJobsRow, error) {
+	rows, err := q.query(ctx, q.countJobsStmt, CountJobs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountJobsRow
+	for rows.Next() {
+		var i CountJobsRow
+		if err := rows.Scan(&i.Status, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const DeleteDuplicateRunningJobs = `-- name: DeleteDuplicateRunningJobs :execrows
+DELETE FROM jobs WHERE status == 'running' AND EXISTS (
+	SELECT 1 FROM jobs AS other WHERE other.archive_id == jobs.archive_id AND other.type == jobs.type
+	AND (other.status == 'pending' OR (other.status == 'running' AND other.job_id > jobs.job_id))
+)
+`
+
+func (q *Queries) DeleteDuplicateRunningJobs(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteDuplicateRunningJobsStmt, DeleteDuplicateRunningJobs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const DeleteEntry = `-- name: DeleteEntry :exec
 DELETE from archive WHERE id == (?1)
 `
@@ -36,6 +109,29 @@ func (q *Queries) DeleteEntry(ctx context.Context, archiveID int64) error {
 	return err
 }
 
+const DeleteNote = `-- name: DeleteNote :exec
+DELETE FROM notes WHERE note_id == (?1)
+`
+
+func (q *Queries) DeleteNote(ctx context.Context, noteID int64) error {
+	_, err := q.exec(ctx, q.deleteNoteStmt, DeleteNote, noteID)
+	return err
+}
+
+const DeleteRendition = `-- name: DeleteRendition :exec
+DELETE FROM rendition WHERE archive_id == (?1) AND mimetype == (?2)
+`
+
+type DeleteRenditionParams struct {
+	ArchiveID int64
+	Mimetype  string
+}
+
+func (q *Queries) DeleteRendition(ctx context.Context, arg DeleteRenditionParams) error {
+	_, err := q.exec(ctx, q.deleteRenditionStmt, DeleteRendition, arg.ArchiveID, arg.Mimetype)
+	return err
+}
+
 const DeleteTag = `-- name: DeleteTag :exec
 DELETE FROM tags WHERE text == (?1)
 `
@@ -63,6 +159,77 @@ func (q *Queries) DeleteTagMap(ctx context.Context, tagID int64) error {
 	return err
 }
 
+const EnqueueJobs = `-- name: EnqueueJobs :execrows
+INSERT OR IGNORE INTO jobs (archive_id, type, status, run_after, date_created, date_modified)
+SELECT id, (?1), 'pending', (?2), (?2), (?2) FROM archive
+WHERE (?3) == 1 OR NOT EXISTS (SELECT 1 FROM jobs WHERE jobs.archive_id == archive.id AND jobs.type == (?1))
+`
+
+type EnqueueJobsParams struct {
+	Type string
+	Now  int64
+	All  int64
+}
+
+func (q *Queries) EnqueueJobs(ctx context.Context, arg EnqueueJobsParams) (int64, error) {
+	result, err := q.exec(ctx, q.enqueueJobsStmt, EnqueueJobs, arg.Type, arg.Now, arg.All)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const FinishJob = `-- name: FinishJob :exec
+UPDATE jobs SET status = (?1), error = (?2), run_after = (?3), date_modified = (?4)
+WHERE job_id == (?5)
+`
+
+type FinishJobParams struct {
+	Status       string
+	Error        string
+	RunAfter     int64
+	DateModified int64
+	JobID        int64
+}
+
+func (q *Queries) FinishJob(ctx context.Context, arg FinishJobParams) error {
+	_, err := q.exec(ctx, q.finishJobStmt, FinishJob,
+		arg.Status,
+		arg.Error,
+		arg.RunAfter,
+		arg.DateModified,
+		arg.JobID,
+	)
+	return err
+}
+
+const GetEntries = `-- name: GetEntries :many
+SELECT id, path, extension FROM archive ORDER BY id ASC
+`
+
+func (q *Queries) GetEntries(ctx context.Context) ([]Archive, error) {
+	rows, err := q.query(ctx, q.getEntriesStmt, GetEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Archive
+	for rows.Next() {
+		var i Archive
+		if err := rows.Scan(&i.ID, &i.Path, &i.Extension); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetEntry = `-- name: GetEntry :one
 SELECT id, path, extension FROM archive WHERE id == (?1)
 `
@@ -90,8 +257,29 @@ func (q *Queries) GetEntryPath(ctx context.Context, archiveID int64) (GetEntryPa
 	return i, err
 }
 
+const GetExif = `-- name: GetExif :one
+SELECT archive_id, date_original, make, model, lens, orientation, latitude, longitude, altitude FROM exif WHERE archive_id == (?1)
+`
+
+func (q *Queries) GetExif(ctx context.Context, archiveID int64) (Exif, error) {
+	row := q.queryRow(ctx, q.getExifStmt, GetExif, archiveID)
+	var i Exif
+	err := row.Scan(
+		&i.ArchiveID,
+		&i.DateOriginal,
+		&i.Make,
+		&i.Model,
+		&i.Lens,
+		&i.Orientation,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Altitude,
+	)
+	return i, err
+}
+
 const GetFileMetadata = `-- name: GetFileMetadata :one
-SELECT archive_id, file_size, file_mimetype, media_width, media_height, media_orientation FROM "archive_metadata" WHERE archive_id == (?1)
+SELECT archive_id, file_size, file_mimetype, media_width, media_height, media_orientation, media_duration, media_framerate, media_video_codec, media_audio_codec, media_bitrate, media_has_audio FROM "archive_metadata" WHERE archive_id == (?1)
 `
 
 func (q *Queries) GetFileMetadata(ctx context.Context, archiveID int64) (ArchiveMetadatum, error) {
@@ -104,6 +292,12 @@ func (q *Queries) GetFileMetadata(ctx context.Context, archiveID int64) (Archive
 		&i.MediaWidth,
 		&i.MediaHeight,
 		&i.MediaOrientation,
+		&i.MediaDuration,
+		&i.MediaFramerate,
+		&i.MediaVideoCodec,
+		&i.MediaAudioCodec,
+		&i.MediaBitrate,
+		&i.MediaHasAudio,
 	)
 	return i, err
 }
@@ -124,6 +318,125 @@ func (q *Queries) GetHashes(ctx context.Context, archiveID int64) (HashesChksum,
 	return i, err
 }
 
+const GetJob = `-- name: GetJob :one
+SELECT job_id, archive_id, type, status, attempts, error, run_after, date_created, date_modified FROM jobs WHERE job_id == (?1)
+`
+
+func (q *Queries) GetJob(ctx context.Context, jobID int64) (Job, error) {
+	row := q.queryRow(ctx, q.getJobStmt, GetJob, jobID)
+	var i Job
+	err := row.Scan(
+		&i.JobID,
+		&i.ArchiveID,
+		&i.Type,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+		&i.RunAfter,
+		&i.DateCreated,
+		&i.DateModified,
+	)
+	return i, err
+}
+
+const GetJobs = `-- name: GetJobs :many
+SELECT job_id, archive_id, type, status, attempts, error, run_after, date_created, date_modified FROM jobs WHERE status == (?1) OR (?1) == ''
+ORDER BY job_id DESC LIMIT (?3) OFFSET (?2)
+`
+
+type GetJobsParams struct {
+	Status string
+	Offset int64
+	Limit  int64
+}
+
+func (q *Queries) GetJobs(ctx context.Context, arg GetJobsParams) ([]Job, error) {
+	rows, err := q.query(ctx, q.getJobsStmt, GetJobs, arg.Status, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Job
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.JobID,
+			&i.ArchiveID,
+			&i.Type,
+			&i.Status,
+			&i.Attempts,
+			&i.Error,
+			&i.RunAfter,
+			&i.DateCreated,
+			&i.DateModified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetJobsByArchiveID = `-- name: GetJobsByArchiveID :many
+SELECT job_id, archive_id, type, status, attempts, error, run_after, date_created, date_modified FROM jobs WHERE archive_id == (?1) ORDER BY job_id ASC
+`
+
+func (q *Queries) GetJobsByArchiveID(ctx context.Context, archiveID int64) ([]Job, error) {
+	rows, err := q.query(ctx, q.getJobsByArchiveIDStmt, GetJobsByArchiveID, archiveID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Job
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.JobID,
+			&i.ArchiveID,
+			&i.Type,
+			&i.Status,
+			&i.Attempts,
+			&i.Error,
+			&i.RunAfter,
+			&i.DateCreated,
+			&i.DateModified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetLayout = `-- name: GetLayout :one
+SELECT hash, depth, width FROM storage_layout WHERE id == 0
+`
+
+type GetLayoutRow struct {
+	Hash  string
+	Depth int64
+	Width int64
+}
+
+func (q *Queries) GetLayout(ctx context.Context) (GetLayoutRow, error) {
+	row := q.queryRow(ctx, q.getLayoutStmt, GetLayout)
+	var i GetLayoutRow
+	err := row.Scan(&i.Hash, &i.Depth, &i.Width)
+	return i, err
+}
+
 const GetMostRecentArchiveID = `-- name: GetMostRecentArchiveID :one
 SELECT id FROM archive ORDER BY ROWID DESC LIMIT 1
 `
@@ -146,6 +459,58 @@ func (q *Queries) GetMostRecentTagID(ctx context.Context) (int64, error) {
 	return tag_id, err
 }
 
+const GetNote = `-- name: GetNote :one
+SELECT note_id, archive_id, title, text, date_created, date_modified FROM notes WHERE note_id == (?1)
+`
+
+func (q *Queries) GetNote(ctx context.Context, noteID int64) (Note, error) {
+	row := q.queryRow(ctx, q.getNoteStmt, GetNote, noteID)
+	var i Note
+	err := row.Scan(
+		&i.NoteID,
+		&i.ArchiveID,
+		&i.Title,
+		&i.Text,
+		&i.DateCreated,
+		&i.DateModified,
+	)
+	return i, err
+}
+
+const GetNotes = `-- name: GetNotes :many
+SELECT note_id, archive_id, title, text, date_created, date_modified FROM notes WHERE archive_id == (?1) ORDER BY note_id ASC
+`
+
+func (q *Queries) GetNotes(ctx context.Context, archiveID int64) ([]Note, error) {
+	rows, err := q.query(ctx, q.getNotesStmt, GetNotes, archiveID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.NoteID,
+			&i.ArchiveID,
+			&i.Title,
+			&i.Text,
+			&i.DateCreated,
+			&i.DateModified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetPagesByDateCreated = `-- name: GetPagesByDateCreated :many
 SELECT id, path, extension FROM archive 
 INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
@@ -265,10 +630,205 @@ func (q *Queries) GetPagesByDateImportedDecending(ctx context.Context, arg GetPa
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Archive
+	var items []Archive
+	for rows.Next() {
+		var i Archive
+		if err := rows.Scan(&i.ID, &i.Path, &i.Extension); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetPagesByDateModifiedAscending = `-- name: GetPagesByDateModifiedAscending :many
+SELECT id, path, extension FROM archive 
+INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
+ORDER BY archive_timestamps.date_modified ASC LIMIT (?2) OFFSET (?1)
+`
+
+type GetPagesByDateModifiedAscendingParams struct {
+	Offset interface{}
+	Limit  interface{}
+}
+
+func (q *Queries) GetPagesByDateModifiedAscending(ctx context.Context, arg GetPagesByDateModifiedAscendingParams) ([]Archive, error) {
+	rows, err := q.query(ctx, q.getPagesByDateModifiedAscendingStmt, GetPagesByDateModifiedAscending, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Archive
+	for rows.Next() {
+		var i Archive
+		if err := rows.Scan(&i.ID, &i.Path, &i.Extension); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetPagesByDateModifiedDescending = `-- name: GetPagesByDateModifiedDescending :many
+SELECT id, path, extension FROM archive 
+INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
+ORDER BY archive_timestamps.date_modified DESC LIMIT (?2) OFFSET (?1)
+`
+
+type GetPagesByDateModifiedDescendingParams struct {
+	Offset interface{}
+	Limit  interface{}
+}
+
+func (q *Queries) GetPagesByDateModifiedDescending(ctx context.Context, arg GetPagesByDateModifiedDescendingParams) ([]Archive, error) {
+	rows, err := q.query(ctx, q.getPagesByDateModifiedDescendingStmt, GetPagesByDateModifiedDescending, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Archive
+	for rows.Next() {
+		var i Archive
+		if err := rows.Scan(&i.ID, &i.Path, &i.Extension); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetPendingJob = `-- name: GetPendingJob :one
+SELECT job_id, archive_id, type, status, attempts, error, run_after, date_created, date_modified FROM jobs WHERE archive_id == (?1) AND type == (?2) AND status == 'pending'
+`
+
+type GetPendingJobParams struct {
+	ArchiveID int64
+	Type      string
+}
+
+func (q *Queries) GetPendingJob(ctx context.Context, arg GetPendingJobParams) (Job, error) {
+	row := q.queryRow(ctx, q.getPendingJobStmt, GetPendingJob, arg.ArchiveID, arg.Type)
+	var i Job
+	err := row.Scan(
+		&i.JobID,
+		&i.ArchiveID,
+		&i.Type,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+		&i.RunAfter,
+		&i.DateCreated,
+		&i.DateModified,
+	)
+	return i, err
+}
+
+const GetPerceptualHash = `-- name: GetPerceptualHash :one
+SELECT hash, hash_extended FROM hashes_perceptual 
+WHERE archive_id == (?1) AND hash_type == (?2)
+`
+
+type GetPerceptualHashParams struct {
+	ArchiveID int64
+	HashType  string
+}
+
+type GetPerceptualHashRow struct {
+	Hash         int64
+	HashExtended []byte
+}
+
+func (q *Queries) GetPerceptualHash(ctx context.Context, arg GetPerceptualHashParams) (GetPerceptualHashRow, error) {
+	row := q.queryRow(ctx, q.getPerceptualHashStmt, GetPerceptualHash, arg.ArchiveID, arg.HashType)
+	var i GetPerceptualHashRow
+	err := row.Scan(&i.Hash, &i.HashExtended)
+	return i, err
+}
+
+const GetPerceptualHashVersion = `-- name: GetPerceptualHashVersion :one
+SELECT version FROM hashes_perceptual_version WHERE hash_type == (?1)
+`
+
+func (q *Queries) GetPerceptualHashVersion(ctx context.Context, hashType string) (int64, error) {
+	row := q.queryRow(ctx, q.getPerceptualHashVersionStmt, GetPerceptualHashVersion, hashType)
+	var version int64
+	err := row.Scan(&version)
+	return version, err
+}
+
+const GetPerceptualHashes = `-- name: GetPerceptualHashes :many
+SELECT archive_id, hash, hash_extended FROM hashes_perceptual
+WHERE hash_type == (?1) ORDER BY archive_id
+`
+
+type GetPerceptualHashesRow struct {
+	ArchiveID    int64
+	Hash         int64
+	HashExtended []byte
+}
+
+func (q *Queries) GetPerceptualHashes(ctx context.Context, hashType string) ([]GetPerceptualHashesRow, error) {
+	rows, err := q.query(ctx, q.getPerceptualHashesStmt, GetPerceptualHashes, hashType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPerceptualHashesRow
+	for rows.Next() {
+		var i GetPerceptualHashesRow
+		if err := rows.Scan(&i.ArchiveID, &i.Hash, &i.HashExtended); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetPerceptualHashesByArchiveID = `-- name: GetPerceptualHashesByArchiveID :many
+SELECT hash_type, hash, hash_extended FROM hashes_perceptual
+WHERE archive_id == (?1) ORDER BY hash_type
+`
+
+type GetPerceptualHashesByArchiveIDRow struct {
+	HashType     string
+	Hash         int64
+	HashExtended []byte
+}
+
+func (q *Queries) GetPerceptualHashesByArchiveID(ctx context.Context, archiveID int64) ([]GetPerceptualHashesByArchiveIDRow, error) {
+	rows, err := q.query(ctx, q.getPerceptualHashesByArchiveIDStmt, GetPerceptualHashesByArchiveID, archiveID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPerceptualHashesByArchiveIDRow
 	for rows.Next() {
-		var i Archive
-		if err := rows.Scan(&i.ID, &i.Path, &i.Extension); err != nil {
+		var i GetPerceptualHashesByArchiveIDRow
+		if err := rows.Scan(&i.HashType, &i.Hash, &i.HashExtended); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -282,27 +842,27 @@ func (q *Queries) GetPagesByDateImportedDecending(ctx context.Context, arg GetPa
 	return items, nil
 }
 
-const GetPagesByDateModifiedAscending = `-- name: GetPagesByDateModifiedAscending :many
-SELECT id, path, extension FROM archive 
-INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-ORDER BY archive_timestamps.date_modified ASC LIMIT (?2) OFFSET (?1)
+const GetRenditions = `-- name: GetRenditions :many
+SELECT archive_id, path, mimetype, video_codec, audio_codec, file_size FROM rendition WHERE archive_id == (?1) ORDER BY mimetype ASC
 `
 
-type GetPagesByDateModifiedAscendingParams struct {
-	Offset interface{}
-	Limit  interface{}
-}
-
-func (q *Queries) GetPagesByDateModifiedAscending(ctx context.Context, arg GetPagesByDateModifiedAscendingParams) ([]Archive, error) {
-	rows, err := q.query(ctx, q.getPagesByDateModifiedAscendingStmt, GetPagesByDateModifiedAscending, arg.Offset, arg.Limit)
+func (q *Queries) GetRenditions(ctx context.Context, archiveID int64) ([]Rendition, error) {
+	rows, err := q.query(ctx, q.getRenditionsStmt, GetRenditions, archiveID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Archive
+	var items []Rendition
 	for rows.Next() {
-		var i Archive
-		if err := rows.Scan(&i.ID, &i.Path, &i.Extension); err != nil {
+		var i Rendition
+		if err := rows.Scan(
+			&i.ArchiveID,
+			&i.Path,
+			&i.Mimetype,
+			&i.VideoCodec,
+			&i.AudioCodec,
+			&i.FileSize,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -316,27 +876,38 @@ func (q *Queries) GetPagesByDateModifiedAscending(ctx context.Context, arg GetPa
 	return items, nil
 }
 
-const GetPagesByDateModifiedDescending = `-- name: GetPagesByDateModifiedDescending :many
-SELECT id, path, extension FROM archive 
-INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-ORDER BY archive_timestamps.date_modified DESC LIMIT (?2) OFFSET (?1)
+const GetStoredFiles = `-- name: GetStoredFiles :many
+SELECT archive.id, archive.path, archive.extension, hashes_chksum.md5, hashes_chksum.sha1, hashes_chksum.sha256
+FROM archive INNER JOIN hashes_chksum ON archive.id == hashes_chksum.archive_id
+ORDER BY archive.id ASC
 `
 
-type GetPagesByDateModifiedDescendingParams struct {
-	Offset interface{}
-	Limit  interface{}
+type GetStoredFilesRow struct {
+	ID        int64
+	Path      string
+	Extension string
+	Md5       []byte
+	Sha1      []byte
+	Sha256    []byte
 }
 
-func (q *Queries) GetPagesByDateModifiedDescending(ctx context.Context, arg GetPagesByDateModifiedDescendingParams) ([]Archive, error) {
-	rows, err := q.query(ctx, q.getPagesByDateModifiedDescendingStmt, GetPagesByDateModifiedDescending, arg.Offset, arg.Limit)
+func (q *Queries) GetStoredFiles(ctx context.Context) ([]GetStoredFilesRow, error) {
+	rows, err := q.query(ctx, q.getStoredFilesStmt, GetStoredFiles)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Archive
+	var items []GetStoredFilesRow
 	for rows.Next() {
-		var i Archive
-		if err := rows.Scan(&i.ID, &i.Path, &i.Extension); err != nil {
+		var i GetStoredFilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Path,
+			&i.Extension,
+			&i.Md5,
+			&i.Sha1,
+			&i.Sha256,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -350,23 +921,6 @@ func (q *Queries) GetPagesByDateModifiedDescending(ctx context.Context, arg GetP
 	return items, nil
 }
 
-const GetPerceptualHash = `-- name: GetPerceptualHash :one
-SELECT hash FROM hashes_perceptual 
-WHERE archive_id == (?1) AND hash_type == (?2)
-`
-
-type GetPerceptualHashParams struct {
-	ArchiveID int64
-	HashType  string
-}
-
-func (q *Queries) GetPerceptualHash(ctx context.Context, arg GetPerceptualHashParams) (int64, error) {
-	row := q.queryRow(ctx, q.getPerceptualHashStmt, GetPerceptualHash, arg.ArchiveID, arg.HashType)
-	var hash int64
-	err := row.Scan(&hash)
-	return hash, err
-}
-
 const GetTagCountByList = `-- name: GetTagCountByList :many
 SELECT tags.text, count(tags.text) FROM tags 
 INNER JOIN tag_map ON tags.tag_id = tag_map.tag_id 
@@ -546,6 +1100,86 @@ func (q *Queries) NewEntry(ctx context.Context, arg NewEntryParams) error {
 	return err
 }
 
+const NewJob = `-- name: NewJob :one
+INSERT OR IGNORE INTO jobs (archive_id, type, status, run_after, date_created, date_modified)
+VALUES (?1, ?2, 'pending', ?3, ?4, ?5)
+RETURNING job_id
+`
+
+type NewJobParams struct {
+	ArchiveID    int64
+	Type         string
+	RunAfter     int64
+	DateCreated  int64
+	DateModified int64
+}
+
+func (q *Queries) NewJob(ctx context.Context, arg NewJobParams) (int64, error) {
+	row := q.queryRow(ctx, q.newJobStmt, NewJob,
+		arg.ArchiveID,
+		arg.Type,
+		arg.RunAfter,
+		arg.DateCreated,
+		arg.DateModified,
+	)
+	var job_id int64
+	err := row.Scan(&job_id)
+	return job_id, err
+}
+
+const NewNote = `-- name: NewNote :one
+INSERT INTO notes (archive_id, title, text, date_created, date_modified)
+VALUES (?1, ?2, ?3, ?4, ?5)
+RETURNING note_id
+`
+
+type NewNoteParams struct {
+	ArchiveID    int64
+	Title        string
+	Text         string
+	DateCreated  int64
+	DateModified int64
+}
+
+func (q *Queries) NewNote(ctx context.Context, arg NewNoteParams) (int64, error) {
+	row := q.queryRow(ctx, q.newNoteStmt, NewNote,
+		arg.ArchiveID,
+		arg.Title,
+		arg.Text,
+		arg.DateCreated,
+		arg.DateModified,
+	)
+	var note_id int64
+	err := row.Scan(&note_id)
+	return note_id, err
+}
+
+const NewRendition = `-- name: NewRendition :exec
+INSERT OR REPLACE INTO rendition (archive_id, path, mimetype, video_codec, audio_codec, file_size)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+`
+
+type NewRenditionParams struct {
+	ArchiveID  int64
+	Path       string
+	Mimetype   string
+	VideoCodec string
+	AudioCodec sql.NullString
+	FileSize   int64
+}
+
+func (q *Queries) NewRendition(ctx context.Context, arg NewRenditionParams) error {
+	_, err := q.exec(ctx, q.newRenditionStmt, NewRendition,
+		arg.ArchiveID,
+		arg.Path,
+		arg.Mimetype,
+		arg.VideoCodec,
+		arg.AudioCodec,
+		arg.FileSize,
+	)
+	return err
+}
+
 const NewTag = `-- name: NewTag :exec
 INSERT INTO tags (text) VALUES (?1)
 `
@@ -598,6 +1232,18 @@ func (q *Queries) RemoveTagsFromArchiveID(ctx context.Context, archiveID int64)
 	return err
 }
 
+const ResetRunningJobs = `-- name: ResetRunningJobs :execrows
+UPDATE jobs SET status = 'pending', date_modified = (?1) WHERE status == 'running'
+`
+
+func (q *Queries) ResetRunningJobs(ctx context.Context, now int64) (int64, error) {
+	result, err := q.exec(ctx, q.resetRunningJobsStmt, ResetRunningJobs, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const ResolveTagAlias = `-- name: ResolveTagAlias :one
 SELECT tags.tag_id, tags.text, tags_alias.text FROM tags
 	INNER JOIN tags_alias on tags.tag_id = tags_alias.tag_id
@@ -663,6 +1309,21 @@ func (q *Queries) ResolveTagAliasList(ctx context.Context, aliasTags []string) (
 	return items, nil
 }
 
+const RetryJob = `-- name: RetryJob :exec
+UPDATE jobs SET status = 'pending', attempts = 0, error = '', run_after = (?1), date_modified = (?1)
+WHERE job_id == (?2) AND status == 'failed'
+`
+
+type RetryJobParams struct {
+	Now   int64
+	JobID int64
+}
+
+func (q *Queries) RetryJob(ctx context.Context, arg RetryJobParams) error {
+	_, err := q.exec(ctx, q.retryJobStmt, RetryJob, arg.Now, arg.JobID)
+	return err
+}
+
 const SearchHash = `-- name: SearchHash :one
 SELECT archive.id FROM archive 
 	INNER JOIN hashes_chksum ON hashes_chksum.archive_id = archive.id
@@ -679,95 +1340,35 @@ func (q *Queries) SearchHash(ctx context.Context, hash interface{}) (int64, erro
 	return id, err
 }
 
-const SearchTag = `-- name: SearchTag :many
-SELECT archive.id, tags.tag_id, tags.text FROM tags 
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-WHERE tags.text == (?1)
-`
-
-type SearchTagRow struct {
-	ID    int64
-	TagID int64
-	Text  string
-}
-
-func (q *Queries) SearchTag(ctx context.Context, tag string) ([]SearchTagRow, error) {
-	rows, err := q.query(ctx, q.searchTagStmt, SearchTag, tag)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []SearchTagRow
-	for rows.Next() {
-		var i SearchTagRow
-		if err := rows.Scan(&i.ID, &i.TagID, &i.Text); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const SearchTagsByListDateCreated = `-- name: SearchTagsByListDateCreated :many
-SELECT DISTINCT archive.id, archive_timestamps.date_created FROM tags 
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-WHERE tags.text IN (/*SLICE:tags_include*/?)
-EXCEPT
-	SELECT DISTINCT archive.id, archive_timestamps.date_created FROM tags
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-	WHERE tags.text IN (/*SLICE:tags_exclude*/?)
-ORDER BY archive_timestamps.date_created DESC
+const SearchNotes = `-- name: SearchNotes :many
+SELECT note_id, archive_id, title, text, date_created, date_modified FROM notes 
+WHERE title LIKE (?1) ESCAPE '\' OR text LIKE (?1) ESCAPE '\'
+ORDER BY date_modified DESC LIMIT (?3) OFFSET (?2)
 `
 
-type SearchTagsByListDateCreatedParams struct {
-	TagsInclude []string
-	TagsExclude []string
+type SearchNotesParams struct {
+	Query  string
+	Offset int64
+	Limit  int64
 }
 
-type SearchTagsByListDateCreatedRow struct {
-	ID          int64
-	DateCreated int64
-}
-
-func (q *Queries) SearchTagsByListDateCreated(ctx context.Context, arg SearchTagsByListDateCreatedParams) ([]SearchTagsByListDateCreatedRow, error) {
-	query := SearchTagsByListDateCreated
-	var queryParams []interface{}
-	if len(arg.TagsInclude) > 0 {
-		for _, v := range arg.TagsInclude {
-			queryParams = append(queryParams, v)
-		}
-		query = strings.Replace(query, "/*SLICE:tags_include*/?", strings.Repeat(",?", len(arg.TagsInclude))[1:], 1)
-	} else {
-		query = strings.Replace(query, "/*SLICE:tags_include*/?", "NULL", 1)
-	}
-	if len(arg.TagsExclude) > 0 {
-		for _, v := range arg.TagsExclude {
-			queryParams = append(queryParams, v)
-		}
-		query = strings.Replace(query, "/*SLICE:tags_exclude*/?", strings.Repeat(",?", len(arg.TagsExclude))[1:], 1)
-	} else {
-		query = strings.Replace(query, "/*SLICE:tags_exclude*/?", "NULL", 1)
-	}
-	rows, err := q.query(ctx, nil, query, queryParams...)
+func (q *Queries) SearchNotes(ctx context.Context, arg SearchNotesParams) ([]Note, error) {
+	rows, err := q.query(ctx, q.searchNotesStmt, SearchNotes, arg.Query, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SearchTagsByListDateCreatedRow
+	var items []Note
 	for rows.Next() {
-		var i SearchTagsByListDateCreatedRow
-		if err := rows.Scan(&i.ID, &i.DateCreated); err != nil {
+		var i Note
+		if err := rows.Scan(
+			&i.NoteID,
+			&i.ArchiveID,
+			&i.Title,
+			&i.Text,
+			&i.DateCreated,
+			&i.DateModified,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -781,59 +1382,29 @@ func (q *Queries) SearchTagsByListDateCreated(ctx context.Context, arg SearchTag
 	return items, nil
 }
 
-const SearchTagsByListDateImported = `-- name: SearchTagsByListDateImported :many
-SELECT DISTINCT archive.id, archive_timestamps.date_imported FROM tags 
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-WHERE tags.text IN (/*SLICE:tags_include*/?)
-EXCEPT
-	SELECT DISTINCT archive.id, archive_timestamps.date_imported FROM tags
+const SearchTag = `-- name: SearchTag :many
+SELECT archive.id, tags.tag_id, tags.text FROM tags 
 	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
 	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-	WHERE tags.text IN (/*SLICE:tags_exclude*/?)
-ORDER BY archive_timestamps.date_imported DESC
+WHERE tags.text == (?1)
 `
 
-type SearchTagsByListDateImportedParams struct {
-	TagsInclude []string
-	TagsExclude []string
-}
-
-type SearchTagsByListDateImportedRow struct {
-	ID           int64
-	DateImported int64
+type SearchTagRow struct {
+	ID    int64
+	TagID int64
+	Text  string
 }
 
-func (q *Queries) SearchTagsByListDateImported(ctx context.Context, arg SearchTagsByListDateImportedParams) ([]SearchTagsByListDateImportedRow, error) {
-	query := SearchTagsByListDateImported
-	var queryParams []interface{}
-	if len(arg.TagsInclude) > 0 {
-		for _, v := range arg.TagsInclude {
-			queryParams = append(queryParams, v)
-		}
-		query = strings.Replace(query, "/*SLICE:tags_include*/?", strings.Repeat(",?", len(arg.TagsInclude))[1:], 1)
-	} else {
-		query = strings.Replace(query, "/*SLICE:tags_include*/?", "NULL", 1)
-	}
-	if len(arg.TagsExclude) > 0 {
-		for _, v := range arg.TagsExclude {
-			queryParams = append(queryParams, v)
-		}
-		query = strings.Replace(query, "/*SLICE:tags_exclude*/?", strings.Repeat(",?", len(arg.TagsExclude))[1:], 1)
-	} else {
-		query = strings.Replace(query, "/*SLICE:tags_exclude*/?", "NULL", 1)
-	}
-	rows, err := q.query(ctx, nil, query, queryParams...)
+func (q *Queries) SearchTag(ctx context.Context, tag string) ([]SearchTagRow, error) {
+	rows, err := q.query(ctx, q.searchTagStmt, SearchTag, tag)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SearchTagsByListDateImportedRow
+	var items []SearchTagRow
 	for rows.Next() {
-		var i SearchTagsByListDateImportedRow
-		if err := rows.Scan(&i.ID, &i.DateImported); err != nil {
+		var i SearchTagRow
+		if err := rows.Scan(&i.ID, &i.TagID, &i.Text); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -847,76 +1418,45 @@ func (q *Queries) SearchTagsByListDateImported(ctx context.Context, arg SearchTa
 	return items, nil
 }
 
-const SearchTagsByListDateModified = `-- name: SearchTagsByListDateModified :many
-SELECT DISTINCT archive.id, archive_timestamps.date_modified FROM tags 
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-WHERE tags.text IN (/*SLICE:tags_include*/?)
-EXCEPT
-	SELECT DISTINCT archive.id, archive_timestamps.date_modified FROM tags
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-	WHERE tags.text IN (/*SLICE:tags_exclude*/?)
-ORDER BY archive_timestamps.date_modified DESC
+const SetExif = `-- name: SetExif :exec
+INSERT OR REPLACE INTO exif
+	(archive_id, date_original, make, model, lens, orientation, latitude, longitude, altitude)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
 `
 
-type SearchTagsByListDateModifiedParams struct {
-	TagsInclude []string
-	TagsExclude []string
-}
-
-type SearchTagsByListDateModifiedRow struct {
-	ID           int64
-	DateModified int64
-}
-
-func (q *Queries) SearchTagsByListDateModified(ctx context.Context, arg SearchTagsByListDateModifiedParams) ([]SearchTagsByListDateModifiedRow, error) {
-	query := SearchTagsByListDateModified
-	var queryParams []interface{}
-	if len(arg.TagsInclude) > 0 {
-		for _, v := range arg.TagsInclude {
-			queryParams = append(queryParams, v)
-		}
-		query = strings.Replace(query, "/*SLICE:tags_include*/?", strings.Repeat(",?", len(arg.TagsInclude))[1:], 1)
-	} else {
-		query = strings.Replace(query, "/*SLICE:tags_include*/?", "NULL", 1)
-	}
-	if len(arg.TagsExclude) > 0 {
-		for _, v := range arg.TagsExclude {
-			queryParams = append(queryParams, v)
-		}
-		query = strings.Replace(query, "/*SLICE:tags_exclude*/?", strings.Repeat(",?", len(arg.TagsExclude))[1:], 1)
-	} else {
-		query = strings.Replace(query, "/*SLICE:tags_exclude*/?", "NULL", 1)
-	}
-	rows, err := q.query(ctx, nil, query, queryParams...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []SearchTagsByListDateModifiedRow
-	for rows.Next() {
-		var i SearchTagsByListDateModifiedRow
-		if err := rows.Scan(&i.ID, &i.DateModified); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetExifParams struct {
+	ArchiveID    int64
+	DateOriginal sql.NullInt64
+	Make         sql.NullString
+	Model        sql.NullString
+	Lens         sql.NullString
+	Orientation  sql.NullInt64
+	Latitude     sql.NullFloat64
+	Longitude    sql.NullFloat64
+	Altitude     sql.NullFloat64
+}
+
+func (q *Queries) SetExif(ctx context.Context, arg SetExifParams) error {
+	_, err := q.exec(ctx, q.setExifStmt, SetExif,
+		arg.ArchiveID,
+		arg.DateOriginal,
+		arg.Make,
+		arg.Model,
+		arg.Lens,
+		arg.Orientation,
+		arg.Latitude,
+		arg.Longitude,
+		arg.Altitude,
+	)
+	return err
 }
 
 const SetFileMetadata = `-- name: SetFileMetadata :exec
 INSERT OR REPLACE INTO "archive_metadata"
-	(archive_id, file_size, file_mimetype, media_width, media_height, media_orientation)
-VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	(archive_id, file_size, file_mimetype, media_width, media_height, media_orientation,
+	media_duration, media_framerate, media_video_codec, media_audio_codec, media_bitrate, media_has_audio)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6,
+	?7, ?8, ?9, ?10, ?11, ?12)
 `
 
 type SetFileMetadataParams struct {
@@ -926,6 +1466,12 @@ type SetFileMetadataParams struct {
 	MediaWidth       sql.NullInt64
 	MediaHeight      sql.NullInt64
 	MediaOrientation sql.NullString
+	MediaDuration    sql.NullInt64
+	MediaFramerate   sql.NullFloat64
+	MediaVideoCodec  sql.NullString
+	MediaAudioCodec  sql.NullString
+	MediaBitrate     sql.NullInt64
+	MediaHasAudio    bool
 }
 
 func (q *Queries) SetFileMetadata(ctx context.Context, arg SetFileMetadataParams) error {
@@ -936,6 +1482,12 @@ func (q *Queries) SetFileMetadata(ctx context.Context, arg SetFileMetadataParams
 		arg.MediaWidth,
 		arg.MediaHeight,
 		arg.MediaOrientation,
+		arg.MediaDuration,
+		arg.MediaFramerate,
+		arg.MediaVideoCodec,
+		arg.MediaAudioCodec,
+		arg.MediaBitrate,
+		arg.MediaHasAudio,
 	)
 	return err
 }
@@ -963,20 +1515,41 @@ func (q *Queries) SetHashes(ctx context.Context, arg SetHashesParams) error {
 	return err
 }
 
+const SetLayout = `-- name: SetLayout :exec
+INSERT OR REPLACE INTO storage_layout (id, hash, depth, width) VALUES (0, ?1, ?2, ?3)
+`
+
+type SetLayoutParams struct {
+	Hash  string
+	Depth int64
+	Width int64
+}
+
+func (q *Queries) SetLayout(ctx context.Context, arg SetLayoutParams) error {
+	_, err := q.exec(ctx, q.setLayoutStmt, SetLayout, arg.Hash, arg.Depth, arg.Width)
+	return err
+}
+
 const SetPerceptualHash = `-- name: SetPerceptualHash :exec
 INSERT OR REPLACE INTO hashes_perceptual
-	(archive_id, hash_type, hash)
-VALUES (?1, ?2, ?3)
+	(archive_id, hash_type, hash, hash_extended)
+VALUES (?1, ?2, ?3, ?4)
 `
 
 type SetPerceptualHashParams struct {
-	ArchiveID int64
-	HashType  string
-	Hash      int64
+	ArchiveID    int64
+	HashType     string
+	Hash         int64
+	HashExtended []byte
 }
 
 func (q *Queries) SetPerceptualHash(ctx context.Context, arg SetPerceptualHashParams) error {
-	_, err := q.exec(ctx, q.setPerceptualHashStmt, SetPerceptualHash, arg.ArchiveID, arg.HashType, arg.Hash)
+	_, err := q.exec(ctx, q.setPerceptualHashStmt, SetPerceptualHash,
+		arg.ArchiveID,
+		arg.HashType,
+		arg.Hash,
+		arg.HashExtended,
+	)
 	return err
 }
 
@@ -1002,3 +1575,54 @@ func (q *Queries) SetTimestamps(ctx context.Context, arg SetTimestampsParams) er
 	)
 	return err
 }
+
+const UpdateEntryPath = `-- name: UpdateEntryPath :exec
+UPDATE archive SET path = ?1 WHERE id == (?2)
+`
+
+type UpdateEntryPathParams struct {
+	Path      string
+	ArchiveID int64
+}
+
+func (q *Queries) UpdateEntryPath(ctx context.Context, arg UpdateEntryPathParams) error {
+	_, err := q.exec(ctx, q.updateEntryPathStmt, UpdateEntryPath, arg.Path, arg.ArchiveID)
+	return err
+}
+
+const UpdateNote = `-- name: UpdateNote :exec
+UPDATE notes SET title = (?1), text = (?2), date_modified = (?3)
+WHERE note_id == (?4)
+`
+
+type UpdateNoteParams struct {
+	Title        string
+	Text         string
+	DateModified int64
+	NoteID       int64
+}
+
+func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) error {
+	_, err := q.exec(ctx, q.updateNoteStmt, UpdateNote,
+		arg.Title,
+		arg.Text,
+		arg.DateModified,
+		arg.NoteID,
+	)
+	return err
+}
+
+const UpdateRenditionPath = `-- name: UpdateRenditionPath :exec
+UPDATE rendition SET path = ?1 WHERE archive_id == (?2) AND mimetype == (?3)
+`
+
+type UpdateRenditionPathParams struct {
+	Path      string
+	ArchiveID int64
+	Mimetype  string
+}
+
+func (q *Queries) UpdateRenditionPath(ctx context.Context, arg UpdateRenditionPathParams) error {
+	_, err := q.exec(ctx, q.updateRenditionPathStmt, UpdateRenditionPath, arg.Path, arg.ArchiveID, arg.Mimetype)
+	return err
+}
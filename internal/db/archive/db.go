@@ -27,9 +27,24 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.assignTagStmt, err = db.PrepareContext(ctx, AssignTag); err != nil {
 		return nil, fmt.Errorf("error preparing query AssignTag: %w", err)
 	}
+	if q.claimJobStmt, err = db.PrepareContext(ctx, ClaimJob); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimJob: %w", err)
+	}
+	if q.countJobsStmt, err = db.PrepareContext(ctx, CountJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query CountJobs: %w", err)
+	}
+	if q.deleteDuplicateRunningJobsStmt, err = db.PrepareContext(ctx, DeleteDuplicateRunningJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteDuplicateRunningJobs: %w", err)
+	}
 	if q.deleteEntryStmt, err = db.PrepareContext(ctx, DeleteEntry); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteEntry: %w", err)
 	}
+	if q.deleteNoteStmt, err = db.PrepareContext(ctx, DeleteNote); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteNote: %w", err)
+	}
+	if q.deleteRenditionStmt, err = db.PrepareContext(ctx, DeleteRendition); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRendition: %w", err)
+	}
 	if q.deleteTagStmt, err = db.PrepareContext(ctx, DeleteTag); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteTag: %w", err)
 	}
@@ -39,24 +54,54 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteTagMapStmt, err = db.PrepareContext(ctx, DeleteTagMap); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteTagMap: %w", err)
 	}
+	if q.enqueueJobsStmt, err = db.PrepareContext(ctx, EnqueueJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query EnqueueJobs: %w", err)
+	}
+	if q.finishJobStmt, err = db.PrepareContext(ctx, FinishJob); err != nil {
+		return nil, fmt.Errorf("error preparing query FinishJob: %w", err)
+	}
+	if q.getEntriesStmt, err = db.PrepareContext(ctx, GetEntries); err != nil {
+		return nil, fmt.Errorf("error preparing query GetEntries: %w", err)
+	}
 	if q.getEntryStmt, err = db.PrepareContext(ctx, GetEntry); err != nil {
 		return nil, fmt.Errorf("error preparing query GetEntry: %w", err)
 	}
 	if q.getEntryPathStmt, err = db.PrepareContext(ctx, GetEntryPath); err != nil {
 		return nil, fmt.Errorf("error preparing query GetEntryPath: %w", err)
 	}
+	if q.getExifStmt, err = db.PrepareContext(ctx, GetExif); err != nil {
+		return nil, fmt.Errorf("error preparing query GetExif: %w", err)
+	}
 	if q.getFileMetadataStmt, err = db.PrepareContext(ctx, GetFileMetadata); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFileMetadata: %w", err)
 	}
 	if q.getHashesStmt, err = db.PrepareContext(ctx, GetHashes); err != nil {
 		return nil, fmt.Errorf("error preparing query GetHashes: %w", err)
 	}
+	if q.getJobStmt, err = db.PrepareContext(ctx, GetJob); err != nil {
+		return nil, fmt.Errorf("error preparing query GetJob: %w", err)
+	}
+	if q.getJobsStmt, err = db.PrepareContext(ctx, GetJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetJobs: %w", err)
+	}
+	if q.getJobsByArchiveIDStmt, err = db.PrepareContext(ctx, GetJobsByArchiveID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetJobsByArchiveID: %w", err)
+	}
+	if q.getLayoutStmt, err = db.PrepareContext(ctx, GetLayout); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLayout: %w", err)
+	}
 	if q.getMostRecentArchiveIDStmt, err = db.PrepareContext(ctx, GetMostRecentArchiveID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMostRecentArchiveID: %w", err)
 	}
 	if q.getMostRecentTagIDStmt, err = db.PrepareContext(ctx, GetMostRecentTagID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMostRecentTagID: %w", err)
 	}
+	if q.getNoteStmt, err = db.PrepareContext(ctx, GetNote); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNote: %w", err)
+	}
+	if q.getNotesStmt, err = db.PrepareContext(ctx, GetNotes); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNotes: %w", err)
+	}
 	if q.getPagesByDateCreatedStmt, err = db.PrepareContext(ctx, GetPagesByDateCreated); err != nil {
 		return nil, fmt.Errorf("error preparing query GetPagesByDateCreated: %w", err)
 	}
@@ -75,9 +120,27 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getPagesByDateModifiedDescendingStmt, err = db.PrepareContext(ctx, GetPagesByDateModifiedDescending); err != nil {
 		return nil, fmt.Errorf("error preparing query GetPagesByDateModifiedDescending: %w", err)
 	}
+	if q.getPendingJobStmt, err = db.PrepareContext(ctx, GetPendingJob); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPendingJob: %w", err)
+	}
 	if q.getPerceptualHashStmt, err = db.PrepareContext(ctx, GetPerceptualHash); err != nil {
 		return nil, fmt.Errorf("error preparing query GetPerceptualHash: %w", err)
 	}
+	if q.getPerceptualHashVersionStmt, err = db.PrepareContext(ctx, GetPerceptualHashVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPerceptualHashVersion: %w", err)
+	}
+	if q.getPerceptualHashesStmt, err = db.PrepareContext(ctx, GetPerceptualHashes); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPerceptualHashes: %w", err)
+	}
+	if q.getPerceptualHashesByArchiveIDStmt, err = db.PrepareContext(ctx, GetPerceptualHashesByArchiveID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPerceptualHashesByArchiveID: %w", err)
+	}
+	if q.getRenditionsStmt, err = db.PrepareContext(ctx, GetRenditions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRenditions: %w", err)
+	}
+	if q.getStoredFilesStmt, err = db.PrepareContext(ctx, GetStoredFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStoredFiles: %w", err)
+	}
 	if q.getTagCountByListStmt, err = db.PrepareContext(ctx, GetTagCountByList); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTagCountByList: %w", err)
 	}
@@ -99,6 +162,15 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.newEntryStmt, err = db.PrepareContext(ctx, NewEntry); err != nil {
 		return nil, fmt.Errorf("error preparing query NewEntry: %w", err)
 	}
+	if q.newJobStmt, err = db.PrepareContext(ctx, NewJob); err != nil {
+		return nil, fmt.Errorf("error preparing query NewJob: %w", err)
+	}
+	if q.newNoteStmt, err = db.PrepareContext(ctx, NewNote); err != nil {
+		return nil, fmt.Errorf("error preparing query NewNote: %w", err)
+	}
+	if q.newRenditionStmt, err = db.PrepareContext(ctx, NewRendition); err != nil {
+		return nil, fmt.Errorf("error preparing query NewRendition: %w", err)
+	}
 	if q.newTagStmt, err = db.PrepareContext(ctx, NewTag); err != nil {
 		return nil, fmt.Errorf("error preparing query NewTag: %w", err)
 	}
@@ -111,26 +183,29 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.removeTagsFromArchiveIDStmt, err = db.PrepareContext(ctx, RemoveTagsFromArchiveID); err != nil {
 		return nil, fmt.Errorf("error preparing query RemoveTagsFromArchiveID: %w", err)
 	}
+	if q.resetRunningJobsStmt, err = db.PrepareContext(ctx, ResetRunningJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query ResetRunningJobs: %w", err)
+	}
 	if q.resolveTagAliasStmt, err = db.PrepareContext(ctx, ResolveTagAlias); err != nil {
 		return nil, fmt.Errorf("error preparing query ResolveTagAlias: %w", err)
 	}
 	if q.resolveTagAliasListStmt, err = db.PrepareContext(ctx, ResolveTagAliasList); err != nil {
 		return nil, fmt.Errorf("error preparing query ResolveTagAliasList: %w", err)
 	}
+	if q.retryJobStmt, err = db.PrepareContext(ctx, RetryJob); err != nil {
+		return nil, fmt.Errorf("error preparing query RetryJob: %w", err)
+	}
 	if q.searchHashStmt, err = db.PrepareContext(ctx, SearchHash); err != nil {
 		return nil, fmt.Errorf("error preparing query SearchHash: %w", err)
 	}
+	if q.searchNotesStmt, err = db.PrepareContext(ctx, SearchNotes); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchNotes: %w", err)
+	}
 	if q.searchTagStmt, err = db.PrepareContext(ctx, SearchTag); err != nil {
 		return nil, fmt.Errorf("error preparing query SearchTag: %w", err)
 	}
-	if q.searchTagsByListDateCreatedStmt, err = db.PrepareContext(ctx, SearchTagsByListDateCreated); err != nil {
-		return nil, fmt.Errorf("error preparing query SearchTagsByListDateCreated: %w", err)
-	}
-	if q.searchTagsByListDateImportedStmt, err = db.PrepareContext(ctx, SearchTagsByListDateImported); err != nil {
-		return nil, fmt.Errorf("error preparing query SearchTagsByListDateImported: %w", err)
-	}
-	if q.searchTagsByListDateModifiedStmt, err = db.PrepareContext(ctx, SearchTagsByListDateModified); err != nil {
-		return nil, fmt.Errorf("error preparing query SearchTagsByListDateModified: %w", err)
+	if q.setExifStmt, err = db.PrepareContext(ctx, SetExif); err != nil {
+		return nil, fmt.Errorf("error preparing query SetExif: %w", err)
 	}
 	if q.setFileMetadataStmt, err = db.PrepareContext(ctx, SetFileMetadata); err != nil {
 		return nil, fmt.Errorf("error preparing query SetFileMetadata: %w", err)
@@ -138,12 +213,24 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.setHashesStmt, err = db.PrepareContext(ctx, SetHashes); err != nil {
 		return nil, fmt.Errorf("error preparing query SetHashes: %w", err)
 	}
+	if q.setLayoutStmt, err = db.PrepareContext(ctx, SetLayout); err != nil {
+		return nil, fmt.Errorf("error preparing query SetLayout: %w", err)
+	}
 	if q.setPerceptualHashStmt, err = db.PrepareContext(ctx, SetPerceptualHash); err != nil {
 		return nil, fmt.Errorf("error preparing query SetPerceptualHash: %w", err)
 	}
 	if q.setTimestampsStmt, err = db.PrepareContext(ctx, SetTimestamps); err != nil {
 		return nil, fmt.Errorf("error preparing query SetTimestamps: %w", err)
 	}
+	if q.updateEntryPathStmt, err = db.PrepareContext(ctx, UpdateEntryPath); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateEntryPath: %w", err)
+	}
+	if q.updateNoteStmt, err = db.PrepareContext(ctx, UpdateNote); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateNote: %w", err)
+	}
+	if q.updateRenditionPathStmt, err = db.PrepareContext(ctx, UpdateRenditionPath); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateRenditionPath: %w", err)
+	}
 	return &q, nil
 }
 
@@ -154,11 +241,36 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing assignTagStmt: %w", cerr)
 		}
 	}
+	if q.claimJobStmt != nil {
+		if cerr := q.claimJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimJobStmt: %w", cerr)
+		}
+	}
+	if q.countJobsStmt != nil {
+		if cerr := q.countJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countJobsStmt: %w", cerr)
+		}
+	}
+	if q.deleteDuplicateRunningJobsStmt != nil {
+		if cerr := q.deleteDuplicateRunningJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteDuplicateRunningJobsStmt: %w", cerr)
+		}
+	}
 	if q.deleteEntryStmt != nil {
 		if cerr := q.deleteEntryStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteEntryStmt: %w", cerr)
 		}
 	}
+	if q.deleteNoteStmt != nil {
+		if cerr := q.deleteNoteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteNoteStmt: %w", cerr)
+		}
+	}
+	if q.deleteRenditionStmt != nil {
+		if cerr := q.deleteRenditionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRenditionStmt: %w", cerr)
+		}
+	}
 	if q.deleteTagStmt != nil {
 		if cerr := q.deleteTagStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteTagStmt: %w", cerr)
@@ -174,6 +286,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteTagMapStmt: %w", cerr)
 		}
 	}
+	if q.enqueueJobsStmt != nil {
+		if cerr := q.enqueueJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enqueueJobsStmt: %w", cerr)
+		}
+	}
+	if q.finishJobStmt != nil {
+		if cerr := q.finishJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing finishJobStmt: %w", cerr)
+		}
+	}
+	if q.getEntriesStmt != nil {
+		if cerr := q.getEntriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getEntriesStmt: %w", cerr)
+		}
+	}
 	if q.getEntryStmt != nil {
 		if cerr := q.getEntryStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getEntryStmt: %w", cerr)
@@ -184,6 +311,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getEntryPathStmt: %w", cerr)
 		}
 	}
+	if q.getExifStmt != nil {
+		if cerr := q.getExifStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getExifStmt: %w", cerr)
+		}
+	}
 	if q.getFileMetadataStmt != nil {
 		if cerr := q.getFileMetadataStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFileMetadataStmt: %w", cerr)
@@ -194,6 +326,26 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getHashesStmt: %w", cerr)
 		}
 	}
+	if q.getJobStmt != nil {
+		if cerr := q.getJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getJobStmt: %w", cerr)
+		}
+	}
+	if q.getJobsStmt != nil {
+		if cerr := q.getJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getJobsStmt: %w", cerr)
+		}
+	}
+	if q.getJobsByArchiveIDStmt != nil {
+		if cerr := q.getJobsByArchiveIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getJobsByArchiveIDStmt: %w", cerr)
+		}
+	}
+	if q.getLayoutStmt != nil {
+		if cerr := q.getLayoutStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLayoutStmt: %w", cerr)
+		}
+	}
 	if q.getMostRecentArchiveIDStmt != nil {
 		if cerr := q.getMostRecentArchiveIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMostRecentArchiveIDStmt: %w", cerr)
@@ -204,6 +356,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getMostRecentTagIDStmt: %w", cerr)
 		}
 	}
+	if q.getNoteStmt != nil {
+		if cerr := q.getNoteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNoteStmt: %w", cerr)
+		}
+	}
+	if q.getNotesStmt != nil {
+		if cerr := q.getNotesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNotesStmt: %w", cerr)
+		}
+	}
 	if q.getPagesByDateCreatedStmt != nil {
 		if cerr := q.getPagesByDateCreatedStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getPagesByDateCreatedStmt: %w", cerr)
@@ -234,11 +396,41 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getPagesByDateModifiedDescendingStmt: %w", cerr)
 		}
 	}
+	if q.getPendingJobStmt != nil {
+		if cerr := q.getPendingJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPendingJobStmt: %w", cerr)
+		}
+	}
 	if q.getPerceptualHashStmt != nil {
 		if cerr := q.getPerceptualHashStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getPerceptualHashStmt: %w", cerr)
 		}
 	}
+	if q.getPerceptualHashVersionStmt != nil {
+		if cerr := q.getPerceptualHashVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPerceptualHashVersionStmt: %w", cerr)
+		}
+	}
+	if q.getPerceptualHashesStmt != nil {
+		if cerr := q.getPerceptualHashesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPerceptualHashesStmt: %w", cerr)
+		}
+	}
+	if q.getPerceptualHashesByArchiveIDStmt != nil {
+		if cerr := q.getPerceptualHashesByArchiveIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPerceptualHashesByArchiveIDStmt: %w", cerr)
+		}
+	}
+	if q.getRenditionsStmt != nil {
+		if cerr := q.getRenditionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRenditionsStmt: %w", cerr)
+		}
+	}
+	if q.getStoredFilesStmt != nil {
+		if cerr := q.getStoredFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStoredFilesStmt: %w", cerr)
+		}
+	}
 	if q.getTagCountByListStmt != nil {
 		if cerr := q.getTagCountByListStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getTagCountByListStmt: %w", cerr)
@@ -274,6 +466,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing newEntryStmt: %w", cerr)
 		}
 	}
+	if q.newJobStmt != nil {
+		if cerr := q.newJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing newJobStmt: %w", cerr)
+		}
+	}
+	if q.newNoteStmt != nil {
+		if cerr := q.newNoteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing newNoteStmt: %w", cerr)
+		}
+	}
+	if q.newRenditionStmt != nil {
+		if cerr := q.newRenditionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing newRenditionStmt: %w", cerr)
+		}
+	}
 	if q.newTagStmt != nil {
 		if cerr := q.newTagStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing newTagStmt: %w", cerr)
@@ -294,6 +501,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing removeTagsFromArchiveIDStmt: %w", cerr)
 		}
 	}
+	if q.resetRunningJobsStmt != nil {
+		if cerr := q.resetRunningJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resetRunningJobsStmt: %w", cerr)
+		}
+	}
 	if q.resolveTagAliasStmt != nil {
 		if cerr := q.resolveTagAliasStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing resolveTagAliasStmt: %w", cerr)
@@ -304,29 +516,29 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing resolveTagAliasListStmt: %w", cerr)
 		}
 	}
+	if q.retryJobStmt != nil {
+		if cerr := q.retryJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing retryJobStmt: %w", cerr)
+		}
+	}
 	if q.searchHashStmt != nil {
 		if cerr := q.searchHashStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing searchHashStmt: %w", cerr)
 		}
 	}
+	if q.searchNotesStmt != nil {
+		if cerr := q.searchNotesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchNotesStmt: %w", cerr)
+		}
+	}
 	if q.searchTagStmt != nil {
 		if cerr := q.searchTagStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing searchTagStmt: %w", cerr)
 		}
 	}
-	if q.searchTagsByListDateCreatedStmt != nil {
-		if cerr := q.searchTagsByListDateCreatedStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing searchTagsByListDateCreatedStmt: %w", cerr)
-		}
-	}
-	if q.searchTagsByListDateImportedStmt != nil {
-		if cerr := q.searchTagsByListDateImportedStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing searchTagsByListDateImportedStmt: %w", cerr)
-		}
-	}
-	if q.searchTagsByListDateModifiedStmt != nil {
-		if cerr := q.searchTagsByListDateModifiedStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing searchTagsByListDateModifiedStmt: %w", cerr)
+	if q.setExifStmt != nil {
+		if cerr := q.setExifStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setExifStmt: %w", cerr)
 		}
 	}
 	if q.setFileMetadataStmt != nil {
@@ -339,6 +551,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing setHashesStmt: %w", cerr)
 		}
 	}
+	if q.setLayoutStmt != nil {
+		if cerr := q.setLayoutStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setLayoutStmt: %w", cerr)
+		}
+	}
 	if q.setPerceptualHashStmt != nil {
 		if cerr := q.setPerceptualHashStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing setPerceptualHashStmt: %w", cerr)
@@ -349,6 +566,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing setTimestampsStmt: %w", cerr)
 		}
 	}
+	if q.updateEntryPathStmt != nil {
+		if cerr := q.updateEntryPathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateEntryPathStmt: %w", cerr)
+		}
+	}
+	if q.updateNoteStmt != nil {
+		if cerr := q.updateNoteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateNoteStmt: %w", cerr)
+		}
+	}
+	if q.updateRenditionPathStmt != nil {
+		if cerr := q.updateRenditionPathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateRenditionPathStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -389,23 +621,44 @@ type Queries struct {
 	db                                   DBTX
 	tx                                   *sql.Tx
 	assignTagStmt                        *sql.Stmt
+	claimJobStmt                         *sql.Stmt
+	countJobsStmt                        *sql.Stmt
+	deleteDuplicateRunningJobsStmt       *sql.Stmt
 	deleteEntryStmt                      *sql.Stmt
-	deleteTagStmt                        *sql.Stmt
+	deleteNoteStmt                       *sql.Stmt
+	deleteRenditionStmt                  *sql.Stmt
 	deleteTagAliasStmt                   *sql.Stmt
 	deleteTagMapStmt                     *sql.Stmt
-	getEntryStmt                         *sql.Stmt
+	deleteTagStmt                        *sql.Stmt
+	enqueueJobsStmt                      *sql.Stmt
+	finishJobStmt                        *sql.Stmt
+	getEntriesStmt                       *sql.Stmt
 	getEntryPathStmt                     *sql.Stmt
+	getEntryStmt                         *sql.Stmt
+	getExifStmt                          *sql.Stmt
 	getFileMetadataStmt                  *sql.Stmt
 	getHashesStmt                        *sql.Stmt
+	getJobStmt                           *sql.Stmt
+	getJobsByArchiveIDStmt               *sql.Stmt
+	getJobsStmt                          *sql.Stmt
+	getLayoutStmt                        *sql.Stmt
 	getMostRecentArchiveIDStmt           *sql.Stmt
 	getMostRecentTagIDStmt               *sql.Stmt
-	getPagesByDateCreatedStmt            *sql.Stmt
+	getNoteStmt                          *sql.Stmt
+	getNotesStmt                         *sql.Stmt
 	getPagesByDateCreatedDescendingStmt  *sql.Stmt
+	getPagesByDateCreatedStmt            *sql.Stmt
 	getPagesByDateImportedAscendingStmt  *sql.Stmt
 	getPagesByDateImportedDecendingStmt  *sql.Stmt
 	getPagesByDateModifiedAscendingStmt  *sql.Stmt
 	getPagesByDateModifiedDescendingStmt *sql.Stmt
+	getPendingJobStmt                    *sql.Stmt
 	getPerceptualHashStmt                *sql.Stmt
+	getPerceptualHashesByArchiveIDStmt   *sql.Stmt
+	getPerceptualHashVersionStmt         *sql.Stmt
+	getPerceptualHashesStmt              *sql.Stmt
+	getRenditionsStmt                    *sql.Stmt
+	getStoredFilesStmt                   *sql.Stmt
 	getTagCountByListStmt                *sql.Stmt
 	getTagCountByRangeStmt               *sql.Stmt
 	getTagCountByTagStmt                 *sql.Stmt
@@ -413,21 +666,29 @@ type Queries struct {
 	getTagsFromArchiveIDStmt             *sql.Stmt
 	getTimestampsStmt                    *sql.Stmt
 	newEntryStmt                         *sql.Stmt
-	newTagStmt                           *sql.Stmt
+	newJobStmt                           *sql.Stmt
+	newNoteStmt                          *sql.Stmt
+	newRenditionStmt                     *sql.Stmt
 	newTagAliasStmt                      *sql.Stmt
+	newTagStmt                           *sql.Stmt
 	removeTagStmt                        *sql.Stmt
 	removeTagsFromArchiveIDStmt          *sql.Stmt
-	resolveTagAliasStmt                  *sql.Stmt
+	resetRunningJobsStmt                 *sql.Stmt
 	resolveTagAliasListStmt              *sql.Stmt
+	resolveTagAliasStmt                  *sql.Stmt
+	retryJobStmt                         *sql.Stmt
 	searchHashStmt                       *sql.Stmt
+	searchNotesStmt                      *sql.Stmt
 	searchTagStmt                        *sql.Stmt
-	searchTagsByListDateCreatedStmt      *sql.Stmt
-	searchTagsByListDateImportedStmt     *sql.Stmt
-	searchTagsByListDateModifiedStmt     *sql.Stmt
+	setExifStmt                          *sql.Stmt
 	setFileMetadataStmt                  *sql.Stmt
 	setHashesStmt                        *sql.Stmt
+	setLayoutStmt                        *sql.Stmt
 	setPerceptualHashStmt                *sql.Stmt
 	setTimestampsStmt                    *sql.Stmt
+	updateEntryPathStmt                  *sql.Stmt
+	updateNoteStmt                       *sql.Stmt
+	updateRenditionPathStmt              *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
@@ -435,23 +696,44 @@ func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 		db:                                   tx,
 		tx:                                   tx,
 		assignTagStmt:                        q.assignTagStmt,
+		claimJobStmt:                         q.claimJobStmt,
+		countJobsStmt:                        q.countJobsStmt,
+		deleteDuplicateRunningJobsStmt:       q.deleteDuplicateRunningJobsStmt,
 		deleteEntryStmt:                      q.deleteEntryStmt,
-		deleteTagStmt:                        q.deleteTagStmt,
+		deleteNoteStmt:                       q.deleteNoteStmt,
+		deleteRenditionStmt:                  q.deleteRenditionStmt,
 		deleteTagAliasStmt:                   q.deleteTagAliasStmt,
 		deleteTagMapStmt:                     q.deleteTagMapStmt,
-		getEntryStmt:                         q.getEntryStmt,
+		deleteTagStmt:                        q.deleteTagStmt,
+		enqueueJobsStmt:                      q.enqueueJobsStmt,
+		finishJobStmt:                        q.finishJobStmt,
+		getEntriesStmt:                       q.getEntriesStmt,
 		getEntryPathStmt:                     q.getEntryPathStmt,
+		getEntryStmt:                         q.getEntryStmt,
+		getExifStmt:                          q.getExifStmt,
 		getFileMetadataStmt:                  q.getFileMetadataStmt,
 		getHashesStmt:                        q.getHashesStmt,
+		getJobStmt:                           q.getJobStmt,
+		getJobsByArchiveIDStmt:               q.getJobsByArchiveIDStmt,
+		getJobsStmt:                          q.getJobsStmt,
+		getLayoutStmt:                        q.getLayoutStmt,
 		getMostRecentArchiveIDStmt:           q.getMostRecentArchiveIDStmt,
 		getMostRecentTagIDStmt:               q.getMostRecentTagIDStmt,
-		getPagesByDateCreatedStmt:            q.getPagesByDateCreatedStmt,
+		getNoteStmt:                          q.getNoteStmt,
+		getNotesStmt:                         q.getNotesStmt,
 		getPagesByDateCreatedDescendingStmt:  q.getPagesByDateCreatedDescendingStmt,
+		getPagesByDateCreatedStmt:            q.getPagesByDateCreatedStmt,
 		getPagesByDateImportedAscendingStmt:  q.getPagesByDateImportedAscendingStmt,
 		getPagesByDateImportedDecendingStmt:  q.getPagesByDateImportedDecendingStmt,
 		getPagesByDateModifiedAscendingStmt:  q.getPagesByDateModifiedAscendingStmt,
 		getPagesByDateModifiedDescendingStmt: q.getPagesByDateModifiedDescendingStmt,
+		getPendingJobStmt:                    q.getPendingJobStmt,
 		getPerceptualHashStmt:                q.getPerceptualHashStmt,
+		getPerceptualHashesByArchiveIDStmt:   q.getPerceptualHashesByArchiveIDStmt,
+		getPerceptualHashVersionStmt:         q.getPerceptualHashVersionStmt,
+		getPerceptualHashesStmt:              q.getPerceptualHashesStmt,
+		getRenditionsStmt:                    q.getRenditionsStmt,
+		getStoredFilesStmt:                   q.getStoredFilesStmt,
 		getTagCountByListStmt:                q.getTagCountByListStmt,
 		getTagCountByRangeStmt:               q.getTagCountByRangeStmt,
 		getTagCountByTagStmt:                 q.getTagCountByTagStmt,
@@ -459,20 +741,28 @@ func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 		getTagsFromArchiveIDStmt:             q.getTagsFromArchiveIDStmt,
 		getTimestampsStmt:                    q.getTimestampsStmt,
 		newEntryStmt:                         q.newEntryStmt,
-		newTagStmt:                           q.newTagStmt,
+		newJobStmt:                           q.newJobStmt,
+		newNoteStmt:                          q.newNoteStmt,
+		newRenditionStmt:                     q.newRenditionStmt,
 		newTagAliasStmt:                      q.newTagAliasStmt,
+		newTagStmt:                           q.newTagStmt,
 		removeTagStmt:                        q.removeTagStmt,
 		removeTagsFromArchiveIDStmt:          q.removeTagsFromArchiveIDStmt,
-		resolveTagAliasStmt:                  q.resolveTagAliasStmt,
+		resetRunningJobsStmt:                 q.resetRunningJobsStmt,
 		resolveTagAliasListStmt:              q.resolveTagAliasListStmt,
+		resolveTagAliasStmt:                  q.resolveTagAliasStmt,
+		retryJobStmt:                         q.retryJobStmt,
 		searchHashStmt:                       q.searchHashStmt,
+		searchNotesStmt:                      q.searchNotesStmt,
 		searchTagStmt:                        q.searchTagStmt,
-		searchTagsByListDateCreatedStmt:      q.searchTagsByListDateCreatedStmt,
-		searchTagsByListDateImportedStmt:     q.searchTagsByListDateImportedStmt,
-		searchTagsByListDateModifiedStmt:     q.searchTagsByListDateModifiedStmt,
+		setExifStmt:                          q.setExifStmt,
 		setFileMetadataStmt:                  q.setFileMetadataStmt,
 		setHashesStmt:                        q.setHashesStmt,
+		setLayoutStmt:                        q.setLayoutStmt,
 		setPerceptualHashStmt:                q.setPerceptualHashStmt,
 		setTimestampsStmt:                    q.setTimestampsStmt,
+		updateEntryPathStmt:                  q.updateEntryPathStmt,
+		updateNoteStmt:                       q.updateNoteStmt,
+		updateRenditionPathStmt:              q.updateRenditionPathStmt,
 	}
 }
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS tags_remove_count;
+DROP TRIGGER IF EXISTS tags_update_count;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS tag_count;
+DROP TABLE IF EXISTS tag_map;
+DROP TABLE IF EXISTS tags_alias;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS hashes_perceptual;
+DROP TABLE IF EXISTS hashes_chksum;
+DROP TABLE IF EXISTS archive_metadata;
+DROP TABLE IF EXISTS archive_timestamps;
+DROP TABLE IF EXISTS archive;
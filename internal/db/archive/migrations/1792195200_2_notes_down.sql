@@ -0,0 +1,14 @@
+CREATE TABLE notes_old (
+	"archive_id"	INTEGER NOT NULL UNIQUE PRIMARY KEY,
+	"title"			TEXT NOT NULL,
+	"text"			TEXT NOT NULL UNIQUE,
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE,
+	CONSTRAINT unique_title UNIQUE (archive_id, title)
+) WITHOUT ROWID;
+
+-- the old table only allowed a single note per entry, keep the oldest one
+INSERT OR IGNORE INTO notes_old (archive_id, title, text)
+	SELECT archive_id, title, text FROM notes ORDER BY note_id ASC;
+
+DROP TABLE notes;
+ALTER TABLE notes_old RENAME TO notes;
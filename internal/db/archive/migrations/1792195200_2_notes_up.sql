@@ -0,0 +1,17 @@
+CREATE TABLE notes_new (
+	"note_id"		INTEGER PRIMARY KEY AUTOINCREMENT,
+	"archive_id"	INTEGER NOT NULL,
+	"title"			TEXT NOT NULL,
+	"text"			TEXT NOT NULL,
+	"date_created"	INTEGER NOT NULL,
+	"date_modified"	INTEGER NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
+
+INSERT INTO notes_new (archive_id, title, text, date_created, date_modified)
+	SELECT archive_id, title, text, CAST(strftime('%s', 'now') AS INTEGER) * 1000, CAST(strftime('%s', 'now') AS INTEGER) * 1000 FROM notes;
+
+DROP TABLE notes;
+ALTER TABLE notes_new RENAME TO notes;
+
+CREATE INDEX notes_archive_id ON notes(archive_id);
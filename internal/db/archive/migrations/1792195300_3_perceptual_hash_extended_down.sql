@@ -0,0 +1,2 @@
+DELETE FROM hashes_perceptual WHERE hash_extended IS NOT NULL;
+ALTER TABLE hashes_perceptual DROP COLUMN "hash_extended";
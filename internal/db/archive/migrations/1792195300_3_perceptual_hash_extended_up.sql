@@ -0,0 +1,2 @@
+-- hashes larger than 64 bits keep their first word in "hash" and every word, big-endian, in "hash_extended"
+ALTER TABLE hashes_perceptual ADD COLUMN "hash_extended" BLOB;
@@ -0,0 +1,20 @@
+-- status is one of 'pending', 'running', 'done' or 'failed'. run_after is the earliest time, in unix
+-- milliseconds, a pending job may run, and is pushed back after every failed attempt
+CREATE TABLE jobs (
+	"job_id"		INTEGER PRIMARY KEY AUTOINCREMENT,
+	"archive_id"	INTEGER NOT NULL,
+	"type"			TEXT NOT NULL,
+	"status"		TEXT NOT NULL DEFAULT 'pending',
+	"attempts"		INTEGER NOT NULL DEFAULT 0,
+	"error"			TEXT NOT NULL DEFAULT '',
+	"run_after"		INTEGER NOT NULL,
+	"date_created"	INTEGER NOT NULL,
+	"date_modified"	INTEGER NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX jobs_status ON jobs(status, run_after);
+CREATE INDEX jobs_archive_id ON jobs(archive_id);
+
+-- an entry never has the same type of job queued twice
+CREATE UNIQUE INDEX jobs_pending ON jobs(archive_id, type) WHERE status == 'pending';
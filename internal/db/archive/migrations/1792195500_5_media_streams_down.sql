@@ -0,0 +1,6 @@
+ALTER TABLE archive_metadata DROP COLUMN "media_has_audio";
+ALTER TABLE archive_metadata DROP COLUMN "media_bitrate";
+ALTER TABLE archive_metadata DROP COLUMN "media_audio_codec";
+ALTER TABLE archive_metadata DROP COLUMN "media_video_codec";
+ALTER TABLE archive_metadata DROP COLUMN "media_framerate";
+ALTER TABLE archive_metadata DROP COLUMN "media_duration";
@@ -0,0 +1,8 @@
+-- stream metadata of videos and audio as probed by ffprobe. media_duration is in milliseconds,
+-- media_framerate in frames per second and media_bitrate in bits per second
+ALTER TABLE archive_metadata ADD COLUMN "media_duration" INTEGER;
+ALTER TABLE archive_metadata ADD COLUMN "media_framerate" REAL;
+ALTER TABLE archive_metadata ADD COLUMN "media_video_codec" TEXT;
+ALTER TABLE archive_metadata ADD COLUMN "media_audio_codec" TEXT;
+ALTER TABLE archive_metadata ADD COLUMN "media_bitrate" INTEGER;
+ALTER TABLE archive_metadata ADD COLUMN "media_has_audio" BOOLEAN NOT NULL DEFAULT 0;
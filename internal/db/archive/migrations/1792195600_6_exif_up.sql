@@ -0,0 +1,14 @@
+-- capture metadata embedded in photos through EXIF or XMP. date_original is in unix milliseconds,
+-- orientation is the EXIF orientation from 1 to 8, and latitude/longitude are in decimal degrees
+CREATE TABLE exif (
+	"archive_id"	INTEGER PRIMARY KEY,
+	"date_original"	INTEGER,
+	"make"			TEXT,
+	"model"			TEXT,
+	"lens"			TEXT,
+	"orientation"	INTEGER,
+	"latitude"		REAL,
+	"longitude"		REAL,
+	"altitude"		REAL,
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
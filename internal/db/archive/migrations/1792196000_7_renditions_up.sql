@@ -0,0 +1,12 @@
+-- web-playable transcodes of entries, at most one per mimetype. path is relative to the media directory,
+-- and file_size is in bytes
+CREATE TABLE rendition (
+	"archive_id"	INTEGER NOT NULL,
+	"path"			TEXT NOT NULL UNIQUE,
+	"mimetype"		TEXT NOT NULL,
+	"video_codec"	TEXT NOT NULL,
+	"audio_codec"	TEXT,
+	"file_size"		INTEGER NOT NULL,
+	UNIQUE("archive_id", "mimetype"),
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
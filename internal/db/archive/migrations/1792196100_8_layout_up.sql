@@ -0,0 +1,11 @@
+-- the layout media is stored in, see file.Layout. there is at most one row, with an id of 0. archives with
+-- entries from before layouts were recorded are stored in the legacy md5 layout, while empty archives are
+-- left to record the layout they're configured with
+CREATE TABLE storage_layout (
+	"id"		INTEGER PRIMARY KEY CHECK (id == 0),
+	"hash"		TEXT NOT NULL,
+	"depth"		INTEGER NOT NULL,
+	"width"		INTEGER NOT NULL
+);
+
+INSERT INTO storage_layout (id, hash, depth, width) SELECT 0, 'md5', 1, 2 WHERE EXISTS (SELECT 1 FROM archive);
@@ -0,0 +1,4 @@
+DROP TRIGGER hashes_perceptual_delete;
+DROP TRIGGER hashes_perceptual_update;
+DROP TRIGGER hashes_perceptual_insert;
+DROP TABLE hashes_perceptual_version;
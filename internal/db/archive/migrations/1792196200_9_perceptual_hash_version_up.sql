@@ -0,0 +1,26 @@
+-- counts the changes to the perceptual hashes of each type, so a cached index of them can tell whether another
+-- process changed them since it was built
+CREATE TABLE hashes_perceptual_version (
+	"hash_type"	TEXT PRIMARY KEY,
+	"version"	INTEGER NOT NULL
+);
+
+CREATE TRIGGER hashes_perceptual_insert AFTER INSERT ON hashes_perceptual
+BEGIN
+	INSERT INTO hashes_perceptual_version(hash_type, version) VALUES(NEW.hash_type, 1)
+	ON CONFLICT(hash_type)
+	DO
+		UPDATE SET version = version + 1;
+END;
+
+CREATE TRIGGER hashes_perceptual_update AFTER UPDATE ON hashes_perceptual
+BEGIN
+	UPDATE hashes_perceptual_version SET version = version + 1 WHERE hash_type IN (OLD.hash_type, NEW.hash_type);
+END;
+
+CREATE TRIGGER hashes_perceptual_delete AFTER DELETE ON hashes_perceptual
+BEGIN
+	UPDATE hashes_perceptual_version SET version = version + 1 WHERE hash_type == OLD.hash_type;
+END;
+
+INSERT INTO hashes_perceptual_version(hash_type, version) SELECT DISTINCT hash_type, 1 FROM hashes_perceptual;
@@ -21,6 +21,12 @@ type ArchiveMetadatum struct {
 	MediaWidth       sql.NullInt64
 	MediaHeight      sql.NullInt64
 	MediaOrientation sql.NullString
+	MediaDuration    sql.NullInt64
+	MediaFramerate   sql.NullFloat64
+	MediaVideoCodec  sql.NullString
+	MediaAudioCodec  sql.NullString
+	MediaBitrate     sql.NullInt64
+	MediaHasAudio    bool
 }
 
 type ArchiveTimestamp struct {
@@ -30,6 +36,18 @@ type ArchiveTimestamp struct {
 	DateCreated  int64
 }
 
+type Exif struct {
+	ArchiveID    int64
+	DateOriginal sql.NullInt64
+	Make         sql.NullString
+	Model        sql.NullString
+	Lens         sql.NullString
+	Orientation  sql.NullInt64
+	Latitude     sql.NullFloat64
+	Longitude    sql.NullFloat64
+	Altitude     sql.NullFloat64
+}
+
 type HashesChksum struct {
 	ArchiveID int64
 	Md5       []byte
@@ -38,15 +56,47 @@ type HashesChksum struct {
 }
 
 type HashesPerceptual struct {
-	ArchiveID int64
-	HashType  string
-	Hash      int64
+	ArchiveID    int64
+	HashType     string
+	Hash         int64
+	HashExtended []byte
+}
+
+type Job struct {
+	JobID        int64
+	ArchiveID    int64
+	Type         string
+	Status       string
+	Attempts     int64
+	Error        string
+	RunAfter     int64
+	DateCreated  int64
+	DateModified int64
 }
 
 type Note struct {
-	ArchiveID int64
-	Title     string
-	Text      string
+	NoteID       int64
+	ArchiveID    int64
+	Title        string
+	Text         string
+	DateCreated  int64
+	DateModified int64
+}
+
+type Rendition struct {
+	ArchiveID  int64
+	Path       string
+	Mimetype   string
+	VideoCodec string
+	AudioCodec sql.NullString
+	FileSize   int64
+}
+
+type StorageLayout struct {
+	ID    int64
+	Hash  string
+	Depth int64
+	Width int64
 }
 
 type Tag struct {
@@ -10,23 +10,44 @@ import (
 
 type Querier interface {
 	AssignTag(ctx context.Context, arg AssignTagParams) error
+	ClaimJob(ctx context.Context, now int64) (Job, error)
+	CountJobs(ctx context.Context) ([]CountJobsRow, error)
+	DeleteDuplicateRunningJobs(ctx context.Context) (int64, error)
 	DeleteEntry(ctx context.Context, archiveID int64) error
+	DeleteNote(ctx context.Context, noteID int64) error
+	DeleteRendition(ctx context.Context, arg DeleteRenditionParams) error
 	DeleteTag(ctx context.Context, tag string) error
 	DeleteTagAlias(ctx context.Context, tag string) error
 	DeleteTagMap(ctx context.Context, tagID int64) error
+	EnqueueJobs(ctx context.Context, arg EnqueueJobsParams) (int64, error)
+	FinishJob(ctx context.Context, arg FinishJobParams) error
+	GetEntries(ctx context.Context) ([]Archive, error)
 	GetEntry(ctx context.Context, archiveID int64) (Archive, error)
 	GetEntryPath(ctx context.Context, archiveID int64) (GetEntryPathRow, error)
+	GetExif(ctx context.Context, archiveID int64) (Exif, error)
 	GetFileMetadata(ctx context.Context, archiveID int64) (ArchiveMetadatum, error)
 	GetHashes(ctx context.Context, archiveID int64) (HashesChksum, error)
+	GetJob(ctx context.Context, jobID int64) (Job, error)
+	GetJobs(ctx context.Context, arg GetJobsParams) ([]Job, error)
+	GetJobsByArchiveID(ctx context.Context, archiveID int64) ([]Job, error)
+	GetLayout(ctx context.Context) (GetLayoutRow, error)
 	GetMostRecentArchiveID(ctx context.Context) (int64, error)
 	GetMostRecentTagID(ctx context.Context) (int64, error)
+	GetNote(ctx context.Context, noteID int64) (Note, error)
+	GetNotes(ctx context.Context, archiveID int64) ([]Note, error)
 	GetPagesByDateCreated(ctx context.Context, arg GetPagesByDateCreatedParams) ([]Archive, error)
 	GetPagesByDateCreatedDescending(ctx context.Context, arg GetPagesByDateCreatedDescendingParams) ([]Archive, error)
 	GetPagesByDateImportedAscending(ctx context.Context, arg GetPagesByDateImportedAscendingParams) ([]Archive, error)
 	GetPagesByDateImportedDecending(ctx context.Context, arg GetPagesByDateImportedDecendingParams) ([]Archive, error)
 	GetPagesByDateModifiedAscending(ctx context.Context, arg GetPagesByDateModifiedAscendingParams) ([]Archive, error)
 	GetPagesByDateModifiedDescending(ctx context.Context, arg GetPagesByDateModifiedDescendingParams) ([]Archive, error)
-	GetPerceptualHash(ctx context.Context, arg GetPerceptualHashParams) (int64, error)
+	GetPendingJob(ctx context.Context, arg GetPendingJobParams) (Job, error)
+	GetPerceptualHash(ctx context.Context, arg GetPerceptualHashParams) (GetPerceptualHashRow, error)
+	GetPerceptualHashVersion(ctx context.Context, hashType string) (int64, error)
+	GetPerceptualHashes(ctx context.Context, hashType string) ([]GetPerceptualHashesRow, error)
+	GetPerceptualHashesByArchiveID(ctx context.Context, archiveID int64) ([]GetPerceptualHashesByArchiveIDRow, error)
+	GetRenditions(ctx context.Context, archiveID int64) ([]Rendition, error)
+	GetStoredFiles(ctx context.Context) ([]GetStoredFilesRow, error)
 	GetTagCountByList(ctx context.Context, archiveIds []int64) ([]GetTagCountByListRow, error)
 	GetTagCountByRange(ctx context.Context, arg GetTagCountByRangeParams) ([]GetTagCountByRangeRow, error)
 	GetTagCountByTag(ctx context.Context, tag string) (TagCount, error)
@@ -34,21 +55,29 @@ type Querier interface {
 	GetTagsFromArchiveID(ctx context.Context, archiveID int64) ([]string, error)
 	GetTimestamps(ctx context.Context, archiveID int64) (ArchiveTimestamp, error)
 	NewEntry(ctx context.Context, arg NewEntryParams) error
+	NewJob(ctx context.Context, arg NewJobParams) (int64, error)
+	NewNote(ctx context.Context, arg NewNoteParams) (int64, error)
+	NewRendition(ctx context.Context, arg NewRenditionParams) error
 	NewTag(ctx context.Context, tag string) error
 	NewTagAlias(ctx context.Context, arg NewTagAliasParams) error
 	RemoveTag(ctx context.Context, arg RemoveTagParams) error
 	RemoveTagsFromArchiveID(ctx context.Context, archiveID int64) error
+	ResetRunningJobs(ctx context.Context, now int64) (int64, error)
 	ResolveTagAlias(ctx context.Context, aliasTag string) (ResolveTagAliasRow, error)
 	ResolveTagAliasList(ctx context.Context, aliasTags []string) ([]ResolveTagAliasListRow, error)
+	RetryJob(ctx context.Context, arg RetryJobParams) error
 	SearchHash(ctx context.Context, hash interface{}) (int64, error)
+	SearchNotes(ctx context.Context, arg SearchNotesParams) ([]Note, error)
 	SearchTag(ctx context.Context, tag string) ([]SearchTagRow, error)
-	SearchTagsByListDateCreated(ctx context.Context, arg SearchTagsByListDateCreatedParams) ([]SearchTagsByListDateCreatedRow, error)
-	SearchTagsByListDateImported(ctx context.Context, arg SearchTagsByListDateImportedParams) ([]SearchTagsByListDateImportedRow, error)
-	SearchTagsByListDateModified(ctx context.Context, arg SearchTagsByListDateModifiedParams) ([]SearchTagsByListDateModifiedRow, error)
+	SetExif(ctx context.Context, arg SetExifParams) error
 	SetFileMetadata(ctx context.Context, arg SetFileMetadataParams) error
 	SetHashes(ctx context.Context, arg SetHashesParams) error
+	SetLayout(ctx context.Context, arg SetLayoutParams) error
 	SetPerceptualHash(ctx context.Context, arg SetPerceptualHashParams) error
 	SetTimestamps(ctx context.Context, arg SetTimestampsParams) error
+	UpdateEntryPath(ctx context.Context, arg UpdateEntryPathParams) error
+	UpdateNote(ctx context.Context, arg UpdateNoteParams) error
+	UpdateRenditionPath(ctx context.Context, arg UpdateRenditionPathParams) error
 }
 
 var _ Querier = (*Queries)(nil)
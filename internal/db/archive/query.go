@@ -0,0 +1,217 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dtbead/moonpool/internal/db"
+	"github.com/dtbead/moonpool/internal/query"
+)
+
+// tagIDs selects the tag_id of a base tag, or of the base tag an alias refers to.
+const (
+	tagIDsExact = `SELECT tag_id FROM tags WHERE text == ?
+		UNION SELECT tag_id FROM tags_alias WHERE text == ?`
+	tagIDsPrefix = `SELECT tag_id FROM tags WHERE substr(text, 1, ?) == ?
+		UNION SELECT tag_id FROM tags_alias WHERE substr(text, 1, ?) == ?`
+)
+
+// rangeColumns maps a query.Range field to the SQL expression it compares against.
+var rangeColumns = map[string]string{
+	query.FieldWidth:     "archive.id IN (SELECT archive_id FROM archive_metadata WHERE media_width BETWEEN ? AND ?)",
+	query.FieldHeight:    "archive.id IN (SELECT archive_id FROM archive_metadata WHERE media_height BETWEEN ? AND ?)",
+	query.FieldSize:      "archive.id IN (SELECT archive_id FROM archive_metadata WHERE file_size BETWEEN ? AND ?)",
+	query.FieldImported:  "archive_timestamps.date_imported BETWEEN ? AND ?",
+	query.FieldCreated:   "archive_timestamps.date_created BETWEEN ? AND ?",
+	query.FieldModified:  "archive_timestamps.date_modified BETWEEN ? AND ?",
+	query.FieldTagCount:  "(SELECT count(*) FROM tag_map WHERE tag_map.archive_id = archive.id) BETWEEN ? AND ?",
+	query.FieldDuration:  "archive.id IN (SELECT archive_id FROM archive_metadata WHERE media_duration BETWEEN ? AND ?)",
+	query.FieldFramerate: "archive.id IN (SELECT archive_id FROM archive_metadata WHERE CAST(round(media_framerate) AS INTEGER) BETWEEN ? AND ?)",
+	query.FieldBitrate:   "archive.id IN (SELECT archive_id FROM archive_metadata WHERE media_bitrate BETWEEN ? AND ?)",
+	query.FieldAudio:     "archive.id IN (SELECT archive_id FROM archive_metadata WHERE media_has_audio BETWEEN ? AND ? AND file_mimetype LIKE 'video/%')",
+}
+
+// matchColumns maps a query.Match field to the archive_metadata column it compares against.
+var matchColumns = map[string]string{
+	query.FieldOrientation: "media_orientation",
+	query.FieldMimetype:    "file_mimetype",
+	query.FieldVideoCodec:  "media_video_codec",
+	query.FieldAudioCodec:  "media_audio_codec",
+}
+
+// compileQuery translates a query into a SQL expression over "archive.id" and its arguments.
+func compileQuery(n query.Node) (string, []any, error) {
+	var b strings.Builder
+	var args []any
+
+	var compile func(n query.Node) error
+	compile = func(n query.Node) error {
+		switch n := n.(type) {
+		case query.Tag:
+			tag := db.DeleteWhitespace(n.Text)
+
+			b.WriteString("archive.id IN (SELECT tag_map.archive_id FROM tag_map WHERE tag_map.tag_id IN (")
+			if n.Prefix {
+				b.WriteString(tagIDsPrefix)
+				l := utf8.RuneCountInString(tag)
+				args = append(args, l, tag, l, tag)
+			} else {
+				b.WriteString(tagIDsExact)
+				args = append(args, tag, tag)
+			}
+			b.WriteString("))")
+		case query.Range:
+			expr, ok := rangeColumns[n.Field]
+			if !ok {
+				return fmt.Errorf("unknown range field '%s'", n.Field)
+			}
+			b.WriteString(expr)
+			args = append(args, n.Min, n.Max)
+		case query.Match:
+			column, ok := matchColumns[n.Field]
+			if !ok {
+				return fmt.Errorf("unknown match field '%s'", n.Field)
+			}
+
+			b.WriteString("archive.id IN (SELECT archive_id FROM archive_metadata WHERE ")
+			if n.Prefix {
+				b.WriteString("substr(" + column + ", 1, ?) == ?)")
+				args = append(args, utf8.RuneCountInString(n.Value), n.Value)
+			} else {
+				b.WriteString(column + " == ?)")
+				args = append(args, n.Value)
+			}
+		case query.Predicate:
+			return fmt.Errorf("unresolved predicate '%s'", n)
+		case query.Not:
+			b.WriteString("NOT (")
+			if err := compile(n.Node); err != nil {
+				return err
+			}
+			b.WriteString(")")
+		case query.And:
+			return compileList(&b, n.Nodes, " AND ", compile)
+		case query.Or:
+			return compileList(&b, n.Nodes, " OR ", compile)
+		default:
+			return fmt.Errorf("unknown query node %T", n)
+		}
+		return nil
+	}
+
+	if n == nil {
+		return "", nil, errors.New("empty query")
+	}
+
+	if err := compile(n); err != nil {
+		return "", nil, err
+	}
+
+	return b.String(), args, nil
+}
+
+func compileList(b *strings.Builder, nodes []query.Node, sep string, compile func(query.Node) error) error {
+	b.WriteString("(")
+	for i, n := range nodes {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		if err := compile(n); err != nil {
+			return err
+		}
+	}
+	b.WriteString(")")
+	return nil
+}
+
+// SearchTagQuery returns a page of archive_id's that match a parsed tag query. Tag aliases are resolved
+// to their base tag. Every query.Predicate must already be resolved into a query.Range or query.Match.
+//
+// Valid sort options are "imported", "created", and "modified".
+// Valid order options are "descending", "ascending".
+// limit limits how many archive_id's are returned, a limit of 0 or less returns every match.
+// offset skips the first matches of the result, e.g. a limit of 50 with an offset of 50 returns matches 51-100.
+func (a archive) SearchTagQuery(ctx context.Context, sort, order string, q query.Node, limit, offset int64) ([]int64, error) {
+	column, direction, err := sortColumn(sort, order)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args, err := compileQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = -1
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := a.db.QueryContext(ctx, `SELECT archive.id FROM archive
+INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
+WHERE `+where+`
+ORDER BY archive_timestamps.`+column+` `+direction+`, archive.id `+direction+`
+LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	archive_ids := make([]int64, 0, 50)
+	for rows.Next() {
+		var archive_id int64
+		if err := rows.Scan(&archive_id); err != nil {
+			return nil, err
+		}
+		archive_ids = append(archive_ids, archive_id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return archive_ids, nil
+}
+
+// CountTagQuery returns the total amount of entries that match a parsed tag query.
+func (a archive) CountTagQuery(ctx context.Context, q query.Node) (int64, error) {
+	where, args, err := compileQuery(q)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = a.db.QueryRowContext(ctx, `SELECT count(*) FROM archive
+INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
+WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+func sortColumn(sort, order string) (column, direction string, err error) {
+	switch sort {
+	case "imported":
+		column = "date_imported"
+	case "created":
+		column = "date_created"
+	case "modified":
+		column = "date_modified"
+	default:
+		return "", "", errors.New("invalid sort or order option")
+	}
+
+	switch order {
+	case "descending", "":
+		direction = "DESC"
+	case "ascending":
+		direction = "ASC"
+	default:
+		return "", "", errors.New("invalid sort or order option")
+	}
+
+	return column, direction, nil
+}
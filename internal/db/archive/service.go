@@ -4,23 +4,28 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
-	"os"
-	"slices"
 	"strings"
 	"time"
 
 	"github.com/dtbead/moonpool/entry"
 	"github.com/dtbead/moonpool/internal/db"
-	"github.com/dtbead/moonpool/internal/db/migration"
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/query"
 	"modernc.org/sqlite"
 )
 
 type archive struct {
 	query *Queries
-	db    *sql.DB
+	db    conn
+}
+
+// conn is either a connection pool or a single connection of it.
+type conn interface {
+	DBTX
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 }
 
 type TX interface {
@@ -31,15 +36,25 @@ type TX interface {
 type Archiver interface {
 	NewEntry(ctx context.Context, path, extension string) (int64, error)
 	GetEntry(ctx context.Context, archive_id int64) (Archive, error)
+	GetEntries(ctx context.Context) ([]Archive, error)
 	GetPage(ctx context.Context, sort string, limit, offset int64, desc bool) ([]Archive, error)
 	DeleteEntry(ctx context.Context, archive_id int64) error
 	RemoveTags(ctx context.Context, archive_id int64) error
-	GetFile(ctx context.Context, archive_id int64, baseDirectory string) (io.ReadCloser, error)
 	GetTags(ctx context.Context, archive_id int64) ([]string, error)
 	GetTagCount(ctx context.Context, tag string) (int64, error)
 	GetTagCountByRange(ctx context.Context, start, end, limit, offset int64) ([]entry.TagCount, error)
 	GetFileMetadata(ctx context.Context, archive_id int64) (entry.FileMetadata, error)
 	SetFileMetadata(ctx context.Context, archive_id int64, m entry.FileMetadata) error
+	GetExif(ctx context.Context, archive_id int64) (entry.Exif, error)
+	SetExif(ctx context.Context, archive_id int64, e entry.Exif) error
+	NewRendition(ctx context.Context, r entry.Rendition) error
+	GetRenditions(ctx context.Context, archive_id int64) ([]entry.Rendition, error)
+	UpdateEntryPath(ctx context.Context, archive_id int64, path string) error
+	UpdateRenditionPath(ctx context.Context, archive_id int64, mimetype, path string) error
+	DeleteRendition(ctx context.Context, archive_id int64, mimetype string) error
+	GetStoredFiles(ctx context.Context) ([]entry.StoredFile, error)
+	GetLayout(ctx context.Context) (file.Layout, error)
+	SetLayout(ctx context.Context, l file.Layout) error
 	GetTagCountByList(ctx context.Context, archive_ids []int64) ([]entry.TagCount, error)
 	SetTimestamps(ctx context.Context, archive_id int64, t db.Timestamp) error
 	GetTimestamps(ctx context.Context, archive_id int64) (db.Timestamp, error)
@@ -53,17 +68,38 @@ type Archiver interface {
 	RemoveTag(ctx context.Context, archive_id int64, tag string) error
 	GetTagID(ctx context.Context, tag string) (Tag, error)
 	SearchTag(ctx context.Context, tag string) ([]SearchTagRow, error)
-	SearchTagByList(ctx context.Context, sort, order string, tags_include, tags_exclude []string) ([]int64, error)
+	SearchTagQuery(ctx context.Context, sort, order string, q query.Node, limit, offset int64) ([]int64, error)
+	CountTagQuery(ctx context.Context, q query.Node) (int64, error)
 	SearchHash(ctx context.Context, hash string) (int64, error)
 	GetHashes(ctx context.Context, archive_id int64) (HashesChksum, error)
 	SetHashes(ctx context.Context, archive_id int64, h Hashes) error
-	GetPerceptualHash(ctx context.Context, archive_id int64, hashType string) (uint64, error)
-	SetPerceptualHash(ctx context.Context, archive_id int64, hashType string, hash uint64) error
+	GetPerceptualHash(ctx context.Context, archive_id int64, hashType string) (entry.PerceptualHash, error)
+	SetPerceptualHash(ctx context.Context, archive_id int64, hashType string, hash []uint64) error
+	GetPerceptualHashes(ctx context.Context, hashType string) ([]entry.PerceptualHash, error)
+	GetPerceptualHashVersion(ctx context.Context, hashType string) (int64, error)
+	GetPerceptualHashesByArchiveID(ctx context.Context, archive_id int64) ([]entry.PerceptualHash, error)
 	DeleteTag(ctx context.Context, tag string) error
+	NewNote(ctx context.Context, archive_id int64, title, text string) (int64, error)
+	GetNote(ctx context.Context, note_id int64) (entry.Note, error)
+	GetNotes(ctx context.Context, archive_id int64) ([]entry.Note, error)
+	UpdateNote(ctx context.Context, note_id int64, title, text string) error
+	DeleteNote(ctx context.Context, note_id int64) error
+	SearchNotes(ctx context.Context, query string, limit, offset int64) ([]entry.Note, error)
+	NewJob(ctx context.Context, archive_id int64, jobType string) (int64, error)
+	EnqueueJobs(ctx context.Context, jobType string, all bool) (int64, error)
+	GetJob(ctx context.Context, job_id int64) (entry.Job, error)
+	GetJobs(ctx context.Context, status string, limit, offset int64) ([]entry.Job, error)
+	GetJobsByArchiveID(ctx context.Context, archive_id int64) ([]entry.Job, error)
+	CountJobs(ctx context.Context) (map[string]int64, error)
+	ClaimJob(ctx context.Context) (entry.Job, error)
+	FinishJob(ctx context.Context, job_id int64, status, jobError string, runAfter time.Time) error
+	RetryJob(ctx context.Context, job_id int64) error
+	ResetRunningJobs(ctx context.Context) (int64, error)
 	GetMostRecentArchiveID(ctx context.Context) (int64, error)
 	GetMostRecentTagID(ctx context.Context) (int64, error)
 	DoesArchiveIDExist(ctx context.Context, id int64) bool
 	NewTx(ctx context.Context, opt *sql.TxOptions) (Querier, TX, error)
+	WithConn(c *sql.Conn) Archiver
 	NewSavepoint(ctx context.Context, name string) error
 	ReleaseSavepoint(ctx context.Context, name string) error
 	Rollback(ctx context.Context, name string) error
@@ -88,6 +124,15 @@ func (a archive) NewTx(ctx context.Context, opt *sql.TxOptions) (Querier, TX, er
 	return q, tx, nil
 }
 
+// WithConn returns an Archiver running every query on c. Savepoints only apply to the connection they
+// were created on, so a series of savepoints has to be created on a single connection.
+func (a archive) WithConn(c *sql.Conn) Archiver {
+	return &archive{
+		query: New(c),
+		db:    c,
+	}
+}
+
 func (a archive) NewSavepoint(ctx context.Context, name string) error {
 	if !db.IsClean(name) {
 		return errors.New("invalid name")
@@ -169,24 +214,6 @@ func (a archive) DeleteEntry(ctx context.Context, archive_id int64) error {
 	return a.query.DeleteEntry(ctx, archive_id)
 }
 
-func (a archive) GetFile(ctx context.Context, archive_id int64, baseDirectory string) (io.ReadCloser, error) {
-	e, err := a.GetEntry(ctx, archive_id)
-	if err != nil {
-		return nil, err
-	}
-
-	if !strings.HasSuffix(baseDirectory, "/") {
-		baseDirectory += "/"
-	}
-
-	f, err := os.OpenFile(baseDirectory+e.Path, os.O_RDONLY, os.ModeType)
-	if err != nil {
-		return nil, err
-	}
-
-	return f, nil
-}
-
 func (a archive) GetFileMetadata(ctx context.Context, archive_id int64) (entry.FileMetadata, error) {
 	m, err := a.query.GetFileMetadata(ctx, archive_id)
 	if err != nil {
@@ -199,6 +226,12 @@ func (a archive) GetFileMetadata(ctx context.Context, archive_id int64) (entry.F
 		MediaHeight:      m.MediaHeight.Int64,
 		MediaWidth:       m.MediaWidth.Int64,
 		MediaOrientation: m.MediaOrientation.String,
+		MediaDuration:    time.Duration(m.MediaDuration.Int64) * time.Millisecond,
+		MediaFramerate:   m.MediaFramerate.Float64,
+		MediaVideoCodec:  m.MediaVideoCodec.String,
+		MediaAudioCodec:  m.MediaAudioCodec.String,
+		MediaBitrate:     m.MediaBitrate.Int64,
+		MediaHasAudio:    m.MediaHasAudio,
 	}, nil
 }
 
@@ -210,9 +243,133 @@ func (a archive) SetFileMetadata(ctx context.Context, archive_id int64, m entry.
 		MediaWidth:       sql.NullInt64{Int64: m.MediaWidth, Valid: true},
 		MediaHeight:      sql.NullInt64{Int64: m.MediaHeight, Valid: true},
 		MediaOrientation: sql.NullString{String: m.MediaOrientation, Valid: true},
+		MediaDuration:    sql.NullInt64{Int64: m.MediaDuration.Milliseconds(), Valid: m.MediaDuration > 0},
+		MediaFramerate:   sql.NullFloat64{Float64: m.MediaFramerate, Valid: m.MediaFramerate > 0},
+		MediaVideoCodec:  sql.NullString{String: m.MediaVideoCodec, Valid: m.MediaVideoCodec != ""},
+		MediaAudioCodec:  sql.NullString{String: m.MediaAudioCodec, Valid: m.MediaAudioCodec != ""},
+		MediaBitrate:     sql.NullInt64{Int64: m.MediaBitrate, Valid: m.MediaBitrate > 0},
+		MediaHasAudio:    m.MediaHasAudio,
 	})
 }
 
+func (a archive) GetExif(ctx context.Context, archive_id int64) (entry.Exif, error) {
+	e, err := a.query.GetExif(ctx, archive_id)
+	if err != nil {
+		return entry.Exif{}, err
+	}
+
+	var date time.Time
+	if e.DateOriginal.Valid {
+		date = time.UnixMilli(e.DateOriginal.Int64)
+	}
+
+	return entry.Exif{
+		DateTimeOriginal: date,
+		Make:             e.Make.String,
+		Model:            e.Model.String,
+		Lens:             e.Lens.String,
+		Orientation:      e.Orientation.Int64,
+		HasGPS:           e.Latitude.Valid && e.Longitude.Valid,
+		Latitude:         e.Latitude.Float64,
+		Longitude:        e.Longitude.Float64,
+		Altitude:         e.Altitude.Float64,
+	}, nil
+}
+
+func (a archive) SetExif(ctx context.Context, archive_id int64, e entry.Exif) error {
+	return a.query.SetExif(ctx, SetExifParams{
+		ArchiveID:    archive_id,
+		DateOriginal: sql.NullInt64{Int64: e.DateTimeOriginal.UTC().UnixMilli(), Valid: !e.DateTimeOriginal.IsZero()},
+		Make:         sql.NullString{String: e.Make, Valid: e.Make != ""},
+		Model:        sql.NullString{String: e.Model, Valid: e.Model != ""},
+		Lens:         sql.NullString{String: e.Lens, Valid: e.Lens != ""},
+		Orientation:  sql.NullInt64{Int64: e.Orientation, Valid: e.Orientation != 0},
+		Latitude:     sql.NullFloat64{Float64: e.Latitude, Valid: e.HasGPS},
+		Longitude:    sql.NullFloat64{Float64: e.Longitude, Valid: e.HasGPS},
+		Altitude:     sql.NullFloat64{Float64: e.Altitude, Valid: e.HasGPS},
+	})
+}
+
+func (a archive) NewRendition(ctx context.Context, r entry.Rendition) error {
+	return a.query.NewRendition(ctx, NewRenditionParams{
+		ArchiveID:  r.ArchiveID,
+		Path:       r.Path,
+		Mimetype:   r.Mimetype,
+		VideoCodec: r.VideoCodec,
+		AudioCodec: sql.NullString{String: r.AudioCodec, Valid: r.AudioCodec != ""},
+		FileSize:   r.FileSize,
+	})
+}
+
+func (a archive) GetRenditions(ctx context.Context, archive_id int64) ([]entry.Rendition, error) {
+	res, err := a.query.GetRenditions(ctx, archive_id)
+	if err != nil {
+		return nil, err
+	}
+
+	renditions := make([]entry.Rendition, len(res))
+	for i, r := range res {
+		renditions[i] = entry.Rendition{
+			ArchiveID:  r.ArchiveID,
+			Path:       r.Path,
+			Mimetype:   r.Mimetype,
+			VideoCodec: r.VideoCodec,
+			AudioCodec: r.AudioCodec.String,
+			FileSize:   r.FileSize,
+		}
+	}
+	return renditions, nil
+}
+
+func (a archive) UpdateEntryPath(ctx context.Context, archive_id int64, path string) error {
+	return a.query.UpdateEntryPath(ctx, UpdateEntryPathParams{Path: path, ArchiveID: archive_id})
+}
+
+func (a archive) UpdateRenditionPath(ctx context.Context, archive_id int64, mimetype, path string) error {
+	return a.query.UpdateRenditionPath(ctx, UpdateRenditionPathParams{Path: path, ArchiveID: archive_id, Mimetype: mimetype})
+}
+
+func (a archive) DeleteRendition(ctx context.Context, archive_id int64, mimetype string) error {
+	return a.query.DeleteRendition(ctx, DeleteRenditionParams{ArchiveID: archive_id, Mimetype: mimetype})
+}
+
+// GetEntries returns every entry, ordered by archive_id.
+func (a archive) GetEntries(ctx context.Context) ([]Archive, error) {
+	return a.query.GetEntries(ctx)
+}
+
+// GetStoredFiles returns the stored file of every entry, ordered by archive_id.
+func (a archive) GetStoredFiles(ctx context.Context) ([]entry.StoredFile, error) {
+	res, err := a.query.GetStoredFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]entry.StoredFile, len(res))
+	for i, f := range res {
+		files[i] = entry.StoredFile{
+			ArchiveID: f.ID,
+			Path:      f.Path,
+			Extension: f.Extension,
+			Hashes:    entry.Hashes{MD5: f.Md5, SHA1: f.Sha1, SHA256: f.Sha256},
+		}
+	}
+	return files, nil
+}
+
+// GetLayout returns the layout media is stored in, or sql.ErrNoRows if none was recorded yet.
+func (a archive) GetLayout(ctx context.Context) (file.Layout, error) {
+	l, err := a.query.GetLayout(ctx)
+	if err != nil {
+		return file.Layout{}, err
+	}
+	return file.Layout{Hash: l.Hash, Depth: int(l.Depth), Width: int(l.Width)}, nil
+}
+
+func (a archive) SetLayout(ctx context.Context, l file.Layout) error {
+	return a.query.SetLayout(ctx, SetLayoutParams{Hash: l.Hash, Depth: int64(l.Depth), Width: int64(l.Width)})
+}
+
 func (a archive) GetTags(ctx context.Context, archive_id int64) ([]string, error) {
 	tags, err := a.query.GetTagsFromArchiveID(ctx, archive_id)
 	if !errors.Is(err, sql.ErrNoRows) && err != nil {
@@ -622,180 +779,357 @@ func (a archive) SearchTag(ctx context.Context, tag string) ([]SearchTagRow, err
 	return t, nil
 }
 
-// Valid sort options are "imported", "created", and "modified".
-// Valid order options are "descending", "ascending".
-func (a archive) SearchTagByList(ctx context.Context, sort, order string, tags_include, tags_exclude []string) ([]int64, error) {
-	if tags_include == nil {
-		return nil, nil
-	}
+func (a archive) DoesArchiveIDExist(ctx context.Context, archive_id int64) bool {
+	res := a.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM archive WHERE id == ? LIMIT 1);`, archive_id)
 
-	for i, tag := range tags_include {
-		tags_include[i] = db.DeleteWhitespace(tag)
+	var ret = 0
+	res.Scan(&ret)
+
+	return ret == 1
+}
+
+func (a archive) GetPerceptualHash(ctx context.Context, archive_id int64, hashType string) (entry.PerceptualHash, error) {
+	phash, err := a.query.GetPerceptualHash(ctx, GetPerceptualHashParams{ArchiveID: archive_id, HashType: hashType})
+	if err != nil {
+		return entry.PerceptualHash{}, err
 	}
+	return entry.PerceptualHash{
+		ArchiveID: archive_id,
+		Type:      hashType,
+		Hash:      decodePerceptualHash(phash.Hash, phash.HashExtended),
+	}, nil
+}
 
-	for i, tag := range tags_exclude {
-		tags_exclude[i] = db.DeleteWhitespace(tag)
+// SetPerceptualHash stores a perceptual hash of any size, replacing an existing hash of the same type.
+func (a archive) SetPerceptualHash(ctx context.Context, archive_id int64, hashType string, hash []uint64) error {
+	if len(hash) == 0 {
+		return errors.New("given empty perceptual hash")
 	}
 
-	tags, err := a.ResolveTagAliasList(ctx, slices.Concat(tags_include, tags_exclude))
+	h, extended := encodePerceptualHash(hash)
+	return a.query.SetPerceptualHash(ctx, SetPerceptualHashParams{
+		ArchiveID:    archive_id,
+		HashType:     hashType,
+		Hash:         h,
+		HashExtended: extended,
+	})
+}
+
+// GetPerceptualHashes returns every perceptual hash of hashType in the archive, ordered by archive_id.
+func (a archive) GetPerceptualHashes(ctx context.Context, hashType string) ([]entry.PerceptualHash, error) {
+	rows, err := a.query.GetPerceptualHashes(ctx, hashType)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: integrate this procedure globally for all other search funcs
-	// or handle all resolving purely in the DB
-	for _, resolved := range tags {
-		for i, tag := range tags_include {
-			if resolved.AliasTag == tag {
-				tags_include[i] = resolved.BaseTag
-			}
+	hashes := make([]entry.PerceptualHash, len(rows))
+	for i, v := range rows {
+		hashes[i] = entry.PerceptualHash{
+			ArchiveID: v.ArchiveID,
+			Type:      hashType,
+			Hash:      decodePerceptualHash(v.Hash, v.HashExtended),
 		}
 	}
+	return hashes, nil
+}
 
-	for _, resolved := range tags {
-		for i, tag := range tags_exclude {
-			if resolved.AliasTag == tag {
-				tags_exclude[i] = resolved.BaseTag
-			}
-		}
+// GetPerceptualHashVersion returns a number that changes whenever a perceptual hash of hashType is added,
+// replaced or removed, by any connection to the archive. It's 0 if no hash of hashType was ever stored.
+func (a archive) GetPerceptualHashVersion(ctx context.Context, hashType string) (int64, error) {
+	version, err := a.query.GetPerceptualHashVersion(ctx, hashType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
 	}
+	return version, err
+}
 
-	archive_ids := make([]int64, 0, 50)
-
-	switch sort {
-	default:
-		return nil, errors.New("invalid sort or order option")
-	case "created":
-		res, err := a.query.SearchTagsByListDateCreated(ctx, SearchTagsByListDateCreatedParams{tags_include, tags_exclude})
-		if err != nil {
-			return nil, err
-		}
+// GetPerceptualHashesByArchiveID returns every type of perceptual hash stored for an archive_id, ordered by type.
+func (a archive) GetPerceptualHashesByArchiveID(ctx context.Context, archive_id int64) ([]entry.PerceptualHash, error) {
+	rows, err := a.query.GetPerceptualHashesByArchiveID(ctx, archive_id)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, v := range res {
-			archive_ids = append(archive_ids, v.ID)
-		}
-	case "imported":
-		res, err := a.query.SearchTagsByListDateImported(ctx, SearchTagsByListDateImportedParams{tags_include, tags_exclude})
-		if err != nil {
-			return nil, err
+	hashes := make([]entry.PerceptualHash, len(rows))
+	for i, v := range rows {
+		hashes[i] = entry.PerceptualHash{
+			ArchiveID: archive_id,
+			Type:      v.HashType,
+			Hash:      decodePerceptualHash(v.Hash, v.HashExtended),
 		}
+	}
+	return hashes, nil
+}
 
-		for _, v := range res {
-			archive_ids = append(archive_ids, v.ID)
-		}
-	case "modified":
-		res, err := a.query.SearchTagsByListDateModified(ctx, SearchTagsByListDateModifiedParams{tags_include, tags_exclude})
-		if err != nil {
-			return nil, err
-		}
+// encodePerceptualHash splits a hash into its first word, and every word as big-endian bytes if the hash
+// is larger than 64 bits.
+func encodePerceptualHash(hash []uint64) (int64, []byte) {
+	if len(hash) == 1 {
+		return int64(hash[0]), nil
+	}
 
-		for _, v := range res {
-			archive_ids = append(archive_ids, v.ID)
-		}
+	extended := make([]byte, 0, len(hash)*8)
+	for _, w := range hash {
+		extended = binary.BigEndian.AppendUint64(extended, w)
 	}
+	return int64(hash[0]), extended
+}
 
-	// SearchTagsByList* returns results in descending order by default
-	if order == "ascending" {
-		slices.Reverse(archive_ids)
+func decodePerceptualHash(hash int64, extended []byte) []uint64 {
+	if len(extended) == 0 {
+		return []uint64{uint64(hash)}
 	}
 
-	return archive_ids, nil
+	words := make([]uint64, 0, len(extended)/8)
+	for i := 0; i+8 <= len(extended); i += 8 {
+		words = append(words, binary.BigEndian.Uint64(extended[i:]))
+	}
+	return words
 }
 
-func (a archive) DoesArchiveIDExist(ctx context.Context, archive_id int64) bool {
-	res := a.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM archive WHERE id == ? LIMIT 1);`, archive_id)
+// NewNote attaches a new note to an archive_id and returns its note_id.
+func (a archive) NewNote(ctx context.Context, archive_id int64, title, text string) (int64, error) {
+	now := time.Now().UTC().UnixMilli()
 
-	var ret = 0
-	res.Scan(&ret)
+	note_id, err := a.query.NewNote(ctx, NewNoteParams{
+		ArchiveID:    archive_id,
+		Title:        title,
+		Text:         text,
+		DateCreated:  now,
+		DateModified: now,
+	})
+	if err != nil {
+		return -1, err
+	}
 
-	return ret == 1
+	return note_id, nil
 }
 
-func (a archive) GetPerceptualHash(ctx context.Context, archive_id int64, hashType string) (uint64, error) {
-	phash, err := a.query.GetPerceptualHash(ctx, GetPerceptualHashParams{ArchiveID: archive_id, HashType: hashType})
+func (a archive) GetNote(ctx context.Context, note_id int64) (entry.Note, error) {
+	n, err := a.query.GetNote(ctx, note_id)
 	if err != nil {
-		return 0, err
+		return entry.Note{}, err
 	}
-	return uint64(phash), nil
+
+	return noteToEntry(n), nil
 }
 
-func (a archive) SetPerceptualHash(ctx context.Context, archive_id int64, hashType string, hash uint64) error {
-	err := a.query.SetPerceptualHash(ctx, SetPerceptualHashParams{ArchiveID: archive_id, HashType: hashType, Hash: int64(hash)})
-	if err != nil {
+// GetNotes returns every note of an archive_id, oldest first.
+func (a archive) GetNotes(ctx context.Context, archive_id int64) ([]entry.Note, error) {
+	n, err := a.query.GetNotes(ctx, archive_id)
+	if !errors.Is(err, sql.ErrNoRows) && err != nil {
+		return nil, err
+	}
+
+	notes := make([]entry.Note, len(n))
+	for i, v := range n {
+		notes[i] = noteToEntry(v)
+	}
+
+	return notes, nil
+}
+
+// UpdateNote replaces the title and text of an existing note. Returns sql.ErrNoRows if note_id does not exist.
+func (a archive) UpdateNote(ctx context.Context, note_id int64, title, text string) error {
+	if _, err := a.query.GetNote(ctx, note_id); err != nil {
 		return err
 	}
-	return nil
+
+	return a.query.UpdateNote(ctx, UpdateNoteParams{
+		Title:        title,
+		Text:         text,
+		DateModified: time.Now().UTC().UnixMilli(),
+		NoteID:       note_id,
+	})
 }
 
-// GetVersion returns the moonpool database version.
-func (a archive) GetVersion(ctx context.Context) (int64, error) {
-	const SQL_GET_USER_VERSION = `PRAGMA main.user_version;`
+func (a archive) DeleteNote(ctx context.Context, note_id int64) error {
+	if _, err := a.query.GetNote(ctx, note_id); err != nil {
+		return err
+	}
 
-	res, err := a.db.QueryContext(ctx, SQL_GET_USER_VERSION)
-	if err != nil {
-		return -1, err
+	return a.query.DeleteNote(ctx, note_id)
+}
+
+// SearchNotes returns every note whose title or text contains query, ignoring case. Results are sorted
+// by the most recently modified note first.
+func (a archive) SearchNotes(ctx context.Context, query string, limit, offset int64) ([]entry.Note, error) {
+	n, err := a.query.SearchNotes(ctx, SearchNotesParams{
+		Query:  "%" + escapeLike(query) + "%",
+		Limit:  limit,
+		Offset: offset,
+	})
+	if !errors.Is(err, sql.ErrNoRows) && err != nil {
+		return nil, err
+	}
+
+	notes := make([]entry.Note, len(n))
+	for i, v := range n {
+		notes[i] = noteToEntry(v)
+	}
+
+	return notes, nil
+}
+
+func noteToEntry(n Note) entry.Note {
+	return entry.Note{
+		NoteID:       n.NoteID,
+		ArchiveID:    n.ArchiveID,
+		Title:        n.Title,
+		Text:         n.Text,
+		DateCreated:  time.UnixMilli(n.DateCreated),
+		DateModified: time.UnixMilli(n.DateModified),
 	}
+}
+
+// NewJob queues a new pending job for an archive_id and returns its job_id. If the same type of job is
+// already pending for archive_id, the existing job_id is returned instead.
+func (a archive) NewJob(ctx context.Context, archive_id int64, jobType string) (int64, error) {
+	now := time.Now().UTC().UnixMilli()
 
-	var userVersion int64
-	if res.Next() {
-		err = res.Scan(&userVersion)
+	job_id, err := a.query.NewJob(ctx, NewJobParams{
+		ArchiveID:    archive_id,
+		Type:         jobType,
+		RunAfter:     now,
+		DateCreated:  now,
+		DateModified: now,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		j, err := a.query.GetPendingJob(ctx, GetPendingJobParams{ArchiveID: archive_id, Type: jobType})
 		if err != nil {
 			return -1, err
 		}
+		return j.JobID, nil
+	}
+	if err != nil {
+		return -1, err
 	}
 
-	return userVersion, nil
+	return job_id, nil
 }
 
-// SetVersion sets the moonpool database version.
-func (a archive) SetVersion(ctx context.Context, version int64) error {
-	const SQL_UPDATE_USER_VERSION = `PRAGMA main.user_version = (?);`
+// EnqueueJobs queues a job of jobType for every entry that never had one, or for every entry if all is set.
+// Entries with a job of jobType already pending are left alone. It returns the amount of jobs queued.
+func (a archive) EnqueueJobs(ctx context.Context, jobType string, all bool) (int64, error) {
+	var everyEntry int64
+	if all {
+		everyEntry = 1
+	}
+
+	return a.query.EnqueueJobs(ctx, EnqueueJobsParams{
+		Type: jobType,
+		Now:  time.Now().UTC().UnixMilli(),
+		All:  everyEntry,
+	})
+}
 
-	res, err := a.db.ExecContext(ctx, SQL_UPDATE_USER_VERSION, version)
+func (a archive) GetJob(ctx context.Context, job_id int64) (entry.Job, error) {
+	j, err := a.query.GetJob(ctx, job_id)
 	if err != nil {
-		return err
+		return entry.Job{}, err
 	}
 
-	r, err := res.RowsAffected()
-	if err != nil {
-		return err
+	return jobToEntry(j), nil
+}
+
+// GetJobs returns jobs of a given status, newest first. An empty status returns jobs of any status.
+func (a archive) GetJobs(ctx context.Context, status string, limit, offset int64) ([]entry.Job, error) {
+	j, err := a.query.GetJobs(ctx, GetJobsParams{Status: status, Limit: limit, Offset: offset})
+	if !errors.Is(err, sql.ErrNoRows) && err != nil {
+		return nil, err
 	}
-	if r != 1 {
-		return errors.New("no rows affected")
+
+	return jobsToEntry(j), nil
+}
+
+// GetJobsByArchiveID returns every job of an archive_id, oldest first.
+func (a archive) GetJobsByArchiveID(ctx context.Context, archive_id int64) ([]entry.Job, error) {
+	j, err := a.query.GetJobsByArchiveID(ctx, archive_id)
+	if !errors.Is(err, sql.ErrNoRows) && err != nil {
+		return nil, err
 	}
 
-	return nil
+	return jobsToEntry(j), nil
 }
-func (a archive) Migrate(ctx context.Context, m migration.Migrator) error {
-	tx, err := a.db.BeginTx(ctx, nil)
+
+// CountJobs returns the amount of jobs of each status. Statuses without any job are left out.
+func (a archive) CountJobs(ctx context.Context) (map[string]int64, error) {
+	rows, err := a.query.CountJobs(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	for _, q := range m.Queries() {
-		_, err = tx.ExecContext(ctx, q)
-		if err != nil {
-			return err
-		}
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Status] = r.Total
 	}
+	return counts, nil
+}
 
-	v, err := a.GetVersion(ctx)
+// ClaimJob marks the oldest pending job that is ready to run as running and returns it. Claiming is a
+// single statement, so concurrent callers never claim the same job. Returns sql.ErrNoRows if no job is ready.
+func (a archive) ClaimJob(ctx context.Context) (entry.Job, error) {
+	j, err := a.query.ClaimJob(ctx, time.Now().UTC().UnixMilli())
 	if err != nil {
-		return err
+		return entry.Job{}, err
 	}
 
-	err = a.SetVersion(ctx, v+1)
-	if err != nil {
-		return err
+	return jobToEntry(j), nil
+}
+
+// FinishJob sets the status of a job after an attempt. A job set back to pending will not run before runAfter.
+func (a archive) FinishJob(ctx context.Context, job_id int64, status, jobError string, runAfter time.Time) error {
+	return a.query.FinishJob(ctx, FinishJobParams{
+		Status:       status,
+		Error:        jobError,
+		RunAfter:     runAfter.UTC().UnixMilli(),
+		DateModified: time.Now().UTC().UnixMilli(),
+		JobID:        job_id,
+	})
+}
+
+// RetryJob queues a failed job again with its attempts reset.
+func (a archive) RetryJob(ctx context.Context, job_id int64) error {
+	return a.query.RetryJob(ctx, RetryJobParams{Now: time.Now().UTC().UnixMilli(), JobID: job_id})
+}
+
+// ResetRunningJobs sets every running job back to pending, returning how many were reset. Jobs are only
+// left running if moonpool exited while processing them. A running job that was queued again in the
+// meantime, or is running more than once, is deleted instead, since only one job of each type may be
+// pending for an entry.
+func (a archive) ResetRunningJobs(ctx context.Context) (int64, error) {
+	if _, err := a.query.DeleteDuplicateRunningJobs(ctx); err != nil {
+		return 0, err
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+	return a.query.ResetRunningJobs(ctx, time.Now().UTC().UnixMilli())
+}
+
+func jobToEntry(j Job) entry.Job {
+	return entry.Job{
+		JobID:        j.JobID,
+		ArchiveID:    j.ArchiveID,
+		Type:         j.Type,
+		Status:       j.Status,
+		Error:        j.Error,
+		Attempts:     j.Attempts,
+		RunAfter:     time.UnixMilli(j.RunAfter),
+		DateCreated:  time.UnixMilli(j.DateCreated),
+		DateModified: time.UnixMilli(j.DateModified),
 	}
+}
 
-	return nil
+func jobsToEntry(j []Job) []entry.Job {
+	jobs := make([]entry.Job, len(j))
+	for i, v := range j {
+		jobs[i] = jobToEntry(v)
+	}
+	return jobs
+}
+
+// escapeLike escapes any LIKE wildcard in s, to be used with "ESCAPE '\'".
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 func IsErrorConstraint(err error) bool {
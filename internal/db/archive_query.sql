@@ -4,9 +4,20 @@ INSERT INTO archive (path, extension) VALUES (:path, :extension);
 -- name: GetEntry :one 
 SELECT * FROM archive WHERE id == (:archive_id);
 
+-- name: GetEntries :many
+SELECT * FROM archive ORDER BY id ASC;
+
 -- name: GetEntryPath :one
 SELECT path, extension FROM archive WHERE id == (:archive_id);
 
+-- name: UpdateEntryPath :exec
+UPDATE archive SET path = :path WHERE id == (:archive_id);
+
+-- name: GetStoredFiles :many
+SELECT archive.id, archive.path, archive.extension, hashes_chksum.md5, hashes_chksum.sha1, hashes_chksum.sha256
+FROM archive INNER JOIN hashes_chksum ON archive.id == hashes_chksum.archive_id
+ORDER BY archive.id ASC;
+
 -- name: GetMostRecentArchiveID :one
 SELECT id FROM archive ORDER BY ROWID DESC LIMIT 1;
 
@@ -102,48 +113,6 @@ hashes_chksum.md5 == unhex((:hash)) OR
 hashes_chksum.sha1 == unhex((:hash)) OR
 hashes_chksum.sha256 == unhex((:hash));
 
--- name: SearchTagsByListDateCreated :many
-SELECT DISTINCT archive.id, archive_timestamps.date_created FROM tags 
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-WHERE tags.text IN (sqlc.slice('tags_include'))
-EXCEPT
-	SELECT DISTINCT archive.id, archive_timestamps.date_created FROM tags
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-	WHERE tags.text IN (sqlc.slice('tags_exclude'))
-ORDER BY archive_timestamps.date_created DESC;
-
--- name: SearchTagsByListDateImported :many
-SELECT DISTINCT archive.id, archive_timestamps.date_imported FROM tags 
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-WHERE tags.text IN (sqlc.slice('tags_include'))
-EXCEPT
-	SELECT DISTINCT archive.id, archive_timestamps.date_imported FROM tags
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-	WHERE tags.text IN (sqlc.slice('tags_exclude'))
-ORDER BY archive_timestamps.date_imported DESC;
-
--- name: SearchTagsByListDateModified :many
-SELECT DISTINCT archive.id, archive_timestamps.date_modified FROM tags 
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-WHERE tags.text IN (sqlc.slice('tags_include'))
-EXCEPT
-	SELECT DISTINCT archive.id, archive_timestamps.date_modified FROM tags
-	INNER JOIN tag_map ON tag_map.tag_id = tags.tag_id
-	INNER JOIN archive ON archive.id = tag_map.archive_id
-	INNER JOIN archive_timestamps ON archive.id = archive_timestamps.archive_id
-	WHERE tags.text IN (sqlc.slice('tags_exclude'))
-ORDER BY archive_timestamps.date_modified DESC;
-
 -- name: GetTimestamps :one
 SELECT * FROM archive_timestamps WHERE archive_id == (:archive_id);
 
@@ -161,13 +130,24 @@ INSERT OR REPLACE INTO hashes_chksum
 VALUES (:archive_id, :md5, :sha1, :sha256);
 
 -- name: GetPerceptualHash :one
-SELECT hash FROM hashes_perceptual 
+SELECT hash, hash_extended FROM hashes_perceptual 
 WHERE archive_id == (:archive_id) AND hash_type == (:hash_type);
 
+-- name: GetPerceptualHashVersion :one
+SELECT version FROM hashes_perceptual_version WHERE hash_type == (:hash_type);
+
+-- name: GetPerceptualHashes :many
+SELECT archive_id, hash, hash_extended FROM hashes_perceptual
+WHERE hash_type == (:hash_type) ORDER BY archive_id;
+
+-- name: GetPerceptualHashesByArchiveID :many
+SELECT hash_type, hash, hash_extended FROM hashes_perceptual
+WHERE archive_id == (:archive_id) ORDER BY hash_type;
+
 -- name: SetPerceptualHash :exec
 INSERT OR REPLACE INTO hashes_perceptual
-	(archive_id, hash_type, hash)
-VALUES (:archive_id, :hash_type, :hash);
+	(archive_id, hash_type, hash, hash_extended)
+VALUES (:archive_id, :hash_type, :hash, :hash_extended);
 
 -- name: GetPagesByDateCreated :many
 SELECT id, path, extension FROM archive 
@@ -201,8 +181,111 @@ ORDER BY archive_timestamps.date_imported DESC LIMIT (:limit) OFFSET (:offset);
 
 -- name: SetFileMetadata :exec
 INSERT OR REPLACE INTO "archive_metadata"
-	(archive_id, file_size, file_mimetype, media_width, media_height, media_orientation)
-VALUES (:archive_id, :file_size, :file_mimetype, :media_width, :media_height, :media_orientation);
+	(archive_id, file_size, file_mimetype, media_width, media_height, media_orientation,
+	media_duration, media_framerate, media_video_codec, media_audio_codec, media_bitrate, media_has_audio)
+VALUES (:archive_id, :file_size, :file_mimetype, :media_width, :media_height, :media_orientation,
+	:media_duration, :media_framerate, :media_video_codec, :media_audio_codec, :media_bitrate, :media_has_audio);
 
 -- name: GetFileMetadata :one
-SELECT * FROM "archive_metadata" WHERE archive_id == (:archive_id);
\ No newline at end of file
+SELECT * FROM "archive_metadata" WHERE archive_id == (:archive_id);
+
+-- name: SetExif :exec
+INSERT OR REPLACE INTO exif
+	(archive_id, date_original, make, model, lens, orientation, latitude, longitude, altitude)
+VALUES (:archive_id, :date_original, :make, :model, :lens, :orientation, :latitude, :longitude, :altitude);
+
+-- name: GetExif :one
+SELECT * FROM exif WHERE archive_id == (:archive_id);
+
+-- name: NewRendition :exec
+INSERT OR REPLACE INTO rendition (archive_id, path, mimetype, video_codec, audio_codec, file_size)
+VALUES (:archive_id, :path, :mimetype, :video_codec, :audio_codec, :file_size);
+
+-- name: GetRenditions :many
+SELECT * FROM rendition WHERE archive_id == (:archive_id) ORDER BY mimetype ASC;
+
+-- name: DeleteRendition :exec
+DELETE FROM rendition WHERE archive_id == (:archive_id) AND mimetype == (:mimetype);
+
+-- name: UpdateRenditionPath :exec
+UPDATE rendition SET path = :path WHERE archive_id == (:archive_id) AND mimetype == (:mimetype);
+
+-- name: GetLayout :one
+SELECT hash, depth, width FROM storage_layout WHERE id == 0;
+
+-- name: SetLayout :exec
+INSERT OR REPLACE INTO storage_layout (id, hash, depth, width) VALUES (0, :hash, :depth, :width);
+
+-- name: NewNote :one
+INSERT INTO notes (archive_id, title, text, date_created, date_modified)
+VALUES (:archive_id, :title, :text, :date_created, :date_modified)
+RETURNING note_id;
+
+-- name: GetNote :one
+SELECT * FROM notes WHERE note_id == (:note_id);
+
+-- name: GetNotes :many
+SELECT * FROM notes WHERE archive_id == (:archive_id) ORDER BY note_id ASC;
+
+-- name: UpdateNote :exec
+UPDATE notes SET title = (:title), text = (:text), date_modified = (:date_modified)
+WHERE note_id == (:note_id);
+
+-- name: DeleteNote :exec
+DELETE FROM notes WHERE note_id == (:note_id);
+
+-- name: SearchNotes :many
+SELECT * FROM notes 
+WHERE title LIKE (:query) ESCAPE '\' OR text LIKE (:query) ESCAPE '\'
+ORDER BY date_modified DESC LIMIT (:limit) OFFSET (:offset);
+
+-- name: NewJob :one
+INSERT OR IGNORE INTO jobs (archive_id, type, status, run_after, date_created, date_modified)
+VALUES (:archive_id, :type, 'pending', :run_after, :date_created, :date_modified)
+RETURNING job_id;
+
+-- name: EnqueueJobs :execrows
+INSERT OR IGNORE INTO jobs (archive_id, type, status, run_after, date_created, date_modified)
+SELECT id, (:type), 'pending', (:now), (:now), (:now) FROM archive
+WHERE (:all) == 1 OR NOT EXISTS (SELECT 1 FROM jobs WHERE jobs.archive_id == archive.id AND jobs.type == (:type));
+
+-- name: GetPendingJob :one
+SELECT * FROM jobs WHERE archive_id == (:archive_id) AND type == (:type) AND status == 'pending';
+
+-- name: GetJob :one
+SELECT * FROM jobs WHERE job_id == (:job_id);
+
+-- name: GetJobs :many
+SELECT * FROM jobs WHERE status == (:status) OR (:status) == ''
+ORDER BY job_id DESC LIMIT (:limit) OFFSET (:offset);
+
+-- name: GetJobsByArchiveID :many
+SELECT * FROM jobs WHERE archive_id == (:archive_id) ORDER BY job_id ASC;
+
+-- name: CountJobs :many
+SELECT status, count(*) AS total FROM jobs GROUP BY status;
+
+-- name: ClaimJob :one
+UPDATE jobs SET status = 'running', attempts = attempts + 1, date_modified = (:now)
+WHERE job_id == (
+	SELECT job_id FROM jobs WHERE status == 'pending' AND run_after <= (:now)
+	ORDER BY run_after ASC, job_id ASC LIMIT 1
+)
+RETURNING *;
+
+-- name: FinishJob :exec
+UPDATE jobs SET status = (:status), error = (:error), run_after = (:run_after), date_modified = (:date_modified)
+WHERE job_id == (:job_id);
+
+-- name: RetryJob :exec
+UPDATE jobs SET status = 'pending', attempts = 0, error = '', run_after = (:now), date_modified = (:now)
+WHERE job_id == (:job_id) AND status == 'failed';
+
+-- name: DeleteDuplicateRunningJobs :execrows
+DELETE FROM jobs WHERE status == 'running' AND EXISTS (
+	SELECT 1 FROM jobs AS other WHERE other.archive_id == jobs.archive_id AND other.type == jobs.type
+	AND (other.status == 'pending' OR (other.status == 'running' AND other.job_id > jobs.job_id))
+);
+
+-- name: ResetRunningJobs :execrows
+UPDATE jobs SET status = 'pending', date_modified = (:now) WHERE status == 'running';
@@ -19,6 +19,12 @@ CREATE TABLE "archive_metadata" (
 	"media_width"  INTEGER,
 	"media_height" INTEGER,
 	"media_orientation" TEXT,
+	"media_duration" INTEGER,
+	"media_framerate" REAL,
+	"media_video_codec" TEXT,
+	"media_audio_codec" TEXT,
+	"media_bitrate" INTEGER,
+	"media_has_audio" BOOLEAN NOT NULL DEFAULT 0,
 	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
 );
 
@@ -34,6 +40,7 @@ CREATE TABLE hashes_perceptual (
 	"archive_id"	INTEGER NOT NULL,
 	"hash_type"		TEXT NOT NULL,
 	"hash"			INTEGER NOT NULL,
+	"hash_extended"	BLOB,
 	PRIMARY KEY (archive_id, hash_type),
 	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
 );
@@ -65,12 +72,33 @@ CREATE TABLE tag_count (
 ) WITHOUT ROWID;
 
 CREATE TABLE notes (
-	"archive_id"	INTEGER NOT NULL UNIQUE PRIMARY KEY,
+	"note_id"		INTEGER PRIMARY KEY AUTOINCREMENT,
+	"archive_id"	INTEGER NOT NULL,
 	"title"			TEXT NOT NULL,
-	"text"			TEXT NOT NULL UNIQUE,
-	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE,
-	CONSTRAINT unique_title UNIQUE (archive_id, title)
-) WITHOUT ROWID;
+	"text"			TEXT NOT NULL,
+	"date_created"	INTEGER NOT NULL,
+	"date_modified"	INTEGER NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX notes_archive_id ON notes(archive_id);
+
+CREATE TABLE jobs (
+	"job_id"		INTEGER PRIMARY KEY AUTOINCREMENT,
+	"archive_id"	INTEGER NOT NULL,
+	"type"			TEXT NOT NULL,
+	"status"		TEXT NOT NULL DEFAULT 'pending',
+	"attempts"		INTEGER NOT NULL DEFAULT 0,
+	"error"			TEXT NOT NULL DEFAULT '',
+	"run_after"		INTEGER NOT NULL,
+	"date_created"	INTEGER NOT NULL,
+	"date_modified"	INTEGER NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX jobs_status ON jobs(status, run_after);
+CREATE INDEX jobs_archive_id ON jobs(archive_id);
+CREATE UNIQUE INDEX jobs_pending ON jobs(archive_id, type) WHERE status == 'pending';
 
 CREATE TRIGGER tags_update_count AFTER INSERT ON tag_map 
 BEGIN	
@@ -86,3 +114,57 @@ BEGIN
 		SET total = total - 1
 		WHERE tag_id == OLD.tag_id;
 END;
+
+CREATE TABLE exif (
+	"archive_id"	INTEGER PRIMARY KEY,
+	"date_original"	INTEGER,
+	"make"			TEXT,
+	"model"			TEXT,
+	"lens"			TEXT,
+	"orientation"	INTEGER,
+	"latitude"		REAL,
+	"longitude"		REAL,
+	"altitude"		REAL,
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE rendition (
+	"archive_id"	INTEGER NOT NULL,
+	"path"			TEXT NOT NULL UNIQUE,
+	"mimetype"		TEXT NOT NULL,
+	"video_codec"	TEXT NOT NULL,
+	"audio_codec"	TEXT,
+	"file_size"		INTEGER NOT NULL,
+	UNIQUE("archive_id", "mimetype"),
+	FOREIGN KEY("archive_id") REFERENCES "archive"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE storage_layout (
+	"id"		INTEGER PRIMARY KEY CHECK (id == 0),
+	"hash"		TEXT NOT NULL,
+	"depth"		INTEGER NOT NULL,
+	"width"		INTEGER NOT NULL
+);
+
+CREATE TABLE hashes_perceptual_version (
+	"hash_type"	TEXT PRIMARY KEY,
+	"version"	INTEGER NOT NULL
+);
+
+CREATE TRIGGER hashes_perceptual_insert AFTER INSERT ON hashes_perceptual
+BEGIN
+	INSERT INTO hashes_perceptual_version(hash_type, version) VALUES(NEW.hash_type, 1)
+	ON CONFLICT(hash_type)
+	DO
+		UPDATE SET version = version + 1;
+END;
+
+CREATE TRIGGER hashes_perceptual_update AFTER UPDATE ON hashes_perceptual
+BEGIN
+	UPDATE hashes_perceptual_version SET version = version + 1 WHERE hash_type IN (OLD.hash_type, NEW.hash_type);
+END;
+
+CREATE TRIGGER hashes_perceptual_delete AFTER DELETE ON hashes_perceptual
+BEGIN
+	UPDATE hashes_perceptual_version SET version = version + 1 WHERE hash_type == OLD.hash_type;
+END;
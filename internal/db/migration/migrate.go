@@ -1,21 +1,44 @@
+// Package migration discovers, orders and applies versioned SQL migrations. The current version
+// of a database is tracked through SQLite's "PRAGMA user_version".
 package migration
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
 )
 
+const (
+	DIRECTION_UP   = "up"
+	DIRECTION_DOWN = "down"
+
+	// LATEST can be given as a target version to migrate to the newest known migration.
+	LATEST int64 = -1
+)
+
+var (
+	ErrInvalidFilename  = errors.New("invalid migration filename")
+	ErrInvalidDirection = errors.New("invalid upgrade direction")
+	ErrMissingMigration = errors.New("missing migration")
+	ErrNewerDatabase    = errors.New("database version is newer than any known migration")
+)
+
 type Migrator struct {
 	Version, Timestamp int64
 	Title              string
-	sql                []string
+	sql                string
 	Upgrade            bool
 }
 
 // NewMigrator creates a new Migrator to be used for updating database version. filename expects a
-// string in the format: "{unix_timestamp}_{db_version}_{title}_{is_upgrade}.sql" with {unix_timestamp} and
+// string in the format: "{unix_timestamp}_{db_version}_{title}_{up|down}.sql" with {unix_timestamp} and
 // {version} being an int64, {title} being a string and {up|down} a string to indicate whether
 // the migration is to upgrade or downgrade to the database version.
 func NewMigrator(r io.Reader, filename string) (Migrator, error) {
@@ -30,7 +53,7 @@ func NewMigrator(r io.Reader, filename string) (Migrator, error) {
 		return Migrator{}, err
 	}
 
-	m.sql = strings.Split(buf.String(), "\n")
+	m.sql = buf.String()
 
 	return m, nil
 }
@@ -38,24 +61,250 @@ func NewMigrator(r io.Reader, filename string) (Migrator, error) {
 func parseFilename(filename string) (Migrator, error) {
 	m := Migrator{}
 
-	var upgrade string
+	name, ok := strings.CutSuffix(path.Base(filename), ".sql")
+	if !ok {
+		return Migrator{}, ErrInvalidFilename
+	}
 
-	scanned, err := fmt.Sscanf(filename, "%d_%d_%s_%s.sql", &m.Timestamp, &m.Version, &m.Title, &upgrade)
+	// title may contain underscores itself, so only the first two and the last field are fixed
+	fields := strings.Split(name, "_")
+	if len(fields) < 4 {
+		return Migrator{}, ErrInvalidFilename
+	}
+
+	var err error
+	m.Timestamp, err = strconv.ParseInt(fields[0], 10, 64)
 	if err != nil {
-		return Migrator{}, err
+		return Migrator{}, fmt.Errorf("%w: bad timestamp, %v", ErrInvalidFilename, err)
+	}
+
+	m.Version, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || m.Version <= 0 {
+		return Migrator{}, fmt.Errorf("%w: bad version '%s'", ErrInvalidFilename, fields[1])
 	}
 
-	if scanned != 4 {
-		return Migrator{}, errors.New("invalid filename")
+	m.Title = strings.Join(fields[2:len(fields)-1], "_")
+	if m.Title == "" {
+		return Migrator{}, ErrInvalidFilename
 	}
 
-	if !strings.EqualFold(upgrade, "up") || !strings.EqualFold(upgrade, "down") {
-		return Migrator{}, errors.New("invalid upgrade direction")
+	switch upgrade := fields[len(fields)-1]; {
+	case strings.EqualFold(upgrade, DIRECTION_UP):
+		m.Upgrade = true
+	case strings.EqualFold(upgrade, DIRECTION_DOWN):
+		m.Upgrade = false
+	default:
+		return Migrator{}, ErrInvalidDirection
 	}
 
 	return m, nil
 }
 
-func (m Migrator) Queries() []string {
+// Query returns the raw SQL script of a migration.
+func (m Migrator) Query() string {
 	return m.sql
 }
+
+func (m Migrator) Direction() string {
+	if m.Upgrade {
+		return DIRECTION_UP
+	}
+	return DIRECTION_DOWN
+}
+
+func (m Migrator) String() string {
+	return fmt.Sprintf("%d_%s (%s)", m.Version, m.Title, m.Direction())
+}
+
+// Set is an ordered collection of migrations belonging to a single database.
+type Set struct {
+	up, down map[int64]Migrator
+	latest   int64
+}
+
+// Load reads every "*.sql" file in the root of fsys as a Migrator. Every version must have an
+// upgrade migration, and versions must be contiguous starting from 1.
+func Load(fsys fs.FS) (Set, error) {
+	s := Set{up: make(map[int64]Migrator), down: make(map[int64]Migrator)}
+
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return Set{}, err
+	}
+
+	for _, filename := range files {
+		f, err := fsys.Open(filename)
+		if err != nil {
+			return Set{}, err
+		}
+
+		m, err := NewMigrator(f, filename)
+		f.Close()
+		if err != nil {
+			return Set{}, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		dst := s.down
+		if m.Upgrade {
+			dst = s.up
+		}
+
+		if _, exists := dst[m.Version]; exists {
+			return Set{}, fmt.Errorf("%s: duplicate %s migration for version %d", filename, m.Direction(), m.Version)
+		}
+		dst[m.Version] = m
+
+		s.latest = max(s.latest, m.Version)
+	}
+
+	for v := int64(1); v <= s.latest; v++ {
+		if _, ok := s.up[v]; !ok {
+			return Set{}, fmt.Errorf("%w: no upgrade for version %d", ErrMissingMigration, v)
+		}
+	}
+
+	return s, nil
+}
+
+// Latest returns the newest version known to a Set.
+func (s Set) Latest() int64 {
+	return s.latest
+}
+
+// Plan returns the ordered list of migrations needed to go from version current to target. A target
+// of LATEST resolves to Set.Latest().
+func (s Set) Plan(current, target int64) ([]Migrator, error) {
+	if target == LATEST {
+		target = s.latest
+	}
+
+	if target < 0 || target > s.latest {
+		return nil, fmt.Errorf("%w: unknown target version %d (latest is %d)", ErrMissingMigration, target, s.latest)
+	}
+
+	if current > s.latest {
+		return nil, fmt.Errorf("%w: database is at version %d, latest known is %d", ErrNewerDatabase, current, s.latest)
+	}
+
+	var plan []Migrator
+	switch {
+	case current < target:
+		for v := current + 1; v <= target; v++ {
+			plan = append(plan, s.up[v])
+		}
+	case current > target:
+		for v := current; v > target; v-- {
+			m, ok := s.down[v]
+			if !ok {
+				return nil, fmt.Errorf("%w: no downgrade for version %d", ErrMissingMigration, v)
+			}
+			plan = append(plan, m)
+		}
+	}
+
+	return plan, nil
+}
+
+// Versions returns every known upgrade version in ascending order.
+func (s Set) Versions() []int64 {
+	v := make([]int64, 0, len(s.up))
+	for k := range s.up {
+		v = append(v, k)
+	}
+	slices.Sort(v)
+	return v
+}
+
+// GetVersion returns the user_version of a database.
+func GetVersion(ctx context.Context, db interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (int64, error) {
+	var v int64
+	if err := db.QueryRowContext(ctx, "PRAGMA main.user_version;").Scan(&v); err != nil {
+		return -1, err
+	}
+	return v, nil
+}
+
+// Migrate brings db from its current user_version to target, applying each migration inside its
+// own transaction. Foreign keys are disabled while a migration runs so that tables can be rebuilt,
+// and are checked for violations before each migration is committed.
+//
+// If dryRun is true, Migrate only returns the plan without touching the database.
+func Migrate(ctx context.Context, db *sql.DB, s Set, target int64, dryRun bool) ([]Migrator, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	current, err := GetVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.Plan(current, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun || len(plan) == 0 {
+		return plan, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF;"); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON;")
+
+	for i, m := range plan {
+		if err := apply(ctx, conn, m); err != nil {
+			return plan[:i], fmt.Errorf("migration %s failed, %w", m, err)
+		}
+	}
+
+	return plan, nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, m Migrator) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(m.sql) != "" {
+		if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check;")
+	if err != nil {
+		return err
+	}
+	violation := rows.Next()
+	rows.Close()
+	if violation {
+		return errors.New("foreign key violation")
+	}
+
+	version := m.Version
+	if !m.Upgrade {
+		version--
+	}
+
+	// PRAGMA statements do not accept bound parameters
+	if _, err := tx.ExecContext(ctx, "PRAGMA main.user_version = "+strconv.FormatInt(version, 10)+";"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Stamp sets the user_version of a database without running any migration. It is used to adopt
+// databases that were created before versioning existed.
+func Stamp(ctx context.Context, db *sql.DB, version int64) error {
+	_, err := db.ExecContext(ctx, "PRAGMA main.user_version = "+strconv.FormatInt(version, 10)+";")
+	return err
+}
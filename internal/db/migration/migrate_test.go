@@ -0,0 +1,157 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+func Test_parseFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     Migrator
+		wantErr  error
+	}{
+		{"upgrade", "1700000000_2_add_notes_up.sql", Migrator{Version: 2, Timestamp: 1700000000, Title: "add_notes", Upgrade: true}, nil},
+		{"downgrade", "1700000000_2_notes_down.sql", Migrator{Version: 2, Timestamp: 1700000000, Title: "notes", Upgrade: false}, nil},
+		{"with directory", "migrations/0_1_reference_UP.sql", Migrator{Version: 1, Timestamp: 0, Title: "reference", Upgrade: true}, nil},
+		{"bad direction", "0_1_reference_sideways.sql", Migrator{}, ErrInvalidDirection},
+		{"missing title", "0_1_up.sql", Migrator{}, ErrInvalidFilename},
+		{"missing extension", "0_1_reference_up", Migrator{}, ErrInvalidFilename},
+		{"zero version", "0_0_reference_up.sql", Migrator{}, ErrInvalidFilename},
+		{"bad timestamp", "abc_1_reference_up.sql", Migrator{}, ErrInvalidFilename},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFilename(tt.filename)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseFilename() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseFilename() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+var testMigrations = fstest.MapFS{
+	"0_1_init_up.sql":     {Data: []byte("CREATE TABLE foo (id INTEGER PRIMARY KEY);")},
+	"0_1_init_down.sql":   {Data: []byte("DROP TABLE foo;")},
+	"1_2_bar_up.sql":      {Data: []byte("ALTER TABLE foo ADD COLUMN bar TEXT;")},
+	"1_2_bar_down.sql":    {Data: []byte("ALTER TABLE foo DROP COLUMN bar;")},
+	"2_3_baz_up.sql":      {Data: []byte("CREATE TABLE baz (id INTEGER PRIMARY KEY);")},
+	"README.md":           {Data: []byte("ignored")},
+	"nested/9_9_x_up.sql": {Data: []byte("ignored")},
+}
+
+func TestSet_Plan(t *testing.T) {
+	s, err := Load(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Latest() != 3 {
+		t.Fatalf("Latest() = %d, want 3", s.Latest())
+	}
+
+	tests := []struct {
+		name            string
+		current, target int64
+		want            []string
+		wantErr         error
+	}{
+		{"fresh to latest", 0, LATEST, []string{"1_init (up)", "2_bar (up)", "3_baz (up)"}, nil},
+		{"partial upgrade", 1, 2, []string{"2_bar (up)"}, nil},
+		{"up to date", 3, LATEST, nil, nil},
+		{"downgrade", 2, 0, []string{"2_bar (down)", "1_init (down)"}, nil},
+		{"missing downgrade", 3, 2, nil, ErrMissingMigration},
+		{"unknown target", 0, 4, nil, ErrMissingMigration},
+		{"newer database", 5, LATEST, nil, ErrNewerDatabase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := s.Plan(tt.current, tt.target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Plan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(plan) != len(tt.want) {
+				t.Fatalf("Plan() = %v, want %v", plan, tt.want)
+			}
+			for i := range plan {
+				if plan[i].String() != tt.want[i] {
+					t.Errorf("Plan()[%d] = %s, want %s", i, plan[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_Gap(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"0_1_init_up.sql": {Data: []byte("")},
+		"0_3_skip_up.sql": {Data: []byte("")},
+	})
+	if !errors.Is(err, ErrMissingMigration) {
+		t.Errorf("Load() error = %v, want %v", err, ErrMissingMigration)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // every connection to ":memory:" is its own database
+
+	s, err := Load(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := Migrate(ctx, db, s, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 2 {
+		t.Errorf("dry run planned %d migrations, want 2", len(plan))
+	}
+	if v, _ := GetVersion(ctx, db); v != 0 {
+		t.Fatalf("dry run changed version to %d", v)
+	}
+
+	if _, err := Migrate(ctx, db, s, LATEST, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := GetVersion(ctx, db); v != 3 {
+		t.Fatalf("got version %d, want 3", v)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO foo (bar) VALUES ('ok');"); err != nil {
+		t.Errorf("migration 2 was not applied, %v", err)
+	}
+
+	if _, err := Migrate(ctx, db, s, 1, false); !errors.Is(err, ErrMissingMigration) {
+		t.Fatalf("downgrade without down script, got error %v", err)
+	}
+
+	if err := Stamp(ctx, db, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(ctx, db, s, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := GetVersion(ctx, db); v != 0 {
+		t.Fatalf("got version %d, want 0", v)
+	}
+	if _, err := db.ExecContext(ctx, "SELECT * FROM foo;"); err == nil {
+		t.Errorf("table foo still exists after downgrade")
+	}
+}
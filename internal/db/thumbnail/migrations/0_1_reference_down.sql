@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "thumbnail_blurhash";
+DROP TABLE IF EXISTS "thumbnail_jpeg";
+DROP TABLE IF EXISTS "thumbnail";
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "thumbnail_avif";
+DROP TABLE IF EXISTS "thumbnail_webp";
+ALTER TABLE "thumbnail" DROP COLUMN "has_avif";
@@ -0,0 +1,17 @@
+ALTER TABLE "thumbnail" ADD COLUMN "has_avif" INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE "thumbnail_webp" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"small"	BLOB,
+	"medium"	BLOB,
+	"large"	BLOB,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
+
+CREATE TABLE "thumbnail_avif" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"small"	BLOB,
+	"medium"	BLOB,
+	"large"	BLOB,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+CREATE TABLE "thumbnail_preview" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"mimetype"	TEXT NOT NULL,
+	"preview"	BLOB NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+CREATE TABLE "thumbnail_storyboard" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"sprite"	BLOB NOT NULL,
+	"vtt"	TEXT NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
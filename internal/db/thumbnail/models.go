@@ -8,6 +8,14 @@ type Thumbnail struct {
 	ArchiveID int64
 	HasJpeg   int64
 	HasWebp   int64
+	HasAvif   int64
+}
+
+type ThumbnailAvif struct {
+	ArchiveID int64
+	Small     []byte
+	Medium    []byte
+	Large     []byte
 }
 
 type ThumbnailBlurhash struct {
@@ -21,3 +29,22 @@ type ThumbnailJpeg struct {
 	Medium    []byte
 	Large     []byte
 }
+
+type ThumbnailPreview struct {
+	ArchiveID int64
+	Mimetype  string
+	Preview   []byte
+}
+
+type ThumbnailStoryboard struct {
+	ArchiveID int64
+	Sprite    []byte
+	Vtt       string
+}
+
+type ThumbnailWebp struct {
+	ArchiveID int64
+	Small     []byte
+	Medium    []byte
+	Large     []byte
+}
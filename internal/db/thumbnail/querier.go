@@ -11,13 +11,23 @@ import (
 type Querier interface {
 	DeleteThumbnail(ctx context.Context, archiveID int64) error
 	DoesArchiveIDExist(ctx context.Context, archiveID int64) (int64, error)
+	DoesPreviewExist(ctx context.Context, archiveID int64) (int64, error)
+	GetAvif(ctx context.Context, archiveID int64) (GetAvifRow, error)
 	GetBlurHash(ctx context.Context, archiveID int64) (string, error)
 	GetJpegMedium(ctx context.Context, archiveID int64) ([]byte, error)
 	GetJpeglarge(ctx context.Context, archiveID int64) ([]byte, error)
 	GetJpegsmall(ctx context.Context, archiveID int64) ([]byte, error)
+	GetPreview(ctx context.Context, archiveID int64) (GetPreviewRow, error)
+	GetPreviewArchiveIDs(ctx context.Context, archiveIds []int64) ([]int64, error)
+	GetStoryboard(ctx context.Context, archiveID int64) (GetStoryboardRow, error)
+	GetWebp(ctx context.Context, archiveID int64) (GetWebpRow, error)
+	NewAvif(ctx context.Context, arg NewAvifParams) error
 	NewBlurHash(ctx context.Context, arg NewBlurHashParams) error
 	NewJpeg(ctx context.Context, arg NewJpegParams) error
+	NewPreview(ctx context.Context, arg NewPreviewParams) error
+	NewStoryboard(ctx context.Context, arg NewStoryboardParams) error
 	NewThumbnail(ctx context.Context, archiveID int64) error
+	NewWebp(ctx context.Context, arg NewWebpParams) error
 }
 
 var _ Querier = (*Queries)(nil)
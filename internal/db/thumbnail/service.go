@@ -23,6 +23,16 @@ type TX interface {
 type Thumbnailer interface {
 	NewJpeg(ctx context.Context, archive_id int64, s Sizes) error
 	GetJpeg(ctx context.Context, archive_id int64, size string) ([]byte, error)
+	NewWebp(ctx context.Context, archive_id int64, s Sizes) error
+	GetWebp(ctx context.Context, archive_id int64, size string) ([]byte, error)
+	NewAvif(ctx context.Context, archive_id int64, s Sizes) error
+	GetAvif(ctx context.Context, archive_id int64, size string) ([]byte, error)
+	NewPreview(ctx context.Context, archive_id int64, mimetype string, preview []byte) error
+	GetPreview(ctx context.Context, archive_id int64) (mimetype string, preview []byte, err error)
+	HasPreview(ctx context.Context, archive_id int64) (bool, error)
+	HasPreviews(ctx context.Context, archive_ids []int64) (map[int64]bool, error)
+	NewStoryboard(ctx context.Context, archive_id int64, sprite []byte, vtt string) error
+	GetStoryboard(ctx context.Context, archive_id int64) (sprite []byte, vtt string, err error)
 	GetBlurHash(ctx context.Context, archive_id int64) (string, error)
 	NewBlurHash(ctx context.Context, archive_id int64, hash string) error
 	DeleteThumbnail(ctx context.Context, archive_id int64) error
@@ -46,17 +56,10 @@ type Sizes struct {
 }
 
 func (t thumbnail) NewJpeg(ctx context.Context, archive_id int64, s Sizes) error {
-	id, err := t.query.DoesArchiveIDExist(ctx, archive_id)
-	if err != nil {
+	if err := t.newThumbnail(ctx, archive_id); err != nil {
 		return err
 	}
 
-	if id <= 0 {
-		if err := t.query.NewThumbnail(ctx, archive_id); err != nil {
-			return err
-		}
-	}
-
 	args := NewJpegParams{
 		ArchiveID: archive_id,
 		Small:     s.Small,
@@ -68,7 +71,7 @@ func (t thumbnail) NewJpeg(ctx context.Context, archive_id int64, s Sizes) error
 		return err
 	}
 
-	if err := t.setJpeg(ctx, archive_id, true); err != nil {
+	if err := t.setFormat(ctx, archive_id, "jpeg", true); err != nil {
 		return err
 	}
 
@@ -88,6 +91,163 @@ func (t thumbnail) GetJpeg(ctx context.Context, archive_id int64, size string) (
 	}
 }
 
+func (t thumbnail) NewWebp(ctx context.Context, archive_id int64, s Sizes) error {
+	if err := t.newThumbnail(ctx, archive_id); err != nil {
+		return err
+	}
+
+	args := NewWebpParams{
+		ArchiveID: archive_id,
+		Small:     s.Small,
+		Medium:    s.Medium,
+		Large:     s.Large,
+	}
+
+	if err := t.query.NewWebp(ctx, args); err != nil {
+		return err
+	}
+
+	return t.setFormat(ctx, archive_id, "webp", true)
+}
+
+func (t thumbnail) GetWebp(ctx context.Context, archive_id int64, size string) ([]byte, error) {
+	if !isSize(size) {
+		return nil, errors.New("invalid size")
+	}
+
+	row, err := t.query.GetWebp(ctx, archive_id)
+	if err != nil {
+		return nil, err
+	}
+
+	return Sizes(row).get(size), nil
+}
+
+func (t thumbnail) NewAvif(ctx context.Context, archive_id int64, s Sizes) error {
+	if err := t.newThumbnail(ctx, archive_id); err != nil {
+		return err
+	}
+
+	args := NewAvifParams{
+		ArchiveID: archive_id,
+		Small:     s.Small,
+		Medium:    s.Medium,
+		Large:     s.Large,
+	}
+
+	if err := t.query.NewAvif(ctx, args); err != nil {
+		return err
+	}
+
+	return t.setFormat(ctx, archive_id, "avif", true)
+}
+
+func (t thumbnail) GetAvif(ctx context.Context, archive_id int64, size string) ([]byte, error) {
+	if !isSize(size) {
+		return nil, errors.New("invalid size")
+	}
+
+	row, err := t.query.GetAvif(ctx, archive_id)
+	if err != nil {
+		return nil, err
+	}
+
+	return Sizes(row).get(size), nil
+}
+
+func (t thumbnail) NewPreview(ctx context.Context, archive_id int64, mimetype string, preview []byte) error {
+	if err := t.newThumbnail(ctx, archive_id); err != nil {
+		return err
+	}
+
+	return t.query.NewPreview(ctx, NewPreviewParams{
+		ArchiveID: archive_id,
+		Mimetype:  mimetype,
+		Preview:   preview,
+	})
+}
+
+func (t thumbnail) GetPreview(ctx context.Context, archive_id int64) (string, []byte, error) {
+	row, err := t.query.GetPreview(ctx, archive_id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return row.Mimetype, row.Preview, nil
+}
+
+func (t thumbnail) HasPreview(ctx context.Context, archive_id int64) (bool, error) {
+	exists, err := t.query.DoesPreviewExist(ctx, archive_id)
+	return exists == 1, err
+}
+
+// HasPreviews returns which of archive_ids have an animated preview.
+func (t thumbnail) HasPreviews(ctx context.Context, archive_ids []int64) (map[int64]bool, error) {
+	ids, err := t.query.GetPreviewArchiveIDs(ctx, archive_ids)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make(map[int64]bool, len(archive_ids))
+	for _, archive_id := range archive_ids {
+		previews[archive_id] = false
+	}
+	for _, archive_id := range ids {
+		previews[archive_id] = true
+	}
+	return previews, nil
+}
+
+func (t thumbnail) NewStoryboard(ctx context.Context, archive_id int64, sprite []byte, vtt string) error {
+	if err := t.newThumbnail(ctx, archive_id); err != nil {
+		return err
+	}
+
+	return t.query.NewStoryboard(ctx, NewStoryboardParams{
+		ArchiveID: archive_id,
+		Sprite:    sprite,
+		Vtt:       vtt,
+	})
+}
+
+func (t thumbnail) GetStoryboard(ctx context.Context, archive_id int64) ([]byte, string, error) {
+	row, err := t.query.GetStoryboard(ctx, archive_id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return row.Sprite, row.Vtt, nil
+}
+
+// newThumbnail creates the row every format of thumbnail of an archive_id references, if it does not exist yet.
+func (t thumbnail) newThumbnail(ctx context.Context, archive_id int64) error {
+	id, err := t.query.DoesArchiveIDExist(ctx, archive_id)
+	if err != nil {
+		return err
+	}
+
+	if id <= 0 {
+		return t.query.NewThumbnail(ctx, archive_id)
+	}
+	return nil
+}
+
+func isSize(size string) bool {
+	return size == "small" || size == "medium" || size == "large"
+}
+
+func (s Sizes) get(size string) []byte {
+	switch size {
+	case "small":
+		return s.Small
+	case "medium":
+		return s.Medium
+	case "large":
+		return s.Large
+	}
+	return nil
+}
+
 func (t thumbnail) DeleteThumbnail(ctx context.Context, archive_id int64) error {
 	err := t.NewSavepoint(ctx, "delete")
 	if err != nil {
@@ -100,7 +260,7 @@ func (t thumbnail) DeleteThumbnail(ctx context.Context, archive_id int64) error
 		return err
 	}
 
-	err = t.setJpeg(ctx, archive_id, false)
+	err = t.setFormat(ctx, archive_id, "jpeg", false)
 	if err != nil {
 		return err
 	}
@@ -113,15 +273,19 @@ func (t thumbnail) DeleteThumbnail(ctx context.Context, archive_id int64) error
 	return nil
 }
 
-func (t thumbnail) setJpeg(ctx context.Context, archive_id int64, hasThumbnail bool) error {
+// setFormat marks whether an archive_id has thumbnails of a given format, one of "jpeg", "webp" or "avif".
+func (t thumbnail) setFormat(ctx context.Context, archive_id int64, format string, hasThumbnail bool) error {
+	if format != "jpeg" && format != "webp" && format != "avif" {
+		return errors.New("invalid format")
+	}
+
+	has := 0
 	if hasThumbnail {
-		if _, err := t.db.ExecContext(ctx, fmt.Sprintf("UPDATE thumbnail SET has_jpeg = 1 WHERE archive_id == %d;", archive_id)); err != nil {
-			return err
-		}
-	} else {
-		if _, err := t.db.ExecContext(ctx, fmt.Sprintf("UPDATE thumbnail SET has_jpeg = 0 WHERE archive_id == %d;", archive_id)); err != nil {
-			return err
-		}
+		has = 1
+	}
+
+	if _, err := t.db.ExecContext(ctx, fmt.Sprintf("UPDATE thumbnail SET has_%s = %d WHERE archive_id == %d;", format, has, archive_id)); err != nil {
+		return err
 	}
 
 	return nil
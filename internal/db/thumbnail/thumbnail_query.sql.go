@@ -7,6 +7,7 @@ package thumbnail
 
 import (
 	"context"
+	"strings"
 )
 
 const DeleteThumbnail = `-- name: DeleteThumbnail :exec
@@ -29,6 +30,34 @@ func (q *Queries) DoesArchiveIDExist(ctx context.Context, archiveID int64) (int6
 	return archive_id, err
 }
 
+const DoesPreviewExist = `-- name: DoesPreviewExist :one
+SELECT EXISTS(SELECT archive_id FROM "thumbnail_preview" WHERE archive_id == (?1) LIMIT 1)
+`
+
+func (q *Queries) DoesPreviewExist(ctx context.Context, archiveID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, DoesPreviewExist, archiveID)
+	var archive_id int64
+	err := row.Scan(&archive_id)
+	return archive_id, err
+}
+
+const GetAvif = `-- name: GetAvif :one
+SELECT small, medium, large FROM "thumbnail_avif" WHERE archive_id == (?1)
+`
+
+type GetAvifRow struct {
+	Small  []byte
+	Medium []byte
+	Large  []byte
+}
+
+func (q *Queries) GetAvif(ctx context.Context, archiveID int64) (GetAvifRow, error) {
+	row := q.db.QueryRowContext(ctx, GetAvif, archiveID)
+	var i GetAvifRow
+	err := row.Scan(&i.Small, &i.Medium, &i.Large)
+	return i, err
+}
+
 const GetBlurHash = `-- name: GetBlurHash :one
 SELECT hash FROM "thumbnail_blurhash" WHERE archive_id == (?1)
 `
@@ -73,6 +102,113 @@ func (q *Queries) GetJpegsmall(ctx context.Context, archiveID int64) ([]byte, er
 	return small, err
 }
 
+const GetPreview = `-- name: GetPreview :one
+SELECT mimetype, preview FROM "thumbnail_preview" WHERE archive_id == (?1)
+`
+
+type GetPreviewRow struct {
+	Mimetype string
+	Preview  []byte
+}
+
+func (q *Queries) GetPreview(ctx context.Context, archiveID int64) (GetPreviewRow, error) {
+	row := q.db.QueryRowContext(ctx, GetPreview, archiveID)
+	var i GetPreviewRow
+	err := row.Scan(&i.Mimetype, &i.Preview)
+	return i, err
+}
+
+const GetPreviewArchiveIDs = `-- name: GetPreviewArchiveIDs :many
+SELECT archive_id FROM "thumbnail_preview" WHERE archive_id IN (/*SLICE:archive_ids*/?)
+`
+
+func (q *Queries) GetPreviewArchiveIDs(ctx context.Context, archiveIds []int64) ([]int64, error) {
+	query := GetPreviewArchiveIDs
+	var queryParams []interface{}
+	if len(archiveIds) > 0 {
+		for _, v := range archiveIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:archive_ids*/?", strings.Repeat(",?", len(archiveIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:archive_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var archive_id int64
+		if err := rows.Scan(&archive_id); err != nil {
+			return nil, err
+		}
+		items = append(items, archive_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetStoryboard = `-- name: GetStoryboard :one
+SELECT sprite, vtt FROM "thumbnail_storyboard" WHERE archive_id == (?1)
+`
+
+type GetStoryboardRow struct {
+	Sprite []byte
+	Vtt    string
+}
+
+func (q *Queries) GetStoryboard(ctx context.Context, archiveID int64) (GetStoryboardRow, error) {
+	row := q.db.QueryRowContext(ctx, GetStoryboard, archiveID)
+	var i GetStoryboardRow
+	err := row.Scan(&i.Sprite, &i.Vtt)
+	return i, err
+}
+
+const GetWebp = `-- name: GetWebp :one
+SELECT small, medium, large FROM "thumbnail_webp" WHERE archive_id == (?1)
+`
+
+type GetWebpRow struct {
+	Small  []byte
+	Medium []byte
+	Large  []byte
+}
+
+func (q *Queries) GetWebp(ctx context.Context, archiveID int64) (GetWebpRow, error) {
+	row := q.db.QueryRowContext(ctx, GetWebp, archiveID)
+	var i GetWebpRow
+	err := row.Scan(&i.Small, &i.Medium, &i.Large)
+	return i, err
+}
+
+const NewAvif = `-- name: NewAvif :exec
+INSERT OR REPLACE INTO "thumbnail_avif" (archive_id, small, medium, large) VALUES (?1, ?2, ?3, ?4)
+`
+
+type NewAvifParams struct {
+	ArchiveID int64
+	Small     []byte
+	Medium    []byte
+	Large     []byte
+}
+
+func (q *Queries) NewAvif(ctx context.Context, arg NewAvifParams) error {
+	_, err := q.db.ExecContext(ctx, NewAvif,
+		arg.ArchiveID,
+		arg.Small,
+		arg.Medium,
+		arg.Large,
+	)
+	return err
+}
+
 const NewBlurHash = `-- name: NewBlurHash :exec
 INSERT INTO "thumbnail_blurhash" (archive_id, hash) VALUES (?1, ?2)
 `
@@ -108,6 +244,36 @@ func (q *Queries) NewJpeg(ctx context.Context, arg NewJpegParams) error {
 	return err
 }
 
+const NewPreview = `-- name: NewPreview :exec
+INSERT OR REPLACE INTO "thumbnail_preview" (archive_id, mimetype, preview) VALUES (?1, ?2, ?3)
+`
+
+type NewPreviewParams struct {
+	ArchiveID int64
+	Mimetype  string
+	Preview   []byte
+}
+
+func (q *Queries) NewPreview(ctx context.Context, arg NewPreviewParams) error {
+	_, err := q.db.ExecContext(ctx, NewPreview, arg.ArchiveID, arg.Mimetype, arg.Preview)
+	return err
+}
+
+const NewStoryboard = `-- name: NewStoryboard :exec
+INSERT OR REPLACE INTO "thumbnail_storyboard" (archive_id, sprite, vtt) VALUES (?1, ?2, ?3)
+`
+
+type NewStoryboardParams struct {
+	ArchiveID int64
+	Sprite    []byte
+	Vtt       string
+}
+
+func (q *Queries) NewStoryboard(ctx context.Context, arg NewStoryboardParams) error {
+	_, err := q.db.ExecContext(ctx, NewStoryboard, arg.ArchiveID, arg.Sprite, arg.Vtt)
+	return err
+}
+
 const NewThumbnail = `-- name: NewThumbnail :exec
 INSERT INTO "thumbnail" (archive_id, has_jpeg, has_webp) VALUES (?1, 0, 0)
 `
@@ -116,3 +282,24 @@ func (q *Queries) NewThumbnail(ctx context.Context, archiveID int64) error {
 	_, err := q.db.ExecContext(ctx, NewThumbnail, archiveID)
 	return err
 }
+
+const NewWebp = `-- name: NewWebp :exec
+INSERT OR REPLACE INTO "thumbnail_webp" (archive_id, small, medium, large) VALUES (?1, ?2, ?3, ?4)
+`
+
+type NewWebpParams struct {
+	ArchiveID int64
+	Small     []byte
+	Medium    []byte
+	Large     []byte
+}
+
+func (q *Queries) NewWebp(ctx context.Context, arg NewWebpParams) error {
+	_, err := q.db.ExecContext(ctx, NewWebp,
+		arg.ArchiveID,
+		arg.Small,
+		arg.Medium,
+		arg.Large,
+	)
+	return err
+}
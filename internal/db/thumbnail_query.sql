@@ -16,6 +16,36 @@ SELECT medium FROM "thumbnail_jpeg" WHERE archive_id == (:archive_id);
 -- name: GetJpeglarge :one
 SELECT large FROM "thumbnail_jpeg" WHERE archive_id == (:archive_id);
 
+-- name: NewWebp :exec
+INSERT OR REPLACE INTO "thumbnail_webp" (archive_id, small, medium, large) VALUES (:archive_id, :small, :medium, :large);
+
+-- name: GetWebp :one
+SELECT small, medium, large FROM "thumbnail_webp" WHERE archive_id == (:archive_id);
+
+-- name: NewAvif :exec
+INSERT OR REPLACE INTO "thumbnail_avif" (archive_id, small, medium, large) VALUES (:archive_id, :small, :medium, :large);
+
+-- name: GetAvif :one
+SELECT small, medium, large FROM "thumbnail_avif" WHERE archive_id == (:archive_id);
+
+-- name: NewPreview :exec
+INSERT OR REPLACE INTO "thumbnail_preview" (archive_id, mimetype, preview) VALUES (:archive_id, :mimetype, :preview);
+
+-- name: GetPreview :one
+SELECT mimetype, preview FROM "thumbnail_preview" WHERE archive_id == (:archive_id);
+
+-- name: GetPreviewArchiveIDs :many
+SELECT archive_id FROM "thumbnail_preview" WHERE archive_id IN (sqlc.slice('archive_ids'));
+
+-- name: DoesPreviewExist :one
+SELECT EXISTS(SELECT archive_id FROM "thumbnail_preview" WHERE archive_id == (:archive_id) LIMIT 1);
+
+-- name: NewStoryboard :exec
+INSERT OR REPLACE INTO "thumbnail_storyboard" (archive_id, sprite, vtt) VALUES (:archive_id, :sprite, :vtt);
+
+-- name: GetStoryboard :one
+SELECT sprite, vtt FROM "thumbnail_storyboard" WHERE archive_id == (:archive_id);
+
 -- name: NewBlurHash :exec
 INSERT INTO "thumbnail_blurhash" (archive_id, hash) VALUES (:archive_id, :hash);
 
@@ -2,6 +2,7 @@ CREATE TABLE "thumbnail" (
 	"archive_id"	INTEGER NOT NULL UNIQUE,
 	"has_jpeg"	INTEGER NOT NULL,
 	"has_webp"	INTEGER NOT NULL,
+	"has_avif"	INTEGER NOT NULL DEFAULT 0,
 	PRIMARY KEY("archive_id")
 );
 
@@ -13,6 +14,36 @@ CREATE TABLE "thumbnail_jpeg" (
 	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
 );
 
+CREATE TABLE "thumbnail_webp" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"small"	BLOB,
+	"medium"	BLOB,
+	"large"	BLOB,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
+
+CREATE TABLE "thumbnail_avif" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"small"	BLOB,
+	"medium"	BLOB,
+	"large"	BLOB,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
+
+CREATE TABLE "thumbnail_preview" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"mimetype"	TEXT NOT NULL,
+	"preview"	BLOB NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
+
+CREATE TABLE "thumbnail_storyboard" (
+	"archive_id"	INTEGER NOT NULL UNIQUE,
+	"sprite"	BLOB NOT NULL,
+	"vtt"	TEXT NOT NULL,
+	FOREIGN KEY("archive_id") REFERENCES "thumbnail"("archive_id") ON DELETE CASCADE
+);
+
 CREATE TABLE "thumbnail_blurhash" (
 	"archive_id"	INTEGER NOT NULL UNIQUE,
 	"hash" TEXT NOT NULL,
@@ -1,18 +1,25 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
+	"errors"
+	"io/fs"
 	"regexp"
 
+	"github.com/dtbead/moonpool/internal/db/migration"
 	_ "modernc.org/sqlite"
 )
 
-//go:embed archive_schema.sql
-var archive_SQL_Schema string
+// archive_schema.sql and thumbnail_schema.sql are kept as the cumulative schema used by sqlc.
+// Databases themselves are only ever created and upgraded through these migrations.
 
-//go:embed thumbnail_schema.sql
-var thumbnail_SQL_Schema string
+//go:embed archive/migrations/*.sql
+var archiveMigrations embed.FS
+
+//go:embed thumbnail/migrations/*.sql
+var thumbnailMigrations embed.FS
 
 const SQL_INIT_PRAGMA = `
 	PRAGMA foreign_keys = ON;
@@ -26,8 +33,10 @@ var (
 	regex_newlinesAndTabs    = regexp.MustCompile(`[\n\t\r]+`)
 )
 
+// OpenSQLite3 opens a database file, creating it if needed. Every connection waits up to 5 seconds
+// for a lock held by another connection, such as a background job, instead of failing immediately.
 func OpenSQLite3(filepath string) (*sql.DB, error) {
-	s, err := sql.Open("sqlite", filepath+"?&mode=rwc")
+	s, err := sql.Open("sqlite", filepath+"?&mode=rwc&_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
@@ -52,20 +61,84 @@ func OpenSQLite3Memory() (*sql.DB, error) {
 	return s, nil
 }
 
+// InitializeArchive creates or upgrades an archive database to the latest schema version.
 func InitializeArchive(db *sql.DB) error {
-	if _, err := db.Exec(archive_SQL_Schema); err != nil {
-		return err
+	_, err := MigrateArchive(context.Background(), db, migration.LATEST, false)
+	return err
+}
+
+// InitializeThumbnail creates or upgrades a thumbnail database to the latest schema version.
+func InitializeThumbnail(db *sql.DB) error {
+	_, err := MigrateThumbnail(context.Background(), db, migration.LATEST, false)
+	return err
+}
+
+func ArchiveMigrations() (migration.Set, error) {
+	sub, err := fs.Sub(archiveMigrations, "archive/migrations")
+	if err != nil {
+		return migration.Set{}, err
 	}
+	return migration.Load(sub)
+}
 
-	return nil
+func ThumbnailMigrations() (migration.Set, error) {
+	sub, err := fs.Sub(thumbnailMigrations, "thumbnail/migrations")
+	if err != nil {
+		return migration.Set{}, err
+	}
+	return migration.Load(sub)
 }
 
-func InitializeThumbnail(db *sql.DB) error {
-	if _, err := db.Exec(thumbnail_SQL_Schema); err != nil {
-		return err
+// MigrateArchive migrates an archive database to a target version and returns every migration
+// that was (or, with dryRun, would be) applied.
+func MigrateArchive(ctx context.Context, db *sql.DB, target int64, dryRun bool) ([]migration.Migrator, error) {
+	s, err := ArchiveMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate(ctx, db, s, "archive", target, dryRun)
+}
+
+// MigrateThumbnail migrates a thumbnail database to a target version and returns every migration
+// that was (or, with dryRun, would be) applied.
+func MigrateThumbnail(ctx context.Context, db *sql.DB, target int64, dryRun bool) ([]migration.Migrator, error) {
+	s, err := ThumbnailMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate(ctx, db, s, "thumbnail", target, dryRun)
+}
+
+// migrate runs a migration.Set against db. Databases created before migrations existed have a
+// user_version of 0 while already containing the reference schema; these are adopted as version 1
+// by checking for the existence of table.
+func migrate(ctx context.Context, db *sql.DB, s migration.Set, table string, target int64, dryRun bool) ([]migration.Migrator, error) {
+	v, err := migration.GetVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == 0 {
+		var exists int
+		err = db.QueryRowContext(ctx, `SELECT 1 FROM sqlite_master WHERE type == 'table' AND name == ?;`, table).Scan(&exists)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		if exists == 1 {
+			if dryRun {
+				return s.Plan(1, target)
+			}
+
+			if err := migration.Stamp(ctx, db, 1); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	return nil
+	return migration.Migrate(ctx, db, s, target, dryRun)
 }
 
 // IsClean checks if a string is alphanumerical and is within [3-24] characters
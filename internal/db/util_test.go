@@ -1,9 +1,13 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	_ "embed"
 	"testing"
 
+	"github.com/dtbead/moonpool/internal/db/migration"
+
 	_ "modernc.org/sqlite"
 )
 
@@ -54,3 +58,122 @@ func Test_DeleteWhitespace(t *testing.T) {
 		})
 	}
 }
+
+func Test_MigrateArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		migrate func(context.Context, *sql.DB, int64, bool) ([]migration.Migrator, error)
+		table   string
+	}{
+		{"archive", MigrateArchive, "archive"},
+		{"thumbnail", MigrateThumbnail, "thumbnail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db, err := OpenSQLite3Memory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1)
+
+			if _, err := tt.migrate(ctx, db, migration.LATEST, false); err != nil {
+				t.Fatalf("failed to upgrade, %v", err)
+			}
+
+			if _, err := tt.migrate(ctx, db, 0, false); err != nil {
+				t.Fatalf("failed to downgrade, %v", err)
+			}
+			if _, err := db.ExecContext(ctx, "SELECT * FROM "+tt.table); err == nil {
+				t.Fatalf("table %s still exists after downgrade", tt.table)
+			}
+
+			if _, err := tt.migrate(ctx, db, migration.LATEST, false); err != nil {
+				t.Fatalf("failed to upgrade after downgrade, %v", err)
+			}
+		})
+	}
+}
+
+func Test_MigrateArchive_Unversioned(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite3Memory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// simulate an archive created before schema versioning existed
+	if _, err := MigrateArchive(ctx, db, 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migration.Stamp(ctx, db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MigrateArchive(ctx, db, migration.LATEST, false); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := migration.GetVersion(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := ArchiveMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != s.Latest() {
+		t.Errorf("got version %d, want %d", v, s.Latest())
+	}
+}
+
+func Test_MigrateArchive_Layout(t *testing.T) {
+	tests := []struct {
+		name       string
+		hasEntries bool
+		want       string
+	}{
+		{"existing archive", true, "md5 1 2"},
+		{"empty archive", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db, err := OpenSQLite3Memory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			db.SetMaxOpenConns(1)
+
+			// the version before storage layouts were recorded
+			if _, err := MigrateArchive(ctx, db, 7, false); err != nil {
+				t.Fatal(err)
+			}
+			if tt.hasEntries {
+				if _, err := db.ExecContext(ctx, "INSERT INTO archive (path, extension) VALUES ('ab/abcdef.png', '.png')"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := MigrateArchive(ctx, db, migration.LATEST, false); err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			err = db.QueryRowContext(ctx, "SELECT hash || ' ' || depth || ' ' || width FROM storage_layout WHERE id == 0").Scan(&got)
+			if err != nil && err != sql.ErrNoRows {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("recorded layout = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
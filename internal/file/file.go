@@ -9,17 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"hash"
-	"image"
 	"io"
-	"mime"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/corona10/goimagehash"
 )
 
 var hashPool sync.Pool
@@ -80,11 +76,6 @@ type hashesString struct {
 	MD5, SHA1, SHA256 string
 }
 
-type PerceptualHashes struct {
-	Type string
-	Hash uint64
-}
-
 func Copy(destination string, r io.Reader) error {
 	baseDirectory := filepath.Dir(destination)
 	if !DoesPathExist(baseDirectory) {
@@ -134,32 +125,6 @@ func GetHash(r io.Reader) (hash Hashes, read int64, err error) {
 	return h, bytesRead, nil
 }
 
-func GetPerceptualHash(i image.Image) (PerceptualHashes, error) {
-	hash, err := goimagehash.PerceptionHash(i)
-	if err != nil {
-		return PerceptualHashes{}, err
-	}
-
-	ph := PerceptualHashes{}
-
-	switch hash.GetKind() {
-	case 0:
-		ph.Type = "unknown"
-	case 1:
-		ph.Type = "AHash"
-	case 2:
-		ph.Type = "PHash"
-	case 3:
-		ph.Type = "DHash"
-	case 4:
-		ph.Type = "WHash"
-	}
-
-	ph.Hash = hash.GetHash()
-	return ph, nil
-
-}
-
 func ByteToHexString(h []byte) string {
 	return hex.EncodeToString(h)
 }
@@ -219,12 +184,6 @@ func CleanPath(s string) string {
 	return path.Clean(strings.ReplaceAll(s, `\`, `/`))
 }
 
-// GetMimeTypeByExtension returns the mime type according to a given extension. It returns
-// a blank string if no type is found.
-func GetMimeTypeByExtension(ext string) string {
-	return mime.TypeByExtension(ext)
-}
-
 func unixTimeToWindowsTicks(unix uint64) uint64 {
 	return (unix * 10000000) + 116444736000000000
 }
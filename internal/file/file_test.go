@@ -2,12 +2,15 @@ package file
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"image"
 	"io"
+	"math/bits"
 	"math/rand/v2"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -15,6 +18,7 @@ import (
 	_ "image/jpeg"
 
 	"github.com/go-test/deep"
+	"github.com/nfnt/resize"
 )
 
 func Test_DateModified(t *testing.T) {
@@ -305,3 +309,191 @@ func TestGetPerceptualHash(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPerceptualHashes(t *testing.T) {
+	f, err := os.Open("testdata/6ba11adbdb35ee10f9353608a7b97ef248733a72.jpg")
+	if err != nil {
+		t.Fatalf("failed to open test file. %v", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatalf("failed to decode test image. %v", err)
+	}
+	resized := resize.Resize(uint(img.Bounds().Dx()/3), 0, img, resize.Lanczos3)
+
+	original, err := GetPerceptualHashes(img, PerceptualHashTypes...)
+	if err != nil {
+		t.Fatalf("GetPerceptualHashes() error = %v", err)
+	}
+
+	smaller, err := GetPerceptualHashes(resized, PerceptualHashTypes...)
+	if err != nil {
+		t.Fatalf("GetPerceptualHashes() error = %v", err)
+	}
+
+	for i, h := range original {
+		t.Run(h.Type, func(t *testing.T) {
+			if h.Type != PerceptualHashTypes[i] {
+				t.Fatalf("GetPerceptualHashes() returned %s at index %d, want %s", h.Type, i, PerceptualHashTypes[i])
+			}
+
+			size := len(h.Words()) * 64
+			if size != PerceptualHashBits(h.Type) {
+				t.Errorf("%s has %d bits, want %d", h.Type, size, PerceptualHashBits(h.Type))
+			}
+
+			if !slices.ContainsFunc(h.Words(), func(w uint64) bool { return w != 0 }) {
+				t.Errorf("%s is empty", h.Type)
+			}
+
+			// a downscaled copy of an image should be a near-duplicate of itself
+			distance := 0
+			for w, word := range h.Words() {
+				distance += bits.OnesCount64(word ^ smaller[i].Words()[w])
+			}
+			if distance > size/5 {
+				t.Errorf("%s distance to downscaled image = %d, want at most %d", h.Type, distance, size/5)
+			}
+		})
+	}
+
+	phash, err := GetPerceptualHash(img)
+	if err != nil {
+		t.Fatalf("GetPerceptualHash() error = %v", err)
+	}
+	if !reflect.DeepEqual(phash, original[2]) {
+		t.Errorf("GetPerceptualHash() = %v, want %v", phash, original[2])
+	}
+
+	if _, err := GetPerceptualHashes(img, "foo"); err != ErrUnknownHashType {
+		t.Errorf("GetPerceptualHashes() error = %v, want %v", err, ErrUnknownHashType)
+	}
+}
+
+func TestVideoHashDistance(t *testing.T) {
+	video := []uint64{0x0, 0xff, 0xffff, 0xffffff, 0xffffffff}
+
+	tests := []struct {
+		name string
+		a, b []uint64
+		want int
+	}{
+		{"identical", video, video, 0},
+		{"trimmed", video, video[1:3], 0},
+		{"trimmed reversed", video[2:], video, 0},
+		{"one bit per frame", video, []uint64{0x1, 0xfe, 0xfffe, 0xfffffe, 0xfffffffe}, 1},
+		{"unrelated", []uint64{0}, []uint64{^uint64(0)}, 64},
+		{"empty", video, nil, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VideoHashDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("VideoHashDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFileType(t *testing.T) {
+	jpeg, err := os.ReadFile("testdata/6ba11adbdb35ee10f9353608a7b97ef248733a72.jpg")
+	if err != nil {
+		t.Fatalf("failed to open test file. %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		header  []byte
+		want    string
+		wantErr error
+	}{
+		{"jpeg", jpeg[:SniffLength], "image/jpeg", nil},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png", nil},
+		{"gif87a", []byte("GIF87a\x01\x00\x01\x00"), "image/gif", nil},
+		{"gif89a", []byte("GIF89a\x01\x00\x01\x00"), "image/gif", nil},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp", nil},
+		{"mp4", []byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00"), "video/mp4", nil},
+		{"mov", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00qt  "), "video/quicktime", nil},
+		{"mov without ftyp", []byte("\x00\x00\x00\x08wide\x00\x00\x00\x00mdat"), "video/quicktime", nil},
+		{"avif", []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf"), "image/avif", nil},
+		{"avif with generic brand", []byte("\x00\x00\x00\x1cftypmif1\x00\x00\x00\x00mif1avifmiaf"), "image/avif", nil},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), "image/heic", nil},
+		{"heic with generic brand", []byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00mif1heic"), "image/heic", nil},
+		{"generic heif brand", []byte("\x00\x00\x00\x14ftypmif1\x00\x00\x00\x00mif1"), "", ErrUnsupportedFileType},
+		{"jxl codestream", []byte("\xff\x0a\xfa\x7f"), "image/jxl", nil},
+		{"jxl container", []byte("\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a"), "image/jxl", nil},
+		{"webm", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\xf7\x81\x01\x42\x82\x84webm\x42\x87"), "video/webm", nil},
+		{"mkv", []byte("\x1a\x45\xdf\xa3\xa3\x42\x86\x81\x01\x42\xf7\x81\x01\x42\x82\x88matroska\x42\x87"), "video/x-matroska", nil},
+		{"truncated ebml", []byte("\x1a\x45\xdf\xa3\xa3\x42\x82\x88matr"), "", ErrUnsupportedFileType},
+		{"mp3 id3", []byte("ID3\x04\x00\x00\x00\x00\x00\x23"), "audio/mpeg", nil},
+		{"mp3 frame", []byte("\xff\xfb\x90\x64"), "audio/mpeg", nil},
+		{"flac", []byte("fLaC\x00\x00\x00\x22"), "audio/flac", nil},
+		{"opus", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x13OpusHead\x01"), "audio/opus", nil},
+		{"ogg vorbis", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1e\x01vorbis\x00"), "audio/ogg", nil},
+		{"unknown ogg codec", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x2aSpeex   "), "", ErrUnsupportedFileType},
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), "audio/wav", nil},
+		{"unknown ftyp brand", []byte("\x00\x00\x00\x20ftypxxxx\x00\x00\x02\x00"), "", ErrUnsupportedFileType},
+		{"text", []byte("hello world"), "", ErrUnsupportedFileType},
+		{"empty", []byte{}, "", ErrUnsupportedFileType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFileType(tt.header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DetectFileType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Mimetype != tt.want {
+				t.Errorf("DetectFileType() = %s, want %s", got.Mimetype, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckExtension(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name    string
+		ext     string
+		want    string
+		wantErr error
+	}{
+		{"matching", ".png", ".png", nil},
+		{"uppercase", ".PNG", ".png", nil},
+		{"no extension", "", ".png", nil},
+		{"mislabeled", ".jpg", "", ErrMislabeledFile},
+		{"unknown extension", ".txt", "", ErrMislabeledFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckExtension(png, tt.ext)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckExtension() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Extension != tt.want {
+				t.Errorf("CheckExtension() = %s, want %s", got.Extension, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMimeTypeByExtension(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want string
+	}{
+		{".jpg", "image/jpeg"},
+		{".JPEG", "image/jpeg"},
+		{".webp", "image/webp"},
+		{".m4v", "video/mp4"},
+		{".HEIF", "image/heic"},
+		{".webm", "video/webm"},
+		{".foobar", ""},
+	}
+	for _, tt := range tests {
+		if got := GetMimeTypeByExtension(tt.ext); got != tt.want {
+			t.Errorf("GetMimeTypeByExtension(%s) = %s, want %s", tt.ext, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+)
+
+// SniffLength is the amount of bytes DetectFileType needs from the start of a file.
+const SniffLength = 512
+
+var (
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+	ErrMislabeledFile      = errors.New("file extension does not match its content")
+)
+
+// FileType is a file format moonpool is able to import. Extension is the extension files of this format are
+// stored with, while Aliases are other extensions the same format is commonly labeled with.
+type FileType struct {
+	Extension string
+	Aliases   []string
+	Mimetype  string
+
+	match func(header []byte) bool
+}
+
+// HasExtension reports whether ext, including its period prefix, is an extension of the file type.
+func (t FileType) HasExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	return ext == t.Extension || slices.Contains(t.Aliases, ext)
+}
+
+// FileTypes are every supported file format, detected by their magic bytes.
+var FileTypes = []FileType{
+	{Extension: ".png", Mimetype: "image/png", match: prefix("\x89PNG\r\n\x1a\n")},
+	{Extension: ".jpg", Aliases: []string{".jpeg", ".jpe", ".jfif"}, Mimetype: "image/jpeg", match: prefix("\xff\xd8\xff")},
+	{Extension: ".gif", Mimetype: "image/gif", match: func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte("GIF87a")) || bytes.HasPrefix(b, []byte("GIF89a"))
+	}},
+	{Extension: ".webp", Mimetype: "image/webp", match: riff("WEBP")},
+	{Extension: ".jxl", Mimetype: "image/jxl", match: func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte("\xff\x0a")) || bytes.HasPrefix(b, []byte("\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a"))
+	}},
+	// AVIF and HEIC are both HEIF files, often with a generic major brand such as "mif1", so their compatible
+	// brands are checked as well. AVIF is checked first, since some AVIF files also list HEIF brands.
+	{Extension: ".avif", Mimetype: "image/avif", match: ftypCompatible("avif", "avis")},
+	{Extension: ".heic", Aliases: []string{".heif", ".hif"}, Mimetype: "image/heic",
+		match: ftypCompatible("heic", "heix", "heim", "heis", "hevc", "hevx")},
+	{Extension: ".mp4", Aliases: []string{".m4v"}, Mimetype: "video/mp4",
+		match: ftyp("isom", "iso2", "iso3", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "dash", "M4V ", "M4VH", "M4VP", "mmp4", "MSNV", "f4v ")},
+	{Extension: ".mov", Aliases: []string{".qt"}, Mimetype: "video/quicktime", match: func(b []byte) bool {
+		// old QuickTime files have no "ftyp" box and start with their movie data instead
+		return ftyp("qt  ")(b) || (len(b) >= 8 && slices.Contains([]string{"moov", "mdat", "wide"}, string(b[4:8])))
+	}},
+	{Extension: ".webm", Mimetype: "video/webm", match: ebml("webm")},
+	{Extension: ".mkv", Mimetype: "video/x-matroska", match: ebml("matroska")},
+	{Extension: ".mp3", Mimetype: "audio/mpeg", match: func(b []byte) bool {
+		// either an ID3v2 tag, or the frame sync of an MPEG-1/2 Layer III frame
+		return bytes.HasPrefix(b, []byte("ID3")) || (len(b) >= 2 && b[0] == 0xff && b[1]&0xe6 == 0xe2)
+	}},
+	{Extension: ".flac", Mimetype: "audio/flac", match: prefix("fLaC")},
+	{Extension: ".opus", Mimetype: "audio/opus", match: ogg("OpusHead")},
+	{Extension: ".ogg", Aliases: []string{".oga"}, Mimetype: "audio/ogg", match: ogg("\x01vorbis")},
+	{Extension: ".wav", Aliases: []string{".wave"}, Mimetype: "audio/wav", match: riff("WAVE")},
+}
+
+// DetectFileType detects the format of a file from its first SniffLength bytes, or fewer if the file is smaller.
+func DetectFileType(header []byte) (FileType, error) {
+	for _, t := range FileTypes {
+		if t.match(header) {
+			return t, nil
+		}
+	}
+	return FileType{}, ErrUnsupportedFileType
+}
+
+// CheckExtension detects the format of a file and makes sure it matches the extension the file is labeled
+// with. An empty extension matches any supported format.
+func CheckExtension(header []byte, ext string) (FileType, error) {
+	t, err := DetectFileType(header)
+	if err != nil {
+		return FileType{}, err
+	}
+
+	if ext != "" && !t.HasExtension(ext) {
+		return FileType{}, fmt.Errorf("%w, labeled as '%s' but is %s", ErrMislabeledFile, ext, t.Mimetype)
+	}
+	return t, nil
+}
+
+// FileTypeByExtension returns the supported file type of an extension, including its period prefix.
+func FileTypeByExtension(ext string) (FileType, bool) {
+	for _, t := range FileTypes {
+		if t.HasExtension(ext) {
+			return t, true
+		}
+	}
+	return FileType{}, false
+}
+
+// GetMimeTypeByExtension returns the mime type according to a given extension. It returns
+// a blank string if no type is found.
+func GetMimeTypeByExtension(ext string) string {
+	if t, ok := FileTypeByExtension(ext); ok {
+		return t.Mimetype
+	}
+	return mime.TypeByExtension(ext)
+}
+
+func prefix(magic string) func([]byte) bool {
+	return func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte(magic))
+	}
+}
+
+// riff matches a RIFF container holding a given form type, such as "WEBP" or "WAVE".
+func riff(form string) func([]byte) bool {
+	return func(b []byte) bool {
+		return len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == form
+	}
+}
+
+// ftyp matches an ISO base media file, such as MP4 or HEIC, whose major brand is one of brands.
+func ftyp(brands ...string) func([]byte) bool {
+	return func(b []byte) bool {
+		return len(b) >= 12 && string(b[4:8]) == "ftyp" && slices.Contains(brands, string(b[8:12]))
+	}
+}
+
+// ftypCompatible matches an ISO base media file whose major brand or any of its compatible brands is one
+// of brands.
+func ftypCompatible(brands ...string) func([]byte) bool {
+	return func(b []byte) bool {
+		if len(b) < 12 || string(b[4:8]) != "ftyp" {
+			return false
+		}
+
+		// the ftyp box holds the major brand, a 4 byte minor version, then every compatible brand
+		size := min(int(binary.BigEndian.Uint32(b[:4])), len(b))
+		for i := 8; i+4 <= size; i += 4 {
+			if i != 12 && slices.Contains(brands, string(b[i:i+4])) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ogg matches an Ogg container whose first stream starts with a given codec header, such as "OpusHead".
+func ogg(codec string) func([]byte) bool {
+	return func(b []byte) bool {
+		// the first page header is 27 bytes plus its segment table, after which the codec header follows
+		if !bytes.HasPrefix(b, []byte("OggS")) || len(b) < 27 {
+			return false
+		}
+
+		start := 27 + int(b[26])
+		return start <= len(b) && bytes.HasPrefix(b[start:], []byte(codec))
+	}
+}
+
+// ebml matches a Matroska file, such as MKV or WebM, with a given DocType.
+func ebml(docType string) func([]byte) bool {
+	return func(b []byte) bool {
+		if !bytes.HasPrefix(b, []byte("\x1a\x45\xdf\xa3")) {
+			return false
+		}
+
+		// the DocType element (0x4282) is followed by its size as a 1 byte variable length integer
+		i := bytes.Index(b, []byte("\x42\x82"))
+		if i < 0 || i+3 > len(b) {
+			return false
+		}
+
+		size := int(b[i+2] & 0x7f)
+		return i+3+size <= len(b) && string(b[i+3:i+3+size]) == docType
+	}
+}
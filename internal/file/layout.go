@@ -0,0 +1,90 @@
+package file
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dtbead/moonpool/entry"
+)
+
+const (
+	HashMD5    = "md5"
+	HashSHA1   = "sha1"
+	HashSHA256 = "sha256"
+
+	// MaxLayoutDepth is the deepest a Layout may nest files.
+	MaxLayoutDepth = 4
+	// MaxLayoutWidth is the most hexadecimal characters a Layout may name a directory with.
+	MaxLayoutWidth = 4
+)
+
+// Layout decides where media is stored, by the hexadecimal hash of its content. Files are nested Depth
+// directories deep, each named after the next Width characters of their hash. For example,
+// Layout{Hash: "sha256", Depth: 2, Width: 2} stores a file at "e3/b0/e3b0c44298fc...b855.png".
+type Layout struct {
+	Hash         string
+	Depth, Width int
+}
+
+var (
+	// DefaultLayout is the layout of new archives. With 256 directories on each level, 65536 directories
+	// keep even a large archive at a few hundred files per directory.
+	DefaultLayout = Layout{Hash: HashSHA256, Depth: 2, Width: 2}
+	// LegacyLayout is the layout of archives created before layouts were configurable, as built by BuildPath.
+	LegacyLayout = Layout{Hash: HashMD5, Depth: 1, Width: 2}
+)
+
+// Validate returns an error if l is not a layout files can be stored in.
+func (l Layout) Validate() error {
+	switch l.Hash {
+	case HashMD5, HashSHA1, HashSHA256:
+	default:
+		return fmt.Errorf("unknown layout hash '%s'", l.Hash)
+	}
+
+	if l.Depth < 0 || l.Depth > MaxLayoutDepth {
+		return fmt.Errorf("layout depth must be between 0 and %d", MaxLayoutDepth)
+	}
+	if l.Width < 1 || l.Width > MaxLayoutWidth {
+		return fmt.Errorf("layout width must be between 1 and %d", MaxLayoutWidth)
+	}
+	return nil
+}
+
+// Path builds the path a file with hashes h is stored at in l. Path expects an extension to have a period
+// prefix already added by caller.
+func (l Layout) Path(h entry.Hashes, extension string) (string, error) {
+	if err := l.Validate(); err != nil {
+		return "", err
+	}
+
+	var sum []byte
+	switch l.Hash {
+	case HashMD5:
+		sum = h.MD5
+	case HashSHA1:
+		sum = h.SHA1
+	case HashSHA256:
+		sum = h.SHA256
+	}
+	if len(sum) == 0 {
+		return "", errors.New("missing " + l.Hash + " hash")
+	}
+
+	name := hex.EncodeToString(sum)
+
+	var b strings.Builder
+	for i := 0; i < l.Depth; i++ {
+		b.WriteString(name[i*l.Width : (i+1)*l.Width])
+		b.WriteByte('/')
+	}
+	b.WriteString(name)
+	b.WriteString(extension)
+	return b.String(), nil
+}
+
+func (l Layout) String() string {
+	return fmt.Sprintf("%s, %d level(s) of %d character(s)", l.Hash, l.Depth, l.Width)
+}
@@ -0,0 +1,50 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/dtbead/moonpool/entry"
+)
+
+func TestLayout_Path(t *testing.T) {
+	h := entry.Hashes{
+		MD5:    []byte{91, 115, 3, 1, 18, 87, 5, 166, 60, 160, 100, 218, 24, 159, 125, 80},
+		SHA1:   []byte{218, 57, 163, 238, 94, 107, 75, 13, 50, 85, 191, 239, 149, 96, 24, 144, 175, 216, 7, 9},
+		SHA256: []byte{227, 176, 196, 66, 152, 252, 28, 20, 154, 251, 244, 200, 153, 111, 185, 36, 39, 174, 65, 228, 100, 155, 147, 76, 164, 149, 153, 27, 120, 82, 184, 85},
+	}
+
+	tests := []struct {
+		name    string
+		l       Layout
+		want    string
+		wantErr bool
+	}{
+		{"legacy", LegacyLayout, "5b/5b730301125705a63ca064da189f7d50.png", false},
+		{"default", DefaultLayout, "e3/b0/e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855.png", false},
+		{"sha1", Layout{HashSHA1, 3, 1}, "d/a/3/da39a3ee5e6b4b0d3255bfef95601890afd80709.png", false},
+		{"flat", Layout{HashMD5, 0, 2}, "5b730301125705a63ca064da189f7d50.png", false},
+		{"wide", Layout{HashMD5, 1, 4}, "5b73/5b730301125705a63ca064da189f7d50.png", false},
+		{"unknown hash", Layout{"crc32", 1, 2}, "", true},
+		{"too deep", Layout{HashMD5, MaxLayoutDepth + 1, 2}, "", true},
+		{"no width", Layout{HashMD5, 1, 0}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.l.Path(h, ".png")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Layout.Path() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Layout.Path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := LegacyLayout.Path(h, ".png"); got != BuildPath(h.MD5, ".png") {
+		t.Errorf("LegacyLayout.Path() = %v, want BuildPath() %v", got, BuildPath(h.MD5, ".png"))
+	}
+
+	if _, err := DefaultLayout.Path(entry.Hashes{MD5: h.MD5}, ".png"); err == nil {
+		t.Errorf("Layout.Path() without a sha256 hash error = nil, want error")
+	}
+}
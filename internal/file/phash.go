@@ -0,0 +1,194 @@
+package file
+
+import (
+	"errors"
+	"image"
+	"math/bits"
+	"slices"
+	"sort"
+
+	"github.com/corona10/goimagehash"
+	"github.com/corona10/goimagehash/transforms"
+	"github.com/nfnt/resize"
+)
+
+// Perceptual hash types. The "16" variants are computed over a 16x16 grid instead of 8x8, producing
+// a 256-bit hash that is more sensitive to small differences such as crops.
+const (
+	AHash   = "AHash"
+	DHash   = "DHash"
+	PHash   = "PHash"
+	WHash   = "WHash"
+	AHash16 = "AHash16"
+	DHash16 = "DHash16"
+	PHash16 = "PHash16"
+)
+
+// VHash is a video fingerprint made of the PHash of every frame sampled from a video, in order. Unlike
+// other hash types it has no fixed size and is compared with VideoHashDistance rather than bit by bit.
+const VHash = "VHash"
+
+// PerceptualHashTypes are every hash type GetPerceptualHashes is able to compute.
+var PerceptualHashTypes = []string{AHash, DHash, PHash, WHash, AHash16, DHash16, PHash16}
+
+var ErrUnknownHashType = errors.New("unknown perceptual hash type")
+
+// PerceptualHashes is a single perceptual hash of an image. Hash holds 64-bit hashes, while Extended holds
+// every 64-bit word of a larger hash, most significant first. For extended hashes, Hash is Extended[0].
+type PerceptualHashes struct {
+	Type     string
+	Hash     uint64
+	Extended []uint64
+}
+
+// Words returns every 64-bit word of a hash.
+func (p PerceptualHashes) Words() []uint64 {
+	if p.Extended != nil {
+		return p.Extended
+	}
+	return []uint64{p.Hash}
+}
+
+// PerceptualHashBits returns the size in bits of a hash type, or 0 if hashType is unknown. A VHash is
+// reported as 64 bits, the largest distance VideoHashDistance returns.
+func PerceptualHashBits(hashType string) int {
+	switch hashType {
+	case AHash, DHash, PHash, WHash, VHash:
+		return 64
+	case AHash16, DHash16, PHash16:
+		return 256
+	default:
+		return 0
+	}
+}
+
+// GetPerceptualHash returns the PHash of an image.
+func GetPerceptualHash(i image.Image) (PerceptualHashes, error) {
+	h, err := GetPerceptualHashes(i, PHash)
+	if err != nil {
+		return PerceptualHashes{}, err
+	}
+	return h[0], nil
+}
+
+// GetPerceptualHashes computes every given hash type from an already decoded image, in the order given.
+func GetPerceptualHashes(i image.Image, hashTypes ...string) ([]PerceptualHashes, error) {
+	if i == nil {
+		return nil, errors.New("given nil image")
+	}
+
+	hashes := make([]PerceptualHashes, 0, len(hashTypes))
+	for _, hashType := range hashTypes {
+		ph := PerceptualHashes{Type: hashType}
+
+		var err error
+		switch hashType {
+		case AHash:
+			ph.Hash, err = imageHash(goimagehash.AverageHash(i))
+		case DHash:
+			ph.Hash, err = imageHash(goimagehash.DifferenceHash(i))
+		case PHash:
+			ph.Hash, err = imageHash(goimagehash.PerceptionHash(i))
+		case WHash:
+			ph.Hash = waveletHash(i)
+		case AHash16:
+			ph.Extended, err = extImageHash(goimagehash.ExtAverageHash(i, 16, 16))
+		case DHash16:
+			ph.Extended, err = extImageHash(goimagehash.ExtDifferenceHash(i, 16, 16))
+		case PHash16:
+			ph.Extended, err = extImageHash(goimagehash.ExtPerceptionHash(i, 16, 16))
+		default:
+			return nil, ErrUnknownHashType
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if ph.Extended != nil {
+			ph.Hash = ph.Extended[0]
+		}
+		hashes = append(hashes, ph)
+	}
+
+	return hashes, nil
+}
+
+// VideoHashDistance returns the distance between two video fingerprints as the average Hamming distance of
+// each frame of one fingerprint to its closest frame in the other. Both directions are compared and the
+// smaller average is returned, so a trimmed clip is still close to the video it was cut from. The result is
+// between 0 and 64, and empty fingerprints are always 64 apart.
+func VideoHashDistance(a, b []uint64) int {
+	if len(a) == 0 || len(b) == 0 {
+		return 64
+	}
+	return min(closestFrames(a, b), closestFrames(b, a))
+}
+
+// closestFrames returns the rounded average distance of every frame in a to its closest frame in b.
+func closestFrames(a, b []uint64) int {
+	var sum int
+	for _, x := range a {
+		closest := 64
+		for _, y := range b {
+			closest = min(closest, bits.OnesCount64(x^y))
+		}
+		sum += closest
+	}
+	return (sum + len(a)/2) / len(a)
+}
+
+func imageHash(h *goimagehash.ImageHash, err error) (uint64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return h.GetHash(), nil
+}
+
+func extImageHash(h *goimagehash.ExtImageHash, err error) ([]uint64, error) {
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(h.GetHash()), nil
+}
+
+// waveletHash computes a 64-bit hash from the low frequency coefficients of a Haar wavelet decomposition.
+// The image is scaled to 64x64 grayscale and decomposed three times, leaving an 8x8 approximation which is
+// compared against its median.
+func waveletHash(i image.Image) uint64 {
+	const size, hashSize = 64, 8
+
+	pixels := transforms.Rgb2Gray(resize.Resize(size, size, i, resize.Bilinear))
+	for n := size; n > hashSize; n /= 2 {
+		haarStep(pixels, n)
+	}
+
+	ll := make([]float64, 0, hashSize*hashSize)
+	for y := 0; y < hashSize; y++ {
+		ll = append(ll, pixels[y][:hashSize]...)
+	}
+
+	sorted := slices.Clone(ll)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for idx, p := range ll {
+		if p > median {
+			hash |= 1 << (len(ll) - idx - 1)
+		}
+	}
+	return hash
+}
+
+// haarStep applies a single level of the 2D Haar transform to the top-left n*n pixels, storing the
+// approximation coefficients in the top-left n/2*n/2 pixels. Detail coefficients are not needed for hashing
+// and are discarded.
+func haarStep(pixels [][]float64, n int) {
+	half := n / 2
+	for y := 0; y < half; y++ {
+		for x := 0; x < half; x++ {
+			sum := pixels[2*y][2*x] + pixels[2*y][2*x+1] + pixels[2*y+1][2*x] + pixels[2*y+1][2*x+1]
+			pixels[y][x] = sum / 2
+		}
+	}
+}
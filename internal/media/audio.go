@@ -0,0 +1,39 @@
+package media
+
+import (
+	"errors"
+	"image"
+	"strconv"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// WaveformWidth and WaveformHeight are the size of a waveform rendered by GenerateAudioThumbnail.
+const (
+	WaveformWidth  = 1280
+	WaveformHeight = 360
+)
+
+// GenerateAudioThumbnail returns the cover art embedded in an audio file, or renders its waveform via ffmpeg
+// if it has none.
+func GenerateAudioThumbnail(filepath string) (image.Image, error) {
+	m, err := ProbeStreams(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.HasAudio {
+		return nil, errors.New("media has no audio stream")
+	}
+
+	if m.HasCover {
+		cover, err := renderFFmpeg(filepath, ffmpeg_go.KwArgs{"map": "0:v:0"})
+		if err == nil {
+			return cover, nil
+		}
+	}
+
+	return renderFFmpeg(filepath, ffmpeg_go.KwArgs{
+		"filter_complex": "showwavespic=s=" + strconv.Itoa(WaveformWidth) + "x" + strconv.Itoa(WaveformHeight) + ":colors=white",
+	})
+}
@@ -0,0 +1,616 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dtbead/moonpool/entry"
+)
+
+// maxMetadataSize is the largest EXIF or XMP block ReadExif reads into memory.
+const maxMetadataSize = 16 << 20
+
+var ErrNoExif = errors.New("media has no exif or xmp metadata")
+
+// EXIF tags read by ReadExif. Tags of IFD0 and the Exif IFD share one namespace, while GPS tags are
+// only looked up in the GPS IFD.
+const (
+	tagMake               = 0x010f
+	tagModel              = 0x0110
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagDateTimeDigitized  = 0x9004
+	tagOffsetTimeOriginal = 0x9011
+	tagLensMake           = 0xa433
+	tagLensModel          = 0xa434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+// ReadExif returns the capture date, camera, lens, orientation and location embedded in a JPEG, PNG or
+// WebP through EXIF or XMP. Fields missing from EXIF are taken from XMP instead, and a malformed XMP packet
+// is ignored if EXIF could be read. It returns ErrNoExif if r has neither, or is of any other format.
+func ReadExif(r io.Reader) (entry.Exif, error) {
+	if r == nil {
+		return entry.Exif{}, errors.New("given nil media")
+	}
+
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(12)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return entry.Exif{}, err
+	}
+
+	var exif, xmp []byte
+	switch {
+	case bytes.HasPrefix(magic, []byte("\xff\xd8")):
+		exif, xmp, err = readJpegMetadata(br)
+	case bytes.HasPrefix(magic, []byte("\x89PNG\r\n\x1a\n")):
+		exif, xmp, err = readPngMetadata(br)
+	case len(magic) >= 12 && string(magic[:4]) == "RIFF" && string(magic[8:12]) == "WEBP":
+		exif, xmp, err = readWebpMetadata(br)
+	default:
+		return entry.Exif{}, ErrNoExif
+	}
+	if err != nil {
+		return entry.Exif{}, err
+	}
+
+	if exif == nil && xmp == nil {
+		return entry.Exif{}, ErrNoExif
+	}
+
+	var e entry.Exif
+	if exif != nil {
+		e, err = parseExif(exif)
+		if err != nil {
+			return entry.Exif{}, err
+		}
+	}
+
+	// a malformed XMP packet should not throw away a readable EXIF
+	if xmp != nil {
+		x, err := parseXMP(xmp)
+		if err != nil && exif == nil {
+			return entry.Exif{}, err
+		}
+		if err == nil {
+			e = mergeExif(e, x)
+		}
+	}
+
+	return e, nil
+}
+
+// readJpegMetadata reads the APP1 segments of a JPEG up to its image data.
+func readJpegMetadata(r *bufio.Reader) (exif, xmp []byte, err error) {
+	if _, err := r.Discard(2); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		marker, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		if marker != 0xff {
+			return nil, nil, errors.New("invalid jpeg marker")
+		}
+
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case kind == 0xff:
+			// fill byte before a marker
+			r.UnreadByte()
+			continue
+		case kind == 0xd8 || kind == 0x01 || (kind >= 0xd0 && kind <= 0xd7):
+			// markers without a segment
+			continue
+		case kind == 0xda || kind == 0xd9:
+			// image data follows, metadata is always before it
+			return exif, xmp, nil
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, nil, err
+		}
+		if length < 2 {
+			return nil, nil, errors.New("invalid jpeg segment length")
+		}
+
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, nil, err
+		}
+
+		if kind != 0xe1 {
+			continue
+		}
+
+		if b, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00")); ok && exif == nil {
+			exif = b
+		} else if b, ok := bytes.CutPrefix(segment, []byte("http://ns.adobe.com/xap/1.0/\x00")); ok && xmp == nil {
+			xmp = b
+		}
+	}
+}
+
+// readPngMetadata reads the eXIf chunk and the XMP iTXt chunk of a PNG.
+func readPngMetadata(r *bufio.Reader) (exif, xmp []byte, err error) {
+	if _, err := r.Discard(8); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		var header struct {
+			Length uint32
+			Type   [4]byte
+		}
+		if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+			return nil, nil, err
+		}
+
+		chunk := string(header.Type[:])
+		switch {
+		case chunk == "IEND":
+			return exif, xmp, nil
+		case (chunk == "eXIf" || chunk == "iTXt") && header.Length <= maxMetadataSize:
+			data := make([]byte, header.Length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, nil, err
+			}
+
+			if chunk == "eXIf" {
+				exif = data
+			} else if b, ok := bytes.CutPrefix(data, []byte("XML:com.adobe.xmp\x00")); ok && len(b) >= 4 && b[0] == 0 {
+				// an uncompressed iTXt chunk, followed by empty language and translated keyword fields
+				_, b, _ = bytes.Cut(b[2:], []byte{0})
+				_, b, _ = bytes.Cut(b, []byte{0})
+				xmp = b
+			}
+		default:
+			if _, err := r.Discard(int(header.Length)); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// CRC
+		if _, err := r.Discard(4); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// readWebpMetadata reads the EXIF and XMP chunks of a WebP.
+func readWebpMetadata(r *bufio.Reader) (exif, xmp []byte, err error) {
+	if _, err := r.Discard(12); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		var header struct {
+			Type   [4]byte
+			Length uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &header); errors.Is(err, io.EOF) {
+			return exif, xmp, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		// chunks are padded to an even size
+		size := int(header.Length) + int(header.Length&1)
+		chunk := string(header.Type[:])
+		if (chunk == "EXIF" || chunk == "XMP ") && header.Length <= maxMetadataSize {
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, nil, err
+			}
+			data = data[:header.Length]
+
+			if chunk == "EXIF" {
+				// some encoders keep the JPEG APP1 prefix
+				exif = bytes.TrimPrefix(data, []byte("Exif\x00\x00"))
+			} else {
+				xmp = data
+			}
+			continue
+		}
+
+		if _, err := r.Discard(size); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// tiff is the TIFF structure EXIF is stored in.
+type tiff struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+// ifdEntry is a single tag of an IFD.
+type ifdEntry struct {
+	kind  uint16
+	count uint32
+	value []byte
+}
+
+// parseExif parses EXIF data, beginning with its TIFF header.
+func parseExif(b []byte) (entry.Exif, error) {
+	if len(b) < 8 {
+		return entry.Exif{}, errors.New("exif is too short")
+	}
+
+	t := tiff{b: b}
+	switch string(b[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return entry.Exif{}, errors.New("invalid exif byte order")
+	}
+
+	if t.order.Uint16(b[2:4]) != 42 {
+		return entry.Exif{}, errors.New("invalid exif header")
+	}
+
+	tags, err := t.readIFD(t.order.Uint32(b[4:8]))
+	if err != nil {
+		return entry.Exif{}, err
+	}
+
+	// the Exif IFD is optional, and a broken one should not hide what IFD0 already has
+	if v, ok := tags[tagExifIFD]; ok {
+		if exifTags, err := t.readIFD(uint32(t.uint(v, 0))); err == nil {
+			for tag, v := range exifTags {
+				tags[tag] = v
+			}
+		}
+	}
+
+	var e entry.Exif
+	e.Make = t.string(tags[tagMake])
+	e.Model = t.string(tags[tagModel])
+	e.Lens = t.string(tags[tagLensModel])
+	if lensMake := t.string(tags[tagLensMake]); lensMake != "" && e.Lens != "" && !strings.HasPrefix(e.Lens, lensMake) {
+		e.Lens = lensMake + " " + e.Lens
+	}
+	e.Orientation = validOrientation(t.uint(tags[tagOrientation], 0))
+
+	date, ok := tags[tagDateTimeOriginal]
+	if !ok {
+		date = tags[tagDateTimeDigitized]
+	}
+	e.DateTimeOriginal = parseExifDate(t.string(date), t.string(tags[tagOffsetTimeOriginal]))
+
+	if v, ok := tags[tagGPSIFD]; ok {
+		if gps, err := t.readIFD(uint32(t.uint(v, 0))); err == nil {
+			t.readGPS(gps, &e)
+		}
+	}
+
+	return e, nil
+}
+
+func (t tiff) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	if uint64(offset)+2 > uint64(len(t.b)) {
+		return nil, errors.New("exif ifd out of bounds")
+	}
+
+	n := int(t.order.Uint16(t.b[offset:]))
+	start := int(offset) + 2
+	if start+n*12 > len(t.b) {
+		return nil, errors.New("exif ifd out of bounds")
+	}
+
+	tags := make(map[uint16]ifdEntry, n)
+	for i := 0; i < n; i++ {
+		e := t.b[start+i*12 : start+(i+1)*12]
+		tag, kind, count := t.order.Uint16(e[0:2]), t.order.Uint16(e[2:4]), t.order.Uint32(e[4:8])
+
+		size := uint64(typeSize(kind)) * uint64(count)
+		if size == 0 {
+			continue
+		}
+
+		// values of up to 4 bytes are stored in place of their offset
+		value := e[8:12]
+		if size > 4 {
+			o := uint64(t.order.Uint32(e[8:12]))
+			if o+size > uint64(len(t.b)) {
+				continue
+			}
+			value = t.b[o : o+size]
+		}
+
+		tags[tag] = ifdEntry{kind: kind, count: count, value: value[:min(size, uint64(len(value)))]}
+	}
+
+	return tags, nil
+}
+
+func (t tiff) readGPS(tags map[uint16]ifdEntry, e *entry.Exif) {
+	lat, ok := t.coordinate(tags[tagGPSLatitude])
+	if !ok {
+		return
+	}
+
+	lon, ok := t.coordinate(tags[tagGPSLongitude])
+	if !ok {
+		return
+	}
+
+	if strings.EqualFold(t.string(tags[tagGPSLatitudeRef]), "S") {
+		lat = -lat
+	}
+	if strings.EqualFold(t.string(tags[tagGPSLongitudeRef]), "W") {
+		lon = -lon
+	}
+
+	e.HasGPS, e.Latitude, e.Longitude = true, lat, lon
+
+	if alt, ok := t.rational(tags[tagGPSAltitude], 0); ok {
+		if t.uint(tags[tagGPSAltitudeRef], 0) == 1 {
+			alt = -alt
+		}
+		e.Altitude = alt
+	}
+}
+
+// coordinate returns a GPS coordinate, stored as degrees, minutes and seconds, in decimal degrees.
+func (t tiff) coordinate(v ifdEntry) (float64, bool) {
+	var c float64
+	for i, unit := range []float64{1, 60, 3600} {
+		f, ok := t.rational(v, i)
+		if !ok {
+			return 0, false
+		}
+		c += f / unit
+	}
+	return c, true
+}
+
+func (t tiff) string(v ifdEntry) string {
+	if v.kind != 2 {
+		return ""
+	}
+	s, _, _ := strings.Cut(string(v.value), "\x00")
+	return strings.TrimSpace(s)
+}
+
+func (t tiff) uint(v ifdEntry, i int) int64 {
+	switch v.kind {
+	case 1, 7:
+		if i < len(v.value) {
+			return int64(v.value[i])
+		}
+	case 3:
+		if (i+1)*2 <= len(v.value) {
+			return int64(t.order.Uint16(v.value[i*2:]))
+		}
+	case 4:
+		if (i+1)*4 <= len(v.value) {
+			return int64(t.order.Uint32(v.value[i*4:]))
+		}
+	}
+	return 0
+}
+
+func (t tiff) rational(v ifdEntry, i int) (float64, bool) {
+	if (v.kind != 5 && v.kind != 10) || (i+1)*8 > len(v.value) {
+		return 0, false
+	}
+
+	num, den := t.order.Uint32(v.value[i*8:]), t.order.Uint32(v.value[i*8+4:])
+	if den == 0 {
+		return 0, false
+	}
+
+	if v.kind == 10 {
+		return float64(int32(num)) / float64(int32(den)), true
+	}
+	return float64(num) / float64(den), true
+}
+
+// typeSize returns the size of a single value of a TIFF type, or 0 if unknown.
+func typeSize(kind uint16) int {
+	switch kind {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	}
+	return 0
+}
+
+// parseExifDate parses an EXIF date such as "2024:01:02 15:04:05", with an optional offset such as "+01:00".
+// Dates without an offset are in local time. A blank or invalid date returns a zero time.
+func parseExifDate(date, offset string) time.Time {
+	const layout = "2006:01:02 15:04:05"
+	if offset != "" {
+		if t, err := time.Parse(layout+"-07:00", date+offset); err == nil {
+			return t
+		}
+	}
+
+	t, err := time.ParseInLocation(layout, date, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func validOrientation(o int64) int64 {
+	if o < 1 || o > 8 {
+		return 0
+	}
+	return o
+}
+
+// parseXMP parses the properties of an XMP packet that are also found in EXIF. Properties may either be
+// attributes of an rdf:Description, or elements of their own.
+func parseXMP(b []byte) (entry.Exif, error) {
+	props := make(map[string]string)
+
+	d := xml.NewDecoder(bytes.NewReader(b))
+	var element string
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return entry.Exif{}, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			element = tok.Name.Local
+			for _, attr := range tok.Attr {
+				if _, ok := props[attr.Name.Local]; !ok {
+					props[attr.Name.Local] = strings.TrimSpace(attr.Value)
+				}
+			}
+		case xml.CharData:
+			if s := strings.TrimSpace(string(tok)); s != "" && element != "" {
+				if _, ok := props[element]; !ok {
+					props[element] = s
+				}
+			}
+		case xml.EndElement:
+			element = ""
+		}
+	}
+
+	var e entry.Exif
+	e.Make = props["Make"]
+	e.Model = props["Model"]
+	e.Lens = props["LensModel"]
+	if e.Lens == "" {
+		e.Lens = props["Lens"]
+	}
+
+	if o, err := strconv.ParseInt(props["Orientation"], 10, 64); err == nil {
+		e.Orientation = validOrientation(o)
+	}
+
+	for _, p := range []string{"DateTimeOriginal", "CreateDate", "DateCreated"} {
+		if t := parseXMPDate(props[p]); !t.IsZero() {
+			e.DateTimeOriginal = t
+			break
+		}
+	}
+
+	lat, latOK := parseXMPCoordinate(props["GPSLatitude"])
+	lon, lonOK := parseXMPCoordinate(props["GPSLongitude"])
+	if latOK && lonOK {
+		e.HasGPS, e.Latitude, e.Longitude = true, lat, lon
+
+		if num, den, ok := strings.Cut(props["GPSAltitude"], "/"); ok {
+			n, nErr := strconv.ParseFloat(num, 64)
+			d, dErr := strconv.ParseFloat(den, 64)
+			if nErr == nil && dErr == nil && d != 0 {
+				e.Altitude = n / d
+				if props["GPSAltitudeRef"] == "1" {
+					e.Altitude = -e.Altitude
+				}
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// parseXMPDate parses an XMP date, which is a subset of ISO 8601. Dates without a timezone are in local time.
+func parseXMPDate(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseXMPCoordinate parses an XMP GPS coordinate such as "51,30.5N" or "51,30,30N" into decimal degrees.
+func parseXMPCoordinate(s string) (float64, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+
+	ref := s[len(s)-1]
+	parts := strings.Split(s[:len(s)-1], ",")
+	if len(parts) > 3 {
+		return 0, false
+	}
+
+	var c float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil || math.IsNaN(f) || f < 0 {
+			return 0, false
+		}
+		c += f / math.Pow(60, float64(i))
+	}
+
+	switch ref {
+	case 'N', 'E':
+	case 'S', 'W':
+		c = -c
+	default:
+		return 0, false
+	}
+	return c, true
+}
+
+// mergeExif fills every field missing from e with the same field of fallback.
+func mergeExif(e, fallback entry.Exif) entry.Exif {
+	if e.DateTimeOriginal.IsZero() {
+		e.DateTimeOriginal = fallback.DateTimeOriginal
+	}
+	if e.Make == "" {
+		e.Make = fallback.Make
+	}
+	if e.Model == "" {
+		e.Model = fallback.Model
+	}
+	if e.Lens == "" {
+		e.Lens = fallback.Lens
+	}
+	if e.Orientation == 0 {
+		e.Orientation = fallback.Orientation
+	}
+	if !e.HasGPS && fallback.HasGPS {
+		e.HasGPS, e.Latitude, e.Longitude, e.Altitude = true, fallback.Latitude, fallback.Longitude, fallback.Altitude
+	}
+	return e
+}
@@ -0,0 +1,291 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dtbead/moonpool/entry"
+)
+
+type exifTag struct {
+	id, kind uint16
+	count    uint32
+	value    []byte
+}
+
+func asciiTag(id uint16, s string) exifTag {
+	return exifTag{id, 2, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func shortTag(id, v uint16) exifTag {
+	return exifTag{id, 3, 1, binary.LittleEndian.AppendUint16(nil, v)}
+}
+
+func longTag(id uint16, v uint32) exifTag {
+	return exifTag{id, 4, 1, binary.LittleEndian.AppendUint32(nil, v)}
+}
+
+func rationalTag(id uint16, v ...[2]uint32) exifTag {
+	var b []byte
+	for _, r := range v {
+		b = binary.LittleEndian.AppendUint32(b, r[0])
+		b = binary.LittleEndian.AppendUint32(b, r[1])
+	}
+	return exifTag{id, 5, uint32(len(v)), b}
+}
+
+// encodeIFD encodes a little-endian IFD beginning at offset, followed by every value larger than 4 bytes.
+func encodeIFD(offset uint32, tags []exifTag) []byte {
+	data := offset + 2 + 12*uint32(len(tags)) + 4
+
+	var ifd, values []byte
+	ifd = binary.LittleEndian.AppendUint16(ifd, uint16(len(tags)))
+	for _, t := range tags {
+		ifd = binary.LittleEndian.AppendUint16(ifd, t.id)
+		ifd = binary.LittleEndian.AppendUint16(ifd, t.kind)
+		ifd = binary.LittleEndian.AppendUint32(ifd, t.count)
+		if len(t.value) <= 4 {
+			ifd = append(ifd, append(t.value, make([]byte, 4-len(t.value))...)...)
+		} else {
+			ifd = binary.LittleEndian.AppendUint32(ifd, data+uint32(len(values)))
+			values = append(values, t.value...)
+		}
+	}
+	ifd = binary.LittleEndian.AppendUint32(ifd, 0)
+
+	return append(ifd, values...)
+}
+
+// newTestExif returns EXIF data of a photo taken by a Canon EOS R5 at the Eiffel Tower.
+func newTestExif() []byte {
+	ifd0 := func(exifIFD, gpsIFD uint32) []exifTag {
+		return []exifTag{
+			asciiTag(tagMake, "Canon"),
+			asciiTag(tagModel, "Canon EOS R5"),
+			shortTag(tagOrientation, 6),
+			longTag(tagExifIFD, exifIFD),
+			longTag(tagGPSIFD, gpsIFD),
+		}
+	}
+
+	exifIFD := []exifTag{
+		asciiTag(tagDateTimeOriginal, "2023:08:14 17:30:12"),
+		asciiTag(tagOffsetTimeOriginal, "+02:00"),
+		asciiTag(tagLensModel, "RF24-105mm F4 L IS USM"),
+	}
+
+	gpsIFD := []exifTag{
+		asciiTag(tagGPSLatitudeRef, "N"),
+		rationalTag(tagGPSLatitude, [2]uint32{48, 1}, [2]uint32{51, 1}, [2]uint32{2400, 100}),
+		asciiTag(tagGPSLongitudeRef, "E"),
+		rationalTag(tagGPSLongitude, [2]uint32{2, 1}, [2]uint32{17, 1}, [2]uint32{4020, 100}),
+		{tagGPSAltitudeRef, 1, 1, []byte{0}},
+		rationalTag(tagGPSAltitude, [2]uint32{35, 1}),
+	}
+
+	header := []byte("II\x2a\x00\x08\x00\x00\x00")
+	exifOffset := uint32(len(header) + len(encodeIFD(8, ifd0(0, 0))))
+	exif := encodeIFD(exifOffset, exifIFD)
+	gpsOffset := exifOffset + uint32(len(exif))
+
+	b := append(header, encodeIFD(8, ifd0(exifOffset, gpsOffset))...)
+	b = append(b, exif...)
+	return append(b, encodeIFD(gpsOffset, gpsIFD)...)
+}
+
+const testXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description xmlns:tiff="http://ns.adobe.com/tiff/1.0/" xmlns:exif="http://ns.adobe.com/exif/1.0/"
+    tiff:Make="FUJIFILM" tiff:Orientation="1" exif:GPSLatitude="35,39.6N" exif:GPSLongitude="139,44.7E">
+   <exif:DateTimeOriginal>2021-03-04T05:06:07Z</exif:DateTimeOriginal>
+   <tiff:Model>X-T4</tiff:Model>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// newTestJpeg returns a JPEG with each of segments as an APP1 segment.
+func newTestJpeg(t *testing.T, segments ...[]byte) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b := []byte("\xff\xd8")
+	for _, s := range segments {
+		b = append(b, 0xff, 0xe1)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(s)+2))
+		b = append(b, s...)
+	}
+	return append(b, buf.Bytes()[2:]...)
+}
+
+// newTestPng returns a PNG with an eXIf chunk holding exif, if given any.
+func newTestPng(t *testing.T, exif []byte) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if exif == nil {
+		return b
+	}
+
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(exif)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, exif...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	// insert after the signature and IHDR chunk
+	return append(append(append([]byte{}, b[:33]...), chunk...), b[33:]...)
+}
+
+// newTestWebp returns a WebP holding an XMP chunk.
+func newTestWebp(xmp string) []byte {
+	chunk := append([]byte("XMP "), binary.LittleEndian.AppendUint32(nil, uint32(len(xmp)))...)
+	chunk = append(chunk, xmp...)
+	if len(xmp)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+
+	vp8l := []byte("VP8L\x05\x00\x00\x00\x2f\x00\x00\x00\x00\x00")
+	body := append(append([]byte("WEBP"), vp8l...), chunk...)
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+func TestReadExif(t *testing.T) {
+	// EXIF takes precedence over XMP, which only fills in what EXIF is missing
+	canon := entry.Exif{
+		DateTimeOriginal: time.Date(2023, 8, 14, 15, 30, 12, 0, time.UTC),
+		Make:             "Canon",
+		Model:            "Canon EOS R5",
+		Lens:             "RF24-105mm F4 L IS USM",
+		Orientation:      6,
+		HasGPS:           true,
+		Latitude:         48 + 51.0/60 + 24.0/3600,
+		Longitude:        2 + 17.0/60 + 40.2/3600,
+		Altitude:         35,
+	}
+
+	fuji := entry.Exif{
+		DateTimeOriginal: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		Make:             "FUJIFILM",
+		Model:            "X-T4",
+		Orientation:      1,
+		HasGPS:           true,
+		Latitude:         35 + 39.6/60,
+		Longitude:        139 + 44.7/60,
+	}
+
+	tests := []struct {
+		name    string
+		b       []byte
+		want    entry.Exif
+		wantErr error
+	}{
+		{"jpeg exif", newTestJpeg(t, append([]byte("Exif\x00\x00"), newTestExif()...)), canon, nil},
+		{"jpeg xmp", newTestJpeg(t, append([]byte("http://ns.adobe.com/xap/1.0/\x00"), testXMP...)), fuji, nil},
+		{"jpeg exif and xmp", newTestJpeg(t,
+			append([]byte("Exif\x00\x00"), newTestExif()...),
+			append([]byte("http://ns.adobe.com/xap/1.0/\x00"), strings.Replace(testXMP, "<tiff:Model>", "<aux:Lens>XF16-80mmF4 R OIS WR</aux:Lens><tiff:Model>", 1)...)), canon, nil},
+		{"jpeg exif and malformed xmp", newTestJpeg(t,
+			append([]byte("Exif\x00\x00"), newTestExif()...),
+			append([]byte("http://ns.adobe.com/xap/1.0/\x00"), testXMP[:len(testXMP)/2]...)), canon, nil},
+		{"png exif", newTestPng(t, newTestExif()), canon, nil},
+		{"webp xmp", newTestWebp(testXMP), fuji, nil},
+		{"jpeg without exif", newTestJpeg(t), entry.Exif{}, ErrNoExif},
+		{"png without exif", newTestPng(t, nil), entry.Exif{}, ErrNoExif},
+		{"unsupported format", []byte("GIF89a"), entry.Exif{}, ErrNoExif},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadExif(bytes.NewReader(tt.b))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadExif() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !got.DateTimeOriginal.Equal(tt.want.DateTimeOriginal) {
+				t.Errorf("ReadExif() DateTimeOriginal = %v, want %v", got.DateTimeOriginal, tt.want.DateTimeOriginal)
+			}
+			got.DateTimeOriginal, tt.want.DateTimeOriginal = time.Time{}, time.Time{}
+
+			if math.Abs(got.Latitude-tt.want.Latitude) > 1e-9 || math.Abs(got.Longitude-tt.want.Longitude) > 1e-9 {
+				t.Errorf("ReadExif() location = %v, %v, want %v, %v", got.Latitude, got.Longitude, tt.want.Latitude, tt.want.Longitude)
+			}
+			got.Latitude, got.Longitude = tt.want.Latitude, tt.want.Longitude
+
+			if got != tt.want {
+				t.Errorf("ReadExif() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseExif_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"too short", []byte("II\x2a\x00")},
+		{"byte order", []byte("XX\x2a\x00\x08\x00\x00\x00")},
+		{"magic number", []byte("II\x2b\x00\x08\x00\x00\x00")},
+		{"ifd out of bounds", []byte("II\x2a\x00\xff\x00\x00\x00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseExif(tt.b); err == nil {
+				t.Errorf("parseExif() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func Test_parseExifDate(t *testing.T) {
+	tests := []struct {
+		name, date, offset string
+		want               time.Time
+	}{
+		{"with offset", "2023:08:14 17:30:12", "-05:00", time.Date(2023, 8, 14, 22, 30, 12, 0, time.UTC)},
+		{"local time", "2023:08:14 17:30:12", "", time.Date(2023, 8, 14, 17, 30, 12, 0, time.Local)},
+		{"invalid offset", "2023:08:14 17:30:12", "foo", time.Date(2023, 8, 14, 17, 30, 12, 0, time.Local)},
+		{"blank", "    :  :     :  :  ", "", time.Time{}},
+		{"zero", "0000:00:00 00:00:00", "", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseExifDate(tt.date, tt.offset); !got.Equal(tt.want) {
+				t.Errorf("parseExifDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseXMPCoordinate(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   float64
+		wantOK bool
+	}{
+		{"51,30.5N", 51 + 30.5/60, true},
+		{"51,30,30S", -(51 + 30.0/60 + 30.0/3600), true},
+		{"0,30W", -0.5, true},
+		{"51,30", 0, false},
+		{"51,30.5X", 0, false},
+		{"foo,30N", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseXMPCoordinate(tt.s)
+		if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseXMPCoordinate(%q) = %v, %v, want %v, %v", tt.s, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package media
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// ThumbnailSizes are the largest width and height, in pixels, of each size of thumbnail.
+type ThumbnailSizes struct {
+	Small, Medium, Large int
+}
+
+// DefaultThumbnailSizes are used for any size a ThumbnailSizes leaves at 0.
+var DefaultThumbnailSizes = ThumbnailSizes{Small: 320, Medium: 800, Large: 1600}
+
+// ThumbnailFormats are the formats thumbnails can be encoded in, mapped to their mimetype.
+var ThumbnailFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"webp": "image/webp",
+	"avif": "image/avif",
+}
+
+// OrDefault returns s with every size of 0 or less replaced by the same size of DefaultThumbnailSizes.
+func (s ThumbnailSizes) OrDefault() ThumbnailSizes {
+	if s.Small <= 0 {
+		s.Small = DefaultThumbnailSizes.Small
+	}
+	if s.Medium <= 0 {
+		s.Medium = DefaultThumbnailSizes.Medium
+	}
+	if s.Large <= 0 {
+		s.Large = DefaultThumbnailSizes.Large
+	}
+	return s
+}
+
+// EncodeWebp encodes an image as a lossy WebP via ffmpeg's libwebp encoder.
+func EncodeWebp(i *image.Image, w io.Writer) error {
+	return encodeFFmpeg(*i, ".webp", ffmpeg_go.KwArgs{
+		"c:v":     "libwebp",
+		"quality": 75,
+	}, w)
+}
+
+// EncodeAvif encodes an image as an AVIF via ffmpeg's libaom encoder.
+func EncodeAvif(i *image.Image, w io.Writer) error {
+	return encodeFFmpeg(*i, ".avif", ffmpeg_go.KwArgs{
+		"c:v":           "libaom-av1",
+		"still-picture": 1,
+		"crf":           32,
+		"cpu-used":      6,
+		"pix_fmt":       "yuv420p",
+	}, w)
+}
+
+// encodeFFmpeg encodes an image into a format without a Go encoder, with ext being the extension of the format
+// and args the ffmpeg output arguments to encode it with.
+// It will create temporary files at "%TMP%/moonpool_encode_xxxxxx.png" and "%TMP%/moonpool_encode_xxxxxx{ext}".
+// An error, as well as ffmpeg output will be wrapped in err
+func encodeFFmpeg(i image.Image, ext string, args ffmpeg_go.KwArgs, w io.Writer) error {
+	name := os.TempDir() + "/moonpool_encode_" + randomString(6)
+	inputPath, outputPath := name+".png", name+ext
+
+	input, err := os.Create(inputPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(inputPath)
+
+	err = png.Encode(input, i)
+	if closeErr := input.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	var ffmpegLog strings.Builder
+	err = ffmpeg_go.Input(inputPath).Output(outputPath, ffmpeg_go.MergeKwArgs([]ffmpeg_go.KwArgs{args, {
+		"frames:v": 1,
+	}})).WithOutput(&ffmpegLog).ErrorToStdOut().WithErrorOutput(&ffmpegLog).Silent(true).Run()
+	if err != nil {
+		return errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+	defer os.Remove(outputPath)
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		return errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
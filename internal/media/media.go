@@ -43,22 +43,13 @@ type ffmpegMetadata struct {
 // GetOrientation returns the orientation a given media is a landscape type. It returns an error
 // if the given io.Reader can't be interpreted as a graphic media.
 func GetOrientation(media io.Reader) (ORIENTATION int, err error) {
-	if media == nil {
-		return -1, errors.New("given nil media")
-	}
-
-	j, err := ffmpeg_go.ProbeReader(media)
-	if err != nil {
-		return -1, err
-	}
-
-	m, err := unmarshalFFmpeg([]byte(j))
+	d, err := GetDimensions(media)
 	if err != nil {
 		return -1, err
 	}
 
-	width := int64(m[0].Width)
-	height := int64(m[0].Height)
+	width := d.Width
+	height := d.Height
 
 	switch {
 	case width > height:
@@ -72,13 +63,32 @@ func GetOrientation(media io.Reader) (ORIENTATION int, err error) {
 	return -1, errors.New("unknown error")
 }
 
-// GetDimensions returns a width and height of a given graphic.
+// GetDimensions returns a width and height of a given graphic. Images with a Go decoder are read directly,
+// anything else is probed by ffprobe. An *os.File is probed by its path, which allows ffprobe to seek through
+// containers such as MOV whose metadata is at the end of the file.
 func GetDimensions(media io.Reader) (struct{ Width, Height int64 }, error) {
 	if media == nil {
 		return struct{ Width, Height int64 }{}, errors.New("given nil media")
 	}
 
-	j, err := ffmpeg_go.ProbeReader(media)
+	if s, ok := media.(io.ReadSeeker); ok {
+		c, _, err := image.DecodeConfig(s)
+		if err == nil {
+			return struct{ Width, Height int64 }{Width: int64(c.Width), Height: int64(c.Height)}, nil
+		}
+
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return struct{ Width, Height int64 }{}, err
+		}
+	}
+
+	var j string
+	var err error
+	if f, ok := media.(*os.File); ok {
+		j, err = ffmpeg_go.Probe(f.Name())
+	} else {
+		j, err = ffmpeg_go.ProbeReader(media)
+	}
 	if err != nil {
 		return struct{ Width, Height int64 }{}, err
 	}
@@ -87,7 +97,19 @@ func GetDimensions(media io.Reader) (struct{ Width, Height int64 }, error) {
 	if err != nil {
 		return struct{ Width, Height int64 }{}, err
 	}
-	return struct{ Width, Height int64 }{Width: int64(m[0].Width), Height: int64(m[0].Height)}, nil
+
+	// the largest stream is the picture itself, rather than e.g. an audio stream or a single tile of a HEIC
+	var width, height float64
+	for _, stream := range m {
+		if stream.Width*stream.Height > width*height {
+			width, height = stream.Width, stream.Height
+		}
+	}
+	if width == 0 || height == 0 {
+		return struct{ Width, Height int64 }{}, errors.New("media has no video stream")
+	}
+
+	return struct{ Width, Height int64 }{Width: int64(width), Height: int64(height)}, nil
 }
 
 func EncodeJpeg(i *image.Image, w io.Writer) error {
@@ -95,20 +117,37 @@ func EncodeJpeg(i *image.Image, w io.Writer) error {
 }
 
 func EncodeJpegIcons(i entry.Icons) (thumbnail.Sizes, error) {
+	return EncodeIcons(i, "jpeg")
+}
+
+// EncodeIcons encodes every size of icon in one of ThumbnailFormats.
+func EncodeIcons(i entry.Icons, format string) (thumbnail.Sizes, error) {
+	var encode func(*image.Image, io.Writer) error
+	switch format {
+	case "jpeg":
+		encode = EncodeJpeg
+	case "webp":
+		encode = EncodeWebp
+	case "avif":
+		encode = EncodeAvif
+	default:
+		return thumbnail.Sizes{}, errors.New("unknown thumbnail format")
+	}
+
 	var small, medium, large bytes.Buffer
 	var err error
 
-	err = EncodeJpeg(i.Small, &small)
+	err = encode(i.Small, &small)
 	if err != nil {
 		return thumbnail.Sizes{}, err
 	}
 
-	err = EncodeJpeg(i.Medium, &medium)
+	err = encode(i.Medium, &medium)
 	if err != nil {
 		return thumbnail.Sizes{}, err
 	}
 
-	err = EncodeJpeg(i.Large, &large)
+	err = encode(i.Large, &large)
 	if err != nil {
 		return thumbnail.Sizes{}, err
 	}
@@ -120,18 +159,21 @@ func EncodeJpegIcons(i entry.Icons) (thumbnail.Sizes, error) {
 	}, nil
 }
 
-func GenerateIcons(i *image.Image) (entry.Icons, error) {
-	small, err := RescaleImage(*i, "small")
+// GenerateIcons rescales an image to fit in every size of sizes.
+func GenerateIcons(i *image.Image, sizes ThumbnailSizes) (entry.Icons, error) {
+	sizes = sizes.OrDefault()
+
+	small, err := RescaleImage(*i, sizes.Small)
 	if err != nil {
 		return entry.Icons{}, err
 	}
 
-	medium, err := RescaleImage(*i, "medium")
+	medium, err := RescaleImage(*i, sizes.Medium)
 	if err != nil {
 		return entry.Icons{}, err
 	}
 
-	large, err := RescaleImage(*i, "large")
+	large, err := RescaleImage(*i, sizes.Large)
 	if err != nil {
 		return entry.Icons{}, err
 	}
@@ -156,25 +198,24 @@ func EncodeBlurHash(i image.Image) (string, error) {
 	return hash, nil
 }
 
-// Valid sizes are "small", "medium", and "large"
-func RescaleImage(i image.Image, size string) (*image.Image, error) {
-	var scaleFactor float64 = 1
-	switch size {
-	case "small":
-		scaleFactor = 0.2
-	case "medium":
-		scaleFactor = 0.4
-	case "large":
-		scaleFactor = 0.6
+// RescaleImage scales an image down to fit in a box of box by box pixels, keeping its aspect ratio. Images
+// that already fit are returned at their original size.
+func RescaleImage(i image.Image, box int) (*image.Image, error) {
+	if box <= 0 {
+		return nil, errors.New("invalid thumbnail size")
 	}
 
-	NewResolution := calculateAspectRatioFit(int64(i.Bounds().Dx()), int64(i.Bounds().Dy()), scaleFactor)
+	NewResolution := calculateAspectRatioFit(int64(i.Bounds().Dx()), int64(i.Bounds().Dy()), int64(box))
+	if NewResolution[0] == int64(i.Bounds().Dx()) && NewResolution[1] == int64(i.Bounds().Dy()) {
+		return &i, nil
+	}
 
 	resized := resize.Resize(uint(NewResolution[0]), uint(NewResolution[1]), i, resize.Lanczos3)
 	return &resized, nil
 }
 
-// DecodeImage takes an io.Reader and returns an image.Image
+// DecodeImage takes an io.Reader and returns an image.Image. If r is an *os.File of an image format without
+// a Go decoder, such as AVIF, HEIC or JPEG XL, it is decoded through ffmpeg instead. Videos are never decoded.
 func DecodeImage(r io.Reader) (image.Image, error) {
 	img, _, err := image.Decode(r)
 	if err != nil && err.Error() == "webp: invalid format" {
@@ -190,13 +231,65 @@ func DecodeImage(r io.Reader) (image.Image, error) {
 	}
 
 	if err != nil {
-		return nil, err
+		f, ok := r.(*os.File)
+		if !ok || !isImageFile(f) {
+			return nil, err
+		}
 
+		img, ffmpegErr := renderFFmpeg(f.Name(), nil)
+		if ffmpegErr != nil {
+			return nil, errors.Join(err, ffmpegErr)
+		}
+		return img, nil
 	}
 
 	return img, nil
 }
 
+// isImageFile reports whether the content of f is a supported image format.
+func isImageFile(f *os.File) bool {
+	header := make([]byte, file.SniffLength)
+	n, err := f.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false
+	}
+
+	t, err := file.DetectFileType(header[:n])
+	return err == nil && strings.HasPrefix(t.Mimetype, "image/")
+}
+
+// renderFFmpeg renders a single frame of a given media with additional ffmpeg output arguments.
+// It will create a temporary file at "%TMP%/moonpool_render_xxxxxx.png".
+// An error, as well as ffmpeg output will be wrapped in err
+func renderFFmpeg(filepath string, args ffmpeg_go.KwArgs) (image.Image, error) {
+	outputPath := os.TempDir() + "/moonpool_render_" + randomString(6) + ".png"
+
+	var ffmpegLog strings.Builder
+	input := ffmpeg_go.Input(filepath).Output(outputPath, ffmpeg_go.MergeKwArgs([]ffmpeg_go.KwArgs{args, {
+		"frames:v": 1,
+		"update":   "true",
+	}})).WithOutput(&ffmpegLog).ErrorToStdOut().WithErrorOutput(&ffmpegLog).Silent(true)
+
+	err := input.Run()
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+	defer os.Remove(outputPath)
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+	defer f.Close()
+
+	i, _, err := image.Decode(f)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+
+	return i, nil
+}
+
 // GenerateVideoThumbnail generates a thumbnail from the middle of a given video via ffmpeg.
 // It will create a temporary file at "%TMP%/moonpool_thumbnail_xxxxxx.jpg".
 // An error, as well as ffmpeg output will be wrapped in err
@@ -238,10 +331,103 @@ func GenerateVideoThumbnail(filepath string) (thumbnail image.Image, err error)
 	return i, nil
 }
 
-func calculateAspectRatioFit(width, height int64, scaleFactor float64) [2]int64 {
-	return [2]int64{
-		int64(float64(width) * scaleFactor), int64(float64(height) * scaleFactor),
+// DefaultVideoFrames is the amount of frames sampled from a video for its fingerprint.
+const DefaultVideoFrames = 16
+
+// ExtractVideoFrames extracts n evenly-spaced frames from a given video via ffmpeg, in order. Frames are taken
+// from the middle of n equal segments of the video, so the first and last frame are never black fades.
+// Each frame is temporarily written to "%TMP%/moonpool_frame_xxxxxx.jpg".
+// An error, as well as ffmpeg output will be wrapped in err
+func ExtractVideoFrames(filepath string, n int) ([]image.Image, error) {
+	if n <= 0 {
+		return nil, errors.New("amount of frames must be greater than 0")
+	}
+
+	j, err := ffmpeg_go.Probe(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := unmarshalFFmpeg([]byte(j))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m) == 0 || m[0].Duration <= 0 {
+		return nil, errors.New("video has no duration")
+	}
+
+	frames := make([]image.Image, 0, n)
+	for i := 0; i < n; i++ {
+		timestamp := m[0].Duration * (float64(i) + 0.5) / float64(n)
+
+		frame, err := extractVideoFrame(filepath, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// extractVideoFrame extracts the frame at timestamp, in seconds, from a given video.
+func extractVideoFrame(filepath string, timestamp float64) (image.Image, error) {
+	outputPath := os.TempDir() + "/moonpool_frame_" + randomString(6) + ".jpg"
+
+	var ffmpegLog strings.Builder
+	input := ffmpeg_go.Input(filepath, ffmpeg_go.KwArgs{
+		"ss": strconv.FormatFloat(timestamp, 'f', 3, 64),
+	}).Output(outputPath, ffmpeg_go.KwArgs{
+		"frames:v": 1,
+		"update":   "true",
+	}).WithOutput(&ffmpegLog).ErrorToStdOut().WithErrorOutput(&ffmpegLog).Silent(true)
+
+	err := input.Run()
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+	defer os.Remove(outputPath)
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+	defer f.Close()
+
+	i, _, err := image.Decode(f)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+
+	return i, nil
+}
+
+// VideoFingerprint returns a file.VHash from the PHash of every frame given, such as those returned by
+// ExtractVideoFrames.
+func VideoFingerprint(frames []image.Image) ([]uint64, error) {
+	fingerprint := make([]uint64, 0, len(frames))
+	for _, frame := range frames {
+		h, err := file.GetPerceptualHash(frame)
+		if err != nil {
+			return nil, err
+		}
+		fingerprint = append(fingerprint, h.Hash)
+	}
+	return fingerprint, nil
+}
+
+// calculateAspectRatioFit returns the largest resolution of the same aspect ratio as width and height that fits
+// in a box of box by box, without scaling up. Neither side is scaled below a single pixel.
+func calculateAspectRatioFit(width, height, box int64) [2]int64 {
+	if width <= box && height <= box {
+		return [2]int64{width, height}
+	}
+
+	if width >= height {
+		return [2]int64{box, max(height*box/width, 1)}
 	}
+	return [2]int64{max(width*box/height, 1), box}
 }
 
 func unmarshalFFmpeg(b []byte) ([]ffmpegMetadata, error) {
@@ -1,10 +1,12 @@
 package media
 
 import (
+	"image"
 	_ "image/gif"
 	_ "image/png"
 	"io"
 	"os"
+	"os/exec"
 	"reflect"
 	"testing"
 
@@ -56,6 +58,8 @@ func TestGetDimensions(t *testing.T) {
 }
 
 func TestGetOrientation(t *testing.T) {
+	requireFFmpeg(t)
+
 	fileLandscape, err := os.Open("testdata/6ba11adbdb35ee10f9353608a7b97ef248733a72.jpg")
 	if err != nil {
 		t.Fatalf("failed to open test file, %v", err)
@@ -109,6 +113,8 @@ func TestGetOrientation(t *testing.T) {
 }
 
 func Test_GenerateVideoThumbnail(t *testing.T) {
+	requireFFmpeg(t)
+
 	type args struct {
 		filepath string
 	}
@@ -143,7 +149,71 @@ func Test_GenerateVideoThumbnail(t *testing.T) {
 	}
 }
 
+func Test_ExtractVideoFrames(t *testing.T) {
+	requireFFmpeg(t)
+
+	tests := []struct {
+		name     string
+		filepath string
+		n        int
+		wantErr  bool
+	}{
+		{"single frame", "testdata/testsrc.mp4", 1, false},
+		{"many frames", "testdata/testsrc.mp4", DefaultVideoFrames, false},
+		{"no frames", "testdata/testsrc.mp4", 0, true},
+		{"missing file", "testdata/doesnotexist.mp4", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractVideoFrames(tt.filepath, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExtractVideoFrames() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && len(got) != tt.n {
+				t.Errorf("ExtractVideoFrames() returned %d frames, want %d", len(got), tt.n)
+			}
+		})
+	}
+}
+
+func Test_VideoFingerprint(t *testing.T) {
+	requireFFmpeg(t)
+
+	frames, err := ExtractVideoFrames("testdata/testsrc.mp4", DefaultVideoFrames)
+	if err != nil {
+		t.Fatalf("failed to extract video frames. %v", err)
+	}
+
+	full, err := VideoFingerprint(frames)
+	if err != nil {
+		t.Fatalf("VideoFingerprint() error = %v", err)
+	}
+
+	if len(full) != DefaultVideoFrames {
+		t.Errorf("VideoFingerprint() returned %d hashes, want %d", len(full), DefaultVideoFrames)
+	}
+
+	// a sparser sampling of the same video should still be a near-duplicate of it
+	frames, err = ExtractVideoFrames("testdata/testsrc.mp4", DefaultVideoFrames/4)
+	if err != nil {
+		t.Fatalf("failed to extract video frames. %v", err)
+	}
+
+	sparse, err := VideoFingerprint(frames)
+	if err != nil {
+		t.Fatalf("VideoFingerprint() error = %v", err)
+	}
+
+	if d := file.VideoHashDistance(full, sparse); d > 10 {
+		t.Errorf("VideoHashDistance() = %d between samplings of the same video, want <= 10", d)
+	}
+}
+
 func Test_unmarshalFFmpeg(t *testing.T) {
+	requireFFmpeg(t)
+
 	testVideo, err := os.Open("testdata/test_video_audio.mp4")
 	if err != nil {
 		t.Fatalf("failed to open test file, %v", err)
@@ -183,3 +253,102 @@ func Test_unmarshalFFmpeg(t *testing.T) {
 		})
 	}
 }
+
+func Test_isImageFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filepath string
+		want     bool
+	}{
+		{"image", "testdata/6ba11adbdb35ee10f9353608a7b97ef248733a72.jpg", true},
+		{"video", "testdata/testsrc.mp4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.filepath)
+			if err != nil {
+				t.Fatalf("failed to open test file, %v", err)
+			}
+			defer f.Close()
+
+			if got := isImageFile(f); got != tt.want {
+				t.Errorf("isImageFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeImage_video(t *testing.T) {
+	f, err := os.Open("testdata/testsrc.mp4")
+	if err != nil {
+		t.Fatalf("failed to open test file, %v", err)
+	}
+	defer f.Close()
+
+	// videos must fail to decode, so that callers fall back to GenerateVideoThumbnail or ExtractVideoFrames
+	if _, err := DecodeImage(f); err == nil {
+		t.Errorf("DecodeImage() decoded a video")
+	}
+}
+
+func Test_calculateAspectRatioFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int64
+		box           int64
+		want          [2]int64
+	}{
+		{"landscape", 3000, 1993, 320, [2]int64{320, 212}},
+		{"portrait", 853, 1280, 320, [2]int64{213, 320}},
+		{"square", 1000, 1000, 320, [2]int64{320, 320}},
+		{"panorama", 12000, 1000, 1600, [2]int64{1600, 133}},
+		{"sliver", 12000, 2, 320, [2]int64{320, 1}},
+		{"smaller than box", 20, 20, 320, [2]int64{20, 20}},
+		{"fits box", 320, 100, 320, [2]int64{320, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateAspectRatioFit(tt.width, tt.height, tt.box); got != tt.want {
+				t.Errorf("calculateAspectRatioFit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateIcons(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		sizes         ThumbnailSizes
+		want          [3]image.Point
+	}{
+		{"default sizes", 4000, 2000, ThumbnailSizes{}, [3]image.Point{{320, 160}, {800, 400}, {1600, 800}}},
+		{"custom sizes", 2000, 4000, ThumbnailSizes{Small: 100, Medium: 200, Large: 400}, [3]image.Point{{50, 100}, {100, 200}, {200, 400}}},
+		{"tiny image", 16, 16, ThumbnailSizes{}, [3]image.Point{{16, 16}, {16, 16}, {16, 16}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var i image.Image = image.NewGray(image.Rect(0, 0, tt.width, tt.height))
+			icons, err := GenerateIcons(&i, tt.sizes)
+			if err != nil {
+				t.Fatalf("GenerateIcons() error = %v", err)
+			}
+
+			for n, icon := range []*image.Image{icons.Small, icons.Medium, icons.Large} {
+				if got := (*icon).Bounds().Size(); got != tt.want[n] {
+					t.Errorf("GenerateIcons() icon %d = %v, want %v", n, got, tt.want[n])
+				}
+			}
+		})
+	}
+}
+
+// requireFFmpeg skips a test if ffmpeg or ffprobe aren't installed.
+func requireFFmpeg(t *testing.T) {
+	t.Helper()
+	for _, bin := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not found in PATH", bin)
+		}
+	}
+}
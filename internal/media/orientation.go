@@ -0,0 +1,71 @@
+package media
+
+import (
+	"image"
+	"image/draw"
+)
+
+// EXIF orientations, describing how a stored picture has to be transformed to be displayed upright.
+const (
+	OrientationNormal     = 1
+	OrientationFlipH      = 2
+	OrientationRotate180  = 3
+	OrientationFlipV      = 4
+	OrientationTranspose  = 5
+	OrientationRotate90   = 6
+	OrientationTransverse = 7
+	OrientationRotate270  = 8
+)
+
+// ApplyOrientation transforms an image according to an EXIF orientation, so that it is displayed upright.
+// An unknown orientation returns the image as is.
+func ApplyOrientation(i image.Image, orientation int64) image.Image {
+	if validOrientation(orientation) == 0 || orientation == OrientationNormal {
+		return i
+	}
+
+	b := i.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), i, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	dw, dh := OrientDimensions(int64(w), int64(h), orientation)
+	dst := image.NewNRGBA(image.Rect(0, 0, int(dw), int(dh)))
+
+	// source returns which pixel of the stored picture ends up at x, y of the upright picture
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case OrientationFlipH:
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case OrientationRotate180:
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case OrientationFlipV:
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case OrientationTranspose:
+		source = func(x, y int) (int, int) { return y, x }
+	case OrientationRotate90:
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case OrientationTransverse:
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case OrientationRotate270:
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+
+	for y := 0; y < int(dh); y++ {
+		for x := 0; x < int(dw); x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
+
+// OrientDimensions returns the width and height of a picture once displayed with an EXIF orientation.
+// Orientations 5 through 8 rotate the picture by 90 degrees, swapping its width and height.
+func OrientDimensions(width, height, orientation int64) (int64, int64) {
+	if orientation >= OrientationTranspose && orientation <= OrientationRotate270 {
+		return height, width
+	}
+	return width, height
+}
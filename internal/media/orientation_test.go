@@ -0,0 +1,75 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyOrientation(t *testing.T) {
+	// a 3x2 picture, each pixel holding its own index
+	// 0 1 2
+	// 3 4 5
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+
+	tests := []struct {
+		name        string
+		orientation int64
+		want        [][]uint8
+	}{
+		{"unknown", 0, [][]uint8{{0, 1, 2}, {3, 4, 5}}},
+		{"normal", OrientationNormal, [][]uint8{{0, 1, 2}, {3, 4, 5}}},
+		{"flip horizontal", OrientationFlipH, [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{"rotate 180", OrientationRotate180, [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{"flip vertical", OrientationFlipV, [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{"transpose", OrientationTranspose, [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{"rotate 90", OrientationRotate90, [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{"transverse", OrientationTransverse, [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{"rotate 270", OrientationRotate270, [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ApplyOrientation(src, tt.orientation)
+
+			b := got.Bounds()
+			if b.Dx() != len(tt.want[0]) || b.Dy() != len(tt.want) {
+				t.Fatalf("ApplyOrientation() size = %dx%d, want %dx%d", b.Dx(), b.Dy(), len(tt.want[0]), len(tt.want))
+			}
+
+			for y, row := range tt.want {
+				for x, want := range row {
+					if c := color.GrayModel.Convert(got.At(b.Min.X+x, b.Min.Y+y)).(color.Gray); c.Y != want {
+						t.Errorf("ApplyOrientation() pixel at %d,%d = %d, want %d", x, y, c.Y, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOrientDimensions(t *testing.T) {
+	tests := []struct {
+		name                  string
+		orientation           int64
+		wantWidth, wantHeight int64
+	}{
+		{"unknown", 0, 300, 200},
+		{"normal", OrientationNormal, 300, 200},
+		{"rotate 180", OrientationRotate180, 300, 200},
+		{"transpose", OrientationTranspose, 200, 300},
+		{"rotate 90", OrientationRotate90, 200, 300},
+		{"rotate 270", OrientationRotate270, 200, 300},
+		{"invalid", 9, 300, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := OrientDimensions(300, 200, tt.orientation)
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("OrientDimensions() = %d, %d, want %d, %d", width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
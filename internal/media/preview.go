@@ -0,0 +1,161 @@
+package media
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// PreviewDuration is the length, in seconds, of an animated preview, and PreviewFramerate its frames per second.
+const (
+	PreviewDuration  = 3
+	PreviewFramerate = 10
+)
+
+// PreviewMimetype is the format GeneratePreview renders previews in.
+const PreviewMimetype = "image/webp"
+
+// GeneratePreview renders a short, muted animated WebP of a video or an animated GIF via ffmpeg, scaled down to
+// fit in a box of box by box pixels. duration is the length of the media in seconds, media longer than
+// PreviewDuration is previewed from its middle.
+// It will create a temporary file at "%TMP%/moonpool_preview_xxxxxx.webp".
+// An error, as well as ffmpeg output will be wrapped in err
+func GeneratePreview(filepath string, duration float64, box int) ([]byte, error) {
+	if box <= 0 {
+		return nil, errors.New("invalid preview size")
+	}
+
+	outputPath := os.TempDir() + "/moonpool_preview_" + randomString(6) + ".webp"
+
+	var ffmpegLog strings.Builder
+	input := ffmpeg_go.Input(filepath, ffmpeg_go.KwArgs{
+		"ss": strconv.FormatFloat(previewStart(duration), 'f', 3, 64),
+	}).Output(outputPath, ffmpeg_go.KwArgs{
+		"t":       PreviewDuration,
+		"an":      "",
+		"vf":      previewFilter(box),
+		"c:v":     "libwebp",
+		"loop":    0,
+		"quality": 60,
+	}).WithOutput(&ffmpegLog).ErrorToStdOut().WithErrorOutput(&ffmpegLog).Silent(true)
+
+	err := input.Run()
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+	defer os.Remove(outputPath)
+
+	b, err := os.ReadFile(outputPath)
+	if err != nil {
+		return nil, errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+
+	return b, nil
+}
+
+// previewStart returns the timestamp, in seconds, a preview of media with a given duration starts at.
+func previewStart(duration float64) float64 {
+	if duration <= PreviewDuration {
+		return 0
+	}
+	return (duration - PreviewDuration) / 2
+}
+
+// previewFilter returns the ffmpeg filter that reduces the framerate of a preview and scales it down to fit
+// in a box, keeping its aspect ratio. Dimensions are kept even, which some encoders require.
+func previewFilter(box int) string {
+	b := strconv.Itoa(box)
+	return "fps=" + strconv.Itoa(PreviewFramerate) +
+		",scale=w='min(" + b + ",iw)':h='min(" + b + ",ih)':force_original_aspect_ratio=decrease:force_divisible_by=2"
+}
+
+// IsAnimatedGif reports whether a GIF has more than one frame. Frames are counted without being decoded.
+func IsAnimatedGif(r io.Reader) (bool, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return false, err
+	}
+	if string(header[:6]) != "GIF87a" && string(header[:6]) != "GIF89a" {
+		return false, errors.New("not a gif")
+	}
+
+	// the global color table follows the logical screen descriptor
+	if err := skipGifColorTable(br, header[10]); err != nil {
+		return false, err
+	}
+
+	frames := 0
+	for {
+		block, err := br.ReadByte()
+		if err != nil {
+			return false, err
+		}
+
+		switch block {
+		case 0x21:
+			// extension, its label followed by data sub-blocks
+			if _, err := br.ReadByte(); err != nil {
+				return false, err
+			}
+		case 0x2c:
+			frames++
+			if frames > 1 {
+				return true, nil
+			}
+
+			descriptor := make([]byte, 9)
+			if _, err := io.ReadFull(br, descriptor); err != nil {
+				return false, err
+			}
+			if err := skipGifColorTable(br, descriptor[8]); err != nil {
+				return false, err
+			}
+
+			// LZW minimum code size, followed by the image data sub-blocks
+			if _, err := br.ReadByte(); err != nil {
+				return false, err
+			}
+		case 0x3b:
+			return false, nil
+		default:
+			return false, errors.New("invalid gif block")
+		}
+
+		if err := skipGifSubBlocks(br); err != nil {
+			return false, err
+		}
+	}
+}
+
+// skipGifColorTable skips the color table a packed field of a descriptor declares, if any.
+func skipGifColorTable(r *bufio.Reader, packed byte) error {
+	if packed&0x80 == 0 {
+		return nil
+	}
+
+	_, err := r.Discard(3 << ((packed & 0x07) + 1))
+	return err
+}
+
+func skipGifSubBlocks(r *bufio.Reader) error {
+	for {
+		size, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+
+		if _, err := r.Discard(int(size)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+func newTestGif(t *testing.T, frames int) []byte {
+	g := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 8, 8), palette.Plan9))
+		g.Delay = append(g.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestIsAnimatedGif(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       []byte
+		want    bool
+		wantErr bool
+	}{
+		{"single frame", newTestGif(t, 1), false, false},
+		{"animated", newTestGif(t, 3), true, false},
+		{"not a gif", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x00\x00"), false, true},
+		{"truncated", newTestGif(t, 1)[:20], false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsAnimatedGif(bytes.NewReader(tt.b))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsAnimatedGif() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsAnimatedGif() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_previewStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		want     float64
+	}{
+		{"unknown", 0, 0},
+		{"shorter than preview", 1.5, 0},
+		{"as long as preview", PreviewDuration, 0},
+		{"long", 63, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previewStart(tt.duration); got != tt.want {
+				t.Errorf("previewStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
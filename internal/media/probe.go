@@ -0,0 +1,106 @@
+package media
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// StreamMetadata is the metadata of the streams of a video or audio file. Duration is in seconds, Framerate
+// in frames per second and Bitrate in bits per second. Cover art embedded in a file is not considered a video
+// stream, but sets HasCover instead.
+type StreamMetadata struct {
+	Duration               float64
+	Framerate              float64
+	VideoCodec, AudioCodec string
+	Bitrate                int64
+	HasAudio, HasCover     bool
+}
+
+type ffprobeStreams struct {
+	Streams []struct {
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		Disposition  struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		BitRate  string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// ProbeStreams returns the duration, framerate, codecs and bitrate of a given video or audio file via ffprobe.
+// Only the first video and audio stream are considered.
+func ProbeStreams(filepath string) (StreamMetadata, error) {
+	j, err := ffmpeg_go.Probe(filepath)
+	if err != nil {
+		return StreamMetadata{}, err
+	}
+
+	return unmarshalStreams([]byte(j))
+}
+
+func unmarshalStreams(b []byte) (StreamMetadata, error) {
+	var probe ffprobeStreams
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return StreamMetadata{}, err
+	}
+
+	var m StreamMetadata
+	for _, s := range probe.Streams {
+		switch {
+		case s.CodecType == "video" && s.Disposition.AttachedPic == 1:
+			m.HasCover = true
+		case s.CodecType == "video" && m.VideoCodec == "":
+			m.VideoCodec = s.CodecName
+			m.Framerate = parseFramerate(s.AvgFrameRate)
+		case s.CodecType == "audio" && m.AudioCodec == "":
+			m.AudioCodec = s.CodecName
+			m.HasAudio = true
+		}
+	}
+
+	if probe.Format.Duration != "" {
+		d, err := strconv.ParseFloat(probe.Format.Duration, 64)
+		if err != nil {
+			return StreamMetadata{}, err
+		}
+		m.Duration = d
+	}
+
+	if probe.Format.BitRate != "" {
+		b, err := strconv.ParseInt(probe.Format.BitRate, 10, 64)
+		if err != nil {
+			return StreamMetadata{}, err
+		}
+		m.Bitrate = b
+	}
+
+	return m, nil
+}
+
+// parseFramerate parses a framerate as given by ffprobe, such as "30000/1001". It returns 0 if the framerate
+// is unknown, which ffprobe reports as "0/0".
+func parseFramerate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		den = "1"
+	}
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+
+	return n / d
+}
@@ -0,0 +1,64 @@
+package media
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_unmarshalStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       string
+		want    StreamMetadata
+		wantErr bool
+	}{
+		{"video", `{"streams": [{"codec_type": "video", "codec_name": "h264", "avg_frame_rate": "30000/1001"},
+			{"codec_type": "audio", "codec_name": "aac", "avg_frame_rate": "0/0"}],
+			"format": {"duration": "5.000000", "bit_rate": "1500000"}}`,
+			StreamMetadata{Duration: 5, Framerate: 30000.0 / 1001, VideoCodec: "h264", AudioCodec: "aac", Bitrate: 1500000, HasAudio: true}, false},
+		{"silent video", `{"streams": [{"codec_type": "video", "codec_name": "vp9", "avg_frame_rate": "60/1"}],
+			"format": {"duration": "2.5"}}`,
+			StreamMetadata{Duration: 2.5, Framerate: 60, VideoCodec: "vp9"}, false},
+		{"mp3", `{"streams": [{"codec_type": "audio", "codec_name": "mp3", "disposition": {"attached_pic": 0}}],
+			"format": {"duration": "215.510204", "bit_rate": "320000"}}`,
+			StreamMetadata{Duration: 215.510204, AudioCodec: "mp3", Bitrate: 320000, HasAudio: true}, false},
+		{"cover art", `{"streams": [{"codec_type": "audio", "codec_name": "flac"},
+			{"codec_type": "video", "codec_name": "mjpeg", "avg_frame_rate": "0/0", "disposition": {"attached_pic": 1}}],
+			"format": {"duration": "10.000000", "bit_rate": "912345"}}`,
+			StreamMetadata{Duration: 10, AudioCodec: "flac", Bitrate: 912345, HasAudio: true, HasCover: true}, false},
+		{"no streams", `{"streams": [], "format": {}}`, StreamMetadata{}, false},
+		{"invalid bitrate", `{"streams": [{"codec_type": "audio", "codec_name": "opus"}], "format": {"bit_rate": "N/A"}}`, StreamMetadata{}, true},
+		{"invalid json", `{"streams": [`, StreamMetadata{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unmarshalStreams([]byte(tt.b))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unmarshalStreams() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unmarshalStreams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseFramerate(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"30/1", 30},
+		{"24000/1001", 24000.0 / 1001},
+		{"25", 25},
+		{"0/0", 0},
+		{"", 0},
+		{"foo/1", 0},
+	}
+	for _, tt := range tests {
+		if got := parseFramerate(tt.s); got != tt.want {
+			t.Errorf("parseFramerate(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
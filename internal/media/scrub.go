@@ -0,0 +1,344 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"slices"
+)
+
+// scrubbedPngChunks are PNG chunks that may hold metadata, such as the device or software that created it.
+var scrubbedPngChunks = []string{"eXIf", "iTXt", "tEXt", "zTXt", "tIME"}
+
+// CanScrubMetadata reports whether ScrubMetadata is able to remove the metadata of a given mimetype.
+func CanScrubMetadata(mimetype string) bool {
+	return slices.Contains([]string{"image/jpeg", "image/png", "image/webp"}, mimetype)
+}
+
+// ScrubMetadata copies a JPEG, PNG or WebP from r to w without its EXIF, XMP, IPTC and text metadata, which
+// may hold the location a photo was taken at and the device it was taken with. Only the EXIF orientation is
+// kept, so the image is still displayed upright. Any other format is copied as is.
+func ScrubMetadata(w io.Writer, r io.Reader) error {
+	if r == nil {
+		return errors.New("given nil media")
+	}
+
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(12)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("\xff\xd8")):
+		return scrubJpeg(w, br)
+	case bytes.HasPrefix(magic, []byte("\x89PNG\r\n\x1a\n")):
+		return scrubPng(w, br)
+	case len(magic) >= 12 && string(magic[:4]) == "RIFF" && string(magic[8:12]) == "WEBP":
+		return scrubWebp(w, br)
+	default:
+		_, err := io.Copy(w, br)
+		return err
+	}
+}
+
+// scrubJpeg copies a JPEG without its APP1 (EXIF and XMP), APP13 (IPTC), comment and MPF segments. Nothing
+// after the end of the primary image is copied, since the embedded images of an MPO or the video of a motion
+// photo carry metadata of their own.
+func scrubJpeg(w io.Writer, r *bufio.Reader) error {
+	if _, err := io.CopyN(w, r, 2); err != nil {
+		return err
+	}
+
+	var kind byte
+	scanned := false
+	for {
+		if !scanned {
+			marker, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if marker != 0xff {
+				return errors.New("invalid jpeg marker")
+			}
+
+			kind, err = r.ReadByte()
+			if err != nil {
+				return err
+			}
+		}
+		scanned = false
+
+		switch {
+		case kind == 0xff:
+			// fill byte before a marker
+			r.UnreadByte()
+			continue
+		case kind == 0xd8 || kind == 0x01 || (kind >= 0xd0 && kind <= 0xd7):
+			// markers without a segment
+			if _, err := w.Write([]byte{0xff, kind}); err != nil {
+				return err
+			}
+			continue
+		case kind == 0xd9:
+			_, err := w.Write([]byte{0xff, kind})
+			return err
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return err
+		}
+		if length < 2 {
+			return errors.New("invalid jpeg segment length")
+		}
+
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return err
+		}
+
+		switch {
+		case kind == 0xda:
+			if err := writeJpegSegment(w, kind, segment); err != nil {
+				return err
+			}
+
+			var err error
+			kind, err = copyJpegScan(w, r)
+			if errors.Is(err, io.EOF) {
+				// a truncated image is copied as far as it goes
+				return nil
+			} else if err != nil {
+				return err
+			}
+			scanned = true
+			continue
+		case kind == 0xe1:
+			if b, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00")); ok {
+				if exif := scrubbedExif(b); exif != nil {
+					if err := writeJpegSegment(w, kind, append([]byte("Exif\x00\x00"), exif...)); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		case kind == 0xe2 && bytes.HasPrefix(segment, []byte("MPF\x00")):
+			// the offsets of the embedded images, which aren't copied
+			continue
+		case kind == 0xed || kind == 0xfe:
+			continue
+		}
+
+		if err := writeJpegSegment(w, kind, segment); err != nil {
+			return err
+		}
+	}
+}
+
+// copyJpegScan copies the entropy-coded data following a start of scan, returning the marker that ends it.
+func copyJpegScan(w io.Writer, r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadSlice(0xff)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			if _, err := w.Write(b); err != nil {
+				return 0, err
+			}
+			continue
+		} else if err != nil {
+			if _, werr := w.Write(b); werr != nil {
+				return 0, werr
+			}
+			return 0, err
+		}
+
+		if _, err := w.Write(b[:len(b)-1]); err != nil {
+			return 0, err
+		}
+
+		kind, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case kind == 0xff:
+			// fill byte before a marker
+			r.UnreadByte()
+		case kind == 0x00 || (kind >= 0xd0 && kind <= 0xd7):
+			// a stuffed 0xff byte or a restart marker, both part of the scan
+			if _, err := w.Write([]byte{0xff, kind}); err != nil {
+				return 0, err
+			}
+		default:
+			return kind, nil
+		}
+	}
+}
+
+func writeJpegSegment(w io.Writer, kind byte, segment []byte) error {
+	header := []byte{0xff, kind, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(segment)
+	return err
+}
+
+// scrubPng copies a PNG without its eXIf and text chunks.
+func scrubPng(w io.Writer, r *bufio.Reader) error {
+	if _, err := io.CopyN(w, r, 8); err != nil {
+		return err
+	}
+
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+		length, chunk := binary.BigEndian.Uint32(header[:4]), string(header[4:])
+
+		if !slices.Contains(scrubbedPngChunks, chunk) {
+			if _, err := w.Write(header); err != nil {
+				return err
+			}
+
+			// data and CRC
+			if _, err := io.CopyN(w, r, int64(length)+4); err != nil {
+				return err
+			}
+
+			if chunk == "IEND" {
+				return nil
+			}
+			continue
+		}
+
+		if chunk != "eXIf" || length > maxMetadataSize {
+			if _, err := r.Discard(int(length) + 4); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data := make([]byte, length+4)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+
+		if exif := scrubbedExif(data[:length]); exif != nil {
+			if err := writePngChunk(w, chunk, exif); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writePngChunk(w io.Writer, chunk string, data []byte) error {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	b = append(b, chunk...)
+	b = append(b, data...)
+	b = binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b[4:]))
+
+	_, err := w.Write(b)
+	return err
+}
+
+// scrubWebp copies a WebP without its EXIF and XMP chunks. The file is read into memory, since the size of
+// the RIFF container precedes its chunks.
+func scrubWebp(w io.Writer, r *bufio.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(b) < 12 {
+		return errors.New("webp is too short")
+	}
+
+	type chunk struct {
+		kind string
+		data []byte
+	}
+
+	var chunks []chunk
+	var exif []byte
+	extended := false
+	for i := 12; i+8 <= len(b); {
+		kind, length := string(b[i:i+4]), int64(binary.LittleEndian.Uint32(b[i+4:i+8]))
+		end := int64(i) + 8 + length
+		if end > int64(len(b)) {
+			return errors.New("invalid webp chunk length")
+		}
+		data := b[i+8 : end]
+
+		// chunks are padded to an even size
+		i = int(end + length&1)
+
+		switch kind {
+		case "EXIF":
+			exif = scrubbedExif(bytes.TrimPrefix(data, []byte("Exif\x00\x00")))
+		case "XMP ":
+		case "VP8X":
+			extended = true
+			chunks = append(chunks, chunk{kind, bytes.Clone(data)})
+		default:
+			chunks = append(chunks, chunk{kind, data})
+		}
+	}
+
+	// EXIF chunks are only valid in the extended format, whose flags tell whether they are present
+	if !extended {
+		exif = nil
+	}
+	if exif != nil {
+		chunks = append(chunks, chunk{"EXIF", exif})
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF\x00\x00\x00\x00WEBP")
+	for _, c := range chunks {
+		if c.kind == "VP8X" && len(c.data) > 0 {
+			// the EXIF and XMP metadata flags
+			c.data[0] &^= 0x0c
+			if exif != nil {
+				c.data[0] |= 0x08
+			}
+		}
+
+		out.WriteString(c.kind)
+		out.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(c.data))))
+		out.Write(c.data)
+		if len(c.data)&1 == 1 {
+			out.WriteByte(0)
+		}
+	}
+
+	riff := out.Bytes()
+	binary.LittleEndian.PutUint32(riff[4:8], uint32(len(riff)-8))
+
+	_, err = w.Write(riff)
+	return err
+}
+
+// scrubbedExif returns EXIF data, beginning with its TIFF header, that holds nothing but the orientation of
+// the original. It returns nil if the original has no orientation, or is displayed as is.
+func scrubbedExif(original []byte) []byte {
+	e, err := parseExif(original)
+	if err != nil || e.Orientation <= OrientationNormal {
+		return nil
+	}
+
+	// a big-endian TIFF header followed by IFD0, holding a single SHORT tag and no next IFD
+	b := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	b = binary.BigEndian.AppendUint16(b, tagOrientation)
+	b = binary.BigEndian.AppendUint16(b, 3)
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint16(b, uint16(e.Orientation))
+	b = append(b, 0, 0)
+	return binary.BigEndian.AppendUint32(b, 0)
+}
@@ -0,0 +1,137 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/dtbead/moonpool/entry"
+)
+
+// newTestExtendedWebp returns a WebP of the extended format holding an EXIF and an XMP chunk.
+func newTestExtendedWebp(exif []byte, xmp string) []byte {
+	chunk := func(kind string, data []byte) []byte {
+		b := append([]byte(kind), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		b = append(b, data...)
+		if len(data)%2 == 1 {
+			b = append(b, 0)
+		}
+		return b
+	}
+
+	// EXIF and XMP flags, followed by a reserved field and the canvas size of 1x1
+	vp8x := chunk("VP8X", []byte{0x0c, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	vp8l := chunk("VP8L", []byte("\x2f\x00\x00\x00\x00\x00"))
+
+	body := append([]byte("WEBP"), vp8x...)
+	body = append(body, vp8l...)
+	body = append(body, chunk("EXIF", exif)...)
+	body = append(body, chunk("XMP ", []byte(xmp))...)
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+func TestScrubMetadata(t *testing.T) {
+	jpeg := newTestJpeg(t, append([]byte("Exif\x00\x00"), newTestExif()...), append([]byte("http://ns.adobe.com/xap/1.0/\x00"), testXMP...))
+	// a comment and an IPTC segment, which are scrubbed as well
+	jpeg = append([]byte("\xff\xd8\xff\xfe\x00\x0ahunter2\x00\xff\xed\x00\x06IPTC"), jpeg[2:]...)
+
+	tests := []struct {
+		name       string
+		b          []byte
+		wantExif   entry.Exif
+		wantErr    error
+		wantDecode bool
+	}{
+		{"jpeg", jpeg, entry.Exif{Orientation: 6}, nil, true},
+		{"jpeg xmp", newTestJpeg(t, append([]byte("http://ns.adobe.com/xap/1.0/\x00"), testXMP...)), entry.Exif{}, ErrNoExif, true},
+		{"jpeg without exif", newTestJpeg(t), entry.Exif{}, ErrNoExif, true},
+		{"png", newTestPng(t, newTestExif()), entry.Exif{Orientation: 6}, nil, true},
+		{"png without exif", newTestPng(t, nil), entry.Exif{}, ErrNoExif, true},
+		{"webp", newTestExtendedWebp(newTestExif(), testXMP), entry.Exif{Orientation: 6}, nil, false},
+		{"webp xmp", newTestWebp(testXMP), entry.Exif{}, ErrNoExif, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ScrubMetadata(&buf, bytes.NewReader(tt.b)); err != nil {
+				t.Fatalf("ScrubMetadata() error = %v", err)
+			}
+			got := buf.Bytes()
+
+			for _, leaked := range []string{"Canon", "FUJIFILM", "hunter2", "IPTC"} {
+				if bytes.Contains(got, []byte(leaked)) {
+					t.Errorf("ScrubMetadata() kept %q", leaked)
+				}
+			}
+
+			e, err := ReadExif(bytes.NewReader(got))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadExif() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if e != tt.wantExif {
+				t.Errorf("ReadExif() = %+v, want %+v", e, tt.wantExif)
+			}
+
+			if tt.wantDecode {
+				if _, _, err := image.Decode(bytes.NewReader(got)); err != nil {
+					t.Errorf("ScrubMetadata() returned an undecodable image, %v", err)
+				}
+			}
+
+			if tt.name == "webp" {
+				if size := binary.LittleEndian.Uint32(got[4:8]); int(size) != len(got)-8 {
+					t.Errorf("ScrubMetadata() RIFF size = %d, want %d", size, len(got)-8)
+				}
+				if flags := got[20]; flags != 0x08 {
+					t.Errorf("ScrubMetadata() VP8X flags = %#x, want %#x", flags, 0x08)
+				}
+			}
+		})
+	}
+}
+
+func TestScrubMetadata_trailingImage(t *testing.T) {
+	exif := append([]byte("Exif\x00\x00"), newTestExif()...)
+	primary := newTestJpeg(t, exif)
+	// an MPF segment pointing at the embedded image, as written by cameras and phones
+	primary = append([]byte("\xff\xd8\xff\xe2\x00\x0aMPF\x00MM\x00\x2a"), primary[2:]...)
+	b := append(primary, newTestJpeg(t, exif)...)
+
+	var buf bytes.Buffer
+	if err := ScrubMetadata(&buf, bytes.NewReader(b)); err != nil {
+		t.Fatalf("ScrubMetadata() error = %v", err)
+	}
+	got := buf.Bytes()
+
+	eoi := bytes.Index(got, []byte("\xff\xd9"))
+	if eoi < 0 {
+		t.Fatalf("ScrubMetadata() returned a jpeg without an end of image")
+	}
+	if bytes.Contains(got[eoi:], []byte("Exif\x00\x00")) {
+		t.Errorf("ScrubMetadata() kept the exif of the embedded image")
+	}
+	if len(got) != eoi+2 {
+		t.Errorf("ScrubMetadata() kept %d bytes after the end of the primary image", len(got)-eoi-2)
+	}
+	if bytes.Contains(got, []byte("MPF\x00")) {
+		t.Errorf("ScrubMetadata() kept the MPF segment")
+	}
+
+	if _, _, err := image.Decode(bytes.NewReader(got)); err != nil {
+		t.Errorf("ScrubMetadata() returned an undecodable image, %v", err)
+	}
+}
+
+func TestScrubMetadata_unsupported(t *testing.T) {
+	b := []byte("GIF89a\x01\x00\x01\x00")
+
+	var buf bytes.Buffer
+	if err := ScrubMetadata(&buf, bytes.NewReader(b)); err != nil {
+		t.Fatalf("ScrubMetadata() error = %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("ScrubMetadata() = %q, want %q", buf.Bytes(), b)
+	}
+}
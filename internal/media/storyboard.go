@@ -0,0 +1,123 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+)
+
+// DefaultStoryboardFrames is the amount of frames in a storyboard, StoryboardColumns the amount of frames in
+// each row of its sprite sheet and StoryboardTileSize the largest width and height, in pixels, of each frame.
+const (
+	DefaultStoryboardFrames = 25
+	StoryboardColumns       = 5
+	StoryboardTileSize      = 160
+)
+
+// Storyboard is a JPEG sprite sheet of frames of a video, and a WebVTT thumbnails track mapping each span of
+// the video to its frame in the sprite sheet.
+type Storyboard struct {
+	Sprite []byte
+	VTT    string
+}
+
+// GenerateStoryboard extracts n evenly-spaced frames from a given video via ffmpeg and tiles them into a
+// sprite sheet, alongside a WebVTT track with a cue for each frame. Cues reference their frame as a media
+// fragment of spriteURL, such as "sprite.jpg#xywh=160,0,160,90", which may be relative to the track itself.
+// Each frame is temporarily written to "%TMP%/moonpool_frame_xxxxxx.jpg".
+// An error, as well as ffmpeg output will be wrapped in err
+func GenerateStoryboard(filepath string, n int, spriteURL string) (Storyboard, error) {
+	if n <= 0 {
+		return Storyboard{}, errors.New("amount of frames must be greater than 0")
+	}
+
+	m, err := ProbeStreams(filepath)
+	if err != nil {
+		return Storyboard{}, err
+	}
+	if m.Duration <= 0 {
+		return Storyboard{}, errors.New("video has no duration")
+	}
+
+	// each frame is taken from the middle of the span of its cue
+	frames := make([]image.Image, 0, n)
+	for i := 0; i < n; i++ {
+		frame, err := extractVideoFrame(filepath, m.Duration*(float64(i)+0.5)/float64(n))
+		if err != nil {
+			return Storyboard{}, err
+		}
+		frames = append(frames, frame)
+	}
+
+	sprite, tile, err := composeSprite(frames, StoryboardColumns, StoryboardTileSize)
+	if err != nil {
+		return Storyboard{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeJpeg(&sprite, &buf); err != nil {
+		return Storyboard{}, err
+	}
+
+	return Storyboard{
+		Sprite: buf.Bytes(),
+		VTT:    storyboardVTT(m.Duration, n, StoryboardColumns, tile, spriteURL),
+	}, nil
+}
+
+// composeSprite scales frames down to fit in a box of box by box pixels and tiles them into rows of columns
+// frames, in order. Every tile is the size of the first frame, which is also returned.
+func composeSprite(frames []image.Image, columns, box int) (image.Image, image.Point, error) {
+	if len(frames) == 0 {
+		return nil, image.Point{}, errors.New("no frames given")
+	}
+	if columns <= 0 {
+		return nil, image.Point{}, errors.New("amount of columns must be greater than 0")
+	}
+
+	scaled := make([]image.Image, len(frames))
+	for i, frame := range frames {
+		s, err := RescaleImage(frame, box)
+		if err != nil {
+			return nil, image.Point{}, err
+		}
+		scaled[i] = *s
+	}
+
+	tile := scaled[0].Bounds().Size()
+	columns = min(columns, len(scaled))
+	rows := (len(scaled) + columns - 1) / columns
+
+	sprite := image.NewRGBA(image.Rect(0, 0, columns*tile.X, rows*tile.Y))
+	for i, frame := range scaled {
+		at := image.Pt(i%columns*tile.X, i/columns*tile.Y)
+		draw.Draw(sprite, image.Rectangle{Min: at, Max: at.Add(tile)}, frame, frame.Bounds().Min, draw.Src)
+	}
+
+	return sprite, tile, nil
+}
+
+// storyboardVTT returns a WebVTT track splitting duration seconds into n equal cues, each referencing its tile
+// of a sprite sheet laid out by composeSprite.
+func storyboardVTT(duration float64, n, columns int, tile image.Point, spriteURL string) string {
+	columns = min(columns, n)
+
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(duration*float64(i)/float64(n)), vttTimestamp(duration*float64(i+1)/float64(n)),
+			spriteURL, i%columns*tile.X, i/columns*tile.Y, tile.X, tile.Y)
+	}
+
+	return vtt.String()
+}
+
+// vttTimestamp formats seconds as a WebVTT timestamp, such as "01:02:03.450".
+func vttTimestamp(seconds float64) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,93 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func Test_composeSprite(t *testing.T) {
+	newFrame := func(c color.Gray) image.Image {
+		i := image.NewGray(image.Rect(0, 0, 40, 20))
+		for x := 0; x < 40; x++ {
+			for y := 0; y < 20; y++ {
+				i.SetGray(x, y, c)
+			}
+		}
+		return i
+	}
+
+	frames := []image.Image{newFrame(color.Gray{Y: 0}), newFrame(color.Gray{Y: 100}), newFrame(color.Gray{Y: 200})}
+
+	tests := []struct {
+		name     string
+		columns  int
+		wantSize image.Point
+		// wantAt is the top-left pixel of the last frame
+		wantAt image.Point
+	}{
+		{"single row", 5, image.Pt(60, 10), image.Pt(40, 0)},
+		{"two rows", 2, image.Pt(40, 20), image.Pt(0, 10)},
+		{"single column", 1, image.Pt(20, 30), image.Pt(0, 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sprite, tile, err := composeSprite(frames, tt.columns, 20)
+			if err != nil {
+				t.Fatalf("composeSprite() error = %v", err)
+			}
+			if tile != image.Pt(20, 10) {
+				t.Errorf("composeSprite() tile = %v, want %v", tile, image.Pt(20, 10))
+			}
+			if got := sprite.Bounds().Size(); got != tt.wantSize {
+				t.Errorf("composeSprite() size = %v, want %v", got, tt.wantSize)
+			}
+
+			got := color.GrayModel.Convert(sprite.At(tt.wantAt.X, tt.wantAt.Y)).(color.Gray)
+			if got.Y < 190 {
+				t.Errorf("composeSprite() last frame at %v = %v, want %v", tt.wantAt, got.Y, 200)
+			}
+		})
+	}
+
+	if _, _, err := composeSprite(nil, 5, 20); err == nil {
+		t.Error("composeSprite() without frames error = nil, want error")
+	}
+}
+
+func Test_storyboardVTT(t *testing.T) {
+	want := `WEBVTT
+
+00:00:00.000 --> 00:00:01.500
+sprite#xywh=0,0,160,90
+
+00:00:01.500 --> 00:00:03.000
+sprite#xywh=160,0,160,90
+
+00:00:03.000 --> 00:00:04.500
+sprite#xywh=0,90,160,90
+`
+
+	if got := storyboardVTT(4.5, 3, 2, image.Pt(160, 90), "sprite"); got != want {
+		t.Errorf("storyboardVTT() = %q, want %q", got, want)
+	}
+}
+
+func Test_vttTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00.000"},
+		{1.5, "00:00:01.500"},
+		{3723.45, "01:02:03.450"},
+		{59.9999, "00:01:00.000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := vttTimestamp(tt.seconds); got != tt.want {
+				t.Errorf("vttTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package media
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	ffmpeg_go "github.com/u2takey/ffmpeg-go"
+)
+
+// TranscodeFormats are the web-playable formats videos can be transcoded into, mapped to their mimetype.
+// "mp4" is H.264 and AAC, and "webm" is VP9 and Opus.
+var TranscodeFormats = map[string]string{
+	"mp4":  "video/mp4",
+	"webm": "video/webm",
+}
+
+// transcodeArgs are the ffmpeg output arguments of each of TranscodeFormats.
+var transcodeArgs = map[string]ffmpeg_go.KwArgs{
+	"mp4": {
+		"f":        "mp4",
+		"c:v":      "libx264",
+		"preset":   "veryfast",
+		"crf":      23,
+		"c:a":      "aac",
+		"b:a":      "160k",
+		"movflags": "+faststart",
+	},
+	"webm": {
+		"f":      "webm",
+		"c:v":    "libvpx-vp9",
+		"crf":    32,
+		"b:v":    0,
+		"row-mt": 1,
+		"c:a":    "libopus",
+		"b:a":    "128k",
+	},
+}
+
+// webPlayableCodecs maps the mimetypes of containers web browsers generally play to the video and audio
+// codecs, as named by ffprobe, they play within them.
+var webPlayableCodecs = map[string]struct{ video, audio []string }{
+	"video/mp4":  {video: []string{"h264", "av1", "vp9"}, audio: []string{"aac", "mp3", "opus", "flac"}},
+	"video/webm": {video: []string{"vp8", "vp9", "av1"}, audio: []string{"vorbis", "opus"}},
+	"video/ogg":  {video: []string{"theora", "vp8"}, audio: []string{"vorbis", "opus", "flac"}},
+}
+
+// IsWebPlayable reports whether web browsers can generally play a video of a mimetype, encoded with codecs
+// as named by ffprobe. An empty audioCodec is a video without audio.
+func IsWebPlayable(mimetype, videoCodec, audioCodec string) bool {
+	codecs, ok := webPlayableCodecs[mimetype]
+	if !ok {
+		return false
+	}
+
+	if !slices.Contains(codecs.video, videoCodec) {
+		return false
+	}
+	return audioCodec == "" || slices.Contains(codecs.audio, audioCodec)
+}
+
+// Transcode re-encodes a video into one of TranscodeFormats at outputPath via ffmpeg, regardless of the
+// extension of outputPath. Subtitle and data streams are dropped, and dimensions are rounded down to be even,
+// which H.264 requires.
+// An error, as well as ffmpeg output will be wrapped in err
+func Transcode(inputPath, outputPath, format string) error {
+	args, ok := transcodeArgs[format]
+	if !ok {
+		return errors.New("invalid transcode format")
+	}
+
+	var ffmpegLog strings.Builder
+	err := ffmpeg_go.Input(inputPath).Output(outputPath, ffmpeg_go.MergeKwArgs([]ffmpeg_go.KwArgs{args, {
+		"vf":      "scale=trunc(iw/2)*2:trunc(ih/2)*2",
+		"pix_fmt": "yuv420p",
+		"sn":      "",
+		"dn":      "",
+	}})).OverWriteOutput().WithOutput(&ffmpegLog).ErrorToStdOut().WithErrorOutput(&ffmpegLog).Silent(true).Run()
+	if err != nil {
+		return errors.Join(err, errors.New(ffmpegLog.String()))
+	}
+
+	return nil
+}
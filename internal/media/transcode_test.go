@@ -0,0 +1,28 @@
+package media
+
+import "testing"
+
+func TestIsWebPlayable(t *testing.T) {
+	tests := []struct {
+		name                             string
+		mimetype, videoCodec, audioCodec string
+		want                             bool
+	}{
+		{"h264 aac mp4", "video/mp4", "h264", "aac", true},
+		{"h264 mp4 without audio", "video/mp4", "h264", "", true},
+		{"vp9 opus webm", "video/webm", "vp9", "opus", true},
+		{"hevc mp4", "video/mp4", "hevc", "aac", false},
+		{"h264 webm", "video/webm", "h264", "opus", false},
+		{"h264 ac3 mp4", "video/mp4", "h264", "ac3", false},
+		{"h264 aac mkv", "video/x-matroska", "h264", "aac", false},
+		{"mpeg4 avi", "video/x-msvideo", "mpeg4", "mp3", false},
+		{"unknown codec", "video/mp4", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsWebPlayable(tt.mimetype, tt.videoCodec, tt.audioCodec); got != tt.want {
+				t.Errorf("IsWebPlayable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
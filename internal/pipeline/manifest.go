@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Manifest records the result of every file of an import, so that an interrupted import can be resumed
+// without hashing the files it already went through. A Manifest is saved as CSV if its path ends
+// in ".csv", and as JSON otherwise.
+type Manifest struct {
+	// Root is the file or folder imported from. It is not saved in CSV manifests.
+	Root    string          `json:"root"`
+	Results []ManifestEntry `json:"results"`
+
+	index map[string]int
+}
+
+// ManifestEntry is the result of a single file. Reason is the error of a failed or skipped file.
+type ManifestEntry struct {
+	Path      string `json:"path"`
+	Status    string `json:"status"`
+	ArchiveID int64  `json:"archive_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+var csvHeader = []string{"path", "status", "archive_id", "reason"}
+
+func NewManifest(root string) *Manifest {
+	return &Manifest{Root: root}
+}
+
+// Record adds the result of a file, replacing any previous result of the same path.
+func (m *Manifest) Record(r Result) {
+	e := ManifestEntry{Path: r.Path, Status: r.Status, ArchiveID: r.ArchiveID}
+	if r.Err != nil {
+		e.Reason = r.Err.Error()
+	}
+
+	m.buildIndex()
+	if i, ok := m.index[e.Path]; ok {
+		m.Results[i] = e
+		return
+	}
+
+	m.index[e.Path] = len(m.Results)
+	m.Results = append(m.Results, e)
+}
+
+// Completed reports whether a file was already imported, found to be a duplicate, or skipped. Failed files
+// are not completed, and are attempted again when resuming.
+func (m *Manifest) Completed(path string) bool {
+	m.buildIndex()
+	i, ok := m.index[path]
+	return ok && m.Results[i].Status != StatusFailed
+}
+
+func (m *Manifest) buildIndex() {
+	if m.index != nil {
+		return
+	}
+
+	m.index = make(map[string]int, len(m.Results))
+	for i, e := range m.Results {
+		m.index[e.Path] = i
+	}
+}
+
+// Save writes the manifest to path. The manifest is written to a temporary file first, so that an
+// interruption never leaves a partially written manifest behind.
+func (m *Manifest) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if isCSV(path) {
+		err = m.writeCSV(tmp)
+	} else {
+		enc := json.NewEncoder(tmp)
+		enc.SetIndent("", "\t")
+		err = enc.Encode(m)
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (m *Manifest) writeCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	if err := c.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range m.Results {
+		err := c.Write([]string{e.Path, e.Status, strconv.FormatInt(e.ArchiveID, 10), e.Reason})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+// LoadManifest reads a manifest previously written by Manifest.Save.
+func LoadManifest(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := new(Manifest)
+	if !isCSV(path) {
+		if err := json.NewDecoder(f).Decode(m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		return nil, errors.New("invalid manifest header")
+	}
+
+	for _, r := range records[1:] {
+		archive_id, err := strconv.ParseInt(r[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		m.Results = append(m.Results, ManifestEntry{Path: r[0], Status: r[1], ArchiveID: archive_id, Reason: r[3]})
+	}
+
+	return m, nil
+}
+
+func isCSV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifest_Record(t *testing.T) {
+	m := NewManifest("/media")
+	m.Record(Result{Path: "/media/a.png", Status: StatusImported, ArchiveID: 1})
+	m.Record(Result{Path: "/media/b.png", Status: StatusFailed, Err: errors.New("permission denied")})
+	m.Record(Result{Path: "/media/c.txt", Status: StatusSkipped, Err: errors.New("unsupported format")})
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/media/a.png", true},
+		{"/media/b.png", false},
+		{"/media/c.txt", true},
+		{"/media/d.png", false},
+	}
+	for _, tt := range tests {
+		if got := m.Completed(tt.path); got != tt.want {
+			t.Errorf("Manifest.Completed(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	m.Record(Result{Path: "/media/b.png", Status: StatusDuplicate})
+	if len(m.Results) != 3 {
+		t.Fatalf("Manifest.Record() added a second result for the same path, got %d results", len(m.Results))
+	}
+	if !m.Completed("/media/b.png") || m.Results[1].Reason != "" {
+		t.Errorf("Manifest.Record() did not replace the failed result, got %+v", m.Results[1])
+	}
+}
+
+func TestManifest_Save(t *testing.T) {
+	m := NewManifest("/media")
+	m.Record(Result{Path: "/media/a.png", Status: StatusImported, ArchiveID: 1})
+	m.Record(Result{Path: "/media/b, \"quoted\".png", Status: StatusFailed, Err: errors.New("permission denied")})
+	m.Record(Result{Path: "/media/c.txt", Status: StatusSkipped, Err: errors.New("unsupported format")})
+
+	tests := []struct {
+		name     string
+		file     string
+		wantRoot string
+	}{
+		{"json", "manifest.json", "/media"},
+		{"csv", "manifest.csv", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := m.Save(path); err != nil {
+				t.Fatalf("Manifest.Save() error = %v", err)
+			}
+
+			got, err := LoadManifest(path)
+			if err != nil {
+				t.Fatalf("LoadManifest() error = %v", err)
+			}
+
+			if got.Root != tt.wantRoot {
+				t.Errorf("LoadManifest() root = %s, want %s", got.Root, tt.wantRoot)
+			}
+			if !reflect.DeepEqual(got.Results, m.Results) {
+				t.Errorf("LoadManifest() = %+v, want %+v", got.Results, m.Results)
+			}
+		})
+	}
+}
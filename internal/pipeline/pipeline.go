@@ -0,0 +1,299 @@
+// Package pipeline imports every file of a folder into moonpool concurrently.
+//
+// Files are hashed by a pool of workers, while a single writer imports the hashed files into the archive
+// one at a time, since SQLite only allows a single writer. Every file is imported within its own savepoint,
+// so that a failing file does not affect any other. The format of every file is detected from its content.
+// Files of an unsupported format are skipped, while files labeled as a different format fail.
+//
+// Probing with ffmpeg, thumbnails, perceptual hashes and metadata are not done by the pipeline itself, but
+// queued as background jobs. Once every file has been imported, Options.Process runs them in parallel with
+// the same amount of workers, otherwise they are left to the job workers of "moonpool launch".
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dtbead/moonpool/api"
+	"github.com/dtbead/moonpool/importer"
+	"github.com/dtbead/moonpool/internal/file"
+)
+
+// Result statuses of a single file.
+const (
+	StatusImported  = "imported"
+	StatusDuplicate = "duplicate"
+	StatusSkipped   = "skipped"
+	StatusFailed    = "failed"
+)
+
+type Options struct {
+	// Jobs is the amount of files hashed at once, and of background jobs processed at once if Process
+	// is set. If 0 or less, a single worker is used.
+	Jobs int
+	// Tags are assigned to every imported file.
+	Tags []string
+	// Process runs every queued background job once all files are imported.
+	Process bool
+	// Progress is called after every file with the progress so far. It is never called concurrently.
+	Progress func(Progress)
+	// Manifest records the result of every file. Files that are already completed in it are left out
+	// entirely, which allows resuming an interrupted import.
+	Manifest *Manifest
+}
+
+// Result is the outcome of importing a single file. ArchiveID is only set if the file was imported.
+type Result struct {
+	Path      string
+	Status    string
+	ArchiveID int64
+	Err       error
+}
+
+// Progress counts the results of an import so far. Total is the amount of files found, excluding the
+// files that were Resumed, i.e. already completed in the Options.Manifest.
+type Progress struct {
+	Total, Done, Resumed                  int
+	Imported, Duplicates, Skipped, Failed int
+	Last                                  Result
+}
+
+func (p *Progress) add(r Result) {
+	p.Done++
+	p.Last = r
+	switch r.Status {
+	case StatusImported:
+		p.Imported++
+	case StatusDuplicate:
+		p.Duplicates++
+	case StatusSkipped:
+		p.Skipped++
+	case StatusFailed:
+		p.Failed++
+	}
+}
+
+// hashed is a file hashed by a worker, waiting to be imported by the writer.
+type hashed struct {
+	path     string
+	file     *os.File
+	importer importer.Importer
+	err      error
+}
+
+// Import imports every file under root, which may also be a single file. A file that fails to import
+// is recorded as failed and does not stop the import, nor are duplicates of files already in the archive
+// considered a failure. An error is only returned if root cannot be read or ctx is cancelled, along with
+// the progress made until then.
+//
+// Paths are recorded as absolute paths, so that a manifest can be resumed from any working directory.
+func Import(ctx context.Context, a *api.API, root string, opts Options) (Progress, error) {
+	var progress Progress
+	report := func(r Result) {
+		progress.add(r)
+		if opts.Manifest != nil {
+			opts.Manifest.Record(r)
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return progress, err
+	}
+
+	var paths []string
+	var unreadable []Result
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// leave out whatever cannot be read, instead of giving up on every other file
+			unreadable = append(unreadable, Result{Path: path, Status: StatusFailed, Err: err})
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+		case opts.Manifest != nil && opts.Manifest.Completed(path):
+			progress.Resumed++
+		default:
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return progress, err
+	}
+	progress.Total = len(paths) + len(unreadable)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := max(opts.Jobs, 1)
+	queue := make(chan string, workers)
+	results := make(chan hashed, workers)
+
+	go func() {
+		defer close(queue)
+		for _, path := range paths {
+			select {
+			case queue <- path:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range queue {
+				h := hash(path)
+				select {
+				case results <- h:
+				case <-ctx.Done():
+					if h.file != nil {
+						h.file.Close()
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for _, r := range unreadable {
+		report(r)
+	}
+
+	writer, release, err := a.Conn(ctx)
+	if err != nil {
+		cancel()
+		for h := range results {
+			if h.file != nil {
+				h.file.Close()
+			}
+		}
+		return progress, err
+	}
+
+	for h := range results {
+		if ctx.Err() != nil {
+			// drain the remaining files once cancelled, leaving them out of the manifest
+			if h.file != nil {
+				h.file.Close()
+			}
+			continue
+		}
+
+		report(write(ctx, writer, h, opts.Tags))
+	}
+
+	if err := release(); err != nil {
+		return progress, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return progress, err
+	}
+
+	if opts.Process {
+		if err := a.RunPendingJobs(ctx, workers); err != nil {
+			return progress, err
+		}
+	}
+
+	return progress, nil
+}
+
+// hash opens and hashes a single file.
+func hash(path string) hashed {
+	f, err := os.Open(path)
+	if err != nil {
+		return hashed{path: path, err: err}
+	}
+
+	i, err := importer.New(f, filepath.Ext(path))
+	if err != nil {
+		f.Close()
+		return hashed{path: path, err: err}
+	}
+
+	return hashed{path: path, file: f, importer: i}
+}
+
+// write imports a single hashed file, assigns its tags and queues its background jobs.
+func write(ctx context.Context, a *api.API, h hashed, tags []string) Result {
+	if errors.Is(h.err, file.ErrUnsupportedFileType) {
+		return Result{Path: h.path, Status: StatusSkipped, Err: h.err}
+	}
+	if h.err != nil {
+		return Result{Path: h.path, Status: StatusFailed, Err: h.err}
+	}
+	defer h.file.Close()
+
+	archive_id, err := importFile(ctx, a, h.importer, tags)
+	if errors.Is(err, api.ErrDuplicateEntry) {
+		return Result{Path: h.path, Status: StatusDuplicate, Err: err}
+	}
+	if err != nil {
+		return Result{Path: h.path, Status: StatusFailed, Err: err}
+	}
+
+	return Result{Path: h.path, Status: StatusImported, ArchiveID: archive_id}
+}
+
+// importFile imports a single file within its own savepoint, so that a failing file leaves no trace
+// in the archive without affecting any other file. a has to be pinned to a single connection by
+// api.API.Conn, since a savepoint only applies to the connection it was created on.
+func importFile(ctx context.Context, a *api.API, i api.Importer, tags []string) (archive_id int64, err error) {
+	err = a.NewSavepoint(ctx, "folderimport")
+	if err != nil {
+		return -1, err
+	}
+	defer func() {
+		if err != nil {
+			// ROLLBACK TO keeps the savepoint open, which would leave every following file
+			// inside a transaction that is never committed. ctx may already be cancelled
+			// by the time a file fails, which must not leave it half-written.
+			ctx := context.WithoutCancel(ctx)
+			a.RollbackSavepoint(ctx, "folderimport")
+			a.ReleaseSavepoint(ctx, "folderimport")
+		}
+	}()
+
+	archive_id, err = a.Import(ctx, i)
+	if err != nil {
+		return -1, err
+	}
+
+	err = a.AssignTags(ctx, archive_id, tags)
+	if err != nil {
+		return -1, err
+	}
+
+	err = a.EnqueueJobs(ctx, archive_id)
+	if err != nil {
+		return -1, err
+	}
+
+	err = a.ReleaseSavepoint(ctx, "folderimport")
+	if err != nil {
+		return -1, err
+	}
+
+	return archive_id, nil
+}
@@ -0,0 +1,237 @@
+package pipeline
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dtbead/moonpool/api"
+	"github.com/dtbead/moonpool/importer"
+	"github.com/dtbead/moonpool/internal/log"
+	"github.com/dtbead/moonpool/internal/storage"
+)
+
+func newMockAPI(t *testing.T) *api.API {
+	dir := t.TempDir()
+	a, err := api.New(api.Config{
+		ArchiveLocation:   filepath.Join(dir, "archive.sqlite3"),
+		ThumbnailLocation: filepath.Join(dir, "thumbnail.sqlite3"),
+		MediaLocation:     filepath.Join(dir, "media"),
+		AnimatedPreviews:  true,
+		TranscodeFormat:   "mp4",
+	}, log.New(log.LogLevelVerbose))
+	if err != nil {
+		t.Fatalf("failed to create mock API. %v", err)
+	}
+	t.Cleanup(func() { a.Close(context.Background()) })
+
+	return a
+}
+
+// writePNG writes a small PNG whose pixels depend on seed, so that every seed has a different hash.
+func writePNG(t *testing.T, path string, seed uint8) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 8), uint8(y * 8), seed, 255})
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s. %v", path, err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("failed to encode %s. %v", path, err)
+	}
+}
+
+func newMockFolder(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "nested"), os.ModePerm); err != nil {
+		t.Fatalf("failed to create folder. %v", err)
+	}
+
+	writePNG(t, filepath.Join(dir, "a.png"), 0)
+	writePNG(t, filepath.Join(dir, "b.PNG"), 1)
+	writePNG(t, filepath.Join(dir, "nested", "c.png"), 2)
+	writePNG(t, filepath.Join(dir, "nested", "d.png"), 3)
+	writePNG(t, filepath.Join(dir, "nested", "a copy.png"), 0)
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not media"), 0o644); err != nil {
+		t.Fatalf("failed to create notes.txt. %v", err)
+	}
+
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobs    int
+		process bool
+	}{
+		{"single worker", 1, false},
+		{"multiple workers", 4, false},
+		{"process jobs", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newMockAPI(t)
+			ctx := context.Background()
+
+			dir := newMockFolder(t)
+			writePNG(t, filepath.Join(dir, "mislabeled.jpg"), 5)
+
+			var calls int
+			got, err := Import(ctx, a, dir, Options{
+				Jobs:     tt.jobs,
+				Tags:     []string{"imported"},
+				Process:  tt.process,
+				Progress: func(Progress) { calls++ },
+			})
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			if got.Total != 7 || got.Done != 7 || got.Imported != 4 || got.Duplicates != 1 || got.Skipped != 1 || got.Failed != 1 {
+				t.Errorf("Import() = %+v, want 4 imported, 1 duplicate, 1 skipped and 1 failed out of 7", got)
+			}
+			if calls != got.Total {
+				t.Errorf("Options.Progress was called %d times, want %d", calls, got.Total)
+			}
+
+			jobs, err := a.GetJobs(ctx, "", 100, 0)
+			if err != nil {
+				t.Fatalf("API.GetJobs() error = %v", err)
+			}
+			if len(jobs) != got.Imported*len(api.JobTypes) {
+				t.Errorf("API.GetJobs() returned %d jobs, want %d", len(jobs), got.Imported*len(api.JobTypes))
+			}
+
+			// metadata jobs depend on ffprobe being installed, so only check whether each job was attempted
+			for _, j := range jobs {
+				if tt.process && j.Attempts != 1 {
+					t.Errorf("%s job for archive_id %d was attempted %d times, want 1", j.Type, j.ArchiveID, j.Attempts)
+				}
+				if !tt.process && j.Status != api.JobPending {
+					t.Errorf("%s job for archive_id %d has status %s, want %s", j.Type, j.ArchiveID, j.Status, api.JobPending)
+				}
+			}
+
+			results, err := a.SearchTag(ctx, "imported")
+			if err != nil {
+				t.Fatalf("API.SearchTag() error = %v", err)
+			}
+			if len(results) != got.Imported {
+				t.Errorf("API.SearchTag() returned %d entries, want %d", len(results), got.Imported)
+			}
+		})
+	}
+}
+
+func TestImport_Resume(t *testing.T) {
+	a := newMockAPI(t)
+	ctx := context.Background()
+
+	dir := newMockFolder(t)
+	broken := filepath.Join(dir, "e.png")
+	if err := os.Symlink(filepath.Join(dir, "missing.png"), broken); err != nil {
+		t.Fatalf("failed to create symlink. %v", err)
+	}
+
+	manifest := NewManifest(dir)
+	got, err := Import(ctx, a, dir, Options{Jobs: 2, Manifest: manifest})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got.Imported != 4 || got.Failed != 1 {
+		t.Errorf("Import() = %+v, want 4 imported and 1 failed", got)
+	}
+	if manifest.Completed(broken) {
+		t.Errorf("Manifest.Completed(%s) = true for a failed file", broken)
+	}
+
+	// fix the failed file, only it should be imported when resuming
+	if err := os.Remove(broken); err != nil {
+		t.Fatalf("failed to remove symlink. %v", err)
+	}
+	writePNG(t, broken, 4)
+
+	var paths []string
+	got, err = Import(ctx, a, dir, Options{Jobs: 2, Manifest: manifest, Progress: func(p Progress) {
+		paths = append(paths, p.Last.Path)
+	}})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if got.Total != 1 || got.Resumed != 6 || got.Imported != 1 || got.Failed != 0 {
+		t.Errorf("Import() = %+v, want 1 imported after resuming from 6 files", got)
+	}
+	if len(paths) != 1 || paths[0] != broken {
+		t.Errorf("Import() went through %v, want only %s", paths, broken)
+	}
+	if !manifest.Completed(broken) {
+		t.Errorf("Manifest.Completed(%s) = false after importing it", broken)
+	}
+}
+
+// cancelingImporter cancels the import once its file is stored.
+type cancelingImporter struct {
+	api.Importer
+	cancel context.CancelFunc
+}
+
+func (c cancelingImporter) Store(ctx context.Context, s storage.Storage, key string) error {
+	defer c.cancel()
+	return c.Importer.Store(ctx, s, key)
+}
+
+func Test_importFile_Cancelled(t *testing.T) {
+	a := newMockAPI(t)
+	dir := newMockFolder(t)
+
+	f, err := os.Open(filepath.Join(dir, "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	i, err := importer.New(f, ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer, release, err := a.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("API.Conn() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := importFile(ctx, writer, cancelingImporter{i, cancel}, []string{"foo"}); err == nil {
+		t.Fatalf("importFile() error = nil, want an error once cancelled")
+	}
+	if err := release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.DoesEntryExist(context.Background(), 1) {
+		t.Errorf("importFile() left a half-written entry after being cancelled")
+	}
+
+	// the savepoint was released, so following imports are committed
+	progress, err := Import(context.Background(), a, dir, Options{})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if progress.Imported != 4 {
+		t.Errorf("Import() imported %d files, want 4", progress.Imported)
+	}
+}
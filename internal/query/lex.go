@@ -0,0 +1,173 @@
+package query
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenQuoted
+	tokenPredicate
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind   tokenKind
+	pos    int
+	text   string
+	field  string
+	prefix bool
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenPredicate:
+		return "'" + t.field + ":" + t.text + "'"
+	default:
+		return "tag " + quote(t.text)
+	}
+}
+
+// isSpecial reports whether r always ends a bare word.
+func isSpecial(r rune) bool {
+	return strings.ContainsRune(`(),&|"`, r) || unicode.IsSpace(r)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return nil, &Error{Pos: i, Msg: "invalid utf-8"}
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: i})
+			i += size
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: i})
+			i += size
+		case r == ',' || r == '&':
+			tokens = append(tokens, token{kind: tokenAnd, pos: i})
+			i += size
+		case r == '|':
+			tokens = append(tokens, token{kind: tokenOr, pos: i})
+			i += size
+		case r == '-' || r == '!':
+			tokens = append(tokens, token{kind: tokenNot, pos: i})
+			i += size
+		case r == '"':
+			t, n, err := lexQuoted(s, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i = n
+		default:
+			t, n, err := lexWord(s, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i = n
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+func lexQuoted(s string, start int) (token, int, error) {
+	var b strings.Builder
+
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) {
+				return token{}, 0, &Error{Pos: i, Msg: "unterminated escape sequence"}
+			}
+			i++
+			b.WriteByte(s[i])
+		case '"':
+			t := token{kind: tokenQuoted, pos: start, text: b.String()}
+			i++
+			if i < len(s) && s[i] == '*' {
+				t.prefix = true
+				i++
+			}
+			return t, i, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return token{}, 0, &Error{Pos: start, Msg: "missing closing quote"}
+}
+
+func lexWord(s string, start int) (token, int, error) {
+	i := start
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if isSpecial(r) {
+			break
+		}
+		i += size
+	}
+
+	word := s[start:i]
+	t := token{kind: tokenWord, pos: start, text: word}
+
+	switch word {
+	case "AND":
+		t.kind = tokenAnd
+		return t, i, nil
+	case "OR":
+		t.kind = tokenOr
+		return t, i, nil
+	case "NOT":
+		t.kind = tokenNot
+		return t, i, nil
+	}
+
+	if field, value, ok := strings.Cut(word, ":"); ok && slices.Contains(Fields, strings.ToLower(field)) {
+		t.kind = tokenPredicate
+		t.field = strings.ToLower(field)
+		t.text = value
+		return t, i, nil
+	}
+
+	if strings.HasSuffix(word, "*") {
+		t.prefix = true
+		t.text = strings.TrimSuffix(word, "*")
+	}
+
+	if n := strings.IndexByte(t.text, '*'); n >= 0 {
+		return token{}, 0, &Error{Pos: start + n, Msg: "wildcard '*' is only allowed at the end of a tag"}
+	}
+
+	return t, i, nil
+}
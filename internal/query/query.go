@@ -0,0 +1,431 @@
+// Package query parses tag search queries into an abstract syntax tree.
+//
+// A query is made of tags combined with boolean operators:
+//
+//	cat AND dog            both tags (also "cat, dog" or "cat & dog")
+//	cat OR dog             either tag (also "cat | dog")
+//	NOT cat                without tag (also "-cat" or "!cat")
+//	(cat OR dog) -bird     parentheses group terms, adjacent groups are implicitly AND'ed
+//	cat*                   any tag beginning with "cat"
+//	"foo, bar"             quoted tags may contain any character, escaped with a backslash
+//
+// Unquoted words that follow each other without an operator are joined into a single tag, so
+// "blue sky" searches for the tag "blue sky". Keywords are only recognized in uppercase.
+//
+// A word of the form "field:value", where field is one of Fields, is parsed as a Predicate instead
+// of a tag, e.g. "width:>1920" or "imported:2024-01..2024-06". Parse leaves the value uninterpreted;
+// callers are expected to resolve each Predicate into a Range or Match before searching.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	FieldWidth       = "width"
+	FieldHeight      = "height"
+	FieldSize        = "size"
+	FieldOrientation = "orientation"
+	FieldType        = "type"
+	FieldMimetype    = "mimetype"
+	FieldImported    = "imported"
+	FieldCreated     = "created"
+	FieldModified    = "modified"
+	FieldTagCount    = "tagcount"
+	FieldDuration    = "duration"
+	FieldFramerate   = "framerate"
+	FieldBitrate     = "bitrate"
+	FieldVideoCodec  = "vcodec"
+	FieldAudioCodec  = "acodec"
+	FieldAudio       = "audio"
+)
+
+// Fields are every predicate field recognized by Parse.
+var Fields = []string{
+	FieldWidth, FieldHeight, FieldSize, FieldOrientation, FieldType, FieldMimetype,
+	FieldImported, FieldCreated, FieldModified, FieldTagCount,
+	FieldDuration, FieldFramerate, FieldBitrate, FieldVideoCodec, FieldAudioCodec, FieldAudio,
+}
+
+const (
+	// MaxTags is the maximum amount of tags and predicates a single query may contain.
+	MaxTags = 256
+	// MaxDepth is the maximum nesting of parentheses and negations in a single query.
+	MaxDepth = 64
+)
+
+var (
+	// ErrSyntax is matched by every error returned from Parse.
+	ErrSyntax = errors.New("invalid query")
+)
+
+// Error describes a syntax error at a byte position in a query.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query: %s at position %d", e.Msg, e.Pos+1)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrSyntax
+}
+
+// Node is a single element of a parsed query.
+type Node interface {
+	String() string
+}
+
+// Tag matches entries that are assigned Text. If Prefix is true, any tag beginning with Text matches.
+type Tag struct {
+	Text   string
+	Prefix bool
+}
+
+// Not matches entries that do not match Node.
+type Not struct {
+	Node Node
+}
+
+// And matches entries that match every one of Nodes.
+type And struct {
+	Nodes []Node
+}
+
+// Or matches entries that match at least one of Nodes.
+type Or struct {
+	Nodes []Node
+}
+
+// Predicate is an unresolved "field:value" term. Pos is the byte position of the term in the query.
+type Predicate struct {
+	Field, Value string
+	Pos          int
+}
+
+// Range matches entries whose numeric Field is within Min and Max, inclusively. Unbounded ends are
+// math.MinInt64 and math.MaxInt64 respectively.
+type Range struct {
+	Field    string
+	Min, Max int64
+}
+
+// Match matches entries whose text Field is equal to Value, or begins with Value if Prefix is true.
+type Match struct {
+	Field  string
+	Value  string
+	Prefix bool
+}
+
+func (t Tag) String() string {
+	s := quote(t.Text)
+	if t.Prefix {
+		s += "*"
+	}
+	return s
+}
+
+func (p Predicate) String() string {
+	return p.Field + ":" + p.Value
+}
+
+func (r Range) String() string {
+	min, max := "", ""
+	if r.Min != math.MinInt64 {
+		min = strconv.FormatInt(r.Min, 10)
+	}
+	if r.Max != math.MaxInt64 {
+		max = strconv.FormatInt(r.Max, 10)
+	}
+	return r.Field + ":[" + min + ".." + max + "]"
+}
+
+func (m Match) String() string {
+	s := m.Field + ":" + quote(m.Value)
+	if m.Prefix {
+		s += "*"
+	}
+	return s
+}
+
+func (n Not) String() string {
+	return "NOT " + n.Node.String()
+}
+
+func (a And) String() string {
+	return join(a.Nodes, " AND ")
+}
+
+func (o Or) String() string {
+	return join(o.Nodes, " OR ")
+}
+
+func join(nodes []Node, sep string) string {
+	s := make([]string, len(nodes))
+	for i, n := range nodes {
+		s[i] = n.String()
+	}
+	return "(" + strings.Join(s, sep) + ")"
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Tags returns every tag referenced in a query, in order of appearance.
+func Tags(n Node) []Tag {
+	var tags []Tag
+	walk(n, func(n Node) {
+		if t, ok := n.(Tag); ok {
+			tags = append(tags, t)
+		}
+	})
+	return tags
+}
+
+func walk(n Node, fn func(Node)) {
+	fn(n)
+	switch n := n.(type) {
+	case Not:
+		walk(n.Node, fn)
+	case And:
+		for _, c := range n.Nodes {
+			walk(c, fn)
+		}
+	case Or:
+		for _, c := range n.Nodes {
+			walk(c, fn)
+		}
+	}
+}
+
+// Transform returns a copy of n with every node replaced by the result of fn, applied bottom-up.
+// Returning an error from fn stops the walk.
+func Transform(n Node, fn func(Node) (Node, error)) (Node, error) {
+	switch v := n.(type) {
+	case Not:
+		c, err := Transform(v.Node, fn)
+		if err != nil {
+			return nil, err
+		}
+		n = Not{Node: c}
+	case And:
+		nodes, err := transformList(v.Nodes, fn)
+		if err != nil {
+			return nil, err
+		}
+		n = And{Nodes: nodes}
+	case Or:
+		nodes, err := transformList(v.Nodes, fn)
+		if err != nil {
+			return nil, err
+		}
+		n = Or{Nodes: nodes}
+	}
+
+	return fn(n)
+}
+
+func transformList(nodes []Node, fn func(Node) (Node, error)) ([]Node, error) {
+	out := make([]Node, len(nodes))
+	for i, c := range nodes {
+		t, err := Transform(c, fn)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = t
+	}
+	return out, nil
+}
+
+// Parse parses a query string into a Node. Every error returned is an *Error.
+func Parse(s string) (Node, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &Error{Pos: 0, Msg: "empty query"}
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := p.peek(); t.kind {
+	case tokenEOF:
+	case tokenRParen:
+		return nil, &Error{Pos: t.pos, Msg: "unexpected ')'"}
+	default:
+		return nil, &Error{Pos: t.pos, Msg: "unexpected " + t.describe()}
+	}
+
+	return n, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	depth  int
+	tags   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	n, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{n}
+	for p.peek().kind == tokenOr {
+		p.next()
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return Or{Nodes: nodes}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{n}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenNot, tokenLParen, tokenWord, tokenQuoted, tokenPredicate:
+			// implicit AND, e.g. "cat -dog" or "(cat OR dog) bird"
+		default:
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return And{Nodes: nodes}, nil
+		}
+
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind != tokenNot {
+		return p.parsePrimary()
+	}
+
+	t := p.next()
+	if p.depth++; p.depth > MaxDepth {
+		return nil, &Error{Pos: t.pos, Msg: "query is nested too deeply"}
+	}
+	defer func() { p.depth-- }()
+
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return Not{Node: n}, nil
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenLParen:
+		if p.depth++; p.depth > MaxDepth {
+			return nil, &Error{Pos: t.pos, Msg: "query is nested too deeply"}
+		}
+		defer func() { p.depth-- }()
+
+		if p.peek().kind == tokenRParen {
+			return nil, &Error{Pos: p.peek().pos, Msg: "empty parentheses"}
+		}
+
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek().kind != tokenRParen {
+			return nil, &Error{Pos: t.pos, Msg: "missing closing ')'"}
+		}
+		p.next()
+		return n, nil
+	case tokenQuoted:
+		return p.newTag(t, t.text, t.prefix)
+	case tokenPredicate:
+		if p.tags++; p.tags > MaxTags {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("query has more than %d tags", MaxTags)}
+		}
+
+		if t.text == "" {
+			return nil, &Error{Pos: t.pos, Msg: "missing value for '" + t.field + "'"}
+		}
+		return Predicate{Field: t.field, Value: t.text, Pos: t.pos}, nil
+	case tokenWord:
+		// consecutive bare words form a single tag separated by one space
+		words := []string{t.text}
+		prefix := t.prefix
+		for p.peek().kind == tokenWord {
+			if prefix {
+				return nil, &Error{Pos: t.pos, Msg: "wildcard '*' is only allowed at the end of a tag"}
+			}
+			w := p.next()
+			words = append(words, w.text)
+			prefix = w.prefix
+		}
+		return p.newTag(t, strings.Join(words, " "), prefix)
+	case tokenEOF:
+		return nil, &Error{Pos: t.pos, Msg: "unexpected end of query, expected a tag"}
+	default:
+		return nil, &Error{Pos: t.pos, Msg: "expected a tag, got " + t.describe()}
+	}
+}
+
+func (p *parser) newTag(t token, text string, prefix bool) (Node, error) {
+	if p.tags++; p.tags > MaxTags {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("query has more than %d tags", MaxTags)}
+	}
+
+	if text == "" && !prefix {
+		return nil, &Error{Pos: t.pos, Msg: "empty tag"}
+	}
+
+	if text == "" && prefix {
+		return nil, &Error{Pos: t.pos, Msg: "wildcard '*' needs at least one character before it"}
+	}
+
+	return Tag{Text: text, Prefix: prefix}, nil
+}
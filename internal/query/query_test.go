@@ -0,0 +1,118 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"single tag", "foo", `"foo"`},
+		{"comma is AND", "foo, bar", `("foo" AND "bar")`},
+		{"comma with exclude", "foo, -bar", `("foo" AND NOT "bar")`},
+		{"keyword AND", "cat AND dog", `("cat" AND "dog")`},
+		{"keyword OR", "cat OR dog", `("cat" OR "dog")`},
+		{"symbols", "cat & dog | bird", `(("cat" AND "dog") OR "bird")`},
+		{"AND binds tighter than OR", "a OR b AND c", `("a" OR ("b" AND "c"))`},
+		{"parentheses", "(a OR b) AND c", `(("a" OR "b") AND "c")`},
+		{"implicit AND", "(a OR b) -c", `(("a" OR "b") AND NOT "c")`},
+		{"implicit AND after quote", `"a" b`, `("a" AND "b")`},
+		{"NOT keyword", "NOT foo", `NOT "foo"`},
+		{"bang", "!foo", `NOT "foo"`},
+		{"double negation", "--foo", `NOT NOT "foo"`},
+		{"negated group", "-(a OR b)", `NOT ("a" OR "b")`},
+		{"multi word tag", "blue   sky, cloud", `("blue sky" AND "cloud")`},
+		{"dash inside tag", "t-shirt", `"t-shirt"`},
+		{"prefix wildcard", "cat*", `"cat"*`},
+		{"multi word prefix wildcard", "blue sk*", `"blue sk"*`},
+		{"quoted with comma", `"foo, bar"`, `"foo, bar"`},
+		{"quoted with escapes", `"say \"hi\" \\o/"`, `"say \"hi\" \\o/"`},
+		{"quoted prefix", `"foo, b"*`, `"foo, b"*`},
+		{"quoted keyword", `"AND"`, `"AND"`},
+		{"lowercase keyword is a tag", "cat and dog", `"cat and dog"`},
+		{"unicode", "猫, -犬", `("猫" AND NOT "犬")`},
+		{"predicate", "width:>1920", `width:>1920`},
+		{"predicate field is case insensitive", "Type:video", `type:video`},
+		{"predicate with wildcard", "mimetype:image/*", `mimetype:image/*`},
+		{"predicate implicit AND", "cat width:>10 -size:<5MB", `("cat" AND width:>10 AND NOT size:<5MB)`},
+		{"predicate does not join words", "blue sky tagcount:0", `("blue sky" AND tagcount:0)`},
+		{"unknown field is a tag", "source:pixiv", `"source:pixiv"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.s)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		wantPos int
+		wantMsg string
+	}{
+		{"empty", "   ", 0, "empty query"},
+		{"trailing operator", "foo AND", 7, "unexpected end of query"},
+		{"leading operator", "OR foo", 0, "expected a tag, got OR"},
+		{"double operator", "foo,,bar", 4, "expected a tag"},
+		{"unclosed paren", "(foo OR bar", 0, "missing closing ')'"},
+		{"unopened paren", "foo)", 3, "unexpected ')'"},
+		{"empty paren", "foo ()", 5, "empty parentheses"},
+		{"unclosed quote", `foo, "bar`, 5, "missing closing quote"},
+		{"empty quote", `""`, 0, "empty tag"},
+		{"lone wildcard", "*", 0, "wildcard '*' needs at least one character"},
+		{"wildcard in middle", "fo*o", 2, "wildcard '*' is only allowed at the end"},
+		{"wildcard before word", "fo* bar", 0, "wildcard '*' is only allowed at the end"},
+		{"missing predicate value", "cat, width:", 5, "missing value for 'width'"},
+		{"too deep", strings.Repeat("(", MaxDepth+1) + "a" + strings.Repeat(")", MaxDepth+1), MaxDepth, "nested too deeply"},
+		{"too many tags", strings.Repeat("a,", MaxTags) + "a", 2 * MaxTags, "more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.s)
+			if !errors.Is(err, ErrSyntax) {
+				t.Fatalf("Parse() error = %v, want %v", err, ErrSyntax)
+			}
+
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Parse() error is not *Error")
+			}
+
+			if e.Pos != tt.wantPos || !strings.Contains(e.Msg, tt.wantMsg) {
+				t.Errorf("Parse() error = %v (pos %d), want '%s' at pos %d", e, e.Pos, tt.wantMsg, tt.wantPos)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	n, err := Parse("(a OR b*) -c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Tags(n)
+	want := []Tag{{"a", false}, {"b", true}, {"c", false}}
+	if len(got) != len(want) {
+		t.Fatalf("Tags() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("Tags()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
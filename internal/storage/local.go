@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dtbead/moonpool/internal/file"
+)
+
+// tempPrefix is the prefix of files a Local is still writing, which are never listed.
+const tempPrefix = ".moonpool_"
+
+// Local stores files in a directory on the local filesystem, with keys as paths relative to it.
+type Local struct {
+	root string
+}
+
+// NewLocal returns a Local storing files in root. An empty root is the current directory.
+func NewLocal(root string) Local {
+	if root == "" {
+		root = "."
+	}
+	return Local{root: file.CleanPath(root)}
+}
+
+// Path returns the path on the local filesystem of the file at key.
+func (l Local) Path(key string) string {
+	return file.CleanPath(l.root + "/" + cleanKey(key))
+}
+
+// Put writes r to a temporary file next to key first, so a partially written file is never read.
+func (l Local) Put(ctx context.Context, key string, r io.Reader) error {
+	p := l.Path(key)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
+
+func (l Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(l.Path(key))
+}
+
+func (l Local) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(l.Path(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if length < 0 {
+		return f, nil
+	}
+	return readCloser{io.LimitReader(f, length), f}, nil
+}
+
+func (l Local) Stat(ctx context.Context, key string) (Info, error) {
+	fi, err := os.Stat(l.Path(key))
+	if err != nil {
+		return Info{}, err
+	}
+	if fi.IsDir() {
+		return Info{}, &fs.PathError{Op: "stat", Path: key, Err: ErrNotExist}
+	}
+
+	return Info{Key: cleanKey(key), Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+// Delete also removes every directory the file leaves empty, up to the root of l.
+func (l Local) Delete(ctx context.Context, key string) error {
+	p := l.Path(key)
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return l.removeEmpty(filepath.Dir(p))
+}
+
+// removeEmpty removes dir and each of its parents for as long as they're empty, up to the root of l.
+func (l Local) removeEmpty(dir string) error {
+	for dir = file.CleanPath(dir); dir != l.root && file.IsDirectoryEmpty(dir); dir = file.CleanPath(filepath.Dir(dir)) {
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rename also removes every directory the file leaves empty, up to the root of l.
+func (l Local) Rename(ctx context.Context, from, to string) error {
+	src, dst := l.Path(from), l.Path(to)
+	if src == dst {
+		return nil
+	}
+
+	if _, err := l.Stat(ctx, from); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+
+	return l.removeEmpty(filepath.Dir(src))
+}
+
+func (l Local) List(ctx context.Context, prefix string) ([]Info, error) {
+	var files []Info
+	err := filepath.WalkDir(l.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, Info{Key: key, Size: fi.Size(), ModTime: fi.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+	return files, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	testStorage(t, NewLocal(t.TempDir()))
+}
+
+func TestLocal_Delete(t *testing.T) {
+	root := t.TempDir()
+	l := NewLocal(root)
+	ctx := context.Background()
+
+	for _, key := range []string{"ab/cd/one.png", "ab/two.png"} {
+		if err := l.Put(ctx, key, strings.NewReader(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.Delete(ctx, "ab/cd/one.png"); err != nil {
+		t.Fatalf("Local.Delete() error = %v", err)
+	}
+	if _, err := os.Stat(root + "/ab/cd"); !os.IsNotExist(err) {
+		t.Errorf("Local.Delete() left empty directory ab/cd, error = %v", err)
+	}
+	if _, err := os.Stat(root + "/ab"); err != nil {
+		t.Errorf("Local.Delete() removed directory ab which is not empty, error = %v", err)
+	}
+
+	if err := l.Delete(ctx, "ab/two.png"); err != nil {
+		t.Fatalf("Local.Delete() error = %v", err)
+	}
+	if _, err := os.Stat(root + "/ab"); !os.IsNotExist(err) {
+		t.Errorf("Local.Delete() left empty directory ab, error = %v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Errorf("Local.Delete() removed root, error = %v", err)
+	}
+}
+
+func TestLocal_Path(t *testing.T) {
+	tests := []struct {
+		name, root, key string
+		want            string
+	}{
+		{"generic", "/media", "ab/abcdef.png", "/media/ab/abcdef.png"},
+		{"trailing slash", "/media/", "ab/abcdef.png", "/media/ab/abcdef.png"},
+		{"current directory", "", "ab/abcdef.png", "ab/abcdef.png"},
+		{"escaping key", "/media", "../../etc/passwd", "/media/etc/passwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewLocal(tt.root).Path(tt.key); got != tt.want {
+				t.Errorf("Local.Path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+const (
+	// defaultRegion is used for S3-compatible services that have no concept of regions.
+	defaultRegion = "us-east-1"
+	// maxCopySize is the largest object S3 copies in a single request.
+	maxCopySize = 5 << 30
+)
+
+// S3 stores files as objects of a bucket in Amazon S3 or any S3-compatible service, such as MinIO.
+type S3 struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+// NewS3 returns an S3 for the bucket of c.
+func NewS3(c Config) (*S3, error) {
+	if c.Bucket == "" {
+		return nil, errors.New("no s3 bucket given")
+	}
+
+	region := c.Region
+	if region == "" {
+		region = defaultRegion
+	}
+
+	cfg := aws.NewConfig().WithRegion(region).WithS3ForcePathStyle(c.PathStyle)
+	if c.Endpoint != "" {
+		cfg = cfg.WithEndpoint(c.Endpoint)
+	}
+	if c.AccessKeyID != "" {
+		cfg = cfg.WithCredentials(credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, ""))
+	}
+
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.New(sess)
+	return &S3{
+		client:   client,
+		uploader: s3manager.NewUploaderWithClient(client),
+		bucket:   c.Bucket,
+		prefix:   cleanKey(c.Prefix),
+	}, nil
+}
+
+// object returns the name of the object of a key, or the prefix of objects if key is a prefix.
+func (s *S3) object(key string) string {
+	key = strings.TrimPrefix(strings.ReplaceAll(key, `\`, "/"), "/")
+	if key != "" && !strings.HasSuffix(key, "/") {
+		key = cleanKey(key)
+	}
+
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// Put uploads large files in parts, so r never has to be read into memory at once.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.object(key)),
+		Body:   r,
+	})
+	return s3Error("put", key, err)
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, -1)
+}
+
+func (s *S3) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.object(key)),
+	}
+	if offset > 0 || length > 0 {
+		r := "bytes=" + strconv.FormatInt(offset, 10) + "-"
+		if length > 0 {
+			r += strconv.FormatInt(offset+length-1, 10)
+		}
+		input.Range = aws.String(r)
+	}
+
+	out, err := s.client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, s3Error("get", key, err)
+	}
+	return out.Body, nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (Info, error) {
+	out, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.object(key)),
+	})
+	if err != nil {
+		return Info{}, s3Error("stat", key, err)
+	}
+
+	return Info{Key: cleanKey(key), Size: aws.Int64Value(out.ContentLength), ModTime: aws.TimeValue(out.LastModified)}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.object(key)),
+	})
+	err = s3Error("delete", key, err)
+	if errors.Is(err, ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Rename copies the object at from within the bucket before deleting it, so it never leaves the service.
+// Objects larger than 5 GiB can't be copied at once, and are passed through Moonpool instead.
+func (s *S3) Rename(ctx context.Context, from, to string) error {
+	if s.object(from) == s.object(to) {
+		return nil
+	}
+
+	info, err := s.Stat(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	if info.Size > maxCopySize {
+		rc, err := s.Get(ctx, from)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		if err := s.Put(ctx, to, rc); err != nil {
+			return err
+		}
+	} else {
+		_, err := s.client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(s.object(to)),
+			CopySource: aws.String(url.PathEscape(s.bucket + "/" + s.object(from))),
+		})
+		if err != nil {
+			return s3Error("rename", from, err)
+		}
+	}
+
+	return s.Delete(ctx, from)
+}
+
+func (s *S3) List(ctx context.Context, prefix string) ([]Info, error) {
+	var files []Info
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.object(prefix)),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			key := aws.StringValue(o.Key)
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			files = append(files, Info{Key: key, Size: aws.Int64Value(o.Size), ModTime: aws.TimeValue(o.LastModified)})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, s3Error("list", prefix, err)
+	}
+
+	// objects are already listed in UTF-8 binary order, the same order as sorted strings
+	return files, nil
+}
+
+// s3Error wraps ErrNotExist into err if it is a missing object or bucket.
+func s3Error(op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+		return &fs.PathError{Op: op, Path: key, Err: ErrNotExist}
+	}
+
+	var aErr awserr.Error
+	if errors.As(err, &aErr) && (aErr.Code() == s3.ErrCodeNoSuchKey || aErr.Code() == "NotFound") {
+		return &fs.PathError{Op: op, Path: key, Err: ErrNotExist}
+	}
+	return err
+}
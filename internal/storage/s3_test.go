@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal S3-compatible server of a single bucket addressed by path, standing in for services
+// such as MinIO.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+}
+
+type fakeS3List struct {
+	XMLName     xml.Name `xml:"ListBucketResult"`
+	Name        string
+	Prefix      string
+	KeyCount    int
+	IsTruncated bool
+	Contents    []fakeS3Object
+}
+
+type fakeS3Object struct {
+	Key          string
+	Size         int
+	LastModified string
+}
+
+var fakeS3ModTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		fakeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		prefix := r.URL.Query().Get("prefix")
+		list := fakeS3List{Name: f.bucket, Prefix: prefix}
+		for k, v := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				list.Contents = append(list.Contents, fakeS3Object{Key: k, Size: len(v),
+					LastModified: fakeS3ModTime.Format("2006-01-02T15:04:05.000Z")})
+			}
+		}
+		sort.Slice(list.Contents, func(i, j int) bool { return list.Contents[i].Key < list.Contents[j].Key })
+		list.KeyCount = len(list.Contents)
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		if err != nil {
+			fakeS3Error(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+
+		_, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+		b, ok := f.objects[sourceKey]
+		if !ok {
+			fakeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		f.objects[key] = bytes.Clone(b)
+
+		w.Header().Set("Content-Type", "application/xml")
+		io.WriteString(w, "<CopyObjectResult><ETag>\"moonpool\"</ETag></CopyObjectResult>")
+	case r.Method == http.MethodPut:
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			fakeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = b
+		w.Header().Set("ETag", `"moonpool"`)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		b, ok := f.objects[key]
+		if !ok {
+			fakeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		http.ServeContent(w, r, key, fakeS3ModTime, bytes.NewReader(b))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fakeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func fakeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, "<Error><Code>"+code+"</Code><Message>"+code+"</Message></Error>")
+}
+
+func newFakeS3(t *testing.T, prefix string) (*S3, *fakeS3) {
+	f := &fakeS3{bucket: "moonpool", objects: make(map[string][]byte)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	s, err := NewS3(Config{Type: "s3", Endpoint: server.URL, Bucket: "moonpool", Prefix: prefix, PathStyle: true,
+		AccessKeyID: "moonpool", SecretAccessKey: "moonpool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, f
+}
+
+func TestS3(t *testing.T) {
+	s, _ := newFakeS3(t, "")
+	testStorage(t, s)
+}
+
+func TestS3_Prefix(t *testing.T) {
+	s, f := newFakeS3(t, "media/")
+	testStorage(t, s)
+
+	for key := range f.objects {
+		if !strings.HasPrefix(key, "media/") {
+			t.Errorf("S3 stored object %s outside of its prefix", key)
+		}
+	}
+}
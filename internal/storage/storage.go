@@ -0,0 +1,194 @@
+// Package storage stores media files on the local filesystem or in an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotExist is wrapped by the errors of every Storage when no file is stored at a key.
+var ErrNotExist = fs.ErrNotExist
+
+// Storage stores files by key, a slash-separated path such as "ab/abcdef.png". Keys are cleaned of any ".."
+// before use, so a key can never point outside of a Storage.
+type Storage interface {
+	// Put stores r at key, replacing any file already stored there.
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange reads length bytes of the file at key, starting at offset. A negative length reads until the
+	// end of the file.
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes the file at key. Deleting a key without a file is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns every file with a key starting with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Info, error)
+}
+
+// Info describes a stored file. Size is in bytes.
+type Info struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Config selects a Storage. Type is either "local", which stores files in the media directory, or "s3".
+// Every other field only applies to "s3", where Endpoint may point at any S3-compatible service, Prefix is
+// prepended to every key, and PathStyle addresses buckets by path rather than by subdomain, which most
+// self-hosted services require. The default AWS credential chain is used if AccessKeyID is empty.
+type Config struct {
+	Type                         string
+	Endpoint, Region             string
+	Bucket, Prefix               string
+	AccessKeyID, SecretAccessKey string
+	PathStyle                    bool
+}
+
+// Open returns the Storage c selects. mediaDirectory is the root of a local Storage.
+func Open(c Config, mediaDirectory string) (Storage, error) {
+	switch strings.ToLower(c.Type) {
+	case "", "local":
+		return NewLocal(mediaDirectory), nil
+	case "s3":
+		return NewS3(c)
+	default:
+		return nil, errors.New("unknown storage type " + c.Type)
+	}
+}
+
+// LocalPath returns a path on the local filesystem to the file at key, for tools such as ffmpeg that only
+// read files by their path. Files of a Storage other than Local are copied to a temporary file at
+// "%TMP%/moonpool_storage_xxxxxx{ext}", which is removed by release.
+func LocalPath(ctx context.Context, s Storage, key string) (name string, release func(), err error) {
+	if l, ok := s.(Local); ok {
+		return l.Path(key), func() {}, nil
+	}
+
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rc.Close()
+
+	// ffmpeg guesses some formats by their extension
+	f, err := os.CreateTemp("", "moonpool_storage_*"+path.Ext(key))
+	if err != nil {
+		return "", nil, err
+	}
+	release = func() { os.Remove(f.Name()) }
+
+	_, err = io.Copy(f, rc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+
+	return f.Name(), release, nil
+}
+
+// renamer is a Storage that moves files itself, without passing them through Moonpool.
+type renamer interface {
+	Rename(ctx context.Context, from, to string) error
+}
+
+// Move moves the file at from to to, replacing any file already stored there. A Storage that can move files
+// itself, such as Local renaming them, does so. Otherwise the file is copied and then deleted.
+func Move(ctx context.Context, s Storage, from, to string) error {
+	if r, ok := s.(renamer); ok {
+		return r.Rename(ctx, from, to)
+	}
+
+	rc, err := s.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := s.Put(ctx, to, rc); err != nil {
+		return err
+	}
+	return s.Delete(ctx, from)
+}
+
+// NewReadSeeker returns an io.ReadSeekCloser over the file of size bytes at key, which only reads the ranges
+// of the file that are read from it. It lets http.ServeContent serve ranged requests from any Storage.
+func NewReadSeeker(ctx context.Context, s Storage, key string, size int64) io.ReadSeekCloser {
+	return &rangeReader{ctx: ctx, s: s, key: key, size: size}
+}
+
+type rangeReader struct {
+	ctx          context.Context
+	s            Storage
+	key          string
+	size, offset int64
+	r            io.ReadCloser
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.r == nil {
+		rc, err := r.s.GetRange(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.r = rc
+	}
+
+	n, err := r.r.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	// the next read starts a new range, unless nothing moved
+	if offset != r.offset {
+		r.Close()
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.r == nil {
+		return nil
+	}
+
+	err := r.r.Close()
+	r.r = nil
+	return err
+}
+
+// cleanKey removes any leading "/" and ".." from a key.
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, `\`, "/")), "/")
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testStorage runs the behavior every Storage shares against s, which must be empty.
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	files := map[string]string{
+		"ab/one.png":   "hello world",
+		"ab/two.png":   "two",
+		"cd/three.png": "three",
+	}
+	for key, content := range files {
+		if err := s.Put(ctx, key, strings.NewReader(content)); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+
+	t.Run("get", func(t *testing.T) {
+		for key, want := range files {
+			rc, err := s.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get(%s) error = %v", key, err)
+			}
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("Get(%s) = %q, want %q", key, got, want)
+			}
+		}
+	})
+
+	t.Run("get range", func(t *testing.T) {
+		tests := []struct {
+			offset, length int64
+			want           string
+		}{
+			{0, 5, "hello"},
+			{6, 5, "world"},
+			{6, -1, "world"},
+			{3, 0, ""},
+		}
+		for _, tt := range tests {
+			rc, err := s.GetRange(ctx, "ab/one.png", tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("GetRange(%d, %d) error = %v", tt.offset, tt.length, err)
+			}
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GetRange(%d, %d) = %q, want %q", tt.offset, tt.length, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		info, err := s.Stat(ctx, "ab/one.png")
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		if info.Key != "ab/one.png" || info.Size != 11 || info.ModTime.IsZero() {
+			t.Errorf("Stat() = %+v, want key ab/one.png of 11 bytes", info)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		tests := []struct {
+			prefix string
+			want   []string
+		}{
+			{"", []string{"ab/one.png", "ab/two.png", "cd/three.png"}},
+			{"ab/", []string{"ab/one.png", "ab/two.png"}},
+			{"cd/th", []string{"cd/three.png"}},
+			{"ef/", nil},
+		}
+		for _, tt := range tests {
+			infos, err := s.List(ctx, tt.prefix)
+			if err != nil {
+				t.Fatalf("List(%q) error = %v", tt.prefix, err)
+			}
+
+			var got []string
+			for _, info := range infos {
+				got = append(got, info.Key)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List(%q) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("read seeker", func(t *testing.T) {
+		rs := NewReadSeeker(ctx, s, "ab/one.png", 11)
+		defer rs.Close()
+
+		if n, err := rs.Seek(0, io.SeekEnd); err != nil || n != 11 {
+			t.Fatalf("Seek(0, io.SeekEnd) = %d, %v, want 11", n, err)
+		}
+		if _, err := rs.Seek(6, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := io.ReadAll(rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "world" {
+			t.Errorf("ReadAll() after Seek(6, io.SeekStart) = %q, want %q", got, "world")
+		}
+	})
+
+	t.Run("local path", func(t *testing.T) {
+		name, release, err := LocalPath(ctx, s, "ab/two.png")
+		if err != nil {
+			t.Fatalf("LocalPath() error = %v", err)
+		}
+		defer release()
+
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "two" {
+			t.Errorf("LocalPath() file = %q, want %q", got, "two")
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		if err := s.Put(ctx, "ab/two.png", strings.NewReader("2")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if info, err := s.Stat(ctx, "ab/two.png"); err != nil || info.Size != 1 {
+			t.Errorf("Stat() after overwrite = %+v, %v, want 1 byte", info, err)
+		}
+	})
+
+	t.Run("move", func(t *testing.T) {
+		if err := Move(ctx, s, "ab/two.png", "ef/gh/two.png"); err != nil {
+			t.Fatalf("Move() error = %v", err)
+		}
+		if _, err := s.Stat(ctx, "ab/two.png"); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Stat() of moved file error = %v, want %v", err, ErrNotExist)
+		}
+		if info, err := s.Stat(ctx, "ef/gh/two.png"); err != nil || info.Size != 1 {
+			t.Errorf("Stat() of moved file destination = %+v, %v, want 1 byte", info, err)
+		}
+
+		if err := Move(ctx, s, "ab/two.png", "ab/three.png"); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Move() of missing file error = %v, want %v", err, ErrNotExist)
+		}
+
+		if err := Move(ctx, s, "ef/gh/two.png", "ab/two.png"); err != nil {
+			t.Fatalf("Move() error = %v", err)
+		}
+	})
+
+	t.Run("escaping key", func(t *testing.T) {
+		if err := s.Put(ctx, "../../escaped.png", strings.NewReader("escaped")); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		if _, err := s.Stat(ctx, "escaped.png"); err != nil {
+			t.Errorf("Stat() of escaping key error = %v, want it stored at escaped.png", err)
+		}
+		if err := s.Delete(ctx, "escaped.png"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := s.Delete(ctx, "cd/three.png"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := s.Get(ctx, "cd/three.png"); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Get() of deleted file error = %v, want %v", err, ErrNotExist)
+		}
+		if err := s.Delete(ctx, "cd/three.png"); err != nil {
+			t.Errorf("Delete() of deleted file error = %v, want nil", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := s.Get(ctx, "missing.png"); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Get() error = %v, want %v", err, ErrNotExist)
+		}
+		if _, err := s.Stat(ctx, "missing.png"); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Stat() error = %v, want %v", err, ErrNotExist)
+		}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Config
+		wantErr bool
+	}{
+		{"default", Config{}, false},
+		{"local", Config{Type: "local"}, false},
+		{"s3", Config{Type: "S3", Bucket: "moonpool"}, false},
+		{"s3 without bucket", Config{Type: "s3"}, true},
+		{"unknown", Config{Type: "ftp"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.c, t.TempDir()); (err != nil) != tt.wantErr {
+				t.Errorf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_cleanKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"ab/abcdef.png", "ab/abcdef.png"},
+		{"/ab/abcdef.png", "ab/abcdef.png"},
+		{"../../ab/abcdef.png", "ab/abcdef.png"},
+		{"ab/../../abcdef.png", "abcdef.png"},
+		{`ab\abcdef.png`, "ab/abcdef.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := cleanKey(tt.key); got != tt.want {
+				t.Errorf("cleanKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
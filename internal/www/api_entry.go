@@ -5,11 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"mime"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/dtbead/moonpool/api"
 	"github.com/dtbead/moonpool/entry"
 	"github.com/dtbead/moonpool/importer"
 	"github.com/dtbead/moonpool/internal/file"
@@ -77,9 +78,36 @@ func (w WWW) entry() {
 			fmt.Printf("[%s] WARNING: failed to get timestamps for archive id %d. %v\n", c.Request().RemoteAddr, archive_id, err)
 		}
 
+		notes, err := w.api.GetNotes(ctx, archive_id)
+		if err != nil {
+			fmt.Printf("[%s] WARNING: failed to get notes for archive id %d. %v\n", c.Request().RemoteAddr, archive_id, err)
+		}
+
+		metadata, err := w.api.GetFileMetadata(ctx, archive_id)
+		if err != nil {
+			fmt.Printf("[%s] WARNING: failed to get metadata for archive id %d. %v\n", c.Request().RemoteAddr, archive_id, err)
+		}
+
+		renditions, err := w.api.GetRenditions(ctx, archive_id)
+		if err != nil {
+			fmt.Printf("[%s] WARNING: failed to get renditions for archive id %d. %v\n", c.Request().RemoteAddr, archive_id, err)
+		}
+
+		var exif map[string]interface{}
+		e, err := w.api.GetExif(ctx, archive_id)
+		if err == nil && w.scrubMetadata(c) {
+			exif = exifToMap(scrubExif(e))
+		} else if err == nil {
+			exif = exifToMap(e)
+		} else if !errors.Is(err, api.ErrNoExif) {
+			fmt.Printf("[%s] WARNING: failed to get exif for archive id %d. %v\n", c.Request().RemoteAddr, archive_id, err)
+		}
+
 		c.JSON(http.StatusOK, map[string]interface{}{
 			"archive_id": archive_id,
 			"extension":  path.FileExtension,
+			"metadata":   fileMetadataToMap(metadata),
+			"exif":       exif,
 			"timestamps": map[string]string{
 				"date_created":  timestamps.DateCreated.String(),
 				"date_modified": timestamps.DateModified.String(),
@@ -90,7 +118,9 @@ func (w WWW) entry() {
 				"sha1":   file.ByteToHexString(hashes.SHA1),
 				"sha256": file.ByteToHexString(hashes.SHA256),
 			},
-			"tags": tags,
+			"tags":       tags,
+			"notes":      notesToMap(notes),
+			"renditions": renditionsToMap(renditions),
 		})
 
 		fmt.Printf("[%s] INFO: sent post %d\n", c.Request().RemoteAddr, archive_id)
@@ -150,21 +180,21 @@ func (w WWW) upload() {
 			return errors.New("too large of filesize")
 		}
 
-		extension, err := mime.ExtensionsByType(formFile.Header.Get("Content-Type"))
-		if err != nil || extension == nil {
-			fmt.Printf("[%s] ERROR: unable to get extension from mimetype. %v\n", c.Request().RemoteAddr, err)
-			c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "bad content-type"})
-			return err
-		}
-
 		multipartFile, err := formFile.Open()
 		if err != nil {
 			fmt.Printf("[%s] WARNING: failed to open multipart file. %v\n", c.Request().RemoteAddr, err)
 			c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "bad file"})
 			return err
 		}
+		defer multipartFile.Close()
 
-		entry, err := importer.New(multipartFile, extension[0])
+		// the file type is detected from its content rather than the client's Content-Type header
+		entry, err := importer.New(multipartFile, filepath.Ext(formFile.Filename))
+		if errors.Is(err, file.ErrUnsupportedFileType) || errors.Is(err, file.ErrMislabeledFile) {
+			fmt.Printf("[%s] WARNING: rejected upload \"%s\". %v\n", c.Request().RemoteAddr, formFile.Filename, err)
+			c.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{"message": err.Error()})
+			return err
+		}
 		if err != nil {
 			fmt.Printf("[%s] ERROR: failed to create new entry. %v\n", c.Request().RemoteAddr, err)
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
@@ -178,6 +208,10 @@ func (w WWW) upload() {
 			return err
 		}
 
+		if err := w.api.EnqueueJobs(context.Background(), archive_id); err != nil {
+			fmt.Printf("[%s] WARNING: failed to queue jobs for archive_id %d. %v\n", c.Request().RemoteAddr, archive_id, err)
+		}
+
 		c.JSON(http.StatusAccepted, map[string]interface{}{"id": archive_id, "url": fmt.Sprintf("%s/post/entry/%d", c.Echo().Server.Addr, archive_id)})
 		fmt.Printf("[%s] INFO: successful import for archive_id %d\n", c.Request().RemoteAddr, archive_id)
 		return nil
@@ -282,6 +316,50 @@ func (w WWW) getHashes() {
 	})
 }
 
+// getStoryboard returns the WebVTT thumbnails track of a video, referencing frames of getStoryboardSprite
+func (w WWW) getStoryboard() {
+	w.echo.GET("api/entry/:id/storyboard", func(c echo.Context) error {
+		archive_id := stringToInt64(c.Param("id"))
+		if archive_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "post not found"})
+			return errors.New("invalid archive id")
+		}
+
+		storyboard, err := w.api.GetStoryboard(c.Request().Context(), archive_id)
+		if errors.Is(err, api.ErrStoryboardNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{"message": "storyboard not found"})
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.Blob(http.StatusOK, "text/vtt; charset=utf-8", []byte(storyboard.VTT))
+	})
+}
+
+// getStoryboardSprite returns the sprite sheet of every frame of the storyboard of a video
+func (w WWW) getStoryboardSprite() {
+	w.echo.GET("api/entry/:id/"+api.StoryboardSpriteURL, func(c echo.Context) error {
+		archive_id := stringToInt64(c.Param("id"))
+		if archive_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "post not found"})
+			return errors.New("invalid archive id")
+		}
+
+		storyboard, err := w.api.GetStoryboard(c.Request().Context(), archive_id)
+		if errors.Is(err, api.ErrStoryboardNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{"message": "storyboard not found"})
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.Blob(http.StatusOK, "image/jpeg", storyboard.Sprite)
+	})
+}
+
 func (w WWW) getFile() {
 	w.echo.GET("api/entry/:id/file", func(c echo.Context) error {
 		archive_id := stringToInt64(c.Param("id"))
@@ -300,8 +378,7 @@ func (w WWW) getFile() {
 			return err
 		}
 
-		fullPath := w.api.Config.MediaLocation + "/" + entry.FileRelative
-		if err := c.File(fullPath); err != nil {
+		if err := w.serveMedia(c, entry.FileRelative); err != nil {
 			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "failed to retrieve content"})
 			return err
 		}
@@ -330,3 +407,285 @@ func (w WWW) deleteEntry() {
 		return c.JSON(http.StatusAccepted, map[string]interface{}{"message": "success"})
 	})
 }
+
+func noteToMap(n entry.Note) map[string]interface{} {
+	return map[string]interface{}{
+		"note_id":       n.NoteID,
+		"archive_id":    n.ArchiveID,
+		"title":         n.Title,
+		"text":          n.Text,
+		"date_created":  n.DateCreated.String(),
+		"date_modified": n.DateModified.String(),
+	}
+}
+
+// fileMetadataToMap returns the metadata of an entry, with its duration in seconds.
+func fileMetadataToMap(m entry.FileMetadata) map[string]interface{} {
+	return map[string]interface{}{
+		"file_size":   m.FileSize,
+		"mimetype":    m.FileMimetype,
+		"width":       m.MediaWidth,
+		"height":      m.MediaHeight,
+		"orientation": m.MediaOrientation,
+		"duration":    m.MediaDuration.Seconds(),
+		"framerate":   m.MediaFramerate,
+		"video_codec": m.MediaVideoCodec,
+		"audio_codec": m.MediaAudioCodec,
+		"bitrate":     m.MediaBitrate,
+		"has_audio":   m.MediaHasAudio,
+	}
+}
+
+// exifToMap returns the capture metadata of an entry. Fields that are unknown are left out.
+func exifToMap(e entry.Exif) map[string]interface{} {
+	m := make(map[string]interface{})
+	if !e.DateTimeOriginal.IsZero() {
+		m["date_original"] = e.DateTimeOriginal.String()
+	}
+	for k, v := range map[string]string{"make": e.Make, "model": e.Model, "lens": e.Lens} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if e.Orientation != 0 {
+		m["orientation"] = e.Orientation
+	}
+	if e.HasGPS {
+		m["gps"] = map[string]float64{
+			"latitude":  e.Latitude,
+			"longitude": e.Longitude,
+			"altitude":  e.Altitude,
+		}
+	}
+	return m
+}
+
+// renditionsToMap returns the web-playable transcodes of an entry, with their path relative to "/media".
+func renditionsToMap(r []entry.Rendition) []map[string]interface{} {
+	renditions := make([]map[string]interface{}, len(r))
+	for i, v := range r {
+		renditions[i] = map[string]interface{}{
+			"path":        v.Path,
+			"mimetype":    v.Mimetype,
+			"video_codec": v.VideoCodec,
+			"audio_codec": v.AudioCodec,
+			"file_size":   v.FileSize,
+		}
+	}
+	return renditions
+}
+
+func notesToMap(n []entry.Note) []map[string]interface{} {
+	notes := make([]map[string]interface{}, len(n))
+	for i, v := range n {
+		notes[i] = noteToMap(v)
+	}
+	return notes
+}
+
+func (w WWW) getNotes() {
+	w.echo.GET("api/entry/:id/notes", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		archive_id := stringToInt64(c.Param("id"))
+		if archive_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "post not found"})
+			return errors.New("invalid archive id")
+		}
+
+		notes, err := w.api.GetNotes(ctx, archive_id)
+		defer isDeadlined(c, err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusOK, notesToMap(notes))
+	})
+}
+
+func (w WWW) newNote() {
+	w.echo.POST("api/entry/:id/notes", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		archive_id := stringToInt64(c.Param("id"))
+		if archive_id <= 0 || !w.api.DoesEntryExist(ctx, archive_id) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "post not found"})
+			return errors.New("invalid archive id")
+		}
+
+		note_id, err := w.api.NewNote(ctx, archive_id, c.FormValue("title"), c.FormValue("text"))
+		if errors.Is(err, api.ErrEmptyNote) {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "no title or text given"})
+			return err
+		}
+		defer isDeadlined(c, err)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, map[string]interface{}{"message": "success", "note_id": note_id})
+	})
+}
+
+func (w WWW) updateNote() {
+	w.echo.PUT("api/entry/:id/notes/:note_id", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		archive_id, note_id := stringToInt64(c.Param("id")), stringToInt64(c.Param("note_id"))
+		if archive_id <= 0 || note_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "note not found"})
+			return errors.New("invalid archive id or note id")
+		}
+
+		n, err := w.api.GetNote(ctx, note_id)
+		if errors.Is(err, api.ErrNoteNotFound) || (err == nil && n.ArchiveID != archive_id) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "note not found"})
+			return api.ErrNoteNotFound
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		err = w.api.UpdateNote(ctx, note_id, c.FormValue("title"), c.FormValue("text"))
+		if errors.Is(err, api.ErrNoteNotFound) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "note not found"})
+			return err
+		}
+
+		if errors.Is(err, api.ErrEmptyNote) {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "no title or text given"})
+			return err
+		}
+		defer isDeadlined(c, err)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusAccepted, map[string]interface{}{"message": "success"})
+	})
+}
+
+func (w WWW) deleteNote() {
+	w.echo.DELETE("api/entry/:id/notes/:note_id", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		archive_id, note_id := stringToInt64(c.Param("id")), stringToInt64(c.Param("note_id"))
+		if archive_id <= 0 || note_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "note not found"})
+			return errors.New("invalid archive id or note id")
+		}
+
+		n, err := w.api.GetNote(ctx, note_id)
+		if errors.Is(err, api.ErrNoteNotFound) || (err == nil && n.ArchiveID != archive_id) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "note not found"})
+			return api.ErrNoteNotFound
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		err = w.api.DeleteNote(ctx, note_id)
+		if errors.Is(err, api.ErrNoteNotFound) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "note not found"})
+			return err
+		}
+		defer isDeadlined(c, err)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusAccepted, map[string]interface{}{"message": "success"})
+	})
+}
+
+// searchNotes returns every note containing the 'query' form value in its title or text
+func (w WWW) searchNotes() {
+	w.echo.GET("api/notes/search", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+
+		limit, offset := stringToInt64(c.FormValue("limit")), stringToInt64(c.FormValue("offset"))
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		notes, err := w.api.SearchNotes(ctx, c.FormValue("query"), limit, offset)
+		defer isDeadlined(c, err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusOK, notesToMap(notes))
+	})
+}
+
+// getSimilar returns entries whose perceptual hash is close to a given archive_id, closest first.
+// The hash type compared is given by "type", defaulting to PHash.
+func (w WWW) getSimilar() {
+	w.echo.GET("api/entry/:id/similar", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		archive_id := stringToInt64(c.Param("id"))
+		if archive_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "post not found"})
+			return errors.New("invalid archive id")
+		}
+
+		distance := int64(api.DefaultSimilarDistance)
+		if c.FormValue("distance") != "" {
+			distance = stringToInt64(c.FormValue("distance"))
+		}
+
+		limit := stringToInt64(c.FormValue("limit"))
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+
+		similar, err := w.api.FindSimilar(ctx, archive_id, c.FormValue("type"), int(distance))
+		if errors.Is(err, api.ErrInvalidDistance) || errors.Is(err, api.ErrUnknownHashType) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		}
+		if errors.Is(err, api.ErrNoPerceptualHash) {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{"message": err.Error()})
+		}
+		defer isDeadlined(c, err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		if int64(len(similar)) > limit {
+			similar = similar[:limit]
+		}
+
+		res := make([]map[string]interface{}, len(similar))
+		for i, s := range similar {
+			res[i] = map[string]interface{}{
+				"archive_id": s.ArchiveID,
+				"distance":   s.Distance,
+			}
+		}
+
+		return c.JSON(http.StatusOK, res)
+	})
+}
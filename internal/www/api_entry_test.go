@@ -0,0 +1,117 @@
+package www
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dtbead/moonpool/api"
+	"github.com/dtbead/moonpool/importer"
+)
+
+const testXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description xmlns:tiff="http://ns.adobe.com/tiff/1.0/" xmlns:exif="http://ns.adobe.com/exif/1.0/"
+    tiff:Make="FUJIFILM" tiff:Model="X-T4" exif:GPSLatitude="35,39.6N" exif:GPSLongitude="139,44.7E">
+   <exif:DateTimeOriginal>2021-03-04T05:06:07Z</exif:DateTimeOriginal>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// importTestPhoto imports a JPEG taken by a Fujifilm X-T4 in Tokyo.
+func importTestPhoto(ctx context.Context, t *testing.T, a *api.API) int64 {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), testXMP...)
+	b := append([]byte("\xff\xd8\xff\xe1"), binary.BigEndian.AppendUint16(nil, uint16(len(xmp)+2))...)
+	b = append(append(b, xmp...), buf.Bytes()[2:]...)
+
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	i, err := importer.New(f, ".jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive_id, err := a.Import(ctx, i)
+	if err != nil {
+		t.Fatalf("API.Import() error = %v", err)
+	}
+	return archive_id
+}
+
+func TestWWW_entry_Scrub(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a, err := api.New(api.Config{ArchiveLocation: ":memory:", ThumbnailLocation: ":memory:", MediaLocation: t.TempDir()}, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close(context.Background())
+
+	archive_id := importTestPhoto(context.Background(), t, a)
+
+	tests := []struct {
+		name      string
+		scrub     bool
+		query     string
+		wantScrub bool
+	}{
+		{"not scrubbed", false, "", false},
+		{"scrubbed by config", true, "", true},
+		{"scrubbed by request", false, "?scrub=true", true},
+		{"request can't opt out", true, "?scrub=false", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(a, Config{ScrubMetadata: tt.scrub, Log: l})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			w.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/entry/%d%s", archive_id, tt.query), nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET /api/entry/%d status = %d, want %d", archive_id, rec.Code, http.StatusOK)
+			}
+
+			var got struct {
+				Exif map[string]interface{} `json:"exif"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := got.Exif["date_original"]; !ok {
+				t.Errorf("GET /api/entry/%d exif = %v, want a capture date", archive_id, got.Exif)
+			}
+			for _, k := range []string{"gps", "make", "model"} {
+				if _, ok := got.Exif[k]; ok == tt.wantScrub {
+					t.Errorf("GET /api/entry/%d exif = %v, want %q scrubbed %v", archive_id, got.Exif, k, tt.wantScrub)
+				}
+			}
+		})
+	}
+}
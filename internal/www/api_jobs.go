@@ -0,0 +1,132 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dtbead/moonpool/api"
+	"github.com/dtbead/moonpool/entry"
+	"github.com/labstack/echo/v4"
+)
+
+var jobStatuses = []string{api.JobPending, api.JobRunning, api.JobDone, api.JobFailed}
+
+// getJobs returns the amount of jobs of each status, and a page of jobs filtered by the 'status' form value
+func (w WWW) getJobs() {
+	w.echo.GET("api/jobs", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+
+		status := c.FormValue("status")
+		if status != "" && !slices.Contains(jobStatuses, status) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"message": "invalid status"})
+		}
+
+		limit, offset := stringToInt64(c.FormValue("limit")), stringToInt64(c.FormValue("offset"))
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		counts, err := w.api.CountJobs(ctx)
+		defer isDeadlined(c, err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		jobs, err := w.api.GetJobs(ctx, status, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"counts": counts,
+			"jobs":   jobsToMap(jobs),
+		})
+	})
+}
+
+func (w WWW) getJob() {
+	w.echo.GET("api/jobs/:id", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		job_id := stringToInt64(c.Param("id"))
+		if job_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "job not found"})
+			return errors.New("invalid job id")
+		}
+
+		j, err := w.api.GetJob(ctx, job_id)
+		if errors.Is(err, api.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "job not found"})
+			return err
+		}
+		defer isDeadlined(c, err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusOK, jobToMap(j))
+	})
+}
+
+// retryJob queues a failed job again
+func (w WWW) retryJob() {
+	w.echo.POST("api/jobs/:id/retry", func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		job_id := stringToInt64(c.Param("id"))
+		if job_id <= 0 {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "job not found"})
+			return errors.New("invalid job id")
+		}
+
+		err := w.api.RetryJob(ctx, job_id)
+		if errors.Is(err, api.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"message": "job not found"})
+			return err
+		}
+		if errors.Is(err, api.ErrJobNotFailed) {
+			return c.JSON(http.StatusConflict, map[string]interface{}{"message": err.Error()})
+		}
+		defer isDeadlined(c, err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"message": "unknown error"})
+			return err
+		}
+
+		return c.JSON(http.StatusAccepted, map[string]interface{}{"message": "success"})
+	})
+}
+
+func jobToMap(j entry.Job) map[string]interface{} {
+	return map[string]interface{}{
+		"job_id":        j.JobID,
+		"archive_id":    j.ArchiveID,
+		"type":          j.Type,
+		"status":        j.Status,
+		"attempts":      j.Attempts,
+		"error":         j.Error,
+		"run_after":     j.RunAfter.String(),
+		"date_created":  j.DateCreated.String(),
+		"date_modified": j.DateModified.String(),
+	}
+}
+
+func jobsToMap(j []entry.Job) []map[string]interface{} {
+	jobs := make([]map[string]interface{}, len(j))
+	for i, v := range j {
+		jobs[i] = jobToMap(v)
+	}
+	return jobs
+}
@@ -2,6 +2,7 @@ package www
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -33,8 +34,25 @@ func (w WWW) Browse() {
 			descedingOrder = false
 		}
 
+		searchOptions.PageAmount = stringToInt64(c.FormValue("amount"))
+		if searchOptions.PageAmount <= 0 || searchOptions.PageAmount > DEFAULT_PAGES_MAX {
+			searchOptions.PageAmount = DEFAULT_PAGES_MAX
+		}
+
+		searchOptions.PageOffset = stringToInt64(c.FormValue("offset"))
+		if searchOptions.PageOffset < 0 {
+			searchOptions.PageOffset = 0
+		}
+
 		if searchOptions.Query != "" {
-			res, err := w.api.QueryTags(ctx, strings.ToLower(searchOptions.Sort), strings.ToLower(searchOptions.Order), api.BuildQuery(searchOptions.Query))
+			res, total, err := w.api.QueryTags(ctx, strings.ToLower(searchOptions.Sort), strings.ToLower(searchOptions.Order),
+				searchOptions.Query, searchOptions.PageAmount, searchOptions.PageOffset)
+			if errors.Is(err, api.ErrInvalidQuery) {
+				return c.Render(http.StatusBadRequest, "browse.html", map[string]interface{}{
+					"queryError":    err.Error(),
+					"searchOptions": searchOptions,
+				})
+			}
 			if err != nil {
 				return err
 			}
@@ -44,9 +62,17 @@ func (w WWW) Browse() {
 				return err
 			}
 
+			previews, err := w.previews(ctx, res)
+			if err != nil {
+				return err
+			}
+
 			if err := c.Render(http.StatusOK, "browse.html", map[string]interface{}{
 				"entries":       res,
+				"previews":      previews,
 				"tagList":       tags,
+				"total":         total,
+				"pageEnd":       searchOptions.PageOffset + int64(len(res)),
 				"searchOptions": searchOptions,
 			}); err != nil {
 				return err
@@ -54,16 +80,6 @@ func (w WWW) Browse() {
 			return nil
 		}
 
-		searchOptions.PageAmount = stringToInt64(c.FormValue("amount"))
-		if searchOptions.PageAmount <= 0 || searchOptions.PageAmount > DEFAULT_PAGES_MAX {
-			searchOptions.PageAmount = DEFAULT_PAGES_MAX
-		}
-
-		searchOptions.PageOffset = stringToInt64(c.FormValue("offset"))
-		if searchOptions.PageOffset < 0 {
-			searchOptions.PageOffset = 0
-		}
-
 		page, err := w.api.GetPage(ctx, searchOptions.Sort, searchOptions.PageAmount, searchOptions.PageOffset, descedingOrder)
 		if err != nil {
 			return err
@@ -79,8 +95,14 @@ func (w WWW) Browse() {
 			return err
 		}
 
+		previews, err := w.previews(ctx, archive_ids)
+		if err != nil {
+			return err
+		}
+
 		if err := c.Render(http.StatusOK, "browse.html", map[string]interface{}{
 			"entries":       archive_ids,
+			"previews":      previews,
 			"tagList":       pageTags,
 			"searchOptions": searchOptions,
 		}); err != nil {
@@ -90,3 +112,8 @@ func (w WWW) Browse() {
 		return nil
 	})
 }
+
+// previews returns which archive_ids have an animated preview to show on hover.
+func (w WWW) previews(ctx context.Context, archive_ids []int64) (map[int64]bool, error) {
+	return w.api.HasPreviews(ctx, archive_ids)
+}
@@ -0,0 +1,83 @@
+package www
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/dtbead/moonpool/internal/file"
+	"github.com/dtbead/moonpool/internal/media"
+	"github.com/dtbead/moonpool/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+func (w WWW) Media() {
+	w.echo.GET("media/*", func(c echo.Context) error {
+		p, err := url.PathUnescape(c.Param("*"))
+		if err != nil {
+			return echo.ErrNotFound
+		}
+
+		return w.serveMedia(c, p)
+	})
+}
+
+// serveMedia serves a file relative to the media storage. JPEG, PNG and WebP files are served without their
+// location and device metadata if the instance is configured to scrub them, or if the request asks for it
+// with "?scrub=true". The stored file itself is never modified.
+func (w WWW) serveMedia(c echo.Context, relative string) error {
+	ctx := c.Request().Context()
+	s := w.api.Storage()
+
+	// cleaning an absolute path removes any ".." that would escape the media storage
+	key := strings.TrimPrefix(path.Clean("/"+relative), "/")
+
+	info, err := s.Stat(ctx, key)
+	if errors.Is(err, storage.ErrNotExist) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	mimetype := file.GetMimeTypeByExtension(path.Ext(key))
+	c.Response().Header().Set("Content-Type", mimetype)
+
+	if !w.scrubMetadata(c) || !media.CanScrubMetadata(mimetype) {
+		// only the ranges a client requests are read, so videos can be seeked without fetching them whole
+		rs := storage.NewReadSeeker(ctx, s, key, info.Size)
+		defer rs.Close()
+
+		http.ServeContent(c.Response(), c.Request(), path.Base(key), info.ModTime, rs)
+		return nil
+	}
+
+	f, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if err := media.ScrubMetadata(&buf, f); err != nil {
+		return err
+	}
+
+	http.ServeContent(c.Response(), c.Request(), path.Base(key), info.ModTime, bytes.NewReader(buf.Bytes()))
+	return nil
+}
+
+// scrubMetadata reports whether a served file should have its metadata scrubbed. A request can only opt into
+// scrubbing, never out of an instance that always scrubs.
+func (w WWW) scrubMetadata(c echo.Context) bool {
+	if w.config.ScrubMetadata {
+		return true
+	}
+
+	scrub, _ := strconv.ParseBool(c.QueryParam("scrub"))
+	return scrub
+}
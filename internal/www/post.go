@@ -2,13 +2,13 @@ package www
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
-	"mime"
 	"net/http"
-	"strconv"
 	"strings"
 
+	"github.com/dtbead/moonpool/api"
 	"github.com/dtbead/moonpool/internal/file"
 	"github.com/labstack/echo/v4"
 )
@@ -74,13 +74,39 @@ func (w WWW) Post() {
 		}
 
 		var mediaType string
-		if strings.HasPrefix(metadata.FileMimetype, "video") {
+		switch {
+		case strings.HasPrefix(metadata.FileMimetype, "video"):
 			mediaType = "video"
-		} else {
+		case strings.HasPrefix(metadata.FileMimetype, "audio"):
+			mediaType = "audio"
+		default:
 			mediaType = "image"
 		}
 
-		pHash, _ := w.api.GetPerceptualHash(ctx, archive_id, "")
+		var exif map[string]string
+		e, err := w.api.GetExif(ctx, archive_id)
+		if err == nil && w.scrubMetadata(c) {
+			exif = exifToStrings(scrubExif(e))
+		} else if err == nil {
+			exif = exifToStrings(e)
+		} else if !errors.Is(err, api.ErrNoExif) {
+			return err
+		}
+
+		renditions, err := w.api.GetRenditions(ctx, archive_id)
+		if err != nil {
+			return err
+		}
+
+		perceptualHashes, err := w.api.GetPerceptualHashes(ctx, archive_id)
+		if err != nil {
+			return err
+		}
+
+		notes, err := w.api.GetNotes(ctx, archive_id)
+		if err != nil {
+			return err
+		}
 
 		if err := c.Render(http.StatusOK, "entry.html", map[string]interface{}{
 			"archive_id":  archive_id,
@@ -90,8 +116,8 @@ func (w WWW) Post() {
 				"md5":    file.ByteToHexString(hashes.MD5),
 				"sha1":   file.ByteToHexString(hashes.SHA1),
 				"sha256": file.ByteToHexString(hashes.SHA256),
-				"phash":  strconv.FormatUint(pHash, 16),
 			},
+			"perceptualHashes": perceptualHashesToMap(perceptualHashes),
 			"timestamps": map[string]string{
 				"imported": timeToString(timestamps.DateImported.Local()),
 				"modified": timeToString(timestamps.DateModified.Local()),
@@ -103,10 +129,18 @@ func (w WWW) Post() {
 				"media_orientation": metadata.MediaOrientation,
 				"height":            int64ToString(metadata.MediaHeight),
 				"width":             int64ToString(metadata.MediaWidth),
+				"duration":          durationToString(metadata.MediaDuration),
+				"framerate":         framerateToString(metadata.MediaFramerate),
+				"video_codec":       metadata.MediaVideoCodec,
+				"audio_codec":       metadata.MediaAudioCodec,
+				"bitrate":           bitrateToString(metadata.MediaBitrate),
 			},
-			"media":     media.FileRelative,
-			"mediaType": mediaType,
-			"extension": mime.TypeByExtension(media.FileExtension),
+			"exif":       exif,
+			"notes":      notes,
+			"media":      media.FileRelative,
+			"renditions": renditions,
+			"mediaType":  mediaType,
+			"extension":  file.GetMimeTypeByExtension(media.FileExtension),
 		}); err != nil {
 			fmt.Printf("error rendering post. %v\n", err)
 			return err
@@ -1,16 +1,67 @@
 package www
 
 import (
+	"cmp"
 	_ "embed"
 	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/dtbead/moonpool/api"
+	"github.com/dtbead/moonpool/internal/media"
 	"github.com/labstack/echo/v4"
 )
 
 //go:embed web/assets/static/404.png
 var defaultThumbnailPNG []byte
 
+// thumbnailFormats returns the thumbnail formats an Accept header explicitly lists, most preferred first.
+// Wildcards are ignored, since JPEG is always served as a fallback.
+func thumbnailFormats(accept string) []string {
+	type accepted struct {
+		format string
+		q      float64
+	}
+
+	var formats []accepted
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mimetype, params, _ := strings.Cut(mediaRange, ";")
+		mimetype = strings.ToLower(strings.TrimSpace(mimetype))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		for format, m := range media.ThumbnailFormats {
+			if m == mimetype && q > 0 {
+				formats = append(formats, accepted{format, q})
+			}
+		}
+	}
+
+	// smaller formats come first among those of equal preference
+	preference := []string{"avif", "webp", "jpeg"}
+	slices.SortStableFunc(formats, func(a, b accepted) int {
+		if a.q != b.q {
+			return cmp.Compare(b.q, a.q)
+		}
+		return cmp.Compare(slices.Index(preference, a.format), slices.Index(preference, b.format))
+	})
+
+	s := make([]string, len(formats))
+	for i, f := range formats {
+		s[i] = f.format
+	}
+	return s
+}
+
 func (w WWW) Thumbnail() {
 	w.echo.GET("thumbnail/:id", func(c echo.Context) error {
 		defaultThumbnail := func(c echo.Context) {
@@ -25,7 +76,27 @@ func (w WWW) Thumbnail() {
 			return errors.New("invalid archive id")
 		}
 
-		thumb, err := w.api.GetThumbnail(c.Request().Context(), archive_id, "small", "jpeg")
+		size := c.QueryParam("size")
+		if size == "" {
+			size = "small"
+		}
+		if size != "small" && size != "medium" && size != "large" {
+			defaultThumbnail(c)
+			return errors.New("invalid thumbnail size")
+		}
+
+		// an explicit format takes precedence over the formats a client accepts
+		formats := thumbnailFormats(c.Request().Header.Get("Accept"))
+		if format := c.QueryParam("format"); format != "" {
+			if _, ok := media.ThumbnailFormats[format]; !ok {
+				defaultThumbnail(c)
+				return errors.New("invalid thumbnail format")
+			}
+			formats = []string{format}
+		}
+
+		c.Response().Header().Add("Vary", "Accept")
+		thumb, format, err := w.api.GetPreferredThumbnail(c.Request().Context(), archive_id, size, formats...)
 		if errors.Is(err, api.ErrThumbnailNotFound) {
 			defaultThumbnail(c)
 			return nil
@@ -36,7 +107,7 @@ func (w WWW) Thumbnail() {
 			return err
 		}
 
-		c.Response().Header().Add("Content-Type", "image/jpeg")
+		c.Response().Header().Add("Content-Type", media.ThumbnailFormats[format])
 		if _, err := c.Response().Write(thumb); err != nil {
 			return err
 		}
@@ -45,3 +116,22 @@ func (w WWW) Thumbnail() {
 		return nil
 	})
 }
+
+func (w WWW) Preview() {
+	w.echo.GET("thumbnail/:id/preview", func(c echo.Context) error {
+		archive_id := stringToInt64(c.Param("id"))
+		if archive_id <= 0 {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		preview, mimetype, err := w.api.GetPreview(c.Request().Context(), archive_id)
+		if errors.Is(err, api.ErrPreviewNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.Blob(http.StatusOK, mimetype, preview)
+	})
+}
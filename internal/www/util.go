@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dtbead/moonpool/entry"
 	"github.com/labstack/echo/v4"
 )
 
@@ -39,6 +42,23 @@ func add(n, s int64) int64 {
 	return n + s
 }
 
+// perceptualHashesToMap formats perceptual hashes as lowercase type names and hexadecimal strings.
+func perceptualHashesToMap(hashes []entry.PerceptualHash) []map[string]string {
+	m := make([]map[string]string, len(hashes))
+	for i, h := range hashes {
+		var b strings.Builder
+		for _, w := range h.Hash {
+			fmt.Fprintf(&b, "%016x", w)
+		}
+		m[i] = map[string]string{"type": strings.ToLower(h.Type), "hash": b.String()}
+	}
+	return m
+}
+
+func neg(n int64) int64 {
+	return -n
+}
+
 func isDeadlined(c echo.Context, err error) error {
 	if errors.Is(err, context.DeadlineExceeded) {
 		fmt.Printf("[%s] WARNING: request timed-out\n", c.Request().RemoteAddr)
@@ -47,3 +67,64 @@ func isDeadlined(c echo.Context, err error) error {
 	}
 	return nil
 }
+
+// durationToString formats a duration as "h:mm:ss", or "m:ss" if shorter than an hour. A zero duration
+// returns a blank string.
+func durationToString(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+
+	d = d.Round(time.Second)
+	h, m, s := int64(d/time.Hour), int64(d%time.Hour/time.Minute), int64(d%time.Minute/time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// framerateToString formats frames per second with up to two decimals, such as "29.97 fps". A zero framerate
+// returns a blank string.
+func framerateToString(f float64) string {
+	if f <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64) + " fps"
+}
+
+// bitrateToString formats bits per second in kilobits, such as "320 kbps". A zero bitrate returns a blank string.
+func bitrateToString(b int64) string {
+	if b <= 0 {
+		return ""
+	}
+	return int64ToString((b+500)/1000) + " kbps"
+}
+
+// scrubExif returns the capture metadata of an entry without the location and device it was taken with,
+// which scrubbing removes from served files as well.
+func scrubExif(e entry.Exif) entry.Exif {
+	return entry.Exif{DateTimeOriginal: e.DateTimeOriginal, Orientation: e.Orientation}
+}
+
+// exifToStrings formats the capture metadata of an entry for the entry page. Fields that are unknown are
+// left out.
+func exifToStrings(e entry.Exif) map[string]string {
+	m := make(map[string]string)
+	if !e.DateTimeOriginal.IsZero() {
+		m["taken"] = timeToString(e.DateTimeOriginal.Local())
+	}
+	if camera := strings.TrimSpace(e.Make + " " + strings.TrimPrefix(e.Model, e.Make)); camera != "" {
+		m["camera"] = camera
+	}
+	if e.Lens != "" {
+		m["lens"] = e.Lens
+	}
+	if e.Orientation != 0 {
+		m["orientation"] = int64ToString(e.Orientation)
+	}
+	if e.HasGPS {
+		m["location"] = strconv.FormatFloat(e.Latitude, 'f', 6, 64) + ", " + strconv.FormatFloat(e.Longitude, 'f', 6, 64)
+		m["altitude"] = strconv.FormatFloat(e.Altitude, 'f', 1, 64) + " m"
+	}
+	return m
+}
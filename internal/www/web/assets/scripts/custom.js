@@ -60,4 +60,174 @@ function getArchiveID() {
 	var url = window.location.href.split('?')[0];
 	var archive_id = url.match(/\/(\d+)$/)[1];
 	return archive_id
-}
\ No newline at end of file
+}
+
+// toggleNoteEditor loads an existing note into the note editor, or clears the editor if the note is already loaded.
+function toggleNoteEditor(note_id) {
+	var idInput = document.getElementById("note_edit_id")
+	if (idInput.value == String(note_id)) {
+		idInput.value = ""
+		document.getElementById("note_edit_title").value = ""
+		document.getElementById("note_edit_text").value = ""
+		return
+	}
+
+	var note = document.getElementById("note_" + note_id)
+	idInput.value = note_id
+	document.getElementById("note_edit_title").value = note.getElementsByClassName("note_title")[0].innerText
+	document.getElementById("note_edit_text").value = note.getElementsByClassName("note_text")[0].innerText
+}
+
+// submitNote creates a new note, or updates the note currently loaded in the note editor.
+function submitNote() {
+	var urlOrigin = window.location.origin
+	var archive_id = getArchiveID()
+	if (archive_id == null) {
+		setStatus("error: got invalid archive_id on note edit")
+		return
+	}
+
+	const formData = new FormData();
+	formData.append("title", document.getElementById("note_edit_title").value)
+	formData.append("text", document.getElementById("note_edit_text").value)
+
+	var note_id = document.getElementById("note_edit_id").value
+	var url = urlOrigin + "/api/entry/" + archive_id + "/notes"
+	var method = 'POST'
+	if (note_id != "") {
+		url += "/" + note_id
+		method = 'PUT'
+	}
+
+	fetch(url, {
+		method: method,
+		body: formData,
+	})
+	.then(response => {
+		if (!response.ok) {
+			response.json().then(body => setStatus("error: " + body.message))
+			return
+		}
+		location.reload();
+	})
+}
+
+function deleteNote(note_id) {
+	var urlOrigin = window.location.origin
+	var archive_id = getArchiveID()
+	if (archive_id == null) {
+		setStatus("error: got invalid archive_id on note delete")
+		return
+	}
+
+	fetch(urlOrigin + "/api/entry/" + archive_id + "/notes/" + note_id, {
+		method: 'DELETE',
+	})
+	.then(response => {
+		if (!response.ok) {
+			response.json().then(body => setStatus("error: " + body.message))
+			return
+		}
+		location.reload();
+	})
+}
+
+// loadSimilar fills the "similar images" strip with entries that have a close perceptual hash.
+// The strip stays hidden if the entry has no perceptual hash or no similar entries.
+function loadSimilar() {
+	var urlOrigin = window.location.origin
+	var archive_id = getArchiveID()
+	if (archive_id == null) {
+		return
+	}
+
+	fetch(urlOrigin + "/api/entry/" + archive_id + "/similar?limit=20")
+	.then(response => {
+		if (!response.ok) {
+			return []
+		}
+		return response.json()
+	})
+	.then(similar => {
+		if (similar.length == 0) {
+			return
+		}
+
+		var list = document.getElementById("similar_list")
+		for (let i = 0; i < similar.length; i++) {
+			var link = document.createElement("a")
+			link.href = "/post/entry/" + similar[i].archive_id
+			link.title = "distance " + similar[i].distance
+
+			var img = document.createElement("img")
+			img.src = "/thumbnail/" + similar[i].archive_id
+			img.className = "h-32 w-auto min-w-16 object-cover rounded-lg"
+
+			link.appendChild(img)
+			list.appendChild(link)
+		}
+		document.getElementById("similar").hidden = false
+	})
+}
+
+// showPreview swaps the thumbnail of a browsed entry for its animated preview.
+function showPreview(img) {
+	img.dataset.thumbnail = img.src
+	img.src = img.dataset.preview
+}
+
+// hidePreview restores the thumbnail of a browsed entry replaced by showPreview.
+function hidePreview(img) {
+	if (img.dataset.thumbnail) {
+		img.src = img.dataset.thumbnail
+	}
+}
+
+// initStoryboard shows the frame of a video's storyboard under the cursor while hovering over its seek bar.
+function initStoryboard(video) {
+	var track = video.querySelector("track[kind=metadata]")
+	var preview = document.getElementById("storyboard_preview")
+	// cues of metadata tracks are only loaded once the track is no longer disabled
+	track.track.mode = "hidden"
+
+	// the seek bar of most browsers spans the bottom of a video
+	const seekBarHeight = 48
+
+	video.addEventListener("mousemove", function (e) {
+		var rect = video.getBoundingClientRect()
+		var cues = track.track.cues
+		if (e.clientY < rect.bottom - seekBarHeight || !video.duration || cues == null) {
+			preview.hidden = true
+			return
+		}
+
+		var time = (e.clientX - rect.left) / rect.width * video.duration
+		var cue = null
+		for (let i = 0; i < cues.length; i++) {
+			if (time >= cues[i].startTime && time < cues[i].endTime) {
+				cue = cues[i]
+				break
+			}
+		}
+		if (cue == null) {
+			preview.hidden = true
+			return
+		}
+
+		// cues reference their frame as "sprite#xywh=x,y,w,h", relative to the track
+		var [sprite, xywh] = cue.text.trim().split("#xywh=")
+		var [x, y, w, h] = xywh.split(",").map(Number)
+
+		preview.style.backgroundImage = "url(" + new URL(sprite, track.src) + ")"
+		preview.style.backgroundPosition = -x + "px " + -y + "px"
+		preview.style.width = w + "px"
+		preview.style.height = h + "px"
+		preview.style.left = video.offsetLeft + (e.clientX - rect.left) - w / 2 + "px"
+		preview.style.top = video.offsetTop + rect.height - seekBarHeight - h + "px"
+		preview.hidden = false
+	})
+
+	video.addEventListener("mouseleave", function () {
+		preview.hidden = true
+	})
+}
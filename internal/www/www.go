@@ -38,7 +38,10 @@ type searchOptions struct {
 type Config struct {
 	DynamicWebReloading     bool
 	DynamicWebReloadingPath string
-	Log                     *slog.Logger
+	// ScrubMetadata removes the location and device metadata of every JPEG, PNG and WebP served, rather than
+	// only when a request asks for it.
+	ScrubMetadata bool
+	Log           *slog.Logger
 }
 
 type Template struct {
@@ -84,25 +87,37 @@ func (w WWW) Start(ListenAddress string) error {
 }
 
 func (w WWW) init() {
-	w.echo.Static("media", w.api.Config.MediaLocation)
 	w.echo.HideBanner = true
 	w.echo.HTTPErrorHandler = w.errorHandler
 
 	w.deleteEntry()
+	w.deleteNote()
 	w.entry()
 	w.getFile()
 	w.getHashes()
+	w.getJob()
+	w.getJobs()
+	w.getNotes()
+	w.getSimilar()
+	w.getStoryboard()
+	w.getStoryboardSprite()
 	w.getTimestamps()
+	w.newNote()
 	w.removeTags()
 	w.replaceTags()
 	w.replaceTags()
+	w.retryJob()
+	w.searchNotes()
 	w.setTimestamps()
+	w.updateNote()
 	w.upload()
 
 	w.Root()
+	w.Media()
 	w.Post()
 	w.Browse()
 	w.Thumbnail()
+	w.Preview()
 }
 
 func (w WWW) Root() {
@@ -160,4 +175,5 @@ func (w WWW) Shutdown(ctx context.Context) error {
 
 var templateFuncMap = map[string]any{
 	"add": add,
+	"neg": neg,
 }